@@ -1,81 +1,35 @@
 #!/usr/bin/env bash
 # detect-codemap.sh — SessionStart hook
-# 检测 codegraph 二进制和 .codemap/ 图谱状态
-# 查找优先级: PATH > ~/.codemap/bin/ > 插件目录 > 开发构建
+# 检测 codegraph 二进制和 .codemap/ 图谱状态，并输出上次会话以来的变化摘要
 
-# ── 平台检测 ──────────────────────────────────────────────────────────────────
+# ── 查找 codegraph 二进制 ─────────────────────────────────────────────────────
 
-case "$(uname -s 2>/dev/null)" in
-  Linux*)   _OS="linux" ;;
-  Darwin*)  _OS="macos" ;;
-  MINGW*|MSYS*|CYGWIN*) _OS="windows" ;;
-  *)        _OS="" ;;
-esac
-
-case "$(uname -m 2>/dev/null)" in
-  x86_64|amd64)  _ARCH="x86_64" ;;
-  aarch64|arm64) _ARCH="aarch64" ;;
-  *)             _ARCH="" ;;
-esac
-
-_BIN_NAME=""
-if [ -n "$_OS" ] && [ -n "$_ARCH" ]; then
-  _BIN_NAME="codegraph-${_ARCH}-${_OS}"
-  [ "$_OS" = "windows" ] && _BIN_NAME="${_BIN_NAME}.exe"
-fi
-
-CODEMAP_HOME="${CODEMAP_HOME:-$HOME/.codemap}"
-CODEMAP_BIN_DIR="${CODEMAP_HOME}/bin"
-
-# ── 多级查找 codegraph 二进制 ─────────────────────────────────────────────────
-
-CODEGRAPH_BIN=""
-
-# 1. PATH 中的 codegraph（用户全局安装）
-if command -v codegraph >/dev/null 2>&1; then
-  CODEGRAPH_BIN="codegraph"
-fi
-
-# 1b. PATH 中的 arch-specific 名称
-if [ -z "$CODEGRAPH_BIN" ] && [ -n "$_BIN_NAME" ]; then
-  if command -v "$_BIN_NAME" >/dev/null 2>&1; then
-    CODEGRAPH_BIN="$(command -v "$_BIN_NAME")"
-  fi
-fi
-
-# 2. ~/.codemap/bin/（用户级专用目录）
-if [ -z "$CODEGRAPH_BIN" ] && [ -n "$_BIN_NAME" ] && [ -f "${CODEMAP_BIN_DIR}/${_BIN_NAME}" ]; then
-  CODEGRAPH_BIN="${CODEMAP_BIN_DIR}/${_BIN_NAME}"
-fi
-
-# 3. 插件目录（向后兼容）
-if [ -z "$CODEGRAPH_BIN" ] && [ -n "$CLAUDE_PLUGIN_ROOT" ] && [ -n "$_BIN_NAME" ]; then
-  if [ -f "$CLAUDE_PLUGIN_ROOT/bin/${_BIN_NAME}" ]; then
-    CODEGRAPH_BIN="$CLAUDE_PLUGIN_ROOT/bin/${_BIN_NAME}"
-  fi
-fi
-
-# 4. 开发构建 (rust-cli/target/)
-if [ -z "$CODEGRAPH_BIN" ]; then
-  _DEV_NAME="codegraph"
-  [ "$_OS" = "windows" ] && _DEV_NAME="codegraph.exe"
-  for _CANDIDATE in \
-    "./rust-cli/target/release/${_DEV_NAME}" \
-    "./rust-cli/target/release/${_DEV_NAME}" \
-    "./rust-cli/target/debug/${_DEV_NAME}" \
-    "./rust-cli/target/debug/${_DEV_NAME}"
-  do
-    if [ -f "$_CANDIDATE" ]; then
-      CODEGRAPH_BIN="$_CANDIDATE"
-      break
-    fi
-  done
-fi
+. "$(dirname "${BASH_SOURCE[0]}")/find-codegraph.sh"
 
 # ── 检测 .codemap/ 图谱 ───────────────────────────────────────────────────────
 
 if [ -f ".codemap/graph.json" ]; then
-  echo "[CodeMap] 检测到 .codemap/ 图谱已存在。建议使用 /codemap:load 加载项目上下文，或 /codemap:update 更新图谱。"
+  # 快速新鲜度检查：只比对文件大小/修改时间，退出码 0=最新，2=过期
+  _STATUS_LINE=""
+  _STATUS_CODE=1
+  if [ -n "$CODEGRAPH_BIN" ]; then
+    _STATUS_LINE="$("$CODEGRAPH_BIN" status --check --format compact 2>/dev/null)"
+    _STATUS_CODE=$?
+  fi
+  if [ "$_STATUS_CODE" -eq 0 ]; then
+    echo "$_STATUS_LINE"
+    echo "[CodeMap] 图谱是最新的。建议使用 /codemap:load 加载项目上下文。"
+  elif [ "$_STATUS_CODE" -eq 2 ]; then
+    echo "$_STATUS_LINE"
+    echo "[CodeMap] 图谱已过期。建议先使用 /codemap:update 增量更新，再 /codemap:load 加载项目上下文。"
+  else
+    echo "[CodeMap] 检测到 .codemap/ 图谱已存在。建议使用 /codemap:load 加载项目上下文，或 /codemap:update 更新图谱。"
+  fi
+  # 上次会话以来的结构变化（新增/删除模块、公开签名变化、新依赖环）
+  # 未更新区域已由上面的 status --check 报告，--no-stale 避免再计算一遍文件哈希
+  if [ -n "$CODEGRAPH_BIN" ]; then
+    "$CODEGRAPH_BIN" brief --no-stale 2>/dev/null
+  fi
 else
   echo "[CodeMap] 未检测到 .codemap/ 图谱。如需生成代码图谱，请使用 /codemap:scan。"
 fi
@@ -0,0 +1,17 @@
+#!/usr/bin/env bash
+# pre-edit-context.sh — PreToolUse hook（Edit / Write / MultiEdit）
+# 修改源文件前注入 codegraph context：大纲、导入方、依赖方模块、负责人与测试
+# 任何失败都静默放行，绝不阻塞工具调用
+
+[ -f ".codemap/graph.json" ] || exit 0
+
+# ── 查找 codegraph 二进制 ─────────────────────────────────────────────────────
+
+. "$(dirname "${BASH_SOURCE[0]}")/find-codegraph.sh"
+[ -n "$CODEGRAPH_BIN" ] || exit 0
+
+# ── 输出 PreToolUse additionalContext（文件不在图谱中时无输出）─────────────────
+
+# stdin 的 hook JSON 原样交给 codegraph，由 JSON 解析器取出 tool_input.file_path；非源文件不加载图谱
+"$CODEGRAPH_BIN" context --format hook --hook-input 2>/dev/null
+exit 0
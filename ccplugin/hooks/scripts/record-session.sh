@@ -0,0 +1,11 @@
+#!/usr/bin/env bash
+# record-session.sh — SessionEnd hook
+# 记录会话结束时的图谱结构（.codemap/session.json），供下次会话开始时 brief 对比
+
+[ -f ".codemap/graph.json" ] || exit 0
+
+. "$(dirname "${BASH_SOURCE[0]}")/find-codegraph.sh"
+[ -n "$CODEGRAPH_BIN" ] || exit 0
+
+"$CODEGRAPH_BIN" brief --record >/dev/null 2>&1
+exit 0
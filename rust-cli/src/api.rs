@@ -0,0 +1,211 @@
+/// 嵌入式 API
+///
+/// 供其他 Rust 程序（如自建索引服务）直接调用的稳定入口。
+/// 与 CLI 命令不同，这里的函数不打印输出、不调用 `process::exit`，
+/// 找不到目标时统一返回 `Err`，结果类型均可直接序列化为 JSON。
+///
+/// ```ignore
+/// use codegraph::api::{self, QueryOptions, ScanOptions};
+///
+/// let graph = ScanOptions::new()
+///     .exclude("vendor/**")
+///     .on_progress(|p| eprintln!("[{}/{}] {}", p.current, p.total, p.path))
+///     .scan_and_save(root)?;
+/// let hits = api::query(&graph, "login", &QueryOptions::default())?;
+/// let impact = api::impact(&graph, "auth", 3)?;
+/// let slice = api::slice(&graph, "auth")?;
+/// ```
+use std::path::{Path, PathBuf};
+
+use crate::slicer::{build_module_slice, generate_overview, get_module_slice_with_deps};
+
+pub use crate::differ::ChangeSet;
+pub use crate::graph::CodeGraph as Graph;
+pub use crate::impact::{ImpactResult, TargetType};
+pub use crate::query::{CallerRef, LineRange, ModuleResult, QueryOptions, SymbolResult};
+pub use crate::scanner::{ProgressCallback, ScanOptions, ScanProgress, UpdateOutcome};
+pub use crate::slicer::{ModuleSlice, ModuleSliceWithDeps, Overview};
+
+/// 可用于 `QueryOptions::type_filter` 的符号类型
+pub const SYMBOL_KINDS: &[&str] = &["function", "class", "type", "variable", "config"];
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 项目根目录下的图谱输出目录（`<root>/.codemap`）
+pub fn codemap_dir(root_dir: &Path) -> PathBuf {
+    root_dir.join(".codemap")
+}
+
+/// 加载项目根目录下已生成的图谱
+pub fn load(root_dir: &Path) -> anyhow::Result<Graph> {
+    Graph::load(&codemap_dir(root_dir))
+}
+
+/// 按名称搜索符号（精确或子串匹配）；类型过滤值非法时返回错误
+pub fn query(
+    graph: &Graph,
+    symbol: &str,
+    opts: &QueryOptions,
+) -> anyhow::Result<Vec<SymbolResult>> {
+    if let Some(kind) = opts.type_filter.as_deref() {
+        if !SYMBOL_KINDS.contains(&kind) {
+            anyhow::bail!(
+                "unknown symbol type '{}' (expected one of: {})",
+                kind,
+                SYMBOL_KINDS.join(", ")
+            );
+        }
+    }
+    Ok(crate::query::query_symbol(graph, symbol, opts))
+}
+
+/// 查询模块的文件与依赖关系
+pub fn query_module(graph: &Graph, module: &str) -> anyhow::Result<ModuleResult> {
+    crate::query::query_module(graph, module).ok_or_else(|| module_not_found(graph, module))
+}
+
+/// 分析修改模块或文件的影响范围；目标不存在时返回错误
+pub fn impact(graph: &Graph, target: &str, max_depth: u32) -> anyhow::Result<ImpactResult> {
+    if crate::impact::find_target(graph, target).is_none() {
+        anyhow::bail!("'{}' matches no module or file in the graph", target);
+    }
+    Ok(crate::impact::analyze_impact(graph, target, max_depth))
+}
+
+/// 生成单个模块的切片
+pub fn slice(graph: &Graph, module: &str) -> anyhow::Result<ModuleSlice> {
+    let mod_data = graph
+        .modules
+        .get(module)
+        .ok_or_else(|| module_not_found(graph, module))?;
+    Ok(build_module_slice(graph, module, mod_data))
+}
+
+/// 生成模块切片并附带其依赖模块的导出与统计
+pub fn slice_with_deps(graph: &Graph, module: &str) -> anyhow::Result<ModuleSliceWithDeps> {
+    if !graph.modules.contains_key(module) {
+        return Err(module_not_found(graph, module));
+    }
+    get_module_slice_with_deps(graph, module)
+}
+
+/// 生成项目概览
+pub fn overview(graph: &Graph) -> Overview {
+    generate_overview(graph)
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+fn module_not_found(graph: &Graph, module: &str) -> anyhow::Error {
+    let mut mods: Vec<&str> = graph.modules.keys().map(|s| s.as_str()).collect();
+    mods.sort();
+    anyhow::anyhow!(
+        "module '{}' not found (available: {})",
+        module,
+        mods.join(", ")
+    )
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::{create_empty_graph, FileEntry, FunctionInfo, ModuleEntry};
+
+    fn make_graph() -> Graph {
+        let mut graph = create_empty_graph("demo", "/tmp/demo");
+        graph.files.insert(
+            "src/auth/login.ts".to_string(),
+            FileEntry {
+                language: "typescript".to_string(),
+                module: "auth".to_string(),
+                hash: "sha256:0000000000000000".to_string(),
+                lines: 10,
+                functions: vec![FunctionInfo {
+                    name: "login".to_string(),
+                    signature: "login(user)".to_string(),
+                    start_line: 1,
+                    end_line: 5,
+                }],
+                exports: vec!["login".to_string()],
+                ..Default::default()
+            },
+        );
+        graph.modules.insert(
+            "auth".to_string(),
+            ModuleEntry {
+                files: vec!["src/auth/login.ts".to_string()],
+                depends_on: vec![],
+                depended_by: vec![],
+            },
+        );
+        graph
+    }
+
+    #[test]
+    fn test_query_rejects_unknown_type_filter() {
+        let graph = make_graph();
+        let opts = QueryOptions {
+            type_filter: Some("macro".to_string()),
+        };
+        assert!(query(&graph, "login", &opts).is_err());
+        let ok = query(&graph, "login", &QueryOptions::default()).unwrap();
+        assert_eq!(ok.len(), 1);
+    }
+
+    #[test]
+    fn test_query_module_not_found_lists_available() {
+        let graph = make_graph();
+        let err = query_module(&graph, "billing").unwrap_err().to_string();
+        assert!(err.contains("billing"));
+        assert!(err.contains("auth"));
+        assert_eq!(query_module(&graph, "auth").unwrap().files.len(), 1);
+    }
+
+    #[test]
+    fn test_impact_unknown_target_is_error() {
+        let graph = make_graph();
+        assert!(impact(&graph, "nowhere", 3).is_err());
+        let res = impact(&graph, "login.ts", 3).unwrap();
+        assert_eq!(res.target_type, TargetType::File);
+        assert_eq!(res.target_module, "auth");
+    }
+
+    #[test]
+    fn test_slice_results() {
+        let graph = make_graph();
+        assert!(slice(&graph, "missing").is_err());
+        assert!(slice_with_deps(&graph, "missing").is_err());
+        let s = slice(&graph, "auth").unwrap();
+        assert_eq!(s.module, "auth");
+        assert_eq!(overview(&graph).modules.len(), 1);
+    }
+
+    #[test]
+    fn test_results_serialize_camel_case() {
+        let graph = make_graph();
+        let res = impact(&graph, "auth", 3).unwrap();
+        let json = serde_json::to_string(&res).unwrap();
+        assert!(json.contains("\"targetType\":\"module\""));
+        assert!(json.contains("\"impactedFiles\""));
+    }
+
+    #[test]
+    fn test_graph_save_and_load_roundtrip() {
+        let dir = std::env::temp_dir().join(format!("codegraph_api_{}", std::process::id()));
+        let graph = make_graph();
+        graph.save(&codemap_dir(&dir)).unwrap();
+        let loaded = load(&dir).unwrap();
+        assert_eq!(loaded.files.len(), 1);
+        assert!(Graph::load(&dir.join("missing")).is_err());
+        let _ = std::fs::remove_dir_all(&dir);
+    }
+
+    #[test]
+    fn test_scan_options_rejects_missing_dir() {
+        let opts = ScanOptions::new().exclude("vendor/**");
+        assert_eq!(opts.exclude_patterns(), ["vendor/**".to_string()]);
+        assert!(opts.scan(Path::new("/definitely/not/here")).is_err());
+    }
+}
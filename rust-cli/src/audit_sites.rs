@@ -0,0 +1,440 @@
+/// 安全审查点清单（audit-sites）
+///
+/// 逐行匹配各语言的高风险写法并标记为审查点，归属到所在的函数（或类）：
+/// - `unsafe`：Rust `unsafe` 块 / 函数 / impl，Go `unsafe.Pointer`
+/// - `ffi`：`extern "C"`、cgo `import "C"`、JNI（`native` 方法、`System.loadLibrary`、`JNIEXPORT`）、
+///   ctypes / cffi 加载动态库
+/// - `exec`：`eval` / `exec`、`child_process`、`subprocess(shell=True)`、`os.system`、
+///   `exec.Command`、`Command::new`、`system` / `popen` 等
+/// - `sql`：在字符串中拼接 SQL（`+`、模板插值、f-string、`format` / `Sprintf` 等）
+///
+/// 关键字只在代码中匹配（模块名也可出现在 import 字符串中），注释里的同名文本不计；
+/// SQL 语句须出现在字符串字面量中。结果用于人工审查而非精确判定。
+use serde::Serialize;
+use std::collections::{BTreeMap, HashSet, VecDeque};
+
+use crate::graph::{AuditSite, ClassInfo, CodeGraph, FunctionInfo};
+use crate::source_text::{enclosing_symbol, Region, SourceLine, SourceText};
+use crate::traverser::Language;
+
+/// 支持的标签
+pub const AUDIT_TAGS: &[&str] = &["unsafe", "ffi", "exec", "sql"];
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 带位置与可达入口的审查点
+#[derive(Debug, Clone, Serialize)]
+pub struct AuditFinding {
+    pub file: String,
+    pub module: String,
+    pub line: u32,
+    pub tag: String,
+    pub detail: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub symbol: Option<String>,
+    /// 能到达该审查点的入口文件（入口所在模块等于或传递依赖审查点所在模块）
+    #[serde(rename = "entryPoints")]
+    pub entry_points: Vec<String>,
+}
+
+/// 单行匹配规则
+struct Rule {
+    needle: &'static str,
+    tag: &'static str,
+    detail: &'static str,
+    /// 要求 needle 前不是标识符字符或 `.`（`eval(` 不匹配 `obj.eval(`）
+    word: bool,
+    /// 模块名：也可出现在字符串中（`require('child_process')`）
+    module: bool,
+}
+
+const fn rule(needle: &'static str, tag: &'static str, detail: &'static str) -> Rule {
+    Rule {
+        needle,
+        tag,
+        detail,
+        word: true,
+        module: false,
+    }
+}
+
+/// 前面可以是任意字符的规则（链式调用等）
+const fn anywhere(needle: &'static str, tag: &'static str, detail: &'static str) -> Rule {
+    Rule {
+        needle,
+        tag,
+        detail,
+        word: false,
+        module: false,
+    }
+}
+
+/// 模块名规则：代码或字符串中出现均可
+const fn module(needle: &'static str, tag: &'static str, detail: &'static str) -> Rule {
+    Rule {
+        needle,
+        tag,
+        detail,
+        word: false,
+        module: true,
+    }
+}
+
+const RUST_RULES: &[Rule] = &[
+    rule("unsafe {", "unsafe", "unsafe block"),
+    rule("unsafe fn", "unsafe", "unsafe fn"),
+    rule("unsafe impl", "unsafe", "unsafe impl"),
+    rule("unsafe trait", "unsafe", "unsafe trait"),
+    rule("extern \"", "ffi", "extern ABI"),
+    anywhere("#[link(", "ffi", "native library link"),
+    rule("Command::new(", "exec", "process spawn"),
+];
+
+const GO_RULES: &[Rule] = &[
+    rule("import \"C\"", "ffi", "cgo"),
+    rule("unsafe.Pointer", "unsafe", "unsafe.Pointer"),
+    rule("exec.Command(", "exec", "exec.Command"),
+    rule("exec.CommandContext(", "exec", "exec.Command"),
+    rule("syscall.Exec(", "exec", "syscall.Exec"),
+];
+
+const JAVA_RULES: &[Rule] = &[
+    rule("native ", "ffi", "JNI native method"),
+    rule("System.loadLibrary(", "ffi", "JNI library load"),
+    rule("System.load(", "ffi", "JNI library load"),
+    anywhere(".exec(", "exec", "Runtime.exec"),
+    rule("new ProcessBuilder(", "exec", "ProcessBuilder"),
+];
+
+const PYTHON_RULES: &[Rule] = &[
+    rule("ctypes.CDLL(", "ffi", "ctypes"),
+    rule("CDLL(", "ffi", "ctypes"),
+    rule("ctypes.cdll.", "ffi", "ctypes"),
+    rule("cdll.LoadLibrary(", "ffi", "ctypes"),
+    rule("ffi.dlopen(", "ffi", "cffi"),
+    rule("eval(", "exec", "eval"),
+    rule("exec(", "exec", "exec"),
+    rule("os.system(", "exec", "os.system"),
+    rule("os.popen(", "exec", "os.popen"),
+    anywhere("shell=True", "exec", "subprocess shell=True"),
+];
+
+const JS_RULES: &[Rule] = &[
+    rule("eval(", "exec", "eval"),
+    rule("new Function(", "exec", "new Function"),
+    module("child_process", "exec", "child_process"),
+    rule("exec(", "exec", "child_process exec"),
+    rule("execSync(", "exec", "child_process exec"),
+    rule("spawn(", "exec", "child_process spawn"),
+    rule("spawnSync(", "exec", "child_process spawn"),
+    module("ffi-napi", "ffi", "ffi-napi"),
+    module("koffi", "ffi", "koffi"),
+];
+
+const C_RULES: &[Rule] = &[
+    rule("system(", "exec", "system"),
+    rule("popen(", "exec", "popen"),
+    rule("execl(", "exec", "exec*"),
+    rule("execlp(", "exec", "exec*"),
+    rule("execv(", "exec", "exec*"),
+    rule("execvp(", "exec", "exec*"),
+    rule("execve(", "exec", "exec*"),
+    rule("JNIEXPORT", "ffi", "JNI export"),
+];
+
+const CPP_RULES: &[Rule] = &[rule("extern \"C\"", "ffi", "extern \"C\"")];
+
+/// SQL 语句开头（小写比对）及其必须伴随的关键字
+const SQL_STATEMENTS: &[(&str, &str)] = &[
+    ("select ", " from "),
+    ("insert into ", ""),
+    ("update ", " set "),
+    ("delete from ", ""),
+];
+
+/// 字符串拼接 / 格式化的迹象
+const SQL_BUILDERS: &[&str] = &[
+    "\" +",
+    "' +",
+    "+ \"",
+    "+ '",
+    "${",
+    "f\"",
+    "f'",
+    ".format(",
+    "format!(",
+    "sprintf(",
+    "Sprintf(",
+    "String.format(",
+    "\" %",
+    "' %",
+    "\" .. ",
+];
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 从源码中提取审查点，按行号排序；同一行同一标签只记一次
+pub fn extract_audit_sites(
+    lang: Language,
+    source: &SourceText,
+    functions: &[FunctionInfo],
+    classes: &[ClassInfo],
+) -> Vec<AuditSite> {
+    let rules: Vec<&Rule> = match lang {
+        Language::Rust => RUST_RULES.iter().collect(),
+        Language::Go => GO_RULES.iter().collect(),
+        Language::Java => JAVA_RULES.iter().collect(),
+        Language::Python => PYTHON_RULES.iter().collect(),
+        Language::TypeScript | Language::JavaScript => JS_RULES.iter().collect(),
+        Language::C => C_RULES.iter().collect(),
+        Language::Cpp => C_RULES.iter().chain(CPP_RULES).collect(),
+    };
+    let mut sites = Vec::new();
+    for (idx, line) in source.lines().iter().enumerate() {
+        if !line.has_code() {
+            continue;
+        }
+        let line_no = idx as u32 + 1;
+        let mut tags: HashSet<&str> = HashSet::new();
+        let mut push = |tag: &'static str, detail: &str| {
+            if tags.insert(tag) {
+                sites.push(AuditSite {
+                    tag: tag.to_string(),
+                    detail: detail.to_string(),
+                    line: line_no,
+                    symbol: enclosing_symbol(functions, classes, line_no),
+                });
+            }
+        };
+        for r in &rules {
+            if contains_rule(line, r) {
+                push(r.tag, r.detail);
+            }
+        }
+        if is_sql_building(line) {
+            push("sql", "SQL string building");
+        }
+    }
+    sites
+}
+
+/// 能到达 module 的入口文件：入口所在模块为 module 本身或其传递依赖方（不限深度）
+pub fn entry_points_reaching(graph: &CodeGraph, module: &str) -> Vec<String> {
+    let mut reached: HashSet<&str> = HashSet::from([module]);
+    let mut queue: VecDeque<&str> = VecDeque::from([module]);
+    while let Some(current) = queue.pop_front() {
+        let Some(entry) = graph.modules.get(current) else {
+            continue;
+        };
+        for dep in &entry.depended_by {
+            if reached.insert(dep) {
+                queue.push_back(dep);
+            }
+        }
+    }
+    graph
+        .files
+        .iter()
+        .filter(|(_, f)| f.is_entry_point && reached.contains(f.module.as_str()))
+        .map(|(path, _)| path.clone())
+        .collect()
+}
+
+/// 汇总指定文件中的审查点（按文件、行号排序），附带可达入口；tag 为 None 表示全部
+pub fn collect_findings<'a>(
+    graph: &CodeGraph,
+    files: impl IntoIterator<Item = &'a String>,
+    tag: Option<&str>,
+) -> Vec<AuditFinding> {
+    let mut entry_cache: BTreeMap<String, Vec<String>> = BTreeMap::new();
+    let mut findings = Vec::new();
+    for path in files {
+        let Some(entry) = graph.files.get(path) else {
+            continue;
+        };
+        for site in &entry.audit_sites {
+            if tag.is_some_and(|t| t != site.tag) {
+                continue;
+            }
+            let entry_points = entry_cache
+                .entry(entry.module.clone())
+                .or_insert_with(|| entry_points_reaching(graph, &entry.module))
+                .clone();
+            findings.push(AuditFinding {
+                file: path.clone(),
+                module: entry.module.clone(),
+                line: site.line,
+                tag: site.tag.clone(),
+                detail: site.detail.clone(),
+                symbol: site.symbol.clone(),
+                entry_points,
+            });
+        }
+    }
+    findings.sort_by(|a, b| a.file.cmp(&b.file).then(a.line.cmp(&b.line)));
+    findings
+}
+
+/// 单行文本：`file:line [tag] detail in symbol (entry: a, b)`
+pub fn format_finding(f: &AuditFinding) -> String {
+    let mut out = format!("{}:{} [{}] {}", f.file, f.line, f.tag, f.detail);
+    if let Some(symbol) = &f.symbol {
+        out.push_str(&format!(" in {}", symbol));
+    }
+    if f.entry_points.is_empty() {
+        out.push_str(" (no entry point)");
+    } else {
+        out.push_str(&format!(" (entry: {})", f.entry_points.join(", ")));
+    }
+    out
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+fn contains_rule(line: &SourceLine, r: &Rule) -> bool {
+    let text = line.raw;
+    text.match_indices(r.needle).any(|(pos, _)| {
+        let allowed = match line.region_at(pos) {
+            Region::Code => true,
+            Region::String => r.module,
+            Region::Comment => false,
+        };
+        allowed
+            && (!r.word
+                || !text[..pos]
+                    .chars()
+                    .next_back()
+                    .is_some_and(|c| c.is_alphanumeric() || c == '_' || c == '.'))
+    })
+}
+
+/// 字符串字面量中出现 SQL 语句，且同一行的代码有拼接或格式化
+fn is_sql_building(line: &SourceLine) -> bool {
+    let literal = line.strings().collect::<Vec<_>>().join(" ").to_lowercase();
+    let has_statement = SQL_STATEMENTS.iter().any(|(start, companion)| {
+        literal
+            .find(start)
+            .is_some_and(|i| companion.is_empty() || literal[i..].contains(companion))
+    });
+    has_statement && SQL_BUILDERS.iter().any(|b| line.code.contains(b))
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn tags(lang: Language, src: &str) -> Vec<(u32, String, Option<String>)> {
+        let functions = vec![FunctionInfo {
+            name: "handler".into(),
+            signature: String::new(),
+            start_line: 2,
+            end_line: 6,
+        }];
+        extract_audit_sites(
+            lang,
+            &SourceText::scan(lang, src.as_bytes()),
+            &functions,
+            &[],
+        )
+        .into_iter()
+        .map(|s| (s.line, s.tag, s.symbol))
+        .collect()
+    }
+
+    fn site(line: u32, tag: &str, symbol: Option<&str>) -> (u32, String, Option<String>) {
+        (line, tag.to_string(), symbol.map(String::from))
+    }
+
+    #[test]
+    fn test_extract_rust_and_python() {
+        let rs = "extern \"C\" { fn abs(x: i32) -> i32; }\n\
+                  fn handler() {\n\
+                  let v = unsafe { abs(-1) };\n\
+                  // unsafe { commented }\n\
+                  let q = format!(\"SELECT * FROM users WHERE id = {}\", id);\n\
+                  }\n\
+                  fn not_unsafe_fn() {}\n";
+        assert_eq!(
+            tags(Language::Rust, rs),
+            vec![
+                site(1, "ffi", None),
+                site(3, "unsafe", Some("handler")),
+                site(5, "sql", Some("handler")),
+            ]
+        );
+
+        let py = "import subprocess\n\
+                  def handler(cmd):\n\
+                  subprocess.run(cmd, shell=True)\n\
+                  lib = ctypes.CDLL(\"libc.so.6\")\n\
+                  cur.execute(f\"DELETE FROM t WHERE id = {x}\")\n\
+                  pattern.eval(x); obj.exec_(y)\n";
+        assert_eq!(
+            tags(Language::Python, py),
+            vec![
+                site(3, "exec", Some("handler")),
+                site(4, "ffi", Some("handler")),
+                site(5, "sql", Some("handler")),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_extract_js_go_java() {
+        let js = "const { exec } = require('child_process');\n\
+                  function handler(req) {\n\
+                  eval(req.body);\n\
+                  const m = /x/.exec(s);\n\
+                  db.query(`SELECT * FROM t WHERE name = '${req.name}'`);\n\
+                  }\n";
+        assert_eq!(
+            tags(Language::TypeScript, js),
+            vec![
+                site(1, "exec", None),
+                site(3, "exec", Some("handler")),
+                site(5, "sql", Some("handler")),
+            ]
+        );
+
+        let go = "import \"C\"\nfunc handler() {\np := unsafe.Pointer(&x)\nexec.Command(\"sh\", \"-c\", s)\n}\n";
+        assert_eq!(
+            tags(Language::Go, go),
+            vec![
+                site(1, "ffi", None),
+                site(3, "unsafe", Some("handler")),
+                site(4, "exec", Some("handler")),
+            ]
+        );
+
+        let java = "class A {\npublic native int crc(byte[] b);\nRuntime.getRuntime().exec(cmd);\n\
+                    String q = \"UPDATE users SET name = '\" + name + \"'\";\n}\n";
+        assert_eq!(
+            tags(Language::Java, java),
+            vec![
+                site(2, "ffi", Some("handler")),
+                site(3, "exec", Some("handler")),
+                site(4, "sql", Some("handler")),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_skips_strings_and_block_comments() {
+        let py = "HELP = \"use eval(expr) or os.system(cmd)\"\n\
+                  def handler(cmd):\n\
+                  \"\"\"Runs a query.\n\
+                  Never call os.system(cmd) here.\n\
+                  \"\"\"\n\
+                  log(\"SELECT a FROM t\")  # eval(x) + \"y\"\n";
+        assert_eq!(tags(Language::Python, py), vec![]);
+
+        let js = "import koffi from 'koffi';\n\
+                  /*\n\
+                  function handler() {\n\
+                  eval(req.body);\n\
+                  */\n";
+        assert_eq!(tags(Language::JavaScript, js), vec![site(1, "ffi", None)]);
+    }
+}
@@ -0,0 +1,759 @@
+/// 跨语言绑定边（PyO3、wasm-bindgen、N-API、C ABI、JNI）
+///
+/// 扫描时逐行识别每个文件的绑定声明（关键字在代码视图中匹配，名称从字符串字面量读取）：
+/// - 导出方：Rust `#[pyfunction]` / `#[pyclass]` / `#[pymodule]`、`#[wasm_bindgen]`、`#[napi]`、
+///   `#[no_mangle]`；Go `//export`；C/C++ 头文件原型、函数定义、`Java_*` JNI 实现、N-API 注册名
+/// - 调用方：Rust `extern "C" { fn … }`、cgo `C.name(`、Java `native` 方法、ctypes 的 `lib.name`、
+///   Node 加载 `.node` 原生模块；Python / JS 的 import 在建图时直接对照导出方
+///
+/// 建图后 [`link_bindings`] 把调用方与导出方连成文件级边，并补上模块依赖，
+/// 使 impact 能从 Rust 函数流向调用它的 Python 代码。
+use std::collections::{BTreeMap, BTreeSet, HashMap};
+use std::path::Path;
+
+use crate::graph::{BindingDecl, BindingEdge, ClassInfo, CodeGraph, FunctionInfo};
+use crate::path_utils::{import_lookup, resolve_relative_import};
+use crate::source_text::{SourceLine, SourceText};
+use crate::traverser::Language;
+
+/// cgo 的类型转换与内置辅助函数，不是对项目 C 函数的调用
+const CGO_BUILTINS: &[&str] = &[
+    "CString",
+    "CBytes",
+    "GoString",
+    "GoStringN",
+    "GoBytes",
+    "free",
+    "char",
+    "schar",
+    "uchar",
+    "short",
+    "ushort",
+    "int",
+    "uint",
+    "long",
+    "ulong",
+    "longlong",
+    "ulonglong",
+    "float",
+    "double",
+    "size_t",
+];
+
+/// Node 原生模块加载器：`require('bindings')('addon')`、`node-gyp-build`
+const NODE_ADDON_LOADERS: &[&str] = &["bindings", "node-gyp-build"];
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 提取文件中的绑定声明（导出与调用），按行号排序
+pub fn extract_bindings(
+    lang: Language,
+    path: &Path,
+    source: &SourceText,
+    functions: &[FunctionInfo],
+    classes: &[ClassInfo],
+) -> Vec<BindingDecl> {
+    let lines = source.lines();
+    let mut decls = match lang {
+        Language::Rust => rust_bindings(&lines),
+        Language::Go => go_bindings(&lines),
+        Language::Java => java_bindings(&lines, classes, path),
+        Language::Python => python_bindings(&lines),
+        Language::TypeScript | Language::JavaScript => node_bindings(&lines),
+        Language::C | Language::Cpp => c_bindings(&lines, path, functions),
+    };
+    decls.sort_by(|a, b| a.line.cmp(&b.line).then(a.name.cmp(&b.name)));
+    decls
+}
+
+/// 连接调用方与导出方，写入 `graph.binding_edges` 并补充模块依赖
+///
+/// 须在模块依赖重建之后调用（scan 与 update 共用）。
+pub fn link_bindings(graph: &mut CodeGraph) {
+    // (abi, name) → 导出文件
+    let mut exports: HashMap<(&str, &str), BTreeSet<&str>> = HashMap::new();
+    for (path, entry) in &graph.files {
+        for d in entry.bindings.iter().filter(|d| d.role == "export") {
+            exports
+                .entry((d.abi.as_str(), d.name.as_str()))
+                .or_default()
+                .insert(path);
+        }
+        // C/C++ 的函数定义即 C ABI 导出
+        if entry.language == "c" || entry.language == "cpp" {
+            for f in &entry.functions {
+                exports
+                    .entry(("c", f.name.as_str()))
+                    .or_default()
+                    .insert(path);
+            }
+        }
+    }
+    let napi_files: BTreeSet<&str> = exports
+        .iter()
+        .filter(|((abi, _), _)| *abi == "napi")
+        .flat_map(|(_, files)| files.iter().copied())
+        .collect();
+    let lookup = import_lookup(graph.files.keys());
+
+    // (from, to, abi, symbol) → 最早的调用行
+    let mut edges: BTreeMap<(String, String, String, String), u32> = BTreeMap::new();
+    let mut add = |from: &str, to: &str, abi: &str, symbol: &str, line: u32| {
+        if from == to {
+            return;
+        }
+        let key = (from.into(), to.into(), abi.into(), symbol.into());
+        let slot = edges.entry(key).or_insert(line);
+        *slot = (*slot).min(line);
+    };
+
+    for (path, entry) in &graph.files {
+        for d in entry.bindings.iter().filter(|d| d.role == "import") {
+            if d.abi == "napi" && d.name == "*" {
+                for to in &napi_files {
+                    add(path, to, "napi", "*", d.line);
+                }
+                continue;
+            }
+            for to in exports
+                .get(&(d.abi.as_str(), d.name.as_str()))
+                .into_iter()
+                .flatten()
+            {
+                add(path, to, &d.abi, &d.name, d.line);
+            }
+        }
+
+        match entry.language.as_str() {
+            // `import mylib` / `from mylib import add`：mylib 须是 #[pymodule]
+            "python" => {
+                for imp in &entry.imports {
+                    let root = imp.source.split('.').next().unwrap_or_default();
+                    let Some(module_files) = exports.get(&("pyo3-module", root)) else {
+                        continue;
+                    };
+                    for to in module_files {
+                        add(path, to, "pyo3", root, imp.import_line);
+                    }
+                    for symbol in imp.symbols.iter().filter(|s| **s != imp.source) {
+                        for to in exports
+                            .get(&("pyo3", symbol.as_str()))
+                            .into_iter()
+                            .flatten()
+                        {
+                            add(path, to, "pyo3", symbol, imp.import_line);
+                        }
+                    }
+                }
+            }
+            // 不指向项目内文件的 import（wasm-pack / napi-rs 生成的包）按符号名对照
+            "javascript" | "typescript" => {
+                for imp in &entry.imports {
+                    if resolve_relative_import(path, &imp.source, &lookup).is_some() {
+                        continue;
+                    }
+                    for symbol in &imp.symbols {
+                        for abi in ["wasm", "napi"] {
+                            for to in exports.get(&(abi, symbol.as_str())).into_iter().flatten() {
+                                add(path, to, abi, symbol, imp.import_line);
+                            }
+                        }
+                    }
+                }
+            }
+            _ => {}
+        }
+    }
+
+    graph.binding_edges = edges
+        .into_iter()
+        .map(|((from, to, abi, symbol), line)| BindingEdge {
+            from,
+            to,
+            abi,
+            symbol,
+            line,
+        })
+        .collect();
+
+    // 模块依赖：调用方模块依赖导出方模块
+    let pairs: BTreeSet<(String, String)> = graph
+        .binding_edges
+        .iter()
+        .filter_map(|e| {
+            let from = &graph.files.get(&e.from)?.module;
+            let to = &graph.files.get(&e.to)?.module;
+            (from != to).then(|| (from.clone(), to.clone()))
+        })
+        .collect();
+    for (from, to) in pairs {
+        if let Some(m) = graph.modules.get_mut(&from) {
+            insert_sorted(&mut m.depends_on, &to);
+        }
+        if let Some(m) = graph.modules.get_mut(&to) {
+            insert_sorted(&mut m.depended_by, &from);
+        }
+    }
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+fn decl(role: &str, abi: &str, name: &str, line: usize) -> BindingDecl {
+    BindingDecl {
+        role: role.to_string(),
+        abi: abi.to_string(),
+        name: name.to_string(),
+        line: line as u32 + 1,
+    }
+}
+
+/// Rust：绑定属性标注的条目为导出；`extern "C" { … }` 块内的 fn 为 C 调用
+fn rust_bindings(lines: &[SourceLine]) -> Vec<BindingDecl> {
+    let mut decls = Vec::new();
+    // 待定属性：(abi, 显式导出名)
+    let mut pending: Option<(&str, Option<String>)> = None;
+    // 位于 extern 块中时的花括号深度；wasm_bindgen 的 extern 块是 JS 导入，不计
+    let mut extern_depth: Option<i32> = None;
+    let mut skip_extern = false;
+
+    for (i, source_line) in lines.iter().enumerate() {
+        let line = source_line.code.trim();
+        if line.is_empty() {
+            continue;
+        }
+
+        if let Some(depth) = extern_depth.as_mut() {
+            if !skip_extern {
+                if let Some(name) = item_name(line, "fn ") {
+                    decls.push(decl("import", "c", name, i));
+                }
+            }
+            *depth += brace_delta(line);
+            if *depth <= 0 {
+                extern_depth = None;
+            }
+            continue;
+        }
+
+        if line.starts_with("#[") {
+            let abi = if line.contains("pymodule") {
+                Some("pyo3-module")
+            } else if line.contains("pyfunction") || line.contains("pyclass") {
+                Some("pyo3")
+            } else if line.contains("wasm_bindgen") {
+                Some("wasm")
+            } else if line.starts_with("#[napi") {
+                Some("napi")
+            } else if line.contains("no_mangle") || line.contains("export_name") {
+                Some("c")
+            } else {
+                None
+            };
+            let attr = source_line.raw.trim();
+            let rename = attr_string(attr, "js_name")
+                .or_else(|| attr_string(attr, "export_name"))
+                .or_else(|| attr_string(attr, "name"));
+            match (&mut pending, abi) {
+                (Some((_, name)), None) => *name = rename.or(name.take()),
+                (_, Some(abi)) => pending = Some((abi, rename)),
+                (None, None) => {}
+            }
+            continue;
+        }
+
+        let opens_extern = line.starts_with("extern")
+            || line.starts_with("unsafe extern")
+            || line.contains(" extern \"");
+        if opens_extern && line.ends_with('{') && !line.contains("fn ") {
+            skip_extern = matches!(pending, Some(("wasm", _)));
+            extern_depth = Some(brace_delta(line));
+            pending = None;
+            continue;
+        }
+
+        if let Some((abi, rename)) = pending.take() {
+            let name = item_name(line, "fn ")
+                .map(|n| (n, true))
+                .or_else(|| item_name(line, "struct ").map(|n| (n, false)))
+                .or_else(|| item_name(line, "enum ").map(|n| (n, false)));
+            if let Some((name, is_fn)) = name {
+                let exported = match rename {
+                    Some(r) => r,
+                    // napi-rs 默认把函数名转为 camelCase
+                    None if abi == "napi" && is_fn => camel_case(name),
+                    None => name.to_string(),
+                };
+                decls.push(decl("export", abi, &exported, i));
+            }
+        }
+    }
+    decls
+}
+
+/// Go：`//export Name` 为 C 导出；`import "C"` 的文件中 `C.name(` 为 C 调用
+fn go_bindings(lines: &[SourceLine]) -> Vec<BindingDecl> {
+    let mut decls = Vec::new();
+    let uses_cgo = lines
+        .iter()
+        .any(|l| l.has_code() && l.raw.trim() == "import \"C\"");
+    let mut called: BTreeSet<&str> = BTreeSet::new();
+    for (i, source_line) in lines.iter().enumerate() {
+        if !source_line.has_code() {
+            if let Some(name) = source_line
+                .comments()
+                .find_map(|c| c.trim().strip_prefix("//export "))
+            {
+                decls.push(decl("export", "c", name.trim(), i));
+            }
+            continue;
+        }
+        if !uses_cgo {
+            continue;
+        }
+        let line = source_line.code;
+        for (pos, _) in line.match_indices("C.") {
+            if pos > 0 && is_ident_byte(line.as_bytes()[pos - 1]) {
+                continue;
+            }
+            let name = ident_at(&line[pos + 2..]);
+            let is_call = line[pos + 2 + name.len()..].starts_with('(');
+            if !name.is_empty() && is_call && !CGO_BUILTINS.contains(&name) && called.insert(name) {
+                decls.push(decl("import", "c", name, i));
+            }
+        }
+    }
+    decls
+}
+
+/// Java：`native` 方法为 JNI 调用，名称为 `包.类.方法`
+fn java_bindings(lines: &[SourceLine], classes: &[ClassInfo], path: &Path) -> Vec<BindingDecl> {
+    let mut decls = Vec::new();
+    let mut package = String::new();
+    let file_class = path
+        .file_stem()
+        .map(|s| s.to_string_lossy().to_string())
+        .unwrap_or_default();
+    for (i, source_line) in lines.iter().enumerate() {
+        let line = source_line.code.trim();
+        if let Some(p) = line.strip_prefix("package ") {
+            package = p.trim_end_matches(';').trim().to_string();
+            continue;
+        }
+        if !has_word(line, "native") {
+            continue;
+        }
+        let Some(paren) = line.find('(') else {
+            continue;
+        };
+        let method = line[..paren]
+            .trim_end()
+            .rsplit(' ')
+            .next()
+            .unwrap_or_default();
+        if method.is_empty() || method == "native" {
+            continue;
+        }
+        let line_no = i as u32 + 1;
+        let class = classes
+            .iter()
+            .filter(|c| c.start_line <= line_no && line_no <= c.end_line)
+            .min_by_key(|c| c.end_line - c.start_line)
+            .map_or(file_class.as_str(), |c| c.name.as_str());
+        let key = if package.is_empty() {
+            format!("{}.{}", class, method)
+        } else {
+            format!("{}.{}.{}", package, class, method)
+        };
+        decls.push(decl("import", "jni", &key, i));
+    }
+    decls
+}
+
+/// Python：ctypes 加载的库对象上访问的函数为 C 调用
+fn python_bindings(lines: &[SourceLine]) -> Vec<BindingDecl> {
+    const LOADERS: &[&str] = &[
+        "CDLL(",
+        "PyDLL(",
+        "WinDLL(",
+        "cdll.LoadLibrary(",
+        "LoadLibrary(",
+    ];
+    let libs: Vec<&str> = lines
+        .iter()
+        .map(|l| l.code)
+        .filter(|l| LOADERS.iter().any(|p| l.contains(p)))
+        .filter_map(|l| {
+            let (lhs, _) = l.split_once('=')?;
+            let var = lhs.trim();
+            (!var.is_empty()
+                && var
+                    .chars()
+                    .all(|c| c.is_alphanumeric() || c == '_' || c == '.'))
+            .then_some(var)
+        })
+        .collect();
+
+    let mut decls = Vec::new();
+    let mut seen: BTreeSet<String> = BTreeSet::new();
+    for (i, line) in lines.iter().map(|l| l.code).enumerate() {
+        for lib in &libs {
+            let needle = format!("{}.", lib);
+            for (pos, _) in line.match_indices(&needle) {
+                if pos > 0 && is_ident_byte(line.as_bytes()[pos - 1]) {
+                    continue;
+                }
+                let name = ident_at(&line[pos + needle.len()..]);
+                if !name.is_empty() && !name.starts_with('_') && seen.insert(name.to_string()) {
+                    decls.push(decl("import", "c", name, i));
+                }
+            }
+        }
+    }
+    decls
+}
+
+/// Node：加载 `.node` 原生模块（或经 bindings / node-gyp-build）视为调用全部 N-API 导出
+fn node_bindings(lines: &[SourceLine]) -> Vec<BindingDecl> {
+    for (i, line) in lines.iter().enumerate() {
+        let loads_addon = (line.code.contains("require(") || line.code.contains("import "))
+            && line
+                .strings()
+                .any(|s| s.ends_with(".node") || NODE_ADDON_LOADERS.contains(&s));
+        if loads_addon {
+            return vec![decl("import", "napi", "*", i)];
+        }
+    }
+    vec![]
+}
+
+/// C/C++：头文件原型为 C 导出；`Java_*` 函数为 JNI 实现；N-API 注册名为 Node 导出
+fn c_bindings(lines: &[SourceLine], path: &Path, functions: &[FunctionInfo]) -> Vec<BindingDecl> {
+    let mut decls = Vec::new();
+    for f in functions {
+        if let Some(key) = f.name.strip_prefix("Java_").and_then(jni_key) {
+            decls.push(decl(
+                "export",
+                "jni",
+                &key,
+                f.start_line.saturating_sub(1) as usize,
+            ));
+        }
+    }
+
+    let is_header = path
+        .extension()
+        .and_then(|e| e.to_str())
+        .is_some_and(|e| matches!(e, "h" | "hpp" | "hh"));
+    for (i, source_line) in lines.iter().enumerate() {
+        let line = source_line.code;
+        if is_header {
+            if let Some(name) = prototype_name(line) {
+                decls.push(decl("export", "c", name, i));
+            }
+        }
+        let registers = [
+            "exports.Set(",
+            "napi_create_function(",
+            "DECLARE_NAPI_METHOD(",
+            "Napi::Function::New(",
+        ]
+        .iter()
+        .any(|p| line.contains(p));
+        if registers {
+            if let Some(name) = source_line.strings().next() {
+                decls.push(decl("export", "napi", name, i));
+            }
+        }
+    }
+    decls
+}
+
+/// 顶格、以 `);` 结尾的函数原型：`int add(int a, int b);`
+fn prototype_name(line: &str) -> Option<&str> {
+    let t = line.trim_end();
+    if !t.ends_with(");") || line.starts_with(char::is_whitespace) {
+        return None;
+    }
+    if ["#", "*", "typedef", "return", "}"]
+        .iter()
+        .any(|p| t.starts_with(p))
+    {
+        return None;
+    }
+    let head = t[..t.find('(')?].trim_end();
+    let name = head.rsplit([' ', '*', '&']).next()?;
+    // 须有返回类型，且不是宏调用
+    (name.len() < head.len() && !name.is_empty() && is_identifier(name)).then_some(name)
+}
+
+/// `Java_com_example_Native_1Lib_crc` → `com.example.Native_Lib.crc`（`_1` 为转义的下划线）
+fn jni_key(mangled: &str) -> Option<String> {
+    let parts: Vec<String> = mangled
+        .replace("_1", "\u{1}")
+        .split('_')
+        .map(|p| p.replace('\u{1}', "_"))
+        .collect();
+    (parts.len() >= 2 && parts.iter().all(|p| !p.is_empty())).then(|| parts.join("."))
+}
+
+/// `pub fn name(`、`struct Name {` 等条目声明中的名称
+fn item_name<'a>(line: &'a str, keyword: &str) -> Option<&'a str> {
+    let pos = line
+        .match_indices(keyword)
+        .map(|(p, _)| p)
+        .find(|&p| p == 0 || line.as_bytes()[p - 1] == b' ')?;
+    let name = ident_at(&line[pos + keyword.len()..]);
+    (!name.is_empty()).then_some(name)
+}
+
+/// 属性中的 `key = "value"`
+fn attr_string(line: &str, key: &str) -> Option<String> {
+    let pos = line
+        .match_indices(key)
+        .map(|(p, _)| p)
+        .find(|&p| p == 0 || !is_ident_byte(line.as_bytes()[p - 1]))?;
+    let rest = line[pos + key.len()..].trim_start().strip_prefix('=')?;
+    first_string(rest).map(String::from)
+}
+
+fn first_string(s: &str) -> Option<&str> {
+    let start = s.find('"')? + 1;
+    let len = s[start..].find('"')?;
+    (len > 0).then(|| &s[start..start + len])
+}
+
+fn ident_at(s: &str) -> &str {
+    let end = s
+        .find(|c: char| !(c.is_alphanumeric() || c == '_'))
+        .unwrap_or(s.len());
+    &s[..end]
+}
+
+fn is_identifier(s: &str) -> bool {
+    s.chars().all(|c| c.is_alphanumeric() || c == '_')
+        && !s.starts_with(|c: char| c.is_ascii_digit())
+}
+
+fn is_ident_byte(b: u8) -> bool {
+    b.is_ascii_alphanumeric() || b == b'_'
+}
+
+fn has_word(line: &str, word: &str) -> bool {
+    line.split(|c: char| !(c.is_alphanumeric() || c == '_'))
+        .any(|w| w == word)
+}
+
+fn brace_delta(line: &str) -> i32 {
+    line.chars().fold(0, |d, c| match c {
+        '{' => d + 1,
+        '}' => d - 1,
+        _ => d,
+    })
+}
+
+/// snake_case → camelCase（napi-rs 的默认导出名）
+fn camel_case(name: &str) -> String {
+    let mut out = String::new();
+    let mut upper = false;
+    for c in name.chars() {
+        if c == '_' && !out.is_empty() {
+            upper = true;
+        } else if upper {
+            out.extend(c.to_uppercase());
+            upper = false;
+        } else {
+            out.push(c);
+        }
+    }
+    out
+}
+
+fn insert_sorted(list: &mut Vec<String>, value: &str) {
+    if let Err(pos) = list.binary_search_by(|v| v.as_str().cmp(value)) {
+        list.insert(pos, value.to_string());
+    }
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::{create_empty_graph, FileEntry, ImportInfo, ModuleEntry};
+
+    fn names(decls: &[BindingDecl]) -> Vec<String> {
+        decls
+            .iter()
+            .map(|d| format!("{} {} {}", d.role, d.abi, d.name))
+            .collect()
+    }
+
+    fn extract(lang: Language, path: &str, src: &str, functions: &[FunctionInfo]) -> Vec<String> {
+        names(&extract_bindings(
+            lang,
+            Path::new(path),
+            &SourceText::scan(lang, src.as_bytes()),
+            functions,
+            &[],
+        ))
+    }
+
+    #[test]
+    fn test_extract_rust_bindings() {
+        let src = "#[pyfunction]\n\
+                   /// doc\n\
+                   pub fn add(a: i64, b: i64) -> i64 { a + b }\n\
+                   #[pymodule]\n\
+                   fn fastmath(m: &Bound<'_, PyModule>) -> PyResult<()> { Ok(()) }\n\
+                   #[napi]\n\
+                   pub fn sum_numbers(a: u32) -> u32 { a }\n\
+                   #[wasm_bindgen(js_name = \"greetUser\")]\n\
+                   pub fn greet(name: &str) {}\n\
+                   #[wasm_bindgen]\n\
+                   extern \"C\" {\n\
+                   fn alert(s: &str);\n\
+                   }\n\
+                   extern \"C\" {\n\
+                   fn crc32(data: *const u8, len: usize) -> u32;\n\
+                   }\n\
+                   #[no_mangle]\n\
+                   pub extern \"C\" fn rs_hash(x: u64) -> u64 { x }\n";
+        assert_eq!(
+            extract(Language::Rust, "src/lib.rs", src, &[]),
+            vec![
+                "export pyo3 add",
+                "export pyo3-module fastmath",
+                "export napi sumNumbers",
+                "export wasm greetUser",
+                "import c crc32",
+                "export c rs_hash",
+            ]
+        );
+    }
+
+    #[test]
+    fn test_extract_go_java_c_python() {
+        let go = "// #include \"hash.h\"\nimport \"C\"\n//export GoCallback\nfunc f() { C.crc32(C.CString(s), C.int(n)) }\n";
+        assert_eq!(
+            extract(Language::Go, "main.go", go, &[]),
+            vec!["export c GoCallback", "import c crc32"]
+        );
+
+        let java =
+            "package com.acme;\nclass Native_Lib {\n  public static native int crc(byte[] b);\n}\n";
+        assert_eq!(
+            extract(Language::Java, "Native_Lib.java", java, &[]),
+            vec!["import jni com.acme.Native_Lib.crc"]
+        );
+
+        let jni = FunctionInfo {
+            name: "Java_com_acme_Native_1Lib_crc".into(),
+            signature: String::new(),
+            start_line: 3,
+            end_line: 5,
+        };
+        let header =
+            "#include <stdint.h>\nuint32_t crc32(const uint8_t *d, size_t n);\nMACRO(x);\n\
+                      exports.Set(\"hello\", Napi::Function::New(env, Hello));\n";
+        assert_eq!(
+            extract(Language::Cpp, "native/hash.h", header, &[jni]),
+            vec![
+                "export c crc32",
+                "export jni com.acme.Native_Lib.crc",
+                "export napi hello",
+            ]
+        );
+
+        let py = "import ctypes\nlib = ctypes.CDLL(\"./libhash.so\")\nlib.crc32.restype = ctypes.c_uint32\nprint(lib.crc32(b\"x\", 1))\n";
+        assert_eq!(
+            extract(Language::Python, "app.py", py, &[]),
+            vec!["import c crc32"]
+        );
+    }
+
+    #[test]
+    fn test_skips_strings_and_comments() {
+        let rs = "const USAGE: &str = \"\n#[no_mangle]\";\npub fn helper() {}\n";
+        assert!(extract(Language::Rust, "src/lib.rs", rs, &[]).is_empty());
+
+        let js = "const hint = \"require('./addon.node')\";\n/*\nconst addon = require('bindings')('addon');\n*/\n";
+        assert!(extract(Language::JavaScript, "index.js", js, &[]).is_empty());
+    }
+
+    #[test]
+    fn test_link_bindings_adds_edges_and_module_deps() {
+        let mut graph = create_empty_graph("p", "/p");
+        let file = |language: &str, module: &str, bindings: Vec<BindingDecl>, imports| FileEntry {
+            language: language.into(),
+            module: module.into(),
+            lines: 1,
+            imports,
+            bindings,
+            ..Default::default()
+        };
+        let import = |source: &str, symbols: &[&str]| ImportInfo {
+            source: source.into(),
+            symbols: symbols.iter().map(|s| s.to_string()).collect(),
+            is_external: true,
+            import_line: 1,
+        };
+        graph.files.insert(
+            "core/src/lib.rs".into(),
+            file(
+                "rust",
+                "core",
+                vec![
+                    decl("export", "pyo3", "add", 1),
+                    decl("export", "pyo3-module", "fastmath", 4),
+                ],
+                vec![],
+            ),
+        );
+        graph.files.insert(
+            "py/app.py".into(),
+            file(
+                "python",
+                "py",
+                vec![],
+                vec![import("fastmath", &["add"]), import("os", &["path"])],
+            ),
+        );
+        graph.files.insert(
+            "web/addon.js".into(),
+            file(
+                "javascript",
+                "web",
+                vec![decl("import", "napi", "*", 0)],
+                vec![],
+            ),
+        );
+        for m in ["core", "py", "web"] {
+            graph.modules.insert(
+                m.into(),
+                ModuleEntry {
+                    files: vec![],
+                    depends_on: vec![],
+                    depended_by: vec![],
+                },
+            );
+        }
+
+        link_bindings(&mut graph);
+
+        let edges: Vec<String> = graph
+            .binding_edges
+            .iter()
+            .map(|e| format!("{} -> {} {} {}", e.from, e.to, e.abi, e.symbol))
+            .collect();
+        assert_eq!(
+            edges,
+            vec![
+                "py/app.py -> core/src/lib.rs pyo3 add",
+                "py/app.py -> core/src/lib.rs pyo3 fastmath",
+            ]
+        );
+        assert_eq!(graph.modules["py"].depends_on, vec!["core"]);
+        assert_eq!(graph.modules["core"].depended_by, vec!["py"]);
+        assert!(graph.modules["web"].depends_on.is_empty());
+    }
+}
@@ -0,0 +1,438 @@
+/// 会话简报（brief 命令）
+///
+/// 会话结束时把图谱的结构摘要（模块、公开签名、模块环）记录到 `.codemap/session.json`，
+/// 下次会话开始时与当前图谱对比，用几百 token 概括期间的结构变化与尚未 update 的区域。
+use serde::{Deserialize, Serialize};
+use std::collections::{BTreeMap, BTreeSet, HashMap};
+use std::path::Path;
+
+use crate::freshness::Freshness;
+use crate::graph::{chrono_now, ensure_git_ignored, to_record_lines, CodeGraph};
+use crate::path_utils::posix_dirname;
+
+/// 会话快照文件名（位于 .codemap/ 下，属于本地状态）
+pub const SESSION_FILE: &str = "session.json";
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 会话结束时记录的图谱结构摘要
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct SessionSnapshot {
+    #[serde(rename = "recordedAt")]
+    pub recorded_at: String,
+    #[serde(rename = "scannedAt")]
+    pub scanned_at: String,
+    #[serde(rename = "commitHash")]
+    pub commit_hash: Option<String>,
+    pub modules: Vec<String>,
+    /// `文件#符号` → 签名（仅导出符号）
+    pub signatures: BTreeMap<String, String>,
+    /// 模块依赖环（每个环按模块名排序）
+    pub cycles: Vec<Vec<String>>,
+}
+
+/// 公开签名的变化
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct SignatureChange {
+    pub file: String,
+    pub symbol: String,
+    pub before: String,
+    pub after: String,
+}
+
+/// 磁盘已变化但图谱尚未 update 的区域
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct StaleArea {
+    /// 模块名；新文件不属于任何模块时为其所在目录
+    pub area: String,
+    pub files: usize,
+}
+
+/// 相对上次会话的变化摘要
+#[derive(Debug, Clone, Serialize)]
+pub struct Brief {
+    /// 上次会话快照的记录时间
+    pub since: String,
+    #[serde(rename = "previousScan")]
+    pub previous_scan: String,
+    #[serde(rename = "currentScan")]
+    pub current_scan: String,
+    #[serde(rename = "previousCommit")]
+    pub previous_commit: Option<String>,
+    #[serde(rename = "currentCommit")]
+    pub current_commit: Option<String>,
+    #[serde(rename = "addedModules")]
+    pub added_modules: Vec<String>,
+    #[serde(rename = "removedModules")]
+    pub removed_modules: Vec<String>,
+    #[serde(rename = "changedSignatures")]
+    pub changed_signatures: Vec<SignatureChange>,
+    #[serde(rename = "addedApis")]
+    pub added_apis: usize,
+    #[serde(rename = "removedApis")]
+    pub removed_apis: usize,
+    #[serde(rename = "newCycles")]
+    pub new_cycles: Vec<Vec<String>>,
+    pub stale: Vec<StaleArea>,
+}
+
+impl Brief {
+    /// 图谱结构没有任何变化（不含 stale 区域）
+    pub fn is_unchanged(&self) -> bool {
+        self.added_modules.is_empty()
+            && self.removed_modules.is_empty()
+            && self.changed_signatures.is_empty()
+            && self.added_apis == 0
+            && self.removed_apis == 0
+            && self.new_cycles.is_empty()
+    }
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 生成当前图谱的会话快照
+pub fn snapshot(graph: &CodeGraph) -> SessionSnapshot {
+    SessionSnapshot {
+        recorded_at: chrono_now(),
+        scanned_at: graph.scanned_at.clone(),
+        commit_hash: graph.commit_hash.clone(),
+        modules: graph.modules.keys().cloned().collect(),
+        signatures: public_signatures(graph),
+        cycles: module_cycles(graph),
+    }
+}
+
+/// 对比上次会话快照与当前图谱；`freshness` 为 status --check 的结果（用于 stale 区域）
+pub fn compare(
+    previous: &SessionSnapshot,
+    graph: &CodeGraph,
+    freshness: Option<&Freshness>,
+) -> Brief {
+    let current = snapshot(graph);
+
+    let before: BTreeSet<&String> = previous.modules.iter().collect();
+    let after: BTreeSet<&String> = current.modules.iter().collect();
+
+    let mut changed_signatures = Vec::new();
+    let mut removed_apis = 0;
+    for (key, old_sig) in &previous.signatures {
+        match current.signatures.get(key) {
+            Some(new_sig) if new_sig != old_sig => {
+                let (file, symbol) = key.rsplit_once('#').unwrap_or((key.as_str(), ""));
+                changed_signatures.push(SignatureChange {
+                    file: file.to_string(),
+                    symbol: symbol.to_string(),
+                    before: old_sig.clone(),
+                    after: new_sig.clone(),
+                });
+            }
+            Some(_) => {}
+            None => removed_apis += 1,
+        }
+    }
+    let added_apis = current
+        .signatures
+        .keys()
+        .filter(|k| !previous.signatures.contains_key(*k))
+        .count();
+
+    let new_cycles = current
+        .cycles
+        .iter()
+        .filter(|c| !previous.cycles.contains(c))
+        .cloned()
+        .collect();
+
+    Brief {
+        since: previous.recorded_at.clone(),
+        previous_scan: previous.scanned_at.clone(),
+        current_scan: current.scanned_at,
+        previous_commit: previous.commit_hash.clone(),
+        current_commit: current.commit_hash,
+        added_modules: after.difference(&before).map(|s| s.to_string()).collect(),
+        removed_modules: before.difference(&after).map(|s| s.to_string()).collect(),
+        changed_signatures,
+        added_apis,
+        removed_apis,
+        new_cycles,
+        stale: freshness.map(|f| stale_areas(graph, f)).unwrap_or_default(),
+    }
+}
+
+/// 导出符号的签名：函数取签名，类 / 类型 / 变量取 `种类 名称`
+pub fn public_signatures(graph: &CodeGraph) -> BTreeMap<String, String> {
+    let mut signatures = BTreeMap::new();
+    for (path, file) in &graph.files {
+        let exported: BTreeSet<&str> = file.exports.iter().map(|s| s.as_str()).collect();
+        let mut add = |name: &str, sig: String| {
+            if exported.contains(name) {
+                signatures.insert(format!("{}#{}", path, name), sig);
+            }
+        };
+        for f in &file.functions {
+            add(&f.name, f.signature.clone());
+        }
+        for c in &file.classes {
+            add(&c.name, format!("class {}", c.name));
+        }
+        for t in &file.types {
+            add(&t.name, format!("{} {}", t.kind, t.name));
+        }
+        for v in &file.variables {
+            add(&v.name, format!("{} {}", v.kind, v.name));
+        }
+    }
+    signatures
+}
+
+/// 模块依赖图中的环（强连通分量，成员数 ≥ 2），按成员名排序
+pub fn module_cycles(graph: &CodeGraph) -> Vec<Vec<String>> {
+    let names: Vec<&String> = graph.modules.keys().collect();
+    let index: HashMap<&str, usize> = names
+        .iter()
+        .enumerate()
+        .map(|(i, n)| (n.as_str(), i))
+        .collect();
+    let edges: Vec<Vec<usize>> = names
+        .iter()
+        .map(|n| {
+            graph.modules[*n]
+                .depends_on
+                .iter()
+                .filter_map(|d| index.get(d.as_str()).copied())
+                .collect()
+        })
+        .collect();
+
+    let mut tarjan = Tarjan {
+        edges: &edges,
+        index: vec![None; names.len()],
+        low: vec![0; names.len()],
+        on_stack: vec![false; names.len()],
+        stack: Vec::new(),
+        next: 0,
+        components: Vec::new(),
+    };
+    for v in 0..names.len() {
+        if tarjan.index[v].is_none() {
+            tarjan.visit(v);
+        }
+    }
+
+    let mut cycles: Vec<Vec<String>> = tarjan
+        .components
+        .into_iter()
+        .filter(|c| c.len() > 1)
+        .map(|c| {
+            let mut members: Vec<String> = c.into_iter().map(|i| names[i].clone()).collect();
+            members.sort();
+            members
+        })
+        .collect();
+    cycles.sort();
+    cycles
+}
+
+/// 按模块汇总 freshness 中的变化文件
+pub fn stale_areas(graph: &CodeGraph, freshness: &Freshness) -> Vec<StaleArea> {
+    let mut areas: BTreeMap<String, usize> = BTreeMap::new();
+    let changed = freshness
+        .added
+        .iter()
+        .chain(&freshness.modified)
+        .chain(&freshness.removed);
+    for path in changed {
+        let area = match graph.files.get(path) {
+            Some(f) => f.module.clone(),
+            None => posix_dirname(path).to_string(),
+        };
+        *areas.entry(area).or_insert(0) += 1;
+    }
+    let mut areas: Vec<StaleArea> = areas
+        .into_iter()
+        .map(|(area, files)| StaleArea { area, files })
+        .collect();
+    areas.sort_by(|a, b| b.files.cmp(&a.files).then(a.area.cmp(&b.area)));
+    areas
+}
+
+/// 写入 .codemap/session.json，并确保 .codemap/.gitignore 忽略它
+pub fn save_snapshot(output_dir: &Path, snapshot: &SessionSnapshot) -> anyhow::Result<()> {
+    std::fs::create_dir_all(output_dir)?;
+    std::fs::write(output_dir.join(SESSION_FILE), to_record_lines(snapshot)?)?;
+    ensure_git_ignored(output_dir, SESSION_FILE)
+}
+
+/// 读取 .codemap/session.json；尚未记录时返回 Ok(None)
+pub fn load_snapshot(output_dir: &Path) -> anyhow::Result<Option<SessionSnapshot>> {
+    let path = output_dir.join(SESSION_FILE);
+    if !path.exists() {
+        return Ok(None);
+    }
+    let data = std::fs::read_to_string(&path)?;
+    Ok(Some(serde_json::from_str(&data)?))
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+/// Tarjan 强连通分量（模块数通常只有几十个，递归深度可控）
+struct Tarjan<'a> {
+    edges: &'a [Vec<usize>],
+    index: Vec<Option<usize>>,
+    low: Vec<usize>,
+    on_stack: Vec<bool>,
+    stack: Vec<usize>,
+    next: usize,
+    components: Vec<Vec<usize>>,
+}
+
+impl Tarjan<'_> {
+    fn visit(&mut self, v: usize) {
+        self.index[v] = Some(self.next);
+        self.low[v] = self.next;
+        self.next += 1;
+        self.stack.push(v);
+        self.on_stack[v] = true;
+
+        for &w in &self.edges[v] {
+            match self.index[w] {
+                None => {
+                    self.visit(w);
+                    self.low[v] = self.low[v].min(self.low[w]);
+                }
+                Some(iw) if self.on_stack[w] => self.low[v] = self.low[v].min(iw),
+                Some(_) => {}
+            }
+        }
+
+        if Some(self.low[v]) == self.index[v] {
+            let mut component = Vec::new();
+            while let Some(w) = self.stack.pop() {
+                self.on_stack[w] = false;
+                component.push(w);
+                if w == v {
+                    break;
+                }
+            }
+            self.components.push(component);
+        }
+    }
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::{create_empty_graph, FileEntry, FunctionInfo, ModuleEntry};
+
+    fn add_module(graph: &mut CodeGraph, name: &str, depends_on: &[&str]) {
+        graph.modules.insert(
+            name.to_string(),
+            ModuleEntry {
+                files: vec![],
+                depends_on: depends_on.iter().map(|s| s.to_string()).collect(),
+                depended_by: vec![],
+            },
+        );
+    }
+
+    fn add_function(graph: &mut CodeGraph, path: &str, module: &str, name: &str, sig: &str) {
+        graph.files.insert(
+            path.to_string(),
+            FileEntry {
+                language: "typescript".to_string(),
+                module: module.to_string(),
+                lines: 10,
+                functions: vec![FunctionInfo {
+                    name: name.to_string(),
+                    signature: sig.to_string(),
+                    start_line: 1,
+                    end_line: 5,
+                }],
+                exports: vec![name.to_string()],
+                ..Default::default()
+            },
+        );
+    }
+
+    #[test]
+    fn test_module_cycles() {
+        let mut graph = create_empty_graph("p", "/p");
+        add_module(&mut graph, "a", &["b"]);
+        add_module(&mut graph, "b", &["c"]);
+        add_module(&mut graph, "c", &["a"]);
+        add_module(&mut graph, "d", &["a"]);
+        add_module(&mut graph, "e", &["f"]);
+        add_module(&mut graph, "f", &["e"]);
+        assert_eq!(
+            module_cycles(&graph),
+            vec![vec!["a", "b", "c"], vec!["e", "f"]]
+        );
+    }
+
+    #[test]
+    fn test_compare_snapshots() {
+        let mut graph = create_empty_graph("p", "/p");
+        add_module(&mut graph, "auth", &[]);
+        add_module(&mut graph, "legacy", &[]);
+        add_function(&mut graph, "src/auth/login.ts", "auth", "login", "login(u)");
+        add_function(&mut graph, "src/legacy/old.ts", "legacy", "old", "old()");
+        let previous = snapshot(&graph);
+
+        graph.modules.remove("legacy");
+        graph.files.remove("src/legacy/old.ts");
+        add_module(&mut graph, "billing", &["auth"]);
+        add_function(&mut graph, "src/billing/pay.ts", "billing", "pay", "pay()");
+        add_function(
+            &mut graph,
+            "src/auth/login.ts",
+            "auth",
+            "login",
+            "login(u, opts)",
+        );
+        graph.modules.get_mut("auth").unwrap().depends_on = vec!["billing".to_string()];
+
+        let freshness = Freshness {
+            modified: vec!["src/auth/login.ts".to_string()],
+            added: vec!["src/new/x.ts".to_string()],
+            ..Default::default()
+        };
+        let brief = compare(&previous, &graph, Some(&freshness));
+        assert_eq!(brief.added_modules, vec!["billing"]);
+        assert_eq!(brief.removed_modules, vec!["legacy"]);
+        assert_eq!(brief.changed_signatures.len(), 1);
+        assert_eq!(brief.changed_signatures[0].symbol, "login");
+        assert_eq!(brief.changed_signatures[0].after, "login(u, opts)");
+        assert_eq!(brief.added_apis, 1);
+        assert_eq!(brief.removed_apis, 1);
+        assert_eq!(brief.new_cycles, vec![vec!["auth", "billing"]]);
+        assert_eq!(
+            brief.stale,
+            vec![
+                StaleArea {
+                    area: "auth".to_string(),
+                    files: 1
+                },
+                StaleArea {
+                    area: "src/new".to_string(),
+                    files: 1
+                },
+            ]
+        );
+        assert!(!brief.is_unchanged());
+        assert!(compare(&snapshot(&graph), &graph, None).is_unchanged());
+    }
+
+    #[test]
+    fn test_save_snapshot_is_git_ignored() {
+        let dir = std::env::temp_dir().join(format!("codegraph_brief_{}", std::process::id()));
+        let graph = create_empty_graph("p", "/p");
+        save_snapshot(&dir, &snapshot(&graph)).unwrap();
+        save_snapshot(&dir, &snapshot(&graph)).unwrap();
+        let ignore = std::fs::read_to_string(dir.join(".gitignore")).unwrap();
+        assert_eq!(ignore.lines().filter(|l| *l == SESSION_FILE).count(), 1);
+        assert!(load_snapshot(&dir).unwrap().is_some());
+        let _ = std::fs::remove_dir_all(&dir);
+    }
+}
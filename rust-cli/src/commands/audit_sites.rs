@@ -0,0 +1,111 @@
+use clap::Args;
+use serde::Serialize;
+use std::collections::BTreeMap;
+use std::path::PathBuf;
+
+use crate::audit_sites::{collect_findings, format_finding, AuditFinding, AUDIT_TAGS};
+use crate::graph::load_graph;
+
+#[derive(Args)]
+pub struct AuditSitesArgs {
+    /// Only show one tag: unsafe, ffi, exec, or sql
+    #[arg(long)]
+    pub tag: Option<String>,
+    /// Only show sites in this module
+    #[arg(long)]
+    pub module: Option<String>,
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+#[derive(Serialize)]
+struct AuditOutput<'a> {
+    /// 每个标签的审查点数
+    counts: BTreeMap<&'a str, usize>,
+    sites: &'a [AuditFinding],
+}
+
+pub fn run(args: AuditSitesArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+    if let Some(tag) = &args.tag {
+        if !AUDIT_TAGS.contains(&tag.as_str()) {
+            eprintln!(
+                "Error: unsupported tag '{}' (expected {})",
+                tag,
+                AUDIT_TAGS.join(", ")
+            );
+            std::process::exit(1);
+        }
+    }
+
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+
+    let graph = match load_graph(&root_dir.join(".codemap")) {
+        Ok(g) => g,
+        Err(_) => {
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    };
+
+    let files: Vec<String> = match &args.module {
+        Some(module) => match graph.modules.get(module) {
+            Some(m) => m.files.clone(),
+            None => {
+                eprintln!("Error: module '{}' not found in the code graph", module);
+                std::process::exit(1);
+            }
+        },
+        None => graph.files.keys().cloned().collect(),
+    };
+    let findings = collect_findings(&graph, &files, args.tag.as_deref());
+
+    let mut counts: BTreeMap<&str, usize> = BTreeMap::new();
+    for f in &findings {
+        *counts.entry(f.tag.as_str()).or_default() += 1;
+    }
+
+    if args.format == "json" {
+        let output = AuditOutput {
+            counts,
+            sites: &findings,
+        };
+        match serde_json::to_string_pretty(&output) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+        return;
+    }
+
+    if findings.is_empty() {
+        println!("No audit sites found.");
+        return;
+    }
+    let summary: Vec<String> = AUDIT_TAGS
+        .iter()
+        .filter_map(|t| counts.get(t).map(|n| format!("{} {}", t, n)))
+        .collect();
+    println!("{} audit site(s): {}", findings.len(), summary.join(", "));
+    for f in &findings {
+        println!("  {}", format_finding(f));
+    }
+}
@@ -0,0 +1,191 @@
+use clap::Args;
+use std::path::PathBuf;
+
+use crate::brief::{compare, load_snapshot, save_snapshot, snapshot, Brief};
+use crate::freshness::check_freshness;
+use crate::graph::{load_graph, load_meta};
+
+/// 文本输出中每类最多列出的条目
+const MAX_ITEMS: usize = 8;
+
+#[derive(Args)]
+pub struct BriefArgs {
+    /// Record the current graph as the end-of-session state instead of printing a digest
+    #[arg(long)]
+    pub record: bool,
+    /// Skip the not-yet-updated section (the caller already ran "status --check")
+    #[arg(long)]
+    pub no_stale: bool,
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+pub fn run(args: BriefArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+    let output_dir = root_dir.join(".codemap");
+
+    let graph = match load_graph(&output_dir) {
+        Ok(g) => g,
+        Err(_) => {
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    };
+
+    let previous = match load_snapshot(&output_dir) {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Warning: ignoring unreadable session snapshot: {}", e);
+            None
+        }
+    };
+
+    // --record，或尚无快照（首次会话）：记录当前状态作为下次对比的基准
+    if args.record || previous.is_none() {
+        if let Err(e) = save_snapshot(&output_dir, &snapshot(&graph)) {
+            eprintln!("Error: cannot write session snapshot: {}", e);
+            std::process::exit(1);
+        }
+        if args.record {
+            return;
+        }
+    }
+    let Some(previous) = previous else {
+        if args.format == "json" {
+            println!("null");
+        } else {
+            println!("[CodeMap] No previous session recorded; baseline saved for next time.");
+        }
+        return;
+    };
+
+    let freshness = if args.no_stale {
+        None
+    } else {
+        load_meta(&output_dir)
+            .ok()
+            .map(|meta| check_freshness(&root_dir, &meta, &graph.config))
+    };
+    let brief = compare(&previous, &graph, freshness.as_ref());
+
+    if args.format == "json" {
+        match serde_json::to_string_pretty(&brief) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+    } else {
+        print_brief(&brief);
+    }
+}
+
+fn print_brief(brief: &Brief) {
+    if brief.is_unchanged() {
+        println!(
+            "[CodeMap] No structural changes since last session ({}).",
+            brief.since
+        );
+    } else {
+        println!("[CodeMap] Since last session ({}):", brief.since);
+        if brief.previous_scan != brief.current_scan {
+            if brief.previous_commit == brief.current_commit {
+                println!("  Graph rescanned at {}", brief.current_scan);
+            } else {
+                println!(
+                    "  Graph rescanned at {} (commit {} -> {})",
+                    brief.current_scan,
+                    short_commit(brief.previous_commit.as_deref()),
+                    short_commit(brief.current_commit.as_deref())
+                );
+            }
+        }
+        if !brief.added_modules.is_empty() || !brief.removed_modules.is_empty() {
+            let mut items: Vec<String> = brief
+                .added_modules
+                .iter()
+                .map(|m| format!("+{}", m))
+                .collect();
+            items.extend(brief.removed_modules.iter().map(|m| format!("-{}", m)));
+            println!("  Modules: {}", capped(&items));
+        }
+        if !brief.changed_signatures.is_empty() {
+            println!("  Changed signatures ({}):", brief.changed_signatures.len());
+            for c in brief.changed_signatures.iter().take(MAX_ITEMS) {
+                println!("    {} {}: {} -> {}", c.file, c.symbol, c.before, c.after);
+            }
+            if brief.changed_signatures.len() > MAX_ITEMS {
+                println!(
+                    "    ... {} more",
+                    brief.changed_signatures.len() - MAX_ITEMS
+                );
+            }
+        }
+        if brief.added_apis > 0 || brief.removed_apis > 0 {
+            println!(
+                "  Public API: {} added, {} removed",
+                brief.added_apis, brief.removed_apis
+            );
+        }
+        if !brief.new_cycles.is_empty() {
+            let cycles: Vec<String> = brief
+                .new_cycles
+                .iter()
+                .map(|c| format!("[{}]", c.join(" <-> ")))
+                .collect();
+            println!("  New dependency cycles: {}", capped(&cycles));
+        }
+    }
+
+    if !brief.stale.is_empty() {
+        let total: usize = brief.stale.iter().map(|s| s.files).sum();
+        let areas: Vec<String> = brief
+            .stale
+            .iter()
+            .map(|s| format!("{} ({})", s.area, s.files))
+            .collect();
+        println!(
+            "  Not yet updated: {} file(s) in {}; run \"codegraph update\"",
+            total,
+            capped(&areas)
+        );
+    }
+}
+
+fn capped(items: &[String]) -> String {
+    if items.len() > MAX_ITEMS {
+        format!(
+            "{}, ... {} more",
+            items[..MAX_ITEMS].join(", "),
+            items.len() - MAX_ITEMS
+        )
+    } else {
+        items.join(", ")
+    }
+}
+
+fn short_commit(commit: Option<&str>) -> &str {
+    match commit {
+        Some(c) => &c[..c.len().min(7)],
+        None => "-",
+    }
+}
@@ -0,0 +1,121 @@
+use clap::Args;
+use serde::Serialize;
+use std::path::PathBuf;
+
+use crate::graph::{load_graph, BrokenImport, CodeGraph};
+use crate::path_utils::strip_extension;
+
+#[derive(Args)]
+pub struct BrokenImportsArgs {
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+#[derive(Serialize)]
+struct BrokenImportReport<'a> {
+    #[serde(flatten)]
+    import: &'a BrokenImport,
+    /// 同名文件（文件被移动或扩展名写错时的可能目标）
+    candidates: Vec<String>,
+}
+
+pub fn run(args: BrokenImportsArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+
+    let graph = match load_graph(&root_dir.join(".codemap")) {
+        Ok(g) => g,
+        Err(_) => {
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    };
+
+    let reports: Vec<BrokenImportReport> = graph
+        .broken_imports
+        .iter()
+        .map(|import| BrokenImportReport {
+            import,
+            candidates: candidates(&graph, &import.source),
+        })
+        .collect();
+
+    if args.format == "json" {
+        match serde_json::to_string_pretty(&reports) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+    } else if reports.is_empty() {
+        println!("No broken imports.");
+    } else {
+        let mut files: Vec<&str> = reports.iter().map(|r| r.import.file.as_str()).collect();
+        files.dedup();
+        println!(
+            "{} broken import(s) in {} file(s):",
+            reports.len(),
+            files.len()
+        );
+        let mut current = "";
+        for r in &reports {
+            if r.import.file != current {
+                current = &r.import.file;
+                println!();
+                println!("  {}", current);
+            }
+            if r.candidates.is_empty() {
+                println!("    {:>4}  {}", r.import.line, r.import.source);
+            } else {
+                println!(
+                    "    {:>4}  {}  (did you mean {}?)",
+                    r.import.line,
+                    r.import.source,
+                    r.candidates.join(", ")
+                );
+            }
+        }
+    }
+
+    if !reports.is_empty() {
+        std::process::exit(1);
+    }
+}
+
+/// 与 import 目标同名（去扩展名）的文件，最多 3 个
+fn candidates(graph: &CodeGraph, source: &str) -> Vec<String> {
+    let last = source.rsplit('/').next().unwrap_or(source);
+    let stem = strip_extension(last);
+    if stem.is_empty() || stem.starts_with('.') {
+        return vec![];
+    }
+    let index_suffix = format!("/{}/index", stem);
+    graph
+        .files
+        .keys()
+        .filter(|path| {
+            let base = strip_extension(path);
+            base.rsplit('/').next() == Some(stem.as_str()) || base.ends_with(&index_suffix)
+        })
+        .take(3)
+        .cloned()
+        .collect()
+}
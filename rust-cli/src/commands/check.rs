@@ -0,0 +1,93 @@
+use clap::Args;
+use serde::Serialize;
+use std::path::PathBuf;
+
+use crate::graph::load_graph;
+use crate::packages::{check_boundaries, PackageEdge};
+
+#[derive(Args)]
+pub struct CheckArgs {
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+#[derive(Serialize)]
+struct CheckReport<'a> {
+    packages: usize,
+    violations: &'a [PackageEdge],
+}
+
+pub fn run(args: CheckArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+
+    let graph = match load_graph(&root_dir.join(".codemap")) {
+        Ok(g) => g,
+        Err(_) => {
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    };
+
+    let violations = check_boundaries(&graph);
+    if args.format == "json" {
+        let report = CheckReport {
+            packages: graph.packages.len(),
+            violations: &violations,
+        };
+        match serde_json::to_string_pretty(&report) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+    } else if graph.packages.is_empty() {
+        println!("No manifest-defined packages found (Cargo.toml, package.json, go.mod, pyproject.toml, pom.xml, build.gradle, *.csproj).");
+    } else if violations.is_empty() {
+        println!(
+            "All cross-package imports follow declared dependencies ({} package(s)).",
+            graph.packages.len()
+        );
+    } else {
+        println!(
+            "{} import(s) bypass declared package dependencies:",
+            violations.len()
+        );
+        let mut current: Option<(&str, &str)> = None;
+        for v in &violations {
+            if current != Some((v.from.as_str(), v.to.as_str())) {
+                current = Some((v.from.as_str(), v.to.as_str()));
+                let manifest = graph
+                    .packages
+                    .get(&v.from)
+                    .map(|p| p.manifest.as_str())
+                    .unwrap_or("?");
+                println!();
+                println!("  {} → {}  (not declared in {})", v.from, v.to, manifest);
+            }
+            println!("    {}:{}  {}", v.file, v.line, v.source);
+        }
+    }
+
+    if !violations.is_empty() {
+        std::process::exit(1);
+    }
+}
@@ -0,0 +1,103 @@
+use clap::Args;
+use std::path::PathBuf;
+
+use crate::concurrency::{concurrency_summary, format_counts, CONCURRENCY_KINDS};
+use crate::graph::load_graph;
+
+#[derive(Args)]
+pub struct ConcurrencyArgs {
+    /// Module name
+    pub module: String,
+    /// Only show one kind: spawn, channel, async, await, or lock
+    #[arg(long)]
+    pub kind: Option<String>,
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+pub fn run(args: ConcurrencyArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+    if let Some(kind) = &args.kind {
+        if !CONCURRENCY_KINDS.contains(&kind.as_str()) {
+            eprintln!(
+                "Error: unsupported kind '{}' (expected {})",
+                kind,
+                CONCURRENCY_KINDS.join(", ")
+            );
+            std::process::exit(1);
+        }
+    }
+
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+
+    let graph = match load_graph(&root_dir.join(".codemap")) {
+        Ok(g) => g,
+        Err(_) => {
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    };
+
+    let Some(summary) = concurrency_summary(&graph, &args.module, args.kind.as_deref()) else {
+        eprintln!(
+            "Error: module '{}' not found in the code graph",
+            args.module
+        );
+        std::process::exit(1);
+    };
+
+    if args.format == "json" {
+        match serde_json::to_string_pretty(&summary) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+        return;
+    }
+
+    let total: usize = summary.counts.values().sum();
+    if total == 0 {
+        println!(
+            "No concurrency constructs found in module {}.",
+            summary.module
+        );
+        return;
+    }
+    println!(
+        "Concurrency in module {} ({} site(s)): {}",
+        summary.module,
+        total,
+        format_counts(&summary.counts)
+    );
+    let mut current_file = "";
+    for group in &summary.symbols {
+        if group.file != current_file {
+            current_file = &group.file;
+            println!();
+            println!("{}", group.file);
+        }
+        let symbol = group.symbol.as_deref().unwrap_or("(top level)");
+        println!("  {} [{}]", symbol, format_counts(&group.counts));
+        for site in &group.sites {
+            println!("    :{} [{}] {}", site.line, site.kind, site.detail);
+        }
+    }
+}
@@ -0,0 +1,229 @@
+use clap::Args;
+use serde::Serialize;
+use serde_json::json;
+use std::io::Read;
+use std::path::{Path, PathBuf};
+
+use crate::context::{file_context, hook_file_path, load_codeowners, FileContext};
+use crate::graph::load_graph;
+use crate::path_utils::posix_normalize;
+use crate::traverser::detect_language;
+
+/// 文本输出中每个文件最多列出的导入方
+const MAX_IMPORTERS: usize = 15;
+
+#[derive(Args)]
+pub struct ContextArgs {
+    /// Files about to be edited (absolute or relative to --dir)
+    #[arg(required_unless_present = "hook_input")]
+    pub files: Vec<String>,
+    /// Output format: text, json, or hook (PreToolUse additionalContext JSON)
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+    /// Read the file from PreToolUse hook JSON on stdin (tool_input.file_path)
+    #[arg(long, conflicts_with = "files")]
+    pub hook_input: bool,
+}
+
+#[derive(Serialize)]
+struct ContextOutput<'a> {
+    files: &'a [FileContext],
+    /// 不在图谱中的文件（新文件或不支持的语言）
+    unknown: &'a [String],
+}
+
+pub fn run(args: ContextArgs) {
+    if !matches!(args.format.as_str(), "text" | "json" | "hook") {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text, json or hook)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+    // hook 模式不能打断工具调用：任何问题都静默退出
+    let hook = args.format == "hook";
+
+    let files = if args.hook_input {
+        let mut input = String::new();
+        let file = std::io::stdin()
+            .read_to_string(&mut input)
+            .ok()
+            .and_then(|_| hook_file_path(&input));
+        match file {
+            // 不支持的语言不会出现在图谱中，不必加载图谱
+            Some(f) if detect_language(Path::new(&f)).is_some() => vec![f],
+            Some(_) => return,
+            None => {
+                if hook {
+                    return;
+                }
+                eprintln!("Error: stdin is not hook JSON with tool_input.file_path");
+                std::process::exit(1);
+            }
+        }
+    } else {
+        args.files
+    };
+
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            if hook {
+                return;
+            }
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+
+    let graph = match load_graph(&root_dir.join(".codemap")) {
+        Ok(g) => g,
+        Err(_) => {
+            if hook {
+                return;
+            }
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    };
+
+    let rules = load_codeowners(&root_dir);
+    let mut contexts: Vec<FileContext> = Vec::new();
+    let mut unknown: Vec<String> = Vec::new();
+    for file in &files {
+        let rel = relative_path(&root_dir, file);
+        match file_context(&graph, &rules, &rel) {
+            Some(ctx) => contexts.push(ctx),
+            None => unknown.push(rel),
+        }
+    }
+
+    match args.format.as_str() {
+        "json" => {
+            let output = ContextOutput {
+                files: &contexts,
+                unknown: &unknown,
+            };
+            match serde_json::to_string_pretty(&output) {
+                Ok(json) => println!("{}", json),
+                Err(e) => {
+                    eprintln!("Serialization error: {}", e);
+                    std::process::exit(1);
+                }
+            }
+        }
+        "hook" => {
+            if contexts.is_empty() {
+                return;
+            }
+            let text: Vec<String> = contexts.iter().map(format_context).collect();
+            let output = json!({
+                "hookSpecificOutput": {
+                    "hookEventName": "PreToolUse",
+                    "additionalContext": format!("[CodeMap] Before editing:\n{}", text.join("\n")),
+                }
+            });
+            println!("{}", output);
+            return;
+        }
+        _ => {
+            for ctx in &contexts {
+                print!("{}", format_context(ctx));
+            }
+            for path in &unknown {
+                println!("== {} (not in code graph)", path);
+            }
+        }
+    }
+
+    if contexts.is_empty() {
+        std::process::exit(1);
+    }
+}
+
+/// 将命令行路径转为图谱中的相对路径（绝对路径去掉项目根前缀）
+fn relative_path(root_dir: &Path, file: &str) -> String {
+    let path = Path::new(file);
+    let abs = if path.is_absolute() {
+        path.canonicalize().unwrap_or_else(|_| path.to_path_buf())
+    } else {
+        root_dir.join(path)
+    };
+    let rel = abs
+        .strip_prefix(root_dir)
+        .map(|r| r.to_string_lossy().replace('\\', "/"))
+        .unwrap_or_else(|_| file.replace('\\', "/"));
+    posix_normalize(&rel)
+}
+
+fn format_context(ctx: &FileContext) -> String {
+    let mut out = format!(
+        "== {} (module {}, {}, {} lines)\n",
+        ctx.file, ctx.module, ctx.language, ctx.lines
+    );
+
+    if !ctx.outline.is_empty() {
+        out.push_str("Outline:\n");
+        for item in &ctx.outline {
+            let label = if item.signature.is_empty() {
+                format!("{} {}", item.kind, item.name)
+            } else {
+                item.signature.clone()
+            };
+            out.push_str(&format!(
+                "  L{}-{}  {}\n",
+                item.start_line, item.end_line, label
+            ));
+        }
+    }
+
+    if ctx.importers.is_empty() {
+        out.push_str("Importers: none\n");
+    } else {
+        out.push_str(&format!("Importers ({}):\n", ctx.importers.len()));
+        for imp in ctx.importers.iter().take(MAX_IMPORTERS) {
+            let symbols: Vec<String> = imp
+                .symbols
+                .iter()
+                .map(|s| {
+                    if s.use_lines.is_empty() {
+                        s.symbol.clone()
+                    } else {
+                        let lines: Vec<String> =
+                            s.use_lines.iter().map(|l| l.to_string()).collect();
+                        format!("{} @ {}", s.symbol, lines.join(","))
+                    }
+                })
+                .collect();
+            out.push_str(&format!("  {}:{}", imp.file, imp.import_line));
+            if !symbols.is_empty() {
+                out.push_str(&format!("  {}", symbols.join("; ")));
+            }
+            out.push('\n');
+        }
+        if ctx.importers.len() > MAX_IMPORTERS {
+            out.push_str(&format!(
+                "  ... {} more\n",
+                ctx.importers.len() - MAX_IMPORTERS
+            ));
+        }
+    }
+
+    let or_none = |items: &[String]| {
+        if items.is_empty() {
+            "none".to_string()
+        } else {
+            items.join(", ")
+        }
+    };
+    out.push_str(&format!(
+        "Dependant modules: {}\n",
+        or_none(&ctx.dependants)
+    ));
+    out.push_str(&format!("Owners: {}\n", or_none(&ctx.owners)));
+    out.push_str(&format!("Tests: {}\n", or_none(&ctx.tests)));
+    out
+}
@@ -0,0 +1,109 @@
+use clap::Args;
+use std::path::PathBuf;
+
+use crate::context::load_codeowners;
+use crate::deprecations::{deprecation_report, DeprecatedSymbol};
+use crate::graph::load_graph;
+
+#[derive(Args)]
+pub struct DeprecatedArgs {
+    /// Only list deprecated symbols defined in this module
+    #[arg(long)]
+    pub module: Option<String>,
+    /// Only list symbols that still have uses
+    #[arg(long)]
+    pub used: bool,
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+pub fn run(args: DeprecatedArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+
+    let graph = match load_graph(&root_dir.join(".codemap")) {
+        Ok(g) => g,
+        Err(_) => {
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    };
+
+    let rules = load_codeowners(&root_dir);
+    let report: Vec<DeprecatedSymbol> = deprecation_report(&graph, &rules)
+        .into_iter()
+        .filter(|d| args.module.as_ref().is_none_or(|m| *m == d.module))
+        .filter(|d| !args.used || d.use_count > 0)
+        .collect();
+
+    if args.format == "json" {
+        match serde_json::to_string_pretty(&report) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+        return;
+    }
+
+    if report.is_empty() {
+        println!("No deprecated symbols found.");
+        return;
+    }
+    let remaining: usize = report.iter().map(|d| d.use_count).sum();
+    println!(
+        "{} deprecated symbol(s), {} remaining use site(s)",
+        report.len(),
+        remaining
+    );
+    for d in &report {
+        println!();
+        println!("[{}] {} ({}:{})", d.kind, d.name, d.file, d.line);
+        if let Some(message) = &d.message {
+            println!("  message: {}", message);
+        }
+        if d.uses.is_empty() {
+            println!("  no remaining uses");
+            continue;
+        }
+        for group in &d.uses {
+            let owners = if group.owners.is_empty() {
+                "(no owner)".to_string()
+            } else {
+                group.owners.join(" ")
+            };
+            println!("  {} — {}", group.module, owners);
+            for site in &group.sites {
+                let mut parts = vec![if site.import_line > 0 {
+                    format!("{}:{}", site.file, site.import_line)
+                } else {
+                    site.file.clone()
+                }];
+                if !site.use_lines.is_empty() {
+                    let uses: Vec<String> =
+                        site.use_lines.iter().map(|l| format!(":{}", l)).collect();
+                    parts.push(format!("(use {})", uses.join(" ")));
+                }
+                println!("    {}", parts.join(" "));
+            }
+        }
+    }
+}
@@ -0,0 +1,111 @@
+use clap::Args;
+use std::path::PathBuf;
+
+use crate::doc_coverage::doc_coverage;
+use crate::graph::load_graph;
+
+#[derive(Args)]
+pub struct DocCoverageArgs {
+    /// Only report this module
+    #[arg(long)]
+    pub module: Option<String>,
+    /// Fail (exit 1) when overall coverage is below this percentage
+    #[arg(long)]
+    pub threshold: Option<f64>,
+    /// Maximum number of undocumented symbols to list in text output
+    #[arg(long, default_value = "20")]
+    pub limit: usize,
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+pub fn run(args: DocCoverageArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+    if let Some(t) = args.threshold {
+        if !(0.0..=100.0).contains(&t) {
+            eprintln!("Error: --threshold must be between 0 and 100");
+            std::process::exit(1);
+        }
+    }
+
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+
+    let graph = match load_graph(&root_dir.join(".codemap")) {
+        Ok(g) => g,
+        Err(_) => {
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    };
+
+    if let Some(module) = &args.module {
+        if !graph.modules.contains_key(module) {
+            eprintln!("Error: module '{}' not found in the code graph", module);
+            std::process::exit(1);
+        }
+    }
+    let report = doc_coverage(&graph, args.module.as_deref());
+
+    if args.format == "json" {
+        match serde_json::to_string_pretty(&report) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+    } else {
+        println!(
+            "Documentation coverage: {:.1}% ({}/{} public symbols)",
+            report.percent, report.documented, report.public
+        );
+        for m in &report.modules {
+            println!(
+                "  {:<24} {:>5.1}%  {}/{}",
+                m.module, m.percent, m.documented, m.public
+            );
+        }
+        if !report.undocumented.is_empty() {
+            println!();
+            println!(
+                "Undocumented public symbols ({}, by fan-in):",
+                report.undocumented.len()
+            );
+            for s in report.undocumented.iter().take(args.limit) {
+                println!(
+                    "  {}:{} {} {} (fan-in {})",
+                    s.file, s.line, s.kind, s.name, s.fan_in
+                );
+            }
+            if report.undocumented.len() > args.limit {
+                println!("  ... {} more", report.undocumented.len() - args.limit);
+            }
+        }
+    }
+
+    if let Some(threshold) = args.threshold {
+        if report.percent < threshold {
+            eprintln!(
+                "Documentation coverage {:.1}% is below the threshold of {:.1}%",
+                report.percent, threshold
+            );
+            std::process::exit(1);
+        }
+    }
+}
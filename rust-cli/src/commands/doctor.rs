@@ -0,0 +1,180 @@
+use clap::Args;
+use serde::Serialize;
+use std::path::PathBuf;
+
+use crate::doctor::{
+    binary_lookup, diagnose, repair, BinaryCandidate, DoctorReport, RepairOutcome,
+    DEFAULT_SAMPLE_SIZE,
+};
+
+#[derive(Args)]
+pub struct DoctorArgs {
+    /// Repair problems by re-scanning the affected files
+    #[arg(long)]
+    pub fix: bool,
+    /// Number of files whose hash is compared against the disk (0 to skip)
+    #[arg(long, default_value_t = DEFAULT_SAMPLE_SIZE)]
+    pub sample: usize,
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+#[derive(Serialize)]
+struct DoctorOutput<'a> {
+    #[serde(flatten)]
+    report: &'a DoctorReport,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    repair: Option<&'a RepairOutcome>,
+    /// 修复后复查的结果
+    #[serde(rename = "afterRepair", skip_serializing_if = "Option::is_none")]
+    after_repair: Option<&'a DoctorReport>,
+    #[serde(rename = "binaryLookup")]
+    binary_lookup: &'a [BinaryCandidate],
+}
+
+pub fn run(args: DoctorArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+
+    let report = diagnose(&root_dir, args.sample);
+    let mut outcome = None;
+    let mut after = None;
+    if args.fix && !report.issues.is_empty() {
+        match repair(&root_dir, &report) {
+            Ok(o) => outcome = Some(o),
+            Err(e) => {
+                eprintln!("Error: repair failed: {}", e);
+                std::process::exit(1);
+            }
+        }
+        after = Some(diagnose(&root_dir, args.sample));
+    }
+    let cwd = std::env::current_dir().unwrap_or_else(|_| root_dir.clone());
+    let lookup = binary_lookup(&cwd);
+
+    if args.format == "json" {
+        let output = DoctorOutput {
+            report: &report,
+            repair: outcome.as_ref(),
+            after_repair: after.as_ref(),
+            binary_lookup: &lookup,
+        };
+        match serde_json::to_string_pretty(&output) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+    } else {
+        println!("Checking {}", root_dir.join(".codemap").display());
+        print_report(&report);
+        if let Some(o) = &outcome {
+            println!();
+            if o.full_rescan {
+                println!("Repaired by a full re-scan.");
+            } else {
+                println!(
+                    "Repaired: {} file(s) re-scanned, {} removed, {} orphan slice(s) deleted.",
+                    o.rescanned.len(),
+                    o.removed.len(),
+                    o.removed_slices.len()
+                );
+            }
+        } else if !report.issues.is_empty() {
+            println!();
+            println!("Run \"codegraph doctor --fix\" to repair by targeted re-scan.");
+        }
+        if let Some(after) = &after {
+            println!();
+            println!("After repair:");
+            print_report(after);
+        }
+        print_binary_lookup(&lookup);
+    }
+
+    let remaining = after.as_ref().unwrap_or(&report);
+    if !remaining.issues.is_empty() {
+        std::process::exit(1);
+    }
+}
+
+fn print_report(report: &DoctorReport) {
+    match &report.version {
+        Some(v) => println!("  Schema version: {}", v),
+        None => println!("  Schema version: (graph.json unreadable)"),
+    }
+    println!(
+        "  Files: {} in graph.json, {} in meta.json",
+        report.graph_files, report.meta_files
+    );
+    println!(
+        "  Hash sample: {} file(s) compared with disk",
+        report.checked_hashes
+    );
+    if report.issues.is_empty() {
+        println!("  No problems found.");
+        return;
+    }
+    println!("  {} problem(s):", report.issues.len());
+    for issue in &report.issues {
+        let kind = serde_json::to_value(issue.kind)
+            .ok()
+            .and_then(|v| v.as_str().map(|s| s.to_string()))
+            .unwrap_or_default();
+        match &issue.file {
+            Some(file) => println!("    [{}] {}: {}", kind, file, issue.message),
+            None => println!("    [{}] {}", kind, issue.message),
+        }
+    }
+}
+
+/// 说明插件包装脚本 ccplugin/bin/codegraph 的二进制查找顺序（第一个找到的位置生效）
+fn print_binary_lookup(candidates: &[BinaryCandidate]) {
+    println!();
+    println!("Binary lookup (ccplugin/bin/codegraph, first match wins):");
+    let winner = candidates.iter().position(|c| c.found);
+    for (i, c) in candidates.iter().enumerate() {
+        let state = if winner == Some(i) {
+            "found, used"
+        } else if c.found {
+            "found"
+        } else if c.source.ends_with("auto-download") {
+            if winner.is_some() {
+                "not needed"
+            } else {
+                "used if nothing above is found"
+            }
+        } else if c.path.is_none() {
+            "not set"
+        } else {
+            "not found"
+        };
+        println!(
+            "  {:<38} {}  [{}]",
+            c.source,
+            c.path.as_deref().unwrap_or("-"),
+            state
+        );
+    }
+    if let Ok(exe) = std::env::current_exe() {
+        println!("  Running binary: {}", exe.display());
+    }
+}
@@ -0,0 +1,90 @@
+use clap::Args;
+use std::path::PathBuf;
+
+use crate::config_keys::{collect_config_symbols, ConfigSymbol};
+use crate::graph::load_graph;
+
+#[derive(Args)]
+pub struct EnvArgs {
+    /// Only list names containing this substring
+    pub filter: Option<String>,
+    /// Restrict to one kind: env (environment variables) or config (config-library keys)
+    #[arg(long)]
+    pub kind: Option<String>,
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+pub fn run(args: EnvArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+    if let Some(kind) = &args.kind {
+        if kind != "env" && kind != "config" {
+            eprintln!(
+                "Error: unsupported kind '{}' (expected env or config)",
+                kind
+            );
+            std::process::exit(1);
+        }
+    }
+
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+
+    let graph = match load_graph(&root_dir.join(".codemap")) {
+        Ok(g) => g,
+        Err(_) => {
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    };
+
+    let symbols: Vec<ConfigSymbol> = collect_config_symbols(&graph)
+        .into_iter()
+        .filter(|s| args.kind.as_ref().is_none_or(|k| *k == s.kind))
+        .filter(|s| args.filter.as_ref().is_none_or(|f| s.name.contains(f)))
+        .collect();
+
+    if args.format == "json" {
+        match serde_json::to_string_pretty(&symbols) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+        return;
+    }
+
+    if symbols.is_empty() {
+        println!("No environment variables or config keys found.");
+        return;
+    }
+    for sym in &symbols {
+        println!(
+            "[{}] {}  ({} file(s); modules: {})",
+            sym.kind,
+            sym.name,
+            sym.sites.len(),
+            sym.modules.join(", ")
+        );
+        for site in &sym.sites {
+            let lines: Vec<String> = site.lines.iter().map(|l| format!(":{}", l)).collect();
+            println!("    {} {}", site.file, lines.join(" "));
+        }
+    }
+}
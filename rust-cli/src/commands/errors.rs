@@ -0,0 +1,96 @@
+use clap::Args;
+use std::path::PathBuf;
+
+use crate::errors::{error_reports, format_error};
+use crate::graph::load_graph;
+
+#[derive(Args)]
+pub struct ErrorsArgs {
+    /// Function name (typically an entry point or handler)
+    pub symbol: String,
+    /// Only use the definition in this file (relative path)
+    #[arg(long)]
+    pub file: Option<String>,
+    /// Also list errors caught inside the function
+    #[arg(long)]
+    pub caught: bool,
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+pub fn run(args: ErrorsArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+
+    let graph = match load_graph(&root_dir.join(".codemap")) {
+        Ok(g) => g,
+        Err(_) => {
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    };
+
+    let reports = error_reports(&graph, &args.symbol, args.file.as_deref());
+    if reports.is_empty() {
+        eprintln!(
+            "Error: function '{}' not found in the code graph",
+            args.symbol
+        );
+        std::process::exit(1);
+    }
+
+    if args.format == "json" {
+        match serde_json::to_string_pretty(&reports) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+        return;
+    }
+
+    for (i, report) in reports.iter().enumerate() {
+        if i > 0 {
+            println!();
+        }
+        println!(
+            "Errors that can escape from {} ({}:{}): {}",
+            report.symbol,
+            report.file,
+            report.line,
+            report.escaping.len()
+        );
+        for e in &report.escaping {
+            println!("  {}", format_error(e));
+        }
+        if args.caught {
+            println!("Caught inside: {}", report.caught.len());
+            for e in &report.caught {
+                println!("  {}", format_error(e));
+            }
+        } else if !report.caught.is_empty() {
+            println!(
+                "({} caught inside; use --caught to list them)",
+                report.caught.len()
+            );
+        }
+    }
+}
@@ -0,0 +1,84 @@
+use clap::{Args, Subcommand};
+use std::path::PathBuf;
+
+use crate::export::{export_tables, ExportFormat};
+use crate::graph::load_graph;
+
+#[derive(Args)]
+pub struct ExportArgs {
+    #[command(subcommand)]
+    pub command: ExportCommand,
+}
+
+#[derive(Subcommand)]
+pub enum ExportCommand {
+    /// Export normalized tables (files, symbols, imports, refs, module_edges, metrics)
+    Table(TableArgs),
+}
+
+#[derive(Args)]
+pub struct TableArgs {
+    /// Output format: csv or parquet
+    #[arg(long, default_value = "csv")]
+    pub format: String,
+    /// Output directory (default: <dir>/.codemap/export)
+    #[arg(long)]
+    pub out: Option<String>,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+pub fn run(args: ExportArgs) {
+    match args.command {
+        ExportCommand::Table(table_args) => run_table(table_args),
+    }
+}
+
+fn run_table(args: TableArgs) {
+    let format = match ExportFormat::parse(&args.format) {
+        Some(f) => f,
+        None => {
+            eprintln!(
+                "Error: unsupported format '{}' (expected csv or parquet)",
+                args.format
+            );
+            std::process::exit(1);
+        }
+    };
+
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+    let codemap_dir = root_dir.join(".codemap");
+
+    let graph = match load_graph(&codemap_dir) {
+        Ok(g) => g,
+        Err(_) => {
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    };
+
+    let out_dir = match args.out {
+        Some(ref p) => PathBuf::from(p),
+        None => codemap_dir.join("export"),
+    };
+
+    match export_tables(&graph, &out_dir, format) {
+        Ok(written) => {
+            println!("Exported {} tables to {}", written.len(), out_dir.display());
+            for path in &written {
+                println!("  - {}", path.display());
+            }
+        }
+        Err(e) => {
+            eprintln!("Export failed: {}", e);
+            std::process::exit(1);
+        }
+    }
+}
@@ -1,8 +1,10 @@
 use clap::Args;
 use std::path::PathBuf;
 
-use crate::graph::load_graph;
+use super::query::load_workspace_graph;
+use crate::graph::{load_graph, CodeGraph};
 use crate::impact::analyze_impact;
+use crate::workspace::resolve_module;
 
 #[derive(Args)]
 pub struct ImpactArgs {
@@ -14,9 +16,19 @@ pub struct ImpactArgs {
     /// Project directory
     #[arg(long, default_value = ".")]
     pub dir: String,
+    /// Follow dependants across all repositories listed in a workspace file (default: codemap.workspace.json)
+    #[arg(long, num_args = 0..=1, default_missing_value = crate::workspace::WORKSPACE_FILE)]
+    pub workspace: Option<String>,
 }
 
 pub fn run(args: ImpactArgs) {
+    if let Some(workspace) = &args.workspace {
+        let graph = load_workspace_graph(workspace);
+        let target = resolve_module(&graph, &args.target).unwrap_or_else(|| args.target.clone());
+        print_impact(&graph, &target, args.depth);
+        return;
+    }
+
     let root_dir = match PathBuf::from(&args.dir).canonicalize() {
         Ok(p) => p,
         Err(e) => {
@@ -34,9 +46,13 @@ pub fn run(args: ImpactArgs) {
         }
     };
 
-    let result = analyze_impact(&graph, &args.target, args.depth);
+    print_impact(&graph, &args.target, args.depth);
+}
+
+fn print_impact(graph: &CodeGraph, target: &str, depth: u32) {
+    let result = analyze_impact(graph, target, depth);
 
-    println!("Impact analysis for: {}", args.target);
+    println!("Impact analysis for: {}", target);
     println!("  Target type: {}", result.target_type.as_str());
     println!("  Target module: {}", result.target_module);
 
@@ -63,4 +79,19 @@ pub fn run(args: ImpactArgs) {
     for file in &result.impacted_files {
         println!("    - {file}");
     }
+    if !result.audit_sites.is_empty() {
+        println!("  Audit sites in target ({}):", result.audit_sites.len());
+        for site in &result.audit_sites {
+            println!("    - {}", crate::audit_sites::format_finding(site));
+        }
+    }
+    if !result.targets.is_empty() {
+        println!("  Affected build targets ({}):", result.targets.len());
+        for t in &result.targets {
+            match &t.via {
+                Some(via) => println!("    - changing {target} affects `{}` (via {via})", t.label),
+                None => println!("    - changing {target} affects `{}`", t.label),
+            }
+        }
+    }
 }
@@ -0,0 +1,200 @@
+use clap::Args;
+use std::path::{Path, PathBuf};
+use std::process::Command;
+
+use crate::graph::{to_record_lines, CodeGraph, MetaInfo};
+use crate::merge::{
+    classify, disk_hash, merge_derived, merge_graphs, merge_meta, rescan_from_disk, MergeKind,
+    SLICES_STALE_MARKER,
+};
+
+/// .gitattributes 中登记的合并规则
+const GITATTRIBUTES_LINES: &[&str] = &[
+    ".codemap/graph.json merge=codegraph",
+    ".codemap/meta.json merge=codegraph",
+    ".codemap/slices/*.json merge=codegraph",
+];
+
+#[derive(Args)]
+pub struct MergeDriverArgs {
+    /// Common ancestor version (%O)
+    #[arg(required_unless_present = "install")]
+    pub base: Option<String>,
+    /// Current branch version; the merge result is written here (%A)
+    #[arg(required_unless_present = "install")]
+    pub ours: Option<String>,
+    /// Other branch version (%B)
+    #[arg(required_unless_present = "install")]
+    pub theirs: Option<String>,
+    /// Path of the merged file inside the repository (%P)
+    #[arg(required_unless_present = "install")]
+    pub path: Option<String>,
+    /// Register the driver in git config and .gitattributes instead of merging
+    #[arg(long)]
+    pub install: bool,
+    /// Project directory (used with --install)
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+pub fn run(args: MergeDriverArgs) {
+    if args.install {
+        install(&args.dir);
+        return;
+    }
+
+    // clap 已保证四个位置参数在非 --install 模式下都存在
+    let (base, ours, theirs, path) = match (args.base, args.ours, args.theirs, args.path) {
+        (Some(b), Some(o), Some(t), Some(p)) => (b, o, t, p),
+        _ => std::process::exit(2),
+    };
+
+    let (kind, root) = match classify(&path) {
+        Some(c) => c,
+        None => {
+            eprintln!("codegraph merge-driver: '{}' is not a .codemap/ file", path);
+            std::process::exit(1);
+        }
+    };
+
+    let result = match kind {
+        MergeKind::Graph => merge_graph_file(&base, &ours, &theirs, &root),
+        MergeKind::Meta => merge_meta_file(&base, &ours, &theirs, &root),
+        MergeKind::Slice => merge_slice_file(&base, &ours, &theirs, &root, &path),
+    };
+
+    if let Err(e) = result {
+        // 非零退出码让 git 将该文件标记为冲突
+        eprintln!("codegraph merge-driver: failed to merge {}: {}", path, e);
+        std::process::exit(1);
+    }
+}
+
+fn merge_graph_file(base: &str, ours: &str, theirs: &str, root: &Path) -> anyhow::Result<()> {
+    let base_graph: CodeGraph = read_json(base)?;
+    let ours_graph: CodeGraph = read_json(ours)?;
+    let theirs_graph: CodeGraph = read_json(theirs)?;
+
+    let rescan = |rel: &str| rescan_from_disk(root, rel, &ours_graph);
+    let (merged, conflicts) = merge_graphs(&base_graph, &ours_graph, &theirs_graph, &rescan);
+    if !conflicts.is_empty() {
+        eprintln!(
+            "codegraph merge-driver: re-scanned {} conflicted file(s): {}",
+            conflicts.len(),
+            conflicts.join(", ")
+        );
+    }
+    std::fs::write(ours, to_record_lines(&merged)?)?;
+    Ok(())
+}
+
+fn merge_meta_file(base: &str, ours: &str, theirs: &str, root: &Path) -> anyhow::Result<()> {
+    let base_meta: MetaInfo = read_json(base)?;
+    let ours_meta: MetaInfo = read_json(ours)?;
+    let theirs_meta: MetaInfo = read_json(theirs)?;
+
+    let hash = |rel: &str| disk_hash(root, rel);
+    let merged = merge_meta(&base_meta, &ours_meta, &theirs_meta, &hash);
+    std::fs::write(ours, to_record_lines(&merged)?)?;
+    Ok(())
+}
+
+fn merge_slice_file(
+    base: &str,
+    ours: &str,
+    theirs: &str,
+    root: &Path,
+    path: &str,
+) -> anyhow::Result<()> {
+    let base_text = std::fs::read_to_string(base)?;
+    let ours_text = std::fs::read_to_string(ours)?;
+    let theirs_text = std::fs::read_to_string(theirs)?;
+
+    match merge_derived(&base_text, &ours_text, &theirs_text) {
+        Some(text) => {
+            if text != ours_text {
+                std::fs::write(ours, text)?;
+            }
+        }
+        None => {
+            // 切片由 graph.json 派生：保留 ours，留下标记让下次 update 重新生成
+            let codemap_dir = root.join(".codemap");
+            std::fs::create_dir_all(&codemap_dir)?;
+            std::fs::write(codemap_dir.join(SLICES_STALE_MARKER), "")?;
+            eprintln!(
+                "codegraph merge-driver: kept ours for {}; run 'codegraph update' to regenerate slices",
+                path
+            );
+        }
+    }
+    Ok(())
+}
+
+fn read_json<T: serde::de::DeserializeOwned>(path: &str) -> anyhow::Result<T> {
+    let data = std::fs::read_to_string(path)?;
+    Ok(serde_json::from_str(&data)?)
+}
+
+// ── 安装 ──────────────────────────────────────────────────────────────────────
+
+fn install(dir: &str) {
+    let root = match PathBuf::from(dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", dir, e);
+            std::process::exit(1);
+        }
+    };
+
+    let settings = [
+        ("merge.codegraph.name", "codegraph .codemap merge driver"),
+        (
+            "merge.codegraph.driver",
+            "codegraph merge-driver %O %A %B %P",
+        ),
+    ];
+    for (key, value) in settings {
+        let status = Command::new("git")
+            .args(["config", key, value])
+            .current_dir(&root)
+            .status();
+        if !matches!(status, Ok(s) if s.success()) {
+            eprintln!(
+                "Error: 'git config {}' failed (is {} a git repository?)",
+                key,
+                root.display()
+            );
+            std::process::exit(1);
+        }
+    }
+
+    let attributes_path = root.join(".gitattributes");
+    let existing = std::fs::read_to_string(&attributes_path).unwrap_or_default();
+    let missing: Vec<&str> = GITATTRIBUTES_LINES
+        .iter()
+        .copied()
+        .filter(|line| !existing.lines().any(|l| l.trim() == *line))
+        .collect();
+    if !missing.is_empty() {
+        let mut content = existing.clone();
+        if !content.is_empty() && !content.ends_with('\n') {
+            content.push('\n');
+        }
+        for line in &missing {
+            content.push_str(line);
+            content.push('\n');
+        }
+        if let Err(e) = std::fs::write(&attributes_path, content) {
+            eprintln!("Error: cannot write {}: {}", attributes_path.display(), e);
+            std::process::exit(1);
+        }
+    }
+
+    println!("Merge driver installed.");
+    println!("  git config: merge.codegraph.driver = codegraph merge-driver %O %A %B %P");
+    println!(
+        "  {}: {} rule(s) added",
+        attributes_path.display(),
+        missing.len()
+    );
+}
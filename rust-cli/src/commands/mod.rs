@@ -1,6 +1,27 @@
+pub mod audit_sites;
+pub mod brief;
+pub mod broken_imports;
+pub mod check;
+pub mod concurrency;
+pub mod context;
+pub mod deprecated;
+pub mod deps;
+pub mod doc_coverage;
+pub mod doctor;
+pub mod env;
+pub mod errors;
+pub mod export;
 pub mod impact;
+pub mod merge_driver;
+pub mod note;
+pub mod path;
+pub mod projects;
 pub mod query;
 pub mod scan;
+pub mod search;
 pub mod slice;
 pub mod status;
+pub mod targets;
+pub mod todos;
+pub mod tui;
 pub mod update;
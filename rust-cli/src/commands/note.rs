@@ -0,0 +1,246 @@
+use clap::{Args, Subcommand};
+use serde::Serialize;
+use std::path::PathBuf;
+
+use crate::graph::{load_graph, CodeGraph};
+use crate::notes::{
+    add_note, find_targets, load_notes, remove_notes, resolve, NoteEntry, NoteTarget, Resolution,
+};
+
+#[derive(Args)]
+pub struct NoteArgs {
+    #[command(subcommand)]
+    pub command: NoteCommand,
+}
+
+#[derive(Subcommand)]
+pub enum NoteCommand {
+    /// Attach a note to a module or symbol
+    Add(NoteAddArgs),
+    /// List all notes and flag those whose target no longer exists
+    List(NoteListArgs),
+    /// Delete all notes of a target by note ID
+    Remove(NoteRemoveArgs),
+}
+
+#[derive(Args)]
+pub struct NoteAddArgs {
+    /// Module name or symbol name
+    pub target: String,
+    /// Note text
+    pub text: String,
+    /// Target kind: module, function, class, type, variable, or config
+    #[arg(long)]
+    pub r#type: Option<String>,
+    /// File defining the symbol (when several symbols share the name)
+    #[arg(long)]
+    pub file: Option<String>,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+#[derive(Args)]
+pub struct NoteListArgs {
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+#[derive(Args)]
+pub struct NoteRemoveArgs {
+    /// Note ID (shown by "note list")
+    pub id: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+#[derive(Serialize)]
+struct ListedNote<'a> {
+    #[serde(flatten)]
+    entry: &'a NoteEntry,
+    resolution: Resolution,
+}
+
+pub fn run(args: NoteArgs) {
+    match args.command {
+        NoteCommand::Add(a) => run_add(a),
+        NoteCommand::List(a) => run_list(a),
+        NoteCommand::Remove(a) => run_remove(a),
+    }
+}
+
+fn run_add(args: NoteAddArgs) {
+    if let Some(kind) = &args.r#type {
+        if kind != "module" && !crate::notes::SYMBOL_KINDS.contains(&kind.as_str()) {
+            eprintln!(
+                "Error: unsupported type '{}' (expected module, function, class, type, variable or config)",
+                kind
+            );
+            std::process::exit(1);
+        }
+    }
+    let (root_dir, graph) = load(&args.dir);
+
+    let mut targets = find_targets(&graph, &args.target, args.r#type.as_deref());
+    if let Some(file) = &args.file {
+        targets.retain(|t| t.file.as_deref() == Some(file.as_str()));
+    }
+    let target: NoteTarget = match targets.len() {
+        0 => {
+            eprintln!(
+                "Error: no module or symbol named '{}' in the code graph.",
+                args.target
+            );
+            std::process::exit(1);
+        }
+        1 => targets.remove(0),
+        _ => {
+            eprintln!(
+                "Error: '{}' is ambiguous; narrow it with --type and/or --file:",
+                args.target
+            );
+            for t in &targets {
+                eprintln!("  {}", describe(t));
+            }
+            std::process::exit(1);
+        }
+    };
+
+    let output_dir = root_dir.join(".codemap");
+    let id = match add_note(&output_dir, &graph, &target, &args.text) {
+        Ok(id) => id,
+        Err(e) => {
+            eprintln!("Error: cannot write note: {}", e);
+            std::process::exit(1);
+        }
+    };
+    // 重新生成切片，让 load 读取的 _overview.json 与模块切片带上新注释
+    if let Err(e) = crate::slicer::save_slices(&output_dir, &graph) {
+        eprintln!("Warning: note saved but slices were not refreshed: {}", e);
+    }
+    println!("Added note {} to {}", id, describe(&target));
+}
+
+fn run_list(args: NoteListArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+    let (root_dir, graph) = load(&args.dir);
+    let entries = match load_notes(&root_dir.join(".codemap")) {
+        Ok(e) => e,
+        Err(e) => {
+            eprintln!("Error: {}", e);
+            std::process::exit(1);
+        }
+    };
+    let listed: Vec<ListedNote> = entries
+        .iter()
+        .map(|entry| ListedNote {
+            entry,
+            resolution: resolve(&graph, &entry.target),
+        })
+        .collect();
+
+    if args.format == "json" {
+        match serde_json::to_string_pretty(&listed) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+        return;
+    }
+
+    if listed.is_empty() {
+        println!("No notes.");
+        return;
+    }
+    let mut flagged = 0;
+    for item in &listed {
+        let target = &item.entry.target;
+        let state = match &item.resolution {
+            Resolution::Found { file } if file != &target.file => {
+                format!("  (moved to {})", file.as_deref().unwrap_or("-"))
+            }
+            Resolution::Found { .. } => String::new(),
+            Resolution::Ambiguous { candidates } => {
+                flagged += 1;
+                format!("  [AMBIGUOUS: {}]", candidates.join(", "))
+            }
+            Resolution::Missing => {
+                flagged += 1;
+                "  [TARGET MISSING]".to_string()
+            }
+        };
+        println!("{}  {}{}", item.entry.id, describe(target), state);
+        for note in &item.entry.notes {
+            println!("    - {}  ({})", note.text, note.created_at);
+        }
+    }
+    if flagged > 0 {
+        println!();
+        println!(
+            "{} note target(s) no longer resolve. Re-add them to the new target and run \"codegraph note remove <id>\".",
+            flagged
+        );
+    }
+}
+
+fn run_remove(args: NoteRemoveArgs) {
+    let root_dir = resolve_dir(&args.dir);
+    let output_dir = root_dir.join(".codemap");
+    match remove_notes(&output_dir, &args.id) {
+        Ok(true) => {
+            if let Ok(graph) = load_graph(&output_dir) {
+                let _ = crate::slicer::save_slices(&output_dir, &graph);
+            }
+            println!("Removed note {}", args.id);
+        }
+        Ok(false) => {
+            eprintln!("Error: no note with ID '{}'.", args.id);
+            std::process::exit(1);
+        }
+        Err(e) => {
+            eprintln!("Error: {}", e);
+            std::process::exit(1);
+        }
+    }
+}
+
+fn resolve_dir(dir: &str) -> PathBuf {
+    match PathBuf::from(dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", dir, e);
+            std::process::exit(1);
+        }
+    }
+}
+
+fn load(dir: &str) -> (PathBuf, CodeGraph) {
+    let root_dir = resolve_dir(dir);
+    match load_graph(&root_dir.join(".codemap")) {
+        Ok(g) => (root_dir, g),
+        Err(_) => {
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    }
+}
+
+fn describe(target: &NoteTarget) -> String {
+    match &target.file {
+        Some(file) => format!("{} {} ({})", target.kind, target.name, file),
+        None => format!("{} {}", target.kind, target.name),
+    }
+}
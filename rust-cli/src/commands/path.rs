@@ -0,0 +1,74 @@
+use clap::Args;
+use std::path::PathBuf;
+
+use super::query::load_workspace_graph;
+use crate::graph::{load_graph, CodeGraph};
+use crate::impact::{dependency_path, find_target};
+use crate::workspace::resolve_module;
+
+#[derive(Args)]
+pub struct PathArgs {
+    /// Starting module or file (the dependant)
+    pub from: String,
+    /// Target module or file (the dependency)
+    pub to: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+    /// Search across all repositories listed in a workspace file (default: codemap.workspace.json)
+    #[arg(long, num_args = 0..=1, default_missing_value = crate::workspace::WORKSPACE_FILE)]
+    pub workspace: Option<String>,
+}
+
+pub fn run(args: PathArgs) {
+    let graph = match &args.workspace {
+        Some(workspace) => load_workspace_graph(workspace),
+        None => {
+            let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+                Ok(p) => p,
+                Err(e) => {
+                    eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+                    std::process::exit(1);
+                }
+            };
+            match load_graph(&root_dir.join(".codemap")) {
+                Ok(g) => g,
+                Err(_) => {
+                    eprintln!("No code graph found. Run \"codegraph scan\" first.");
+                    std::process::exit(1);
+                }
+            }
+        }
+    };
+
+    let from = resolve(&graph, &args.from);
+    let to = resolve(&graph, &args.to);
+
+    match dependency_path(&graph, &from, &to) {
+        Some(path) => {
+            println!("Dependency path ({} hop(s)):", path.len() - 1);
+            for (i, module) in path.iter().enumerate() {
+                let arrow = if i == 0 { "  " } else { "  → " };
+                println!("{}{}", arrow, module);
+            }
+        }
+        None => {
+            println!("No dependency path from '{}' to '{}'.", from, to);
+            std::process::exit(1);
+        }
+    }
+}
+
+/// 将模块名或文件路径解析为模块；联邦模式下裸模块名自动补全 repo: 前缀
+fn resolve(graph: &CodeGraph, target: &str) -> String {
+    if let Some(module) = resolve_module(graph, target) {
+        return module;
+    }
+    match find_target(graph, target) {
+        Some((_, module)) => module,
+        None => {
+            eprintln!("'{}' matches no module or file in the graph.", target);
+            std::process::exit(1);
+        }
+    }
+}
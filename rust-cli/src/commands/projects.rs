@@ -0,0 +1,85 @@
+use clap::Args;
+
+use crate::registry::{codemap_home, load_registry, project_status, prune_missing, ProjectState};
+
+#[derive(Args)]
+pub struct ProjectsArgs {
+    /// Remove projects whose directory or .codemap/ no longer exists
+    #[arg(long)]
+    pub prune: bool,
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+}
+
+pub fn run(args: ProjectsArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+
+    let Some(home) = codemap_home() else {
+        eprintln!("Error: cannot locate $CODEMAP_HOME (set CODEMAP_HOME or HOME)");
+        std::process::exit(1);
+    };
+
+    if args.prune {
+        match prune_missing(&home) {
+            Ok(removed) => {
+                for p in &removed {
+                    eprintln!("Removed {} ({})", p.name, p.path);
+                }
+            }
+            Err(e) => {
+                eprintln!("Error: {}", e);
+                std::process::exit(1);
+            }
+        }
+    }
+
+    let projects = match load_registry(&home) {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: {}", e);
+            std::process::exit(1);
+        }
+    };
+    let statuses: Vec<_> = projects.iter().map(project_status).collect();
+
+    if args.format == "json" {
+        match serde_json::to_string_pretty(&statuses) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+        return;
+    }
+
+    if statuses.is_empty() {
+        println!("No projects registered. Run \"codegraph scan\" in a project first.");
+        return;
+    }
+    println!("Projects ({}):", statuses.len());
+    for s in &statuses {
+        let r = &s.record;
+        let mut state = s.state.as_str().to_string();
+        if let Some(c) = s.changes.filter(|_| s.state == ProjectState::Stale) {
+            state = format!("{} +{} ~{} -{}", state, c.added, c.modified, c.removed);
+        }
+        if s.head_moved {
+            state.push_str(", HEAD moved");
+        }
+        println!();
+        println!("{} [{}]", r.name, state);
+        println!("  path:      {}", r.path);
+        println!("  scanned:   {} ({} files)", r.last_scan, r.files);
+        if !r.languages.is_empty() {
+            println!("  languages: {}", r.languages.join(", "));
+        }
+    }
+}
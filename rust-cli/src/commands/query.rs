@@ -1,11 +1,11 @@
 use clap::Args;
-use std::path::PathBuf;
+use std::path::{Path, PathBuf};
 
 #[derive(Args)]
 pub struct QueryArgs {
     /// Symbol or module name to query
     pub symbol: String,
-    /// Filter by type: function, class, type, or variable
+    /// Filter by type: function, class, type, variable, or config (environment variables and config keys)
     #[arg(long)]
     pub r#type: Option<String>,
     /// Project directory
@@ -14,9 +14,25 @@ pub struct QueryArgs {
     /// Query a module instead of a symbol
     #[arg(long)]
     pub module: bool,
+    /// Query across all repositories listed in a workspace file (default: codemap.workspace.json); results are prefixed by repo
+    #[arg(long, num_args = 0..=1, default_missing_value = crate::workspace::WORKSPACE_FILE)]
+    pub workspace: Option<String>,
 }
 
 pub fn run(args: QueryArgs) {
+    if let Some(workspace) = &args.workspace {
+        let graph = load_workspace_graph(workspace);
+        // 裸模块名在仓库间唯一时自动补全 repo: 前缀
+        let symbol = if args.module {
+            crate::workspace::resolve_module(&graph, &args.symbol)
+                .unwrap_or_else(|| args.symbol.clone())
+        } else {
+            args.symbol.clone()
+        };
+        print_results(&graph, &symbol, &args, &[]);
+        return;
+    }
+
     let root = PathBuf::from(&args.dir);
     let root = match root.canonicalize() {
         Ok(p) => p,
@@ -40,12 +56,41 @@ pub fn run(args: QueryArgs) {
         }
     };
 
+    let notes = crate::notes::load_notes(&output_dir)
+        .map(|entries| crate::notes::attached_notes(&graph, &entries))
+        .unwrap_or_default();
+    print_results(&graph, &args.symbol, &args, &notes);
+
+    // 启用了 external 层时，附带输出第三方依赖中的匹配
+    if !args.module {
+        if let Ok(Some(layer)) = crate::external::load(&output_dir) {
+            let opts = crate::query::QueryOptions {
+                type_filter: args.r#type.clone(),
+            };
+            let results = crate::external::query_symbol(&layer, &graph, &args.symbol, &opts);
+            if !results.is_empty() {
+                println!("\nThird-party APIs (read-only, .codemap/external.json):");
+                println!("{}", crate::query::format_symbol_results(&results));
+            }
+        }
+    }
+}
+
+fn print_results(
+    graph: &crate::graph::CodeGraph,
+    symbol: &str,
+    args: &QueryArgs,
+    notes: &[crate::notes::AttachedNote],
+) {
     if args.module {
         // 模块查询模式
-        match crate::query::query_module(&graph, &args.symbol) {
-            Some(result) => println!("{}", crate::query::format_module_result(&result)),
+        match crate::query::query_module(graph, symbol) {
+            Some(mut result) => {
+                crate::notes::attach_to_module(&mut result, notes);
+                println!("{}", crate::query::format_module_result(&result));
+            }
             None => {
-                eprintln!("Module '{}' not found.", args.symbol);
+                eprintln!("Module '{}' not found.", symbol);
                 // 列出可用模块
                 let mut mods: Vec<&str> = graph.modules.keys().map(|s| s.as_str()).collect();
                 mods.sort();
@@ -60,7 +105,20 @@ pub fn run(args: QueryArgs) {
         let opts = crate::query::QueryOptions {
             type_filter: args.r#type.clone(),
         };
-        let results = crate::query::query_symbol(&graph, &args.symbol, &opts);
+        let mut results = crate::query::query_symbol(graph, symbol, &opts);
+        crate::notes::attach_to_symbols(&mut results, notes);
         println!("{}", crate::query::format_symbol_results(&results));
     }
 }
+
+/// 加载 workspace 文件并合成联邦图谱；失败时打印错误并退出
+pub(crate) fn load_workspace_graph(workspace: &str) -> crate::graph::CodeGraph {
+    match crate::workspace::Federation::load(Path::new(workspace)) {
+        Ok(fed) => fed.merged_graph(),
+        Err(e) => {
+            eprintln!("Error: failed to load workspace '{}': {}", workspace, e);
+            eprintln!("Hint: run 'codegraph scan' in each repository listed in the workspace.");
+            std::process::exit(1);
+        }
+    }
+}
@@ -1,6 +1,8 @@
 use clap::Args;
 use std::path::PathBuf;
 
+use crate::traverser::SubmodulePolicy;
+
 #[derive(Args)]
 pub struct ScanArgs {
     /// Project directory to scan
@@ -8,11 +10,28 @@ pub struct ScanArgs {
     /// Additional glob patterns to exclude
     #[arg(long, num_args = 1..)]
     pub exclude: Vec<String>,
+    /// Also scan the exported API of imported third-party packages into .codemap/external.json
+    #[arg(long)]
+    pub external: bool,
+    /// Descend into symlinked directories (loops are skipped, files reachable by several paths are kept once)
+    #[arg(long)]
+    pub follow_symlinks: bool,
+    /// How to treat git submodules: skip, external (read-only layer), or scan (as project modules)
+    #[arg(long, default_value = "scan")]
+    pub submodules: String,
 }
 
 pub fn run(args: ScanArgs) {
     let dir = args.dir.unwrap_or_else(|| ".".to_string());
     let root = PathBuf::from(&dir);
+    let Some(submodules) = SubmodulePolicy::parse(&args.submodules) else {
+        eprintln!(
+            "Error: unsupported submodule policy '{}' (expected {})",
+            args.submodules,
+            SubmodulePolicy::ALL.join(", ")
+        );
+        std::process::exit(1);
+    };
     let root = match root.canonicalize() {
         Ok(p) => p,
         Err(e) => {
@@ -23,18 +42,39 @@ pub fn run(args: ScanArgs) {
 
     println!("Scanning {}...", root.display());
 
-    match crate::scanner::scan_and_save(&root, &args.exclude) {
+    // 同时生成 slices/（与 Node.js scan 行为一致）
+    let opts = crate::scanner::ScanOptions::new()
+        .excludes(args.exclude)
+        .scan_external(args.external)
+        .follow_symlinks(args.follow_symlinks)
+        .submodules(submodules);
+    match opts.scan_and_save(&root) {
         Ok(graph) => {
             let codemap_dir = root.join(".codemap");
-            // 生成 slices/（与 Node.js scan 行为一致）
-            if let Err(e) = crate::slicer::save_slices(&codemap_dir, &graph) {
-                eprintln!("Warning: failed to save slices: {}", e);
-            }
             println!("Scan complete.");
             println!("  Files:     {}", graph.summary.total_files);
             println!("  Functions: {}", graph.summary.total_functions);
             println!("  Modules:   {}", graph.summary.modules.join(", "));
+            if !graph.config.submodule_paths.is_empty() {
+                println!(
+                    "  Submodules: {} ({})",
+                    graph.config.submodule_paths.join(", "),
+                    graph.config.submodules.as_str()
+                );
+            }
+            if args.external {
+                if let Ok(Some(layer)) = crate::external::load(&codemap_dir) {
+                    println!(
+                        "  External:  {} package(s), {} file(s)",
+                        layer.modules.len(),
+                        layer.files.len()
+                    );
+                }
+            }
             println!("  Output:    {}", codemap_dir.display());
+            if let Err(e) = crate::registry::register_project(&root, &graph) {
+                eprintln!("Warning: could not update the project registry: {}", e);
+            }
         }
         Err(e) => {
             eprintln!("Scan failed: {}", e);
@@ -0,0 +1,120 @@
+use clap::Args;
+use std::path::PathBuf;
+
+use crate::graph::load_graph;
+use crate::query::QueryOptions;
+use crate::registry::{codemap_home, load_registry, search_projects, ProjectRecord};
+
+/// 可用的符号类型过滤
+const SYMBOL_TYPES: &[&str] = &["function", "class", "type", "variable", "config"];
+
+#[derive(Args)]
+pub struct SearchArgs {
+    /// Symbol name (substring match)
+    pub symbol: String,
+    /// Search every project in the registry ($CODEMAP_HOME/projects.json) instead of --dir
+    #[arg(long)]
+    pub all: bool,
+    /// Filter by type: function, class, type, variable, or config
+    #[arg(long)]
+    pub r#type: Option<String>,
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory (ignored with --all)
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+pub fn run(args: SearchArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+    if let Some(t) = &args.r#type {
+        if !SYMBOL_TYPES.contains(&t.as_str()) {
+            eprintln!(
+                "Error: unsupported type '{}' (expected {})",
+                t,
+                SYMBOL_TYPES.join(", ")
+            );
+            std::process::exit(1);
+        }
+    }
+
+    let projects = if args.all {
+        let Some(home) = codemap_home() else {
+            eprintln!("Error: cannot locate $CODEMAP_HOME (set CODEMAP_HOME or HOME)");
+            std::process::exit(1);
+        };
+        match load_registry(&home) {
+            Ok(p) => p,
+            Err(e) => {
+                eprintln!("Error: {}", e);
+                std::process::exit(1);
+            }
+        }
+    } else {
+        let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+            Ok(p) => p,
+            Err(e) => {
+                eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+                std::process::exit(1);
+            }
+        };
+        let graph = match load_graph(&root_dir.join(".codemap")) {
+            Ok(g) => g,
+            Err(_) => {
+                eprintln!("No code graph found. Run \"codegraph scan\" first.");
+                std::process::exit(1);
+            }
+        };
+        vec![ProjectRecord::from_graph(&root_dir, &graph)]
+    };
+
+    let opts = QueryOptions {
+        type_filter: args.r#type.clone(),
+    };
+    let matches = search_projects(&projects, &args.symbol, &opts);
+
+    if args.format == "json" {
+        match serde_json::to_string_pretty(&matches) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+        return;
+    }
+
+    if matches.is_empty() {
+        println!(
+            "No symbols matching '{}' in {} project(s).",
+            args.symbol,
+            projects.len()
+        );
+        return;
+    }
+    let total: usize = matches.iter().map(|m| m.results.len()).sum();
+    println!(
+        "{} match(es) for '{}' in {} of {} project(s):",
+        total,
+        args.symbol,
+        matches.len(),
+        projects.len()
+    );
+    for m in &matches {
+        println!();
+        println!("{} ({})", m.project, m.path);
+        for r in &m.results {
+            println!(
+                "  [{}] {}  {}:{}  ({})",
+                r.kind, r.name, r.file, r.lines.start, r.module
+            );
+        }
+    }
+}
@@ -37,10 +37,16 @@ pub fn run(args: SliceArgs) {
         }
     };
 
+    // 持久注释（.codemap/notes/）
+    let notes = crate::notes::load_notes(&codemap_dir)
+        .map(|entries| crate::notes::attached_notes(&graph, &entries))
+        .unwrap_or_default();
+
     match args.module {
         None => {
             // 输出 overview
-            let overview = crate::slicer::generate_overview(&graph);
+            let mut overview = crate::slicer::generate_overview(&graph);
+            crate::notes::attach_to_overview(&mut overview, &graph, &notes);
             match serde_json::to_string_pretty(&overview) {
                 Ok(json) => println!("{}", json),
                 Err(e) => {
@@ -50,36 +56,30 @@ pub fn run(args: SliceArgs) {
             }
         }
         Some(mod_name) => {
-            if args.with_deps {
-                match crate::slicer::get_module_slice_with_deps(&graph, &mod_name) {
-                    Ok(slice) => match serde_json::to_string_pretty(&slice) {
-                        Ok(json) => println!("{}", json),
-                        Err(e) => {
-                            eprintln!("Serialization error: {}", e);
-                            std::process::exit(1);
-                        }
-                    },
-                    Err(e) => {
-                        eprintln!("Error: {}", e);
-                        std::process::exit(1);
-                    }
+            // 启用了 external 层时附带模块用到的第三方 API
+            let layer = crate::external::load(&codemap_dir).ok().flatten();
+            let attach = |slice: &mut crate::slicer::ModuleSlice| {
+                if let Some(layer) = &layer {
+                    crate::external::attach_apis(slice, &graph, layer);
                 }
+                crate::notes::attach_to_slice(slice, &graph, &notes);
+            };
+            let json = if args.with_deps {
+                crate::api::slice_with_deps(&graph, &mod_name).and_then(|mut slice| {
+                    attach(&mut slice.slice);
+                    Ok(serde_json::to_string_pretty(&slice)?)
+                })
             } else {
-                match graph.modules.get(&mod_name) {
-                    Some(mod_data) => {
-                        let slice = crate::slicer::build_module_slice(&graph, &mod_name, mod_data);
-                        match serde_json::to_string_pretty(&slice) {
-                            Ok(json) => println!("{}", json),
-                            Err(e) => {
-                                eprintln!("Serialization error: {}", e);
-                                std::process::exit(1);
-                            }
-                        }
-                    }
-                    None => {
-                        eprintln!("Error: module \"{}\" not found in graph.", mod_name);
-                        std::process::exit(1);
-                    }
+                crate::api::slice(&graph, &mod_name).and_then(|mut slice| {
+                    attach(&mut slice);
+                    Ok(serde_json::to_string_pretty(&slice)?)
+                })
+            };
+            match json {
+                Ok(json) => println!("{}", json),
+                Err(e) => {
+                    eprintln!("Error: {}", e);
+                    std::process::exit(1);
                 }
             }
         }
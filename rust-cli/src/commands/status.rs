@@ -1,15 +1,60 @@
 use clap::Args;
+use serde::Serialize;
 use std::path::PathBuf;
 
-use crate::graph::{load_graph, load_meta};
+use crate::freshness::{check_freshness, Freshness};
+use crate::graph::{load_graph, load_meta, CodeGraph, MetaInfo};
 
 #[derive(Args)]
 pub struct StatusArgs {
     /// Project directory
     pub dir: Option<String>,
+    /// Compare file sizes/mtimes (and git HEAD) with meta.json; exit 2 when the graph is stale
+    #[arg(long)]
+    pub check: bool,
+    /// Output format: text, json, or compact (one line, for hooks)
+    #[arg(long, default_value = "text")]
+    pub format: String,
+}
+
+#[derive(Serialize)]
+struct StatusOutput<'a> {
+    project: &'a str,
+    #[serde(rename = "scannedAt")]
+    scanned_at: &'a str,
+    commit: Option<&'a str>,
+    files: u32,
+    functions: u32,
+    classes: u32,
+    modules: &'a [String],
+    #[serde(rename = "brokenImports")]
+    broken_imports: usize,
+    #[serde(rename = "bindingEdges")]
+    binding_edges: usize,
+    #[serde(rename = "trackedFiles")]
+    tracked_files: usize,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    freshness: Option<FreshnessOutput<'a>>,
+}
+
+#[derive(Serialize)]
+struct FreshnessOutput<'a> {
+    stale: bool,
+    #[serde(rename = "headMoved")]
+    head_moved: bool,
+    #[serde(flatten)]
+    detail: &'a Freshness,
 }
 
 pub fn run(args: StatusArgs) {
+    if !matches!(args.format.as_str(), "text" | "json" | "compact") {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text, json or compact)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+
     let dir = args.dir.unwrap_or_else(|| ".".to_string());
     let root_dir = match PathBuf::from(&dir).canonicalize() {
         Ok(p) => p,
@@ -35,6 +80,89 @@ pub fn run(args: StatusArgs) {
 
     let meta = load_meta(&output_dir).ok();
 
+    let freshness = if args.check {
+        match &meta {
+            Some(m) => Some(check_freshness(&root_dir, m, &graph.config)),
+            None => {
+                eprintln!("Error: .codemap/meta.json is missing or unreadable. Run \"codegraph doctor --fix\".");
+                std::process::exit(1);
+            }
+        }
+    } else {
+        None
+    };
+
+    match args.format.as_str() {
+        "json" => print_json(&graph, meta.as_ref(), freshness.as_ref()),
+        "compact" => print_compact(&graph, freshness.as_ref()),
+        _ => print_text(&graph, meta.as_ref(), &output_dir, freshness.as_ref()),
+    }
+
+    if freshness.as_ref().is_some_and(|f| f.is_stale()) {
+        std::process::exit(2);
+    }
+}
+
+fn print_json(graph: &CodeGraph, meta: Option<&MetaInfo>, freshness: Option<&Freshness>) {
+    let output = StatusOutput {
+        project: &graph.project.name,
+        scanned_at: &graph.scanned_at,
+        commit: graph.commit_hash.as_deref(),
+        files: graph.summary.total_files,
+        functions: graph.summary.total_functions,
+        classes: graph.summary.total_classes,
+        modules: &graph.summary.modules,
+        broken_imports: graph.broken_imports.len(),
+        binding_edges: graph.binding_edges.len(),
+        tracked_files: meta.map(|m| m.file_hashes.len()).unwrap_or(0),
+        freshness: freshness.map(|f| FreshnessOutput {
+            stale: f.is_stale(),
+            head_moved: f.head_moved(),
+            detail: f,
+        }),
+    };
+    match serde_json::to_string_pretty(&output) {
+        Ok(json) => println!("{}", json),
+        Err(e) => {
+            eprintln!("Serialization error: {}", e);
+            std::process::exit(1);
+        }
+    }
+}
+
+/// 单行输出，供 SessionStart hook 直接注入会话
+fn print_compact(graph: &CodeGraph, freshness: Option<&Freshness>) {
+    let counts = format!(
+        "{} files, {} modules",
+        graph.summary.total_files,
+        graph.summary.modules.len()
+    );
+    let line = match freshness {
+        None => format!("[CodeMap] {}, scanned {}", counts, graph.scanned_at),
+        Some(f) if f.is_stale() => format!(
+            "[CodeMap] graph stale: +{} ~{} -{} files since {}; run \"codegraph update\"",
+            f.added.len(),
+            f.modified.len(),
+            f.removed.len(),
+            graph.scanned_at
+        ),
+        Some(_) => format!(
+            "[CodeMap] graph fresh: {}, scanned {}",
+            counts, graph.scanned_at
+        ),
+    };
+    match freshness {
+        Some(f) if f.head_moved() => println!("{} (HEAD moved since scan)", line),
+        _ => println!("{}", line),
+    }
+}
+
+fn print_text(
+    graph: &CodeGraph,
+    meta: Option<&MetaInfo>,
+    output_dir: &std::path::Path,
+    freshness: Option<&Freshness>,
+) {
     println!("Project: {}", graph.project.name);
     println!("Scanned at: {}", graph.scanned_at);
     println!(
@@ -45,6 +173,10 @@ pub fn run(args: StatusArgs) {
     println!("Functions: {}", graph.summary.total_functions);
     println!("Classes: {}", graph.summary.total_classes);
     println!("Modules: {}", graph.summary.modules.join(", "));
+    if !graph.packages.is_empty() {
+        let names: Vec<&str> = graph.packages.keys().map(|k| k.as_str()).collect();
+        println!("Packages: {}", names.join(", "));
+    }
 
     // 语言分布
     if !graph.summary.languages.is_empty() {
@@ -57,12 +189,92 @@ pub fn run(args: StatusArgs) {
         println!("Languages: {}", lang_str.join(", "));
     }
 
+    // 符号链接与子模块策略（非默认或存在子模块时才显示）
+    if graph.config.follow_symlinks {
+        println!("Symlinks: followed");
+    }
+    if !graph.config.submodule_paths.is_empty() {
+        println!(
+            "Submodules ({}): {}",
+            graph.config.submodules.as_str(),
+            graph.config.submodule_paths.join(", ")
+        );
+    }
+
+    println!("Broken imports: {}", graph.broken_imports.len());
+    if !graph.binding_edges.is_empty() {
+        println!(
+            "Cross-language binding edges: {}",
+            graph.binding_edges.len()
+        );
+    }
+
+    // 持久注释（目标消失或有歧义的单独计数）
+    if let Ok(entries) = crate::notes::load_notes(output_dir) {
+        if !entries.is_empty() {
+            let unresolved = entries
+                .iter()
+                .filter(|e| {
+                    !matches!(
+                        crate::notes::resolve(graph, &e.target),
+                        crate::notes::Resolution::Found { .. }
+                    )
+                })
+                .count();
+            println!(
+                "Notes: {} target(s), {} unresolved",
+                entries.len(),
+                unresolved
+            );
+        }
+    }
+
     // 上次更新时间（来自 meta）
-    if let Some(ref m) = meta {
+    if let Some(m) = meta {
         println!("Last update: {}", m.last_scan_at);
     }
 
     // 已追踪文件数
-    let tracked = meta.as_ref().map(|m| m.file_hashes.len()).unwrap_or(0);
+    let tracked = meta.map(|m| m.file_hashes.len()).unwrap_or(0);
     println!("Tracked files: {tracked}");
+
+    // 第三方依赖只读层（scan --external）
+    if let Ok(Some(layer)) = crate::external::load(output_dir) {
+        println!(
+            "External APIs: {} package(s), {} file(s) (scanned at {})",
+            layer.modules.len(),
+            layer.files.len(),
+            layer.scanned_at
+        );
+    }
+
+    // 新鲜度（--check）
+    if let Some(f) = freshness {
+        println!();
+        if f.is_stale() {
+            println!(
+                "Stale: {} added, {} modified, {} removed. Run \"codegraph update\".",
+                f.added.len(),
+                f.modified.len(),
+                f.removed.len()
+            );
+            for (mark, paths) in [("+", &f.added), ("~", &f.modified), ("-", &f.removed)] {
+                for p in paths.iter().take(10) {
+                    println!("  {} {}", mark, p);
+                }
+                if paths.len() > 10 {
+                    println!("  {} ... {} more", mark, paths.len() - 10);
+                }
+            }
+        } else {
+            println!("Fresh: no file changes since the last scan.");
+        }
+        if f.head_moved() {
+            println!(
+                "HEAD moved: {} -> {}",
+                f.scanned_commit.as_deref().unwrap_or("-"),
+                f.head_commit.as_deref().unwrap_or("-")
+            );
+        }
+    }
 }
@@ -0,0 +1,105 @@
+use clap::Args;
+use std::path::PathBuf;
+
+use crate::graph::load_graph;
+
+/// 支持的构建工具
+const RUNNERS: &[&str] = &["make", "just", "npm"];
+
+#[derive(Args)]
+pub struct TargetsArgs {
+    /// Only show one runner: make, just, or npm
+    #[arg(long)]
+    pub runner: Option<String>,
+    /// Only show targets that run this file
+    #[arg(long)]
+    pub file: Option<String>,
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+pub fn run(args: TargetsArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+    if let Some(runner) = &args.runner {
+        if !RUNNERS.contains(&runner.as_str()) {
+            eprintln!(
+                "Error: unsupported runner '{}' (expected {})",
+                runner,
+                RUNNERS.join(", ")
+            );
+            std::process::exit(1);
+        }
+    }
+
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+
+    let graph = match load_graph(&root_dir.join(".codemap")) {
+        Ok(g) => g,
+        Err(_) => {
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    };
+
+    let targets: Vec<_> = graph
+        .targets
+        .iter()
+        .filter(|t| args.runner.as_deref().is_none_or(|r| t.runner == r))
+        .filter(|t| {
+            args.file
+                .as_deref()
+                .is_none_or(|f| t.runs.iter().any(|r| r == f))
+        })
+        .collect();
+
+    if args.format == "json" {
+        match serde_json::to_string_pretty(&targets) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+        return;
+    }
+
+    if targets.is_empty() {
+        println!("No build targets found.");
+        return;
+    }
+    println!("Build targets ({}):", targets.len());
+    let mut current_file = "";
+    for t in targets {
+        if t.file != current_file {
+            current_file = &t.file;
+            println!();
+            println!("{}", t.file);
+        }
+        println!("  {} :{}", t.name, t.line);
+        if !t.depends_on.is_empty() {
+            println!("    depends on: {}", t.depends_on.join(", "));
+        }
+        if !t.runs.is_empty() {
+            println!("    runs: {}", t.runs.join(", "));
+        }
+        for cmd in &t.commands {
+            println!("    $ {}", cmd);
+        }
+    }
+}
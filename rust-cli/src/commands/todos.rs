@@ -0,0 +1,115 @@
+use clap::Args;
+use serde::Serialize;
+use std::collections::BTreeMap;
+use std::path::PathBuf;
+
+use crate::graph::load_graph;
+use crate::todos::{collect_todos, format_todo, TodoFilter, TodoItem, TODO_TAGS};
+
+#[derive(Args)]
+pub struct TodosArgs {
+    /// Only show one tag: TODO, FIXME, HACK, or XXX
+    #[arg(long)]
+    pub tag: Option<String>,
+    /// Only show annotations with this owner, as in TODO(owner)
+    #[arg(long)]
+    pub owner: Option<String>,
+    /// Only show annotations in this module
+    #[arg(long)]
+    pub module: Option<String>,
+    /// Output format: text or json
+    #[arg(long, default_value = "text")]
+    pub format: String,
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+#[derive(Serialize)]
+struct TodosOutput<'a> {
+    /// 每个标签的注释数
+    counts: BTreeMap<&'a str, usize>,
+    todos: &'a [TodoItem],
+}
+
+pub fn run(args: TodosArgs) {
+    if args.format != "text" && args.format != "json" {
+        eprintln!(
+            "Error: unsupported format '{}' (expected text or json)",
+            args.format
+        );
+        std::process::exit(1);
+    }
+    if let Some(tag) = &args.tag {
+        if !TODO_TAGS.iter().any(|t| t.eq_ignore_ascii_case(tag)) {
+            eprintln!(
+                "Error: unsupported tag '{}' (expected {})",
+                tag,
+                TODO_TAGS.join(", ")
+            );
+            std::process::exit(1);
+        }
+    }
+
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+
+    let graph = match load_graph(&root_dir.join(".codemap")) {
+        Ok(g) => g,
+        Err(_) => {
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    };
+
+    if let Some(module) = &args.module {
+        if !graph.modules.contains_key(module) {
+            eprintln!("Error: module '{}' not found in the code graph", module);
+            std::process::exit(1);
+        }
+    }
+    let filter = TodoFilter {
+        tag: args.tag.as_deref(),
+        owner: args.owner.as_deref(),
+        module: args.module.as_deref(),
+    };
+    let todos = collect_todos(&graph, &filter);
+
+    let mut counts: BTreeMap<&str, usize> = BTreeMap::new();
+    for t in &todos {
+        *counts.entry(t.tag.as_str()).or_default() += 1;
+    }
+
+    if args.format == "json" {
+        let output = TodosOutput {
+            counts,
+            todos: &todos,
+        };
+        match serde_json::to_string_pretty(&output) {
+            Ok(json) => println!("{}", json),
+            Err(e) => {
+                eprintln!("Serialization error: {}", e);
+                std::process::exit(1);
+            }
+        }
+        return;
+    }
+
+    if todos.is_empty() {
+        println!("No TODO annotations found.");
+        return;
+    }
+    let summary: Vec<String> = TODO_TAGS
+        .iter()
+        .filter_map(|t| counts.get(t).map(|n| format!("{} {}", t, n)))
+        .collect();
+    println!("{} annotation(s): {}", todos.len(), summary.join(", "));
+    for t in &todos {
+        println!("  {}", format_todo(t));
+    }
+}
@@ -0,0 +1,175 @@
+use clap::Args;
+use std::io::{IsTerminal, Write};
+use std::path::{Path, PathBuf};
+
+use crossterm::event::{self, Event, KeyCode, KeyEvent, KeyEventKind, KeyModifiers};
+use crossterm::style::{Attribute, Print, SetAttribute};
+use crossterm::{cursor, execute, queue, terminal};
+
+use crate::graph::load_graph;
+use crate::tui::{editor_command, Action, App, Key, Style};
+
+#[derive(Args)]
+pub struct TuiArgs {
+    /// Project directory
+    #[arg(long, default_value = ".")]
+    pub dir: String,
+}
+
+pub fn run(args: TuiArgs) {
+    let root_dir = match PathBuf::from(&args.dir).canonicalize() {
+        Ok(p) => p,
+        Err(e) => {
+            eprintln!("Error: cannot resolve directory '{}': {}", args.dir, e);
+            std::process::exit(1);
+        }
+    };
+    let graph = match load_graph(&root_dir.join(".codemap")) {
+        Ok(g) => g,
+        Err(_) => {
+            eprintln!("No code graph found. Run \"codegraph scan\" first.");
+            std::process::exit(1);
+        }
+    };
+    if !std::io::stdin().is_terminal() || !std::io::stdout().is_terminal() {
+        eprintln!("Error: codegraph tui needs an interactive terminal");
+        std::process::exit(1);
+    }
+
+    // panic 时先恢复终端，否则 shell 会停留在 raw 模式与备用屏幕
+    let default_hook = std::panic::take_hook();
+    std::panic::set_hook(Box::new(move |info| {
+        let _ = leave_screen();
+        default_hook(info);
+    }));
+
+    let mut app = App::new(graph);
+    let result = enter_screen().and_then(|_| event_loop(&mut app, &root_dir));
+    let _ = leave_screen();
+    if let Err(e) = result {
+        eprintln!("Error: {}", e);
+        std::process::exit(1);
+    }
+}
+
+// ── 终端 ──────────────────────────────────────────────────────────────────────
+
+fn event_loop(app: &mut App, root_dir: &Path) -> std::io::Result<()> {
+    let mut out = std::io::stdout();
+    loop {
+        draw(&mut out, app)?;
+        let Event::Key(event) = event::read()? else {
+            // 窗口大小变化等事件：重绘即可
+            continue;
+        };
+        if event.kind != KeyEventKind::Press {
+            continue;
+        }
+        if event.modifiers.contains(KeyModifiers::CONTROL) && event.code == KeyCode::Char('c') {
+            return Ok(());
+        }
+        let Some(key) = map_key(event) else {
+            continue;
+        };
+        match app.handle_key(key) {
+            Action::None => {}
+            Action::Quit => return Ok(()),
+            Action::Edit { file, line } => {
+                leave_screen()?;
+                let path = root_dir.join(&file);
+                let status = open_editor(&path, line);
+                enter_screen()?;
+                app.message = match status {
+                    Ok(()) => format!("Edited {}:{}", file, line),
+                    Err(e) => format!("Cannot start editor: {}", e),
+                };
+            }
+        }
+    }
+}
+
+fn draw(out: &mut std::io::Stdout, app: &App) -> std::io::Result<()> {
+    let (width, height) = terminal::size()?;
+    let canvas = app.render(width, height);
+    queue!(out, cursor::MoveTo(0, 0))?;
+    for y in 0..canvas.height {
+        queue!(out, cursor::MoveTo(0, y))?;
+        for (style, text) in canvas.row_runs(y) {
+            let attr = match style {
+                Style::Normal => Attribute::Reset,
+                Style::Bold => Attribute::Bold,
+                Style::Dim => Attribute::Dim,
+                Style::Selected => Attribute::Reverse,
+            };
+            queue!(
+                out,
+                SetAttribute(Attribute::Reset),
+                SetAttribute(attr),
+                Print(text)
+            )?;
+        }
+        queue!(out, SetAttribute(Attribute::Reset))?;
+    }
+    out.flush()
+}
+
+fn enter_screen() -> std::io::Result<()> {
+    terminal::enable_raw_mode()?;
+    execute!(
+        std::io::stdout(),
+        terminal::EnterAlternateScreen,
+        cursor::Hide
+    )
+}
+
+fn leave_screen() -> std::io::Result<()> {
+    execute!(
+        std::io::stdout(),
+        SetAttribute(Attribute::Reset),
+        cursor::Show,
+        terminal::LeaveAlternateScreen
+    )?;
+    terminal::disable_raw_mode()
+}
+
+/// 用 $VISUAL / $EDITOR 打开 file:line，等待编辑器退出
+fn open_editor(path: &Path, line: u32) -> std::io::Result<()> {
+    let editor = std::env::var("VISUAL")
+        .ok()
+        .filter(|v| !v.trim().is_empty())
+        .or_else(|| std::env::var("EDITOR").ok())
+        .unwrap_or_default();
+    let parts = editor_command(&editor, &path.to_string_lossy(), line);
+    let status = std::process::Command::new(&parts[0])
+        .args(&parts[1..])
+        .status()?;
+    if status.success() {
+        Ok(())
+    } else {
+        Err(std::io::Error::other(format!(
+            "{} exited with {}",
+            parts[0], status
+        )))
+    }
+}
+
+fn map_key(event: KeyEvent) -> Option<Key> {
+    let key = match event.code {
+        KeyCode::Up => Key::Up,
+        KeyCode::Down => Key::Down,
+        KeyCode::PageUp => Key::PageUp,
+        KeyCode::PageDown => Key::PageDown,
+        KeyCode::Home => Key::Home,
+        KeyCode::End => Key::End,
+        KeyCode::Left => Key::Left,
+        KeyCode::Right => Key::Right,
+        KeyCode::Tab => Key::Tab,
+        KeyCode::BackTab => Key::BackTab,
+        KeyCode::Enter => Key::Enter,
+        KeyCode::Esc => Key::Esc,
+        KeyCode::Backspace => Key::Backspace,
+        KeyCode::Char(c) => Key::Char(c),
+        _ => return None,
+    };
+    Some(key)
+}
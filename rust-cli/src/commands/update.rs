@@ -1,5 +1,4 @@
 use clap::Args;
-use std::collections::HashMap;
 use std::path::PathBuf;
 
 #[derive(Args)]
@@ -22,67 +21,33 @@ pub fn run(args: UpdateArgs) {
         }
     };
 
-    let codemap_dir = root.join(".codemap");
+    if !root.join(".codemap").join("graph.json").exists() {
+        eprintln!(
+            "Error: could not load graph from {}/.codemap/",
+            root.display()
+        );
+        eprintln!("Run 'codegraph scan {}' first.", root.display());
+        std::process::exit(1);
+    }
 
-    // 加载现有图谱
-    let mut graph = match crate::graph::load_graph(&codemap_dir) {
-        Ok(g) => g,
+    // 解析变更文件、合并到图谱并重新生成 slices（与 Node.js update 行为一致）
+    let opts = crate::scanner::ScanOptions::new().excludes(args.exclude);
+    let outcome = match opts.update(&root) {
+        Ok(outcome) => outcome,
         Err(e) => {
-            eprintln!(
-                "Error: could not load graph from {}/.codemap/: {}",
-                root.display(),
-                e
-            );
-            eprintln!("Run 'codegraph scan {}' first.", root.display());
+            eprintln!("Error: {}", e);
             std::process::exit(1);
         }
     };
 
-    // 遍历磁盘当前文件，计算哈希
-    let files = crate::traverser::traverse_files(&root, &args.exclude);
-    let has_cpp = crate::traverser::has_cpp_source_files(&files);
-
-    let mut new_hashes: HashMap<String, String> = HashMap::new();
-    let mut file_contents: HashMap<String, Vec<u8>> = HashMap::new();
-
-    for abs_path in &files {
-        if crate::traverser::detect_language(abs_path).is_none() {
-            continue;
-        }
-        let content = match std::fs::read(abs_path) {
-            Ok(c) => c,
-            Err(_) => continue,
-        };
-
-        let rel_path = abs_path
-            .strip_prefix(&root)
-            .unwrap_or(abs_path)
-            .to_string_lossy()
-            .replace('\\', "/");
-
-        let hash = crate::graph::compute_file_hash(&content);
-        new_hashes.insert(rel_path.clone(), hash);
-        file_contents.insert(rel_path, content);
-    }
-
-    // 从 meta.fileHashes 读取旧哈希（与 Node.js update 逻辑一致）
-    // 若 meta.json 不存在或无 fileHashes，回退到从 graph.files 提取
-    let old_hashes: HashMap<String, String> = match crate::graph::load_meta(&codemap_dir) {
-        Ok(meta) if !meta.file_hashes.is_empty() => meta.file_hashes.into_iter().collect(),
-        _ => graph
-            .files
-            .iter()
-            .map(|(p, f)| (p.clone(), f.hash.clone()))
-            .collect(),
-    };
-
-    // 检测变更
-    let changes = crate::differ::detect_changed_files(&old_hashes, &new_hashes);
-
+    let changes = outcome.changes;
     if changes.is_empty() {
         println!("No changes detected.");
         return;
     }
+    if let Err(e) = crate::registry::register_project(&root, &outcome.graph) {
+        eprintln!("Warning: could not update the project registry: {}", e);
+    }
 
     println!(
         "Changes: +{} added, ~{} modified, -{} removed",
@@ -91,113 +56,6 @@ pub fn run(args: UpdateArgs) {
         changes.removed.len()
     );
 
-    // 解析变更文件（新增 + 修改）
-    let mut updated_files: HashMap<String, crate::graph::FileEntry> = HashMap::new();
-
-    for rel_path in changes.added.iter().chain(changes.modified.iter()) {
-        let content = match file_contents.get(rel_path) {
-            Some(c) => c,
-            None => continue,
-        };
-
-        // 重建绝对路径以检测语言
-        let abs_path = root.join(rel_path.replace('/', std::path::MAIN_SEPARATOR_STR));
-        let base_lang = match crate::traverser::detect_language(&abs_path) {
-            Some(l) => l,
-            None => continue,
-        };
-        let lang = crate::traverser::effective_language(&abs_path, base_lang, has_cpp);
-
-        let adapter = crate::languages::get_adapter(lang);
-
-        let mut ts_parser = tree_sitter::Parser::new();
-        if ts_parser.set_language(&adapter.language()).is_err() {
-            eprintln!(
-                "Warning: failed to set language for {:?}, skipping",
-                abs_path
-            );
-            continue;
-        }
-        let tree = match ts_parser.parse(content, None) {
-            Some(t) => t,
-            None => continue,
-        };
-
-        let lang_functions = adapter.extract_functions(&tree, content);
-        let lang_imports = adapter.extract_imports(&tree, content);
-        let lang_exports = adapter.extract_exports(&tree, content);
-        let lang_classes = adapter.extract_classes(&tree, content);
-        let lang_variables = adapter.extract_variables(&tree, content);
-        let lines = content.iter().filter(|&&b| b == b'\n').count() as u32 + 1;
-
-        let functions = crate::scanner::convert_functions(&lang_functions);
-        let classes = crate::scanner::convert_classes(&lang_classes);
-        let types = crate::scanner::convert_types(&lang_classes, lang);
-        let imports = crate::scanner::convert_imports(&lang_imports);
-        let exports = crate::scanner::convert_exports(&lang_exports);
-        let variables = crate::scanner::convert_variables(&lang_variables);
-
-        // 构建 symbol_refs（与 scan_project 一致）
-        let imported_symbols: std::collections::HashSet<String> = imports
-            .iter()
-            .flat_map(|imp| imp.symbols.iter().cloned())
-            .collect();
-        let symbol_uses = crate::scanner::scan_symbol_uses(&tree, content, &imported_symbols);
-        let mut symbol_refs: std::collections::BTreeMap<String, crate::graph::SymbolRef> =
-            std::collections::BTreeMap::new();
-        for imp in &imports {
-            for sym in &imp.symbols {
-                let use_lines = symbol_uses.get(sym).cloned().unwrap_or_default();
-                symbol_refs.insert(
-                    sym.clone(),
-                    crate::graph::SymbolRef {
-                        symbol: sym.clone(),
-                        import_line: imp.import_line,
-                        use_lines,
-                    },
-                );
-            }
-        }
-
-        let module_name = crate::scanner::detect_module_name(&abs_path, &root);
-        let hash = new_hashes[rel_path].clone();
-
-        updated_files.insert(
-            rel_path.clone(),
-            crate::graph::FileEntry {
-                language: lang.as_str().to_string(),
-                module: module_name,
-                hash,
-                lines,
-                functions,
-                classes,
-                types,
-                variables,
-                imports,
-                exports,
-                is_entry_point: crate::graph::is_entry_point(&abs_path),
-                symbol_refs,
-            },
-        );
-    }
-
-    // 合并变更到图谱
-    crate::differ::merge_graph_update(&mut graph, updated_files, &changes.removed);
-
-    // 更新扫描时间
-    graph.scanned_at = crate::graph::chrono_now();
-
-    // 保存更新后的图谱
-    if let Err(e) = crate::graph::save_graph(&codemap_dir, &graph) {
-        eprintln!("Error saving graph: {}", e);
-        std::process::exit(1);
-    }
-
-    // 重新生成 slices（与 Node.js update 行为一致）
-    if let Err(e) = crate::slicer::save_slices(&codemap_dir, &graph) {
-        eprintln!("Warning: failed to save slices: {}", e);
-    }
-
     println!("Update complete.");
     println!(
         "  +{} ~{} -{}",
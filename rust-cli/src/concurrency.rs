@@ -0,0 +1,417 @@
+/// 并发与异步构造图（concurrency）
+///
+/// 逐行匹配各语言的并发原语并归属到所在的函数（或类）：
+/// - `spawn`：goroutine、`tokio::spawn` / `thread::spawn`、asyncio 任务、`threading.Thread`、
+///   Java 线程池与 `CompletableFuture`、`pthread_create` / `std::thread`、Web Worker
+/// - `channel`：Go channel 创建与收发、`select`，Rust mpsc / broadcast / oneshot，队列
+/// - `async`：`async fn` / `async def` / `async function` 等异步定义
+/// - `await`：`.await`、`await`、`co_await`
+/// - `lock`：互斥锁获取（`.lock()`、`.Lock()`、`synchronized`、`with lock:`、`lock_guard` 等）
+///
+/// 只在代码视图中匹配（注释与字符串内容已屏蔽），结果用于竞态排查时定位起点，
+/// 而非精确的并发分析。
+use serde::Serialize;
+use std::collections::{BTreeMap, HashSet};
+
+use crate::graph::{ClassInfo, CodeGraph, ConcurrencySite, FunctionInfo};
+use crate::source_text::{enclosing_symbol, SourceText};
+use crate::traverser::Language;
+
+/// 支持的类别
+pub const CONCURRENCY_KINDS: &[&str] = &["spawn", "channel", "async", "await", "lock"];
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 单个函数（或文件顶层）中的并发原语
+#[derive(Debug, Clone, Serialize)]
+pub struct SymbolConcurrency {
+    pub file: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub symbol: Option<String>,
+    pub counts: BTreeMap<String, usize>,
+    pub sites: Vec<ConcurrencySite>,
+}
+
+/// 模块的并发原语汇总
+#[derive(Debug, Clone, Serialize)]
+pub struct ConcurrencySummary {
+    pub module: String,
+    pub counts: BTreeMap<String, usize>,
+    pub symbols: Vec<SymbolConcurrency>,
+}
+
+/// 单行匹配规则：needle 以标识符字符开头时要求前一个字符不是标识符字符
+struct Pattern {
+    needle: &'static str,
+    kind: &'static str,
+    detail: &'static str,
+}
+
+const fn pat(needle: &'static str, kind: &'static str, detail: &'static str) -> Pattern {
+    Pattern {
+        needle,
+        kind,
+        detail,
+    }
+}
+
+const RUST_PATTERNS: &[Pattern] = &[
+    pat("tokio::spawn(", "spawn", "tokio task"),
+    pat("task::spawn(", "spawn", "tokio task"),
+    pat("spawn_blocking(", "spawn", "blocking task"),
+    pat("thread::spawn(", "spawn", "thread"),
+    pat("thread::scope(", "spawn", "scoped threads"),
+    pat("mpsc::channel", "channel", "mpsc channel"),
+    pat("mpsc::sync_channel", "channel", "mpsc channel"),
+    pat("unbounded_channel", "channel", "mpsc channel"),
+    pat("broadcast::channel", "channel", "broadcast channel"),
+    pat("oneshot::channel", "channel", "oneshot channel"),
+    pat("watch::channel", "channel", "watch channel"),
+    pat(".recv()", "channel", "receive"),
+    pat("select!", "channel", "select"),
+    pat("async fn", "async", "async fn"),
+    pat("async move", "async", "async block"),
+    pat(".await", "await", "await"),
+    pat(".lock()", "lock", "mutex lock"),
+    pat(".try_lock()", "lock", "mutex lock"),
+    pat(".read().await", "lock", "rwlock read"),
+    pat(".write().await", "lock", "rwlock write"),
+];
+
+const GO_PATTERNS: &[Pattern] = &[
+    pat(".Go(func", "spawn", "errgroup goroutine"),
+    pat("make(chan", "channel", "make chan"),
+    pat("<-", "channel", "channel op"),
+    pat("select {", "channel", "select"),
+    pat(".Lock()", "lock", "mutex Lock"),
+    pat(".RLock()", "lock", "mutex RLock"),
+];
+
+const PYTHON_PATTERNS: &[Pattern] = &[
+    pat("asyncio.create_task(", "spawn", "asyncio task"),
+    pat("asyncio.ensure_future(", "spawn", "asyncio task"),
+    pat("asyncio.gather(", "spawn", "asyncio gather"),
+    pat("create_task(", "spawn", "asyncio task"),
+    pat("run_in_executor(", "spawn", "executor"),
+    pat("threading.Thread(", "spawn", "thread"),
+    pat("Thread(", "spawn", "thread"),
+    pat("ThreadPoolExecutor(", "spawn", "executor"),
+    pat("ProcessPoolExecutor(", "spawn", "executor"),
+    pat(".submit(", "spawn", "executor task"),
+    pat("multiprocessing.Process(", "spawn", "process"),
+    pat("asyncio.Queue(", "channel", "queue"),
+    pat("queue.Queue(", "channel", "queue"),
+    pat("async def", "async", "async def"),
+    pat("await ", "await", "await"),
+    pat(".acquire(", "lock", "lock acquire"),
+];
+
+const JS_PATTERNS: &[Pattern] = &[
+    pat("new Worker(", "spawn", "worker"),
+    pat("new SharedWorker(", "spawn", "worker"),
+    pat("postMessage(", "channel", "message"),
+    pat("new MessageChannel(", "channel", "message channel"),
+    pat("async function", "async", "async function"),
+    pat("async (", "async", "async arrow"),
+    pat("async ", "async", "async method"),
+    pat("await ", "await", "await"),
+    pat("locks.request(", "lock", "Web Lock"),
+];
+
+const JAVA_PATTERNS: &[Pattern] = &[
+    pat("Executors.new", "spawn", "executor"),
+    pat(".submit(", "spawn", "executor task"),
+    pat(".execute(", "spawn", "executor task"),
+    pat("new Thread(", "spawn", "thread"),
+    pat("CompletableFuture.supplyAsync(", "spawn", "async task"),
+    pat("CompletableFuture.runAsync(", "spawn", "async task"),
+    pat("BlockingQueue", "channel", "blocking queue"),
+    pat("synchronized", "lock", "synchronized"),
+    pat(".lock()", "lock", "Lock.lock"),
+    pat(".tryLock(", "lock", "Lock.tryLock"),
+];
+
+const C_PATTERNS: &[Pattern] = &[
+    pat("pthread_create(", "spawn", "pthread"),
+    pat("pthread_mutex_lock(", "lock", "pthread mutex"),
+    pat("pthread_rwlock_rdlock(", "lock", "pthread rwlock"),
+    pat("pthread_rwlock_wrlock(", "lock", "pthread rwlock"),
+];
+
+const CPP_PATTERNS: &[Pattern] = &[
+    pat("std::thread", "spawn", "std::thread"),
+    pat("std::jthread", "spawn", "std::thread"),
+    pat("std::async(", "spawn", "std::async"),
+    pat("co_await", "await", "co_await"),
+    pat("lock_guard", "lock", "lock_guard"),
+    pat("unique_lock", "lock", "unique_lock"),
+    pat("scoped_lock", "lock", "scoped_lock"),
+    pat("shared_lock", "lock", "shared_lock"),
+    pat(".lock()", "lock", "mutex lock"),
+];
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 从源码中提取并发原语，按行号排序；同一行同一类别只记一次
+pub fn extract_concurrency(
+    lang: Language,
+    source: &SourceText,
+    functions: &[FunctionInfo],
+    classes: &[ClassInfo],
+) -> Vec<ConcurrencySite> {
+    let patterns: Vec<&Pattern> = match lang {
+        Language::Rust => RUST_PATTERNS.iter().collect(),
+        Language::Go => GO_PATTERNS.iter().collect(),
+        Language::Python => PYTHON_PATTERNS.iter().collect(),
+        Language::TypeScript | Language::JavaScript => JS_PATTERNS.iter().collect(),
+        Language::Java => JAVA_PATTERNS.iter().collect(),
+        Language::C => C_PATTERNS.iter().collect(),
+        Language::Cpp => C_PATTERNS.iter().chain(CPP_PATTERNS).collect(),
+    };
+    let mut sites = Vec::new();
+    for (idx, line) in source.code_lines().into_iter().enumerate() {
+        if line.trim().is_empty() {
+            continue;
+        }
+        let line_no = idx as u32 + 1;
+        let mut kinds: HashSet<&str> = HashSet::new();
+        let mut push = |kind: &'static str, detail: &str| {
+            if kinds.insert(kind) {
+                sites.push(ConcurrencySite {
+                    kind: kind.to_string(),
+                    detail: detail.to_string(),
+                    line: line_no,
+                    symbol: enclosing_symbol(functions, classes, line_no),
+                });
+            }
+        };
+        let trimmed = line.trim_start();
+        // 语句级构造：`go f()`、`with lock:`
+        if lang == Language::Go && trimmed.starts_with("go ") {
+            push("spawn", "goroutine");
+        }
+        if lang == Language::Python && is_python_lock_block(trimmed) {
+            push("lock", "with lock");
+        }
+        for p in &patterns {
+            if contains_pattern(line, p.needle) {
+                push(p.kind, p.detail);
+            }
+        }
+    }
+    sites
+}
+
+/// 汇总模块内的并发原语，按文件与所在符号分组；kind 为 None 表示全部类别
+pub fn concurrency_summary(
+    graph: &CodeGraph,
+    module: &str,
+    kind: Option<&str>,
+) -> Option<ConcurrencySummary> {
+    let files = &graph.modules.get(module)?.files;
+    let mut counts: BTreeMap<String, usize> = BTreeMap::new();
+    let mut symbols = Vec::new();
+    for path in files {
+        let Some(entry) = graph.files.get(path) else {
+            continue;
+        };
+        // 按所在符号分组，保持首次出现的行序
+        let mut groups: Vec<SymbolConcurrency> = Vec::new();
+        for site in &entry.concurrency {
+            if kind.is_some_and(|k| k != site.kind) {
+                continue;
+            }
+            *counts.entry(site.kind.clone()).or_default() += 1;
+            let group = match groups.iter_mut().position(|g| g.symbol == site.symbol) {
+                Some(i) => &mut groups[i],
+                None => {
+                    groups.push(SymbolConcurrency {
+                        file: path.clone(),
+                        symbol: site.symbol.clone(),
+                        counts: BTreeMap::new(),
+                        sites: vec![],
+                    });
+                    groups.last_mut().unwrap()
+                }
+            };
+            *group.counts.entry(site.kind.clone()).or_default() += 1;
+            group.sites.push(site.clone());
+        }
+        symbols.extend(groups);
+    }
+    symbols.sort_by(|a, b| a.file.cmp(&b.file));
+    Some(ConcurrencySummary {
+        module: module.to_string(),
+        counts,
+        symbols,
+    })
+}
+
+/// 计数文本：`spawn 2, lock 1`（按 CONCURRENCY_KINDS 顺序）
+pub fn format_counts(counts: &BTreeMap<String, usize>) -> String {
+    CONCURRENCY_KINDS
+        .iter()
+        .filter_map(|k| counts.get(*k).map(|n| format!("{} {}", k, n)))
+        .collect::<Vec<_>>()
+        .join(", ")
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+fn contains_pattern(line: &str, needle: &str) -> bool {
+    if !needle.starts_with(|c: char| c.is_alphanumeric() || c == '_') {
+        return line.contains(needle);
+    }
+    line.match_indices(needle).any(|(pos, _)| {
+        !line[..pos]
+            .chars()
+            .next_back()
+            .is_some_and(|c| c.is_alphanumeric() || c == '_')
+    })
+}
+
+/// `with self._lock:`、`async with lock:`、`with mutex, other:` 等
+fn is_python_lock_block(trimmed: &str) -> bool {
+    let Some(rest) = trimmed
+        .strip_prefix("with ")
+        .or_else(|| trimmed.strip_prefix("async with "))
+    else {
+        return false;
+    };
+    let lower = rest.to_lowercase();
+    lower.contains("lock") || lower.contains("mutex") || lower.contains("semaphore")
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn kinds(lang: Language, src: &str) -> Vec<(u32, String, Option<String>)> {
+        let functions = vec![FunctionInfo {
+            name: "worker".into(),
+            signature: String::new(),
+            start_line: 2,
+            end_line: 8,
+        }];
+        extract_concurrency(
+            lang,
+            &SourceText::scan(lang, src.as_bytes()),
+            &functions,
+            &[],
+        )
+        .into_iter()
+        .map(|s| (s.line, s.kind, s.symbol))
+        .collect()
+    }
+
+    fn site(line: u32, kind: &str, symbol: Option<&str>) -> (u32, String, Option<String>) {
+        (line, kind.to_string(), symbol.map(String::from))
+    }
+
+    #[test]
+    fn test_extract_rust_go_python() {
+        let rs = "use std::sync::Mutex;\n\
+                  async fn worker(state: Arc<Mutex<u32>>) {\n\
+                  let (tx, mut rx) = mpsc::channel(8);\n\
+                  tokio::spawn(async move { tx.send(1).await });\n\
+                  let mut guard = state.lock().unwrap();\n\
+                  // tokio::spawn in a comment\n\
+                  }\n";
+        assert_eq!(
+            kinds(Language::Rust, rs),
+            vec![
+                site(2, "async", Some("worker")),
+                site(3, "channel", Some("worker")),
+                site(4, "spawn", Some("worker")),
+                site(4, "async", Some("worker")),
+                site(4, "await", Some("worker")),
+                site(5, "lock", Some("worker")),
+            ]
+        );
+
+        let go = "var mu sync.Mutex\nfunc worker(ch chan int) {\n\tgo process(ch)\n\tmu.Lock()\n\tv := <-ch\n\tgoto done\n}\n";
+        assert_eq!(
+            kinds(Language::Go, go),
+            vec![
+                site(3, "spawn", Some("worker")),
+                site(4, "lock", Some("worker")),
+                site(5, "channel", Some("worker")),
+            ]
+        );
+
+        let py = "import asyncio\nasync def worker(self):\n    async with self._lock:\n        task = asyncio.create_task(job())\n        await task\n    t = threading.Thread(target=run)\n";
+        assert_eq!(
+            kinds(Language::Python, py),
+            vec![
+                site(2, "async", Some("worker")),
+                site(3, "lock", Some("worker")),
+                site(4, "spawn", Some("worker")),
+                site(5, "await", Some("worker")),
+                site(6, "spawn", Some("worker")),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_skips_strings_and_block_comments() {
+        let ts = "/*\n\
+                  async function worker() { await job(); }\n\
+                  */\n\
+                  const usage = \"call await worker() or new Worker(url)\";\n";
+        assert_eq!(kinds(Language::TypeScript, ts), vec![]);
+    }
+
+    #[test]
+    fn test_summary_groups_by_symbol() {
+        let mut graph = crate::graph::create_empty_graph("p", "/tmp/p");
+        let sites = vec![
+            ConcurrencySite {
+                kind: "spawn".into(),
+                detail: "goroutine".into(),
+                line: 3,
+                symbol: Some("serve".into()),
+            },
+            ConcurrencySite {
+                kind: "lock".into(),
+                detail: "mutex Lock".into(),
+                line: 9,
+                symbol: None,
+            },
+            ConcurrencySite {
+                kind: "channel".into(),
+                detail: "channel op".into(),
+                line: 4,
+                symbol: Some("serve".into()),
+            },
+        ];
+        let entry = crate::graph::FileEntry {
+            language: "go".into(),
+            module: "server".into(),
+            lines: 10,
+            concurrency: sites,
+            ..Default::default()
+        };
+        graph.files.insert("server/main.go".into(), entry);
+        graph.modules.insert(
+            "server".into(),
+            crate::graph::ModuleEntry {
+                files: vec!["server/main.go".into()],
+                depends_on: vec![],
+                depended_by: vec![],
+            },
+        );
+
+        let summary = concurrency_summary(&graph, "server", None).unwrap();
+        assert_eq!(format_counts(&summary.counts), "spawn 1, channel 1, lock 1");
+        assert_eq!(summary.symbols.len(), 2);
+        assert_eq!(summary.symbols[0].symbol.as_deref(), Some("serve"));
+        assert_eq!(summary.symbols[0].sites.len(), 2);
+        assert_eq!(summary.symbols[1].symbol, None);
+
+        let locks = concurrency_summary(&graph, "server", Some("lock")).unwrap();
+        assert_eq!(locks.symbols.len(), 1);
+        assert!(concurrency_summary(&graph, "missing", None).is_none());
+    }
+}
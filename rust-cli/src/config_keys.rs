@@ -0,0 +1,395 @@
+/// 环境变量与配置键读取检测
+///
+/// 逐行匹配各语言读取环境变量（`process.env.X`、`os.Getenv("X")`、`std::env::var("X")` 等）
+/// 和常见配置库（node-config / nconf、viper、Spring、config-rs 等）的调用形式，记录为
+/// `config` 符号及其全部读取位置。调用须位于代码中（注释与字符串里的同名文本不计），
+/// 只识别字面量键名；动态拼接的键无法静态确定，直接忽略。
+use serde::Serialize;
+use std::collections::BTreeMap;
+
+use crate::graph::{CodeGraph, ConfigKey};
+use crate::source_text::{Region, SourceLine, SourceText};
+use crate::traverser::Language;
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 单个文件中对某个键的读取
+#[derive(Debug, Clone, Serialize)]
+pub struct ReadSite {
+    pub file: String,
+    pub module: String,
+    pub lines: Vec<u32>,
+}
+
+/// 跨文件汇总后的配置符号
+#[derive(Debug, Clone, Serialize)]
+pub struct ConfigSymbol {
+    pub name: String,
+    /// "env" | "config"
+    pub kind: String,
+    /// 读取该键的模块（去重排序）
+    pub modules: Vec<String>,
+    pub sites: Vec<ReadSite>,
+}
+
+/// 读取形式
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum Form {
+    /// 成员访问：`process.env.X` 或 `process.env["X"]`
+    Member,
+    /// 调用或下标的首个字符串参数：`os.Getenv("X")`、`os.environ["X"]`
+    Argument,
+    /// 同一前缀的一族方法：`viper.GetString("k")`、`viper.GetInt("k")`
+    Family,
+}
+
+struct Pattern {
+    prefix: &'static str,
+    /// "env" | "config"
+    kind: &'static str,
+    form: Form,
+}
+
+const fn env(prefix: &'static str, form: Form) -> Pattern {
+    Pattern {
+        prefix,
+        kind: "env",
+        form,
+    }
+}
+
+const fn config(prefix: &'static str, form: Form) -> Pattern {
+    Pattern {
+        prefix,
+        kind: "config",
+        form,
+    }
+}
+
+const JS_PATTERNS: &[Pattern] = &[
+    env("process.env", Form::Member),
+    env("import.meta.env", Form::Member),
+    config("config.get", Form::Argument),
+    config("nconf.get", Form::Argument),
+];
+
+const PYTHON_PATTERNS: &[Pattern] = &[
+    env("os.environ.get", Form::Argument),
+    env("os.environ", Form::Argument),
+    env("os.getenv", Form::Argument),
+    env("environ.get", Form::Argument),
+    config("config.get", Form::Argument),
+    config("settings.get", Form::Argument),
+];
+
+const GO_PATTERNS: &[Pattern] = &[
+    env("os.Getenv", Form::Argument),
+    env("os.LookupEnv", Form::Argument),
+    config("viper.Get", Form::Family),
+    config("viper.IsSet", Form::Argument),
+];
+
+const RUST_PATTERNS: &[Pattern] = &[
+    env("std::env::var_os", Form::Argument),
+    env("std::env::var", Form::Argument),
+    env("env::var_os", Form::Argument),
+    env("env::var", Form::Argument),
+    env("env!", Form::Argument),
+    env("option_env!", Form::Argument),
+    config("config.get", Form::Family),
+    config("settings.get", Form::Family),
+];
+
+const JAVA_PATTERNS: &[Pattern] = &[
+    env("System.getenv", Form::Argument),
+    config("System.getProperty", Form::Argument),
+    config("@Value", Form::Argument),
+    config("env.getProperty", Form::Argument),
+    config("environment.getProperty", Form::Argument),
+];
+
+const C_PATTERNS: &[Pattern] = &[
+    env("std::getenv", Form::Argument),
+    env("getenv", Form::Argument),
+    env("secure_getenv", Form::Argument),
+];
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 从源码中提取环境变量与配置键的读取位置，按键名排序
+pub fn extract_config_keys(lang: Language, source: &SourceText) -> Vec<ConfigKey> {
+    let patterns = match lang {
+        Language::TypeScript | Language::JavaScript => JS_PATTERNS,
+        Language::Python => PYTHON_PATTERNS,
+        Language::Go => GO_PATTERNS,
+        Language::Rust => RUST_PATTERNS,
+        Language::Java => JAVA_PATTERNS,
+        Language::C | Language::Cpp => C_PATTERNS,
+    };
+    let mut keys: BTreeMap<(String, &str), Vec<u32>> = BTreeMap::new();
+    for (idx, line) in source.lines().iter().enumerate() {
+        if !line.has_code() {
+            continue;
+        }
+        for (name, kind) in scan_line(line, patterns) {
+            let lines = keys.entry((name, kind)).or_default();
+            let line_no = idx as u32 + 1;
+            if lines.last() != Some(&line_no) {
+                lines.push(line_no);
+            }
+        }
+    }
+
+    keys.into_iter()
+        .map(|((name, kind), lines)| ConfigKey {
+            name,
+            kind: kind.to_string(),
+            lines,
+        })
+        .collect()
+}
+
+/// 汇总整个图谱的配置键读取，按 (kind, name) 排序
+pub fn collect_config_symbols(graph: &CodeGraph) -> Vec<ConfigSymbol> {
+    let mut by_key: BTreeMap<(String, String), ConfigSymbol> = BTreeMap::new();
+    for (file_path, entry) in &graph.files {
+        for key in &entry.config_keys {
+            let symbol = by_key
+                .entry((key.kind.clone(), key.name.clone()))
+                .or_insert_with(|| ConfigSymbol {
+                    name: key.name.clone(),
+                    kind: key.kind.clone(),
+                    modules: vec![],
+                    sites: vec![],
+                });
+            if !symbol.modules.contains(&entry.module) {
+                symbol.modules.push(entry.module.clone());
+            }
+            symbol.sites.push(ReadSite {
+                file: file_path.clone(),
+                module: entry.module.clone(),
+                lines: key.lines.clone(),
+            });
+        }
+    }
+    by_key
+        .into_values()
+        .map(|mut s| {
+            s.modules.sort();
+            s
+        })
+        .collect()
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+/// 在一行代码中匹配所有模式，返回 (键名, kind)；键名从原文中读取
+fn scan_line(line: &SourceLine, patterns: &[Pattern]) -> Vec<(String, &'static str)> {
+    let text = line.raw;
+    let bytes = text.as_bytes();
+    let mut found = Vec::new();
+    let mut pos = 0;
+    while pos < bytes.len() {
+        // 同一位置取第一个命中的模式（表中长前缀在前）
+        let hit = patterns.iter().find_map(|p| {
+            if line.region_at(pos) != Region::Code
+                || !text[pos..].starts_with(p.prefix)
+                || !boundary_before(bytes, pos)
+            {
+                return None;
+            }
+            read_key(&text[pos + p.prefix.len()..], p.form).map(|(name, used)| (p, name, used))
+        });
+        match hit {
+            Some((p, name, used)) => {
+                found.push((name, p.kind));
+                pos += p.prefix.len() + used;
+            }
+            None => pos += text[pos..].chars().next().map_or(1, char::len_utf8),
+        }
+    }
+    found
+}
+
+/// 前缀之前不能是标识符字符或成员 / 路径分隔符（避免 `myprocess.env`、`std::env::var` 重复命中）
+fn boundary_before(bytes: &[u8], pos: usize) -> bool {
+    pos == 0
+        || !matches!(bytes[pos - 1], b'a'..=b'z' | b'A'..=b'Z' | b'0'..=b'9' | b'_' | b'.' | b':' | b'$')
+}
+
+/// 解析前缀之后的键名，返回 (键名, 消耗的字节数)
+fn read_key(rest: &str, form: Form) -> Option<(String, usize)> {
+    let mut i = 0;
+    match form {
+        Form::Member => {
+            if let Some(after) = rest.strip_prefix('.') {
+                let ident = take_ident(after);
+                // `process.env.hasOwnProperty(...)` 之类是方法调用而非读取
+                if ident.is_empty() || after[ident.len()..].trim_start().starts_with('(') {
+                    return None;
+                }
+                return Some((ident.to_string(), 1 + ident.len()));
+            }
+            if !rest.starts_with('[') {
+                return None;
+            }
+        }
+        Form::Family => {
+            i = take_ident(rest).len();
+        }
+        Form::Argument => {
+            // 前缀本身须是完整标识符：`os.environ` 不匹配 `os.environment`
+            if rest.starts_with(|c: char| c.is_alphanumeric() || c == '_') {
+                return None;
+            }
+        }
+    }
+    // 跳过 Rust turbofish：`settings.get::<String>("k")`
+    if rest[i..].starts_with("::<") {
+        i += rest[i..].find('>')? + 1;
+    }
+    i += rest[i..].len() - rest[i..].trim_start().len();
+    if !rest[i..].starts_with(['(', '[']) {
+        return None;
+    }
+    i += 1;
+    i += rest[i..].len() - rest[i..].trim_start().len();
+    let (literal, used) = take_string(&rest[i..])?;
+    let name = clean_key(literal)?;
+    Some((name, i + used))
+}
+
+fn take_ident(s: &str) -> &str {
+    let end = s
+        .find(|c: char| !(c.is_alphanumeric() || c == '_'))
+        .unwrap_or(s.len());
+    &s[..end]
+}
+
+/// 读取开头的字符串字面量（' " `），返回 (内容, 消耗的字节数)
+fn take_string(s: &str) -> Option<(&str, usize)> {
+    let quote = s.chars().next().filter(|c| matches!(c, '"' | '\'' | '`'))?;
+    let end = s[1..].find(quote)?;
+    Some((&s[1..1 + end], end + 2))
+}
+
+/// 规范化键名：Spring `${key:default}` 取 key；拒绝插值与空白
+fn clean_key(literal: &str) -> Option<String> {
+    let mut key = literal;
+    if let Some(inner) = key.strip_prefix("${").and_then(|k| k.strip_suffix('}')) {
+        key = inner.split(':').next().unwrap_or(inner);
+    }
+    if key.is_empty() || key.contains("${") || key.contains(char::is_whitespace) {
+        return None;
+    }
+    Some(key.to_string())
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn keys(lang: Language, src: &str) -> Vec<(String, String, Vec<u32>)> {
+        extract_config_keys(lang, &SourceText::scan(lang, src.as_bytes()))
+            .into_iter()
+            .map(|k| (k.name, k.kind, k.lines))
+            .collect()
+    }
+
+    fn key(name: &str, kind: &str, lines: &[u32]) -> (String, String, Vec<u32>) {
+        (name.to_string(), kind.to_string(), lines.to_vec())
+    }
+
+    #[test]
+    fn test_extract_js_and_python() {
+        let js = "const url = process.env.DATABASE_URL;\n\
+                  const port = process.env['PORT'] || config.get(\"server.port\");\n\
+                  // process.env.COMMENTED\n\
+                  if (process.env.hasOwnProperty(x)) {}\n\
+                  const mode = import.meta.env.MODE; const u2 = process.env.DATABASE_URL;\n\
+                  const x = myprocess.env.NOPE + process.env[`A_${y}`];\n";
+        assert_eq!(
+            keys(Language::TypeScript, js),
+            vec![
+                key("DATABASE_URL", "env", &[1, 5]),
+                key("MODE", "env", &[5]),
+                key("PORT", "env", &[2]),
+                key("server.port", "config", &[2]),
+            ]
+        );
+
+        let py = "import os\n\
+                  a = os.environ[\"HOME\"]\n\
+                  b = os.environ.get('DEBUG', '0')\n\
+                  c = os.getenv(\"TOKEN\")\n\
+                  # os.getenv(\"SKIPPED\")\n\
+                  d = os.environment(\"NOPE\")\n";
+        assert_eq!(
+            keys(Language::Python, py),
+            vec![
+                key("DEBUG", "env", &[3]),
+                key("HOME", "env", &[2]),
+                key("TOKEN", "env", &[4]),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_extract_go_rust_java_c() {
+        let go = "addr := os.Getenv(\"ADDR\")\nv, ok := os.LookupEnv(\"LEVEL\")\nn := viper.GetInt(\"workers\")\n";
+        assert_eq!(
+            keys(Language::Go, go),
+            vec![
+                key("ADDR", "env", &[1]),
+                key("LEVEL", "env", &[2]),
+                key("workers", "config", &[3]),
+            ]
+        );
+
+        let rs = "let a = std::env::var(\"HOME\");\nlet b = env::var_os(\"PATH\");\n\
+                  const V: &str = env!(\"CARGO_PKG_VERSION\");\nlet c = option_env!(\"CI\");\n\
+                  let d = settings.get::<String>(\"db.url\");\n";
+        assert_eq!(
+            keys(Language::Rust, rs),
+            vec![
+                key("CARGO_PKG_VERSION", "env", &[3]),
+                key("CI", "env", &[4]),
+                key("HOME", "env", &[1]),
+                key("PATH", "env", &[2]),
+                key("db.url", "config", &[5]),
+            ]
+        );
+
+        let java = "String h = System.getenv(\"HOME\");\n@Value(\"${app.timeout:30}\")\n\
+                    String p = System.getProperty(\"user.dir\");\n";
+        assert_eq!(
+            keys(Language::Java, java),
+            vec![
+                key("HOME", "env", &[1]),
+                key("app.timeout", "config", &[2]),
+                key("user.dir", "config", &[3]),
+            ]
+        );
+
+        let c = "const char *h = getenv(\"HOME\");\nauto p = std::getenv(\"PATH\");\n";
+        assert_eq!(
+            keys(Language::Cpp, c),
+            vec![key("HOME", "env", &[1]), key("PATH", "env", &[2])]
+        );
+    }
+
+    #[test]
+    fn test_skips_strings_and_block_comments() {
+        let js = "const help = \"set process.env.IN_STRING first\";\n\
+                  /*\n\
+                  const old = process.env.IN_BLOCK;\n\
+                  */\n\
+                  const url = process.env.API_URL; // process.env.TRAILING\n";
+        assert_eq!(
+            keys(Language::JavaScript, js),
+            vec![key("API_URL", "env", &[5])]
+        );
+    }
+}
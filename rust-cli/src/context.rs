@@ -0,0 +1,426 @@
+/// 编辑前上下文（context 命令 / PreToolUse hook）
+///
+/// 为即将修改的文件汇总：符号大纲、导入方（含使用行）、一层依赖方模块、
+/// CODEOWNERS 负责人与相关测试文件，让修改前就能看到影响范围。
+use serde::Serialize;
+use std::collections::BTreeSet;
+use std::path::Path;
+
+use crate::graph::{CodeGraph, FileEntry};
+use crate::path_utils::{import_lookup, resolve_relative_import, strip_extension};
+
+/// CODEOWNERS 的标准位置（GitHub / GitLab 按此顺序查找，第一个存在的生效）
+const CODEOWNERS_PATHS: &[&str] = &["CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS"];
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 大纲条目：函数、类、类型或模块级变量
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct OutlineItem {
+    /// function / class / type / variable
+    pub kind: String,
+    pub name: String,
+    /// 函数签名；其他条目为空
+    #[serde(skip_serializing_if = "String::is_empty")]
+    pub signature: String,
+    #[serde(rename = "startLine")]
+    pub start_line: u32,
+    #[serde(rename = "endLine")]
+    pub end_line: u32,
+}
+
+/// 导入目标文件的一个文件
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct Importer {
+    pub file: String,
+    pub module: String,
+    #[serde(rename = "importLine")]
+    pub import_line: u32,
+    pub symbols: Vec<UsedSymbol>,
+}
+
+/// 导入的符号及其在导入方中的使用行
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct UsedSymbol {
+    pub symbol: String,
+    #[serde(rename = "useLines")]
+    pub use_lines: Vec<u32>,
+}
+
+/// 单个文件的编辑前上下文
+#[derive(Debug, Clone, Serialize)]
+pub struct FileContext {
+    pub file: String,
+    pub module: String,
+    pub language: String,
+    pub lines: u32,
+    pub outline: Vec<OutlineItem>,
+    pub importers: Vec<Importer>,
+    /// 依赖所在模块的模块（深度 1）
+    pub dependants: Vec<String>,
+    pub owners: Vec<String>,
+    pub tests: Vec<String>,
+}
+
+/// CODEOWNERS 中的一条规则
+#[derive(Debug, Clone, PartialEq)]
+pub struct OwnerRule {
+    pub pattern: String,
+    pub owners: Vec<String>,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 生成文件的编辑前上下文；文件不在图谱中时返回 None
+pub fn file_context(graph: &CodeGraph, rules: &[OwnerRule], path: &str) -> Option<FileContext> {
+    let entry = graph.files.get(path)?;
+    let importers = importers_of(graph, path);
+    let dependants = graph
+        .modules
+        .get(&entry.module)
+        .map(|m| m.depended_by.clone())
+        .unwrap_or_default();
+    let tests = tests_for(graph, path, &importers);
+
+    Some(FileContext {
+        file: path.to_string(),
+        module: entry.module.clone(),
+        language: entry.language.clone(),
+        lines: entry.lines,
+        outline: outline(entry),
+        importers,
+        dependants,
+        owners: owners_for(rules, path),
+        tests,
+    })
+}
+
+/// 读取项目的 CODEOWNERS（不存在时返回空规则）
+pub fn load_codeowners(root_dir: &Path) -> Vec<OwnerRule> {
+    CODEOWNERS_PATHS
+        .iter()
+        .find_map(|p| std::fs::read_to_string(root_dir.join(p)).ok())
+        .map(|text| parse_codeowners(&text))
+        .unwrap_or_default()
+}
+
+/// 解析 CODEOWNERS：每行 `<pattern> <owner>...`，`#` 开头为注释
+pub fn parse_codeowners(text: &str) -> Vec<OwnerRule> {
+    text.lines()
+        .filter_map(|line| {
+            let line = line.split(" #").next().unwrap_or(line).trim();
+            if line.is_empty() || line.starts_with('#') || line.starts_with('[') {
+                return None;
+            }
+            let mut parts = line.split_whitespace();
+            let pattern = parts.next()?.to_string();
+            Some(OwnerRule {
+                pattern,
+                owners: parts.map(|s| s.to_string()).collect(),
+            })
+        })
+        .collect()
+}
+
+/// 文件的负责人：最后一条匹配的规则生效（与 GitHub 语义一致）
+pub fn owners_for(rules: &[OwnerRule], path: &str) -> Vec<String> {
+    rules
+        .iter()
+        .rev()
+        .find(|r| codeowners_match(&r.pattern, path))
+        .map(|r| r.owners.clone())
+        .unwrap_or_default()
+}
+
+/// 按常见命名约定判断是否为测试文件
+pub fn is_test_file(path: &str) -> bool {
+    let name = path.rsplit('/').next().unwrap_or(path);
+    let stem = strip_extension(name);
+    path.split('/')
+        .any(|c| matches!(c, "test" | "tests" | "__tests__" | "spec" | "testdata"))
+        || stem.ends_with(".test")
+        || stem.ends_with(".spec")
+        || stem.ends_with("_test")
+        || stem.starts_with("test_")
+        || ((stem.ends_with("Test") || stem.ends_with("Tests"))
+            && stem != "Test"
+            && stem != "Tests")
+}
+
+/// 从 PreToolUse hook 的 JSON 输入中取出 `tool_input.file_path`
+///
+/// 用 JSON 解析器处理转义（`\"`、Windows 路径的 `\\`、`\uXXXX`），不是 JSON 或没有该字段时返回 None。
+pub fn hook_file_path(input: &str) -> Option<String> {
+    let value: serde_json::Value = serde_json::from_str(input).ok()?;
+    let path = value.get("tool_input")?.get("file_path")?.as_str()?;
+    (!path.is_empty()).then(|| path.to_string())
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+fn outline(entry: &FileEntry) -> Vec<OutlineItem> {
+    let mut items: Vec<OutlineItem> = Vec::new();
+    for f in &entry.functions {
+        items.push(OutlineItem {
+            kind: "function".to_string(),
+            name: f.name.clone(),
+            signature: f.signature.clone(),
+            start_line: f.start_line,
+            end_line: f.end_line,
+        });
+    }
+    for c in &entry.classes {
+        items.push(OutlineItem {
+            kind: "class".to_string(),
+            name: c.name.clone(),
+            signature: String::new(),
+            start_line: c.start_line,
+            end_line: c.end_line,
+        });
+    }
+    for t in &entry.types {
+        items.push(OutlineItem {
+            kind: "type".to_string(),
+            name: t.name.clone(),
+            signature: String::new(),
+            start_line: t.start_line,
+            end_line: t.end_line,
+        });
+    }
+    for v in &entry.variables {
+        items.push(OutlineItem {
+            kind: "variable".to_string(),
+            name: v.name.clone(),
+            signature: String::new(),
+            start_line: v.start_line,
+            end_line: v.start_line,
+        });
+    }
+    items.sort_by(|a, b| a.start_line.cmp(&b.start_line).then(a.name.cmp(&b.name)));
+    items
+}
+
+/// 通过相对 import 指向 path 的文件（解析规则与模块依赖一致）
+fn importers_of(graph: &CodeGraph, path: &str) -> Vec<Importer> {
+    let lookup = import_lookup(graph.files.keys());
+    let mut importers = Vec::new();
+    for (importer, entry) in &graph.files {
+        if importer == path {
+            continue;
+        }
+        for imp in &entry.imports {
+            if imp.is_external
+                || resolve_relative_import(importer, &imp.source, &lookup).as_deref() != Some(path)
+            {
+                continue;
+            }
+            let symbols = imp
+                .symbols
+                .iter()
+                .map(|s| UsedSymbol {
+                    symbol: s.clone(),
+                    use_lines: entry
+                        .symbol_refs
+                        .get(s)
+                        .map(|r| r.use_lines.clone())
+                        .unwrap_or_default(),
+                })
+                .collect();
+            importers.push(Importer {
+                file: importer.clone(),
+                module: entry.module.clone(),
+                import_line: imp.import_line,
+                symbols,
+            });
+        }
+    }
+    importers
+}
+
+/// 相关测试：导入 path 的测试文件，以及按命名约定对应的测试文件
+/// （`foo.test.ts`、`foo_test.go`、`test_foo.py`、`FooTest.java` 等）
+fn tests_for(graph: &CodeGraph, path: &str, importers: &[Importer]) -> Vec<String> {
+    let name = path.rsplit('/').next().unwrap_or(path);
+    let stem = strip_extension(name);
+    let mut tests: BTreeSet<String> = importers
+        .iter()
+        .filter(|i| is_test_file(&i.file))
+        .map(|i| i.file.clone())
+        .collect();
+    if is_test_file(path) {
+        return tests.into_iter().collect();
+    }
+    let candidates: Vec<String> = [
+        format!("{}.test", stem),
+        format!("{}.spec", stem),
+        format!("{}_test", stem),
+        format!("test_{}", stem),
+        format!("{}Test", stem),
+        format!("{}Tests", stem),
+    ]
+    .into();
+    for file in graph.files.keys() {
+        let file_stem = strip_extension(file.rsplit('/').next().unwrap_or(file));
+        if candidates.contains(&file_stem) {
+            tests.insert(file.clone());
+        }
+    }
+    tests.into_iter().collect()
+}
+
+/// CODEOWNERS 模式匹配（gitignore 语法）：
+/// 含 `/` 的模式相对仓库根锚定，否则匹配任意层级；匹配目录时覆盖其下所有文件
+fn codeowners_match(pattern: &str, path: &str) -> bool {
+    let trimmed = pattern.trim_end_matches('/');
+    if trimmed.is_empty() {
+        return false;
+    }
+    let anchored = trimmed.contains('/');
+    let trimmed = trimmed.trim_start_matches('/');
+    let full = if anchored {
+        trimmed.to_string()
+    } else {
+        format!("**/{}", trimmed)
+    };
+    let p = full.as_bytes();
+    // 文件自身或其任一上级目录命中即匹配
+    let dir_only = pattern.ends_with('/');
+    if !dir_only && glob_match(p, path.as_bytes()) {
+        return true;
+    }
+    path.match_indices('/')
+        .any(|(i, _)| glob_match(p, &path.as_bytes()[..i]))
+}
+
+/// 简单 glob：`*` 不跨 `/`，`**` 跨任意层级，`?` 匹配单个非 `/` 字符
+fn glob_match(p: &[u8], t: &[u8]) -> bool {
+    match p.first() {
+        None => t.is_empty(),
+        Some(b'*') if p.get(1) == Some(&b'*') => {
+            let rest = &p[2..];
+            if let Some(rest) = rest.strip_prefix(b"/") {
+                glob_match(rest, t)
+                    || (0..t.len()).any(|i| t[i] == b'/' && glob_match(rest, &t[i + 1..]))
+            } else {
+                (0..=t.len()).any(|i| glob_match(rest, &t[i..]))
+            }
+        }
+        Some(b'*') => (0..=t.len())
+            .take_while(|&i| i == 0 || t[i - 1] != b'/')
+            .any(|i| glob_match(&p[1..], &t[i..])),
+        Some(b'?') => !t.is_empty() && t[0] != b'/' && glob_match(&p[1..], &t[1..]),
+        Some(c) => t.first() == Some(c) && glob_match(&p[1..], &t[1..]),
+    }
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::{create_empty_graph, ImportInfo, ModuleEntry, SymbolRef};
+
+    fn file(module: &str, imports: Vec<ImportInfo>) -> FileEntry {
+        FileEntry {
+            language: "typescript".to_string(),
+            module: module.to_string(),
+            lines: 10,
+            imports,
+            ..Default::default()
+        }
+    }
+
+    fn import(source: &str, symbols: &[&str], line: u32) -> ImportInfo {
+        ImportInfo {
+            source: source.to_string(),
+            symbols: symbols.iter().map(|s| s.to_string()).collect(),
+            is_external: false,
+            import_line: line,
+        }
+    }
+
+    #[test]
+    fn test_file_context_importers_and_tests() {
+        let mut graph = create_empty_graph("p", "/p");
+        graph
+            .files
+            .insert("src/auth/login.ts".to_string(), file("auth", vec![]));
+        let mut api = file("api", vec![import("../auth/login", &["login"], 2)]);
+        api.symbol_refs.insert(
+            "login".to_string(),
+            SymbolRef {
+                symbol: "login".to_string(),
+                import_line: 2,
+                use_lines: vec![14, 30],
+            },
+        );
+        graph.files.insert("src/api/routes.ts".to_string(), api);
+        graph.files.insert(
+            "src/auth/login.test.ts".to_string(),
+            file("auth", vec![import("./login", &["login"], 1)]),
+        );
+        graph
+            .files
+            .insert("src/other.ts".to_string(), file("other", vec![]));
+        graph.modules.insert(
+            "auth".to_string(),
+            ModuleEntry {
+                files: vec![],
+                depends_on: vec![],
+                depended_by: vec!["api".to_string()],
+            },
+        );
+        let rules = parse_codeowners("# owners\n* @all\nsrc/auth/ @team-auth\n");
+
+        let ctx = file_context(&graph, &rules, "src/auth/login.ts").unwrap();
+        assert_eq!(ctx.importers.len(), 2);
+        assert_eq!(ctx.importers[0].file, "src/api/routes.ts");
+        assert_eq!(ctx.importers[0].symbols[0].use_lines, vec![14, 30]);
+        assert_eq!(ctx.dependants, vec!["api"]);
+        assert_eq!(ctx.owners, vec!["@team-auth"]);
+        assert_eq!(ctx.tests, vec!["src/auth/login.test.ts"]);
+        assert!(file_context(&graph, &rules, "src/missing.ts").is_none());
+    }
+
+    #[test]
+    fn test_codeowners_match() {
+        assert!(codeowners_match("*", "a/b.ts"));
+        assert!(codeowners_match("*.js", "web/app.js"));
+        assert!(!codeowners_match("*.js", "web/app.ts"));
+        assert!(codeowners_match("/docs/", "docs/a/b.md"));
+        assert!(!codeowners_match("/docs/", "src/docs.ts"));
+        assert!(codeowners_match("apps/", "x/apps/y.ts"));
+        assert!(codeowners_match("src/auth", "src/auth/login.ts"));
+        assert!(!codeowners_match("src/auth", "lib/src/auth/login.ts"));
+        assert!(codeowners_match("src/**/db.rs", "src/a/b/db.rs"));
+        assert!(codeowners_match("src/*.rs", "src/main.rs"));
+        assert!(!codeowners_match("src/*.rs", "src/a/main.rs"));
+    }
+
+    #[test]
+    fn test_is_test_file() {
+        assert!(is_test_file("src/a.test.ts"));
+        assert!(is_test_file("pkg/a_test.go"));
+        assert!(is_test_file("test_a.py"));
+        assert!(is_test_file("src/FooTest.java"));
+        assert!(is_test_file("rust-cli/tests/compat.rs"));
+        assert!(!is_test_file("src/attest.ts"));
+        assert!(!is_test_file("src/Test.java"));
+    }
+
+    #[test]
+    fn test_hook_file_path() {
+        let input = r#"{"session_id":"s","tool_name":"Edit","tool_input":{"old_string":"\"file_path\": \"x.ts\"","file_path":"C:\\src\\say \"hi\"\\caf\u00e9.ts"}}"#;
+        assert_eq!(
+            hook_file_path(input).as_deref(),
+            Some(r#"C:\src\say "hi"\café.ts"#)
+        );
+        assert_eq!(
+            hook_file_path(r#"{"tool_input":{"file_path":"/p/src/a.ts"}}"#).as_deref(),
+            Some("/p/src/a.ts")
+        );
+        assert_eq!(hook_file_path(r#"{"tool_input":{"file_path":""}}"#), None);
+        assert_eq!(hook_file_path(r#"{"file_path":"a.ts"}"#), None);
+        assert_eq!(hook_file_path("not json"), None);
+    }
+}
@@ -0,0 +1,448 @@
+/// 弃用标记与迁移报告（deprecated）
+///
+/// 扫描时逐行识别弃用标记并归属到符号（属性与装饰器只在代码中匹配，文档标签只在注释中匹配）：
+/// - 标注在声明前的标记归属于其后的第一个符号：Rust `#[deprecated]`、JSDoc / Javadoc `@deprecated`、
+///   Java `@Deprecated`、Python `@deprecated(...)`（typing_extensions / warnings）、Go `// Deprecated:`
+/// - Python 函数体内的 `warnings.warn(..., DeprecationWarning)` 归属于所在函数
+///
+/// 报告阶段用图谱中的引用关系列出仍在使用弃用符号的位置，按模块分组并附 CODEOWNERS 负责人。
+use serde::Serialize;
+use std::collections::BTreeMap;
+
+use crate::context::{owners_for, OwnerRule};
+use crate::graph::{CodeGraph, Deprecation, FileEntry};
+use crate::query::find_callers;
+use crate::source_text::{SourceLine, SourceText};
+use crate::traverser::Language;
+
+/// 标记与其后符号声明之间允许的最大行数（覆盖较长的文档注释）
+const MAX_MARKER_GAP: u32 = 30;
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 一个文件中对弃用符号的使用
+#[derive(Debug, Clone, Serialize)]
+pub struct UseSite {
+    pub file: String,
+    /// 导入行（0 表示同文件内使用）
+    #[serde(rename = "importLine")]
+    pub import_line: u32,
+    #[serde(rename = "useLines")]
+    pub use_lines: Vec<u32>,
+    pub owners: Vec<String>,
+}
+
+/// 某个模块内的使用位置
+#[derive(Debug, Clone, Serialize)]
+pub struct ModuleUses {
+    pub module: String,
+    /// 该模块内使用位置的负责人（去重排序）
+    pub owners: Vec<String>,
+    pub sites: Vec<UseSite>,
+}
+
+/// 弃用符号及其剩余使用位置
+#[derive(Debug, Clone, Serialize)]
+pub struct DeprecatedSymbol {
+    pub name: String,
+    pub kind: String,
+    pub file: String,
+    pub module: String,
+    pub line: u32,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub message: Option<String>,
+    /// 使用位置总数（文件数）
+    #[serde(rename = "useCount")]
+    pub use_count: usize,
+    pub uses: Vec<ModuleUses>,
+}
+
+/// 尚未归属到符号的标记
+struct Marker {
+    line: u32,
+    message: Option<String>,
+    /// true：归属于所在函数；false：归属于其后的第一个符号
+    enclosing: bool,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 提取文件中的弃用符号，按行号排序
+pub fn extract_deprecations(
+    lang: Language,
+    source: &SourceText,
+    entry: &FileEntry,
+) -> Vec<Deprecation> {
+    let markers = find_markers(lang, &source.lines());
+
+    // (起始行, 结束行, 名称, 类型)
+    let mut symbols: Vec<(u32, u32, &str, &str)> = Vec::new();
+    symbols.extend(
+        entry
+            .functions
+            .iter()
+            .map(|f| (f.start_line, f.end_line, f.name.as_str(), "function")),
+    );
+    symbols.extend(
+        entry
+            .classes
+            .iter()
+            .map(|c| (c.start_line, c.end_line, c.name.as_str(), "class")),
+    );
+    symbols.extend(
+        entry
+            .types
+            .iter()
+            .map(|t| (t.start_line, t.end_line, t.name.as_str(), "type")),
+    );
+    symbols.extend(
+        entry
+            .variables
+            .iter()
+            .map(|v| (v.start_line, v.start_line, v.name.as_str(), "variable")),
+    );
+    symbols.sort();
+
+    let mut found: BTreeMap<(&str, &str), Deprecation> = BTreeMap::new();
+    for m in markers {
+        let target = if m.enclosing {
+            symbols
+                .iter()
+                .filter(|s| s.3 == "function" && s.0 <= m.line && m.line <= s.1)
+                .min_by_key(|s| s.1 - s.0)
+        } else {
+            symbols
+                .iter()
+                .find(|s| s.0 >= m.line && s.0 - m.line <= MAX_MARKER_GAP)
+        };
+        let Some(&(start, _, name, kind)) = target else {
+            continue;
+        };
+        let d = found.entry((name, kind)).or_insert_with(|| Deprecation {
+            name: name.to_string(),
+            kind: kind.to_string(),
+            line: start,
+            message: None,
+        });
+        // 同一符号的多个标记（如 `@Deprecated` + Javadoc `@deprecated`）取第一条说明
+        if d.message.is_none() {
+            d.message = m.message;
+        }
+    }
+    let mut result: Vec<Deprecation> = found.into_values().collect();
+    result.sort_by(|a, b| a.line.cmp(&b.line).then(a.name.cmp(&b.name)));
+    result
+}
+
+/// 汇总图谱中的弃用符号及其使用位置，按文件、行号排序
+pub fn deprecation_report(graph: &CodeGraph, rules: &[OwnerRule]) -> Vec<DeprecatedSymbol> {
+    let mut report = Vec::new();
+    for (path, entry) in &graph.files {
+        for d in &entry.deprecations {
+            let (_, refs) = find_callers(graph, path, &d.name);
+            let mut by_module: BTreeMap<String, Vec<UseSite>> = BTreeMap::new();
+            for r in refs {
+                by_module.entry(r.module).or_default().push(UseSite {
+                    owners: owners_for(rules, &r.file),
+                    file: r.file,
+                    import_line: r.import_line,
+                    use_lines: r.use_lines,
+                });
+            }
+            let use_count = by_module.values().map(Vec::len).sum();
+            let uses = by_module
+                .into_iter()
+                .map(|(module, sites)| {
+                    let mut owners: Vec<String> = sites
+                        .iter()
+                        .flat_map(|s| s.owners.iter().cloned())
+                        .collect();
+                    owners.sort();
+                    owners.dedup();
+                    ModuleUses {
+                        module,
+                        owners,
+                        sites,
+                    }
+                })
+                .collect();
+            report.push(DeprecatedSymbol {
+                name: d.name.clone(),
+                kind: d.kind.clone(),
+                file: path.clone(),
+                module: entry.module.clone(),
+                line: d.line,
+                message: d.message.clone(),
+                use_count,
+                uses,
+            });
+        }
+    }
+    report
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+fn find_markers(lang: Language, lines: &[SourceLine]) -> Vec<Marker> {
+    let mut markers = Vec::new();
+    for (i, source_line) in lines.iter().enumerate() {
+        // 属性、注解与装饰器在代码视图中匹配，说明文字从原文读取
+        let code = source_line.code.trim();
+        let line = source_line.raw.trim();
+        let line_no = i as u32 + 1;
+        let next = |message: Option<String>| Marker {
+            line: line_no,
+            message,
+            enclosing: false,
+        };
+        match lang {
+            Language::Rust => {
+                if code.starts_with("#[deprecated") {
+                    let message =
+                        attr_value(line, "note").or_else(|| attr_value(line, "deprecated"));
+                    markers.push(next(message));
+                }
+            }
+            Language::Go => {
+                if let Some(rest) = source_line
+                    .comments()
+                    .find_map(|c| c.trim().strip_prefix("// Deprecated:"))
+                {
+                    markers.push(next(non_empty(rest)));
+                }
+            }
+            Language::Python => {
+                let decorator = [
+                    "@deprecated(",
+                    "@typing_extensions.deprecated(",
+                    "@warnings.deprecated(",
+                ]
+                .iter()
+                .any(|p| code.starts_with(p));
+                if decorator {
+                    markers.push(next(first_string(line)));
+                } else if code.contains("warnings.warn(") {
+                    // 类别参数可能在续行中
+                    let window = &lines[i..lines.len().min(i + 4)];
+                    let call: String = window.iter().map(|l| l.raw).collect::<Vec<_>>().join(" ");
+                    if window.iter().any(|l| l.code.contains("DeprecationWarning")) {
+                        markers.push(Marker {
+                            line: line_no,
+                            message: first_string(&call),
+                            enclosing: true,
+                        });
+                    }
+                }
+            }
+            _ => {
+                if code.starts_with("@Deprecated") {
+                    markers.push(next(None));
+                } else if code.starts_with("[[deprecated") {
+                    // C++14 属性
+                    markers.push(next(first_string(line)));
+                } else if let Some(rest) = source_line.comments().find_map(|c| {
+                    // 仅识别注释中的 JSDoc / Javadoc 标签
+                    let pos = c.find("@deprecated")?;
+                    Some(c[pos + "@deprecated".len()..].trim_end_matches("*/"))
+                }) {
+                    markers.push(next(non_empty(rest)));
+                }
+            }
+        }
+    }
+    markers
+}
+
+/// `#[deprecated(note = "x")]` 或 `#[deprecated = "x"]` 中的字符串
+fn attr_value(line: &str, key: &str) -> Option<String> {
+    let pos = line.find(key)?;
+    let rest = line[pos + key.len()..].trim_start().strip_prefix('=')?;
+    first_string(rest)
+}
+
+fn first_string(s: &str) -> Option<String> {
+    let quote = s.find(['"', '\''])?;
+    let q = s[quote..].chars().next()?;
+    let len = s[quote + 1..].find(q)?;
+    non_empty(&s[quote + 1..quote + 1 + len])
+}
+
+fn non_empty(s: &str) -> Option<String> {
+    let t = s.trim();
+    (!t.is_empty()).then(|| t.to_string())
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::fixtures::file_with_symbols as entry;
+    use crate::graph::{create_empty_graph, ClassInfo, FunctionInfo, ImportInfo, SymbolRef};
+
+    fn func(name: &str, start: u32, end: u32) -> FunctionInfo {
+        FunctionInfo {
+            name: name.into(),
+            signature: String::new(),
+            start_line: start,
+            end_line: end,
+        }
+    }
+
+    fn scan(lang: Language, src: &str) -> SourceText {
+        SourceText::scan(lang, src.as_bytes())
+    }
+
+    fn summary(found: Vec<Deprecation>) -> Vec<String> {
+        found
+            .iter()
+            .map(|d| format!("{}:{} {:?}", d.name, d.line, d.message))
+            .collect()
+    }
+
+    #[test]
+    fn test_extract_markers_per_language() {
+        let ts = "/**\n * Old login.\n * @deprecated Use loginV2 instead.\n */\nexport function login() {}\nexport function loginV2() {}\n";
+        let e = entry(
+            "auth",
+            vec![func("login", 5, 5), func("loginV2", 6, 6)],
+            vec![],
+        );
+        assert_eq!(
+            summary(extract_deprecations(
+                Language::TypeScript,
+                &scan(Language::TypeScript, ts),
+                &e
+            )),
+            vec!["login:5 Some(\"Use loginV2 instead.\")"]
+        );
+
+        let rs = "#[deprecated(since = \"1.2\", note = \"use parse_v2\")]\npub fn parse() {}\n";
+        let e = entry("core", vec![func("parse", 2, 2)], vec![]);
+        assert_eq!(
+            summary(extract_deprecations(
+                Language::Rust,
+                &scan(Language::Rust, rs),
+                &e
+            )),
+            vec!["parse:2 Some(\"use parse_v2\")"]
+        );
+
+        let go = "// Client talks to the API.\n//\n// Deprecated: use NewClient.\ntype Client struct{}\n";
+        let mut e = entry("api", vec![], vec![]);
+        e.types.push(crate::graph::TypeInfo {
+            name: "Client".into(),
+            kind: "struct".into(),
+            start_line: 4,
+            end_line: 4,
+        });
+        assert_eq!(
+            summary(extract_deprecations(
+                Language::Go,
+                &scan(Language::Go, go),
+                &e
+            )),
+            vec!["Client:4 Some(\"use NewClient.\")"]
+        );
+
+        let py = "def old(x):\n    warnings.warn(\n        \"old() is going away\", DeprecationWarning\n    )\n    return x\n\n@deprecated(\"Use Store\")\nclass Cache:\n    pass\n";
+        let e = entry(
+            "py",
+            vec![func("old", 1, 5)],
+            vec![ClassInfo {
+                name: "Cache".into(),
+                start_line: 8,
+                end_line: 9,
+            }],
+        );
+        assert_eq!(
+            summary(extract_deprecations(
+                Language::Python,
+                &scan(Language::Python, py),
+                &e
+            )),
+            vec![
+                "old:1 Some(\"old() is going away\")",
+                "Cache:8 Some(\"Use Store\")"
+            ]
+        );
+
+        let java = "class A {\n  /** @deprecated use b() */\n  @Deprecated\n  void a() {}\n}\n";
+        let e = entry("j", vec![func("a", 4, 4)], vec![]);
+        assert_eq!(
+            summary(extract_deprecations(
+                Language::Java,
+                &scan(Language::Java, java),
+                &e
+            )),
+            vec!["a:4 Some(\"use b()\")"]
+        );
+    }
+
+    #[test]
+    fn test_markers_in_strings_are_ignored() {
+        let py = "def load():\n    \"\"\"Example:\n\n@deprecated(\"doc\")\n    \"\"\"\n    # warnings.warn(\"x\", DeprecationWarning)\n    return 1\n\n\nclass Cache:\n    pass\n";
+        let e = entry(
+            "py",
+            vec![func("load", 1, 7)],
+            vec![ClassInfo {
+                name: "Cache".into(),
+                start_line: 10,
+                end_line: 11,
+            }],
+        );
+        assert!(extract_deprecations(Language::Python, &scan(Language::Python, py), &e).is_empty());
+    }
+
+    #[test]
+    fn test_deprecation_report_groups_uses_by_module() {
+        let mut graph = create_empty_graph("p", "/p");
+        let mut lib = entry("auth", vec![func("login", 5, 9)], vec![]);
+        lib.deprecations.push(Deprecation {
+            name: "login".into(),
+            kind: "function".into(),
+            line: 5,
+            message: Some("use loginV2".into()),
+        });
+        graph.files.insert("src/auth/login.ts".into(), lib);
+        for (path, module) in [
+            ("src/api/a.ts", "api"),
+            ("src/api/b.ts", "api"),
+            ("src/web/c.ts", "web"),
+        ] {
+            let mut user = entry(module, vec![], vec![]);
+            user.imports.push(ImportInfo {
+                source: "../auth/login".into(),
+                symbols: vec!["login".into()],
+                is_external: false,
+                import_line: 1,
+            });
+            user.symbol_refs.insert(
+                "login".into(),
+                SymbolRef {
+                    symbol: "login".into(),
+                    import_line: 1,
+                    use_lines: vec![4],
+                },
+            );
+            graph.files.insert(path.into(), user);
+        }
+        let rules = crate::context::parse_codeowners("src/api/ @api-team\nsrc/api/b.ts @bob\n");
+
+        let report = deprecation_report(&graph, &rules);
+        assert_eq!(report.len(), 1);
+        assert_eq!(report[0].use_count, 3);
+        let modules: Vec<(&str, Vec<String>, usize)> = report[0]
+            .uses
+            .iter()
+            .map(|u| (u.module.as_str(), u.owners.clone(), u.sites.len()))
+            .collect();
+        assert_eq!(
+            modules,
+            vec![
+                ("api", vec!["@api-team".to_string(), "@bob".to_string()], 2),
+                ("web", vec![], 1),
+            ]
+        );
+    }
+}
@@ -0,0 +1,393 @@
+/// 外部依赖清单
+///
+/// 将图谱中的外部 import 按第三方包归类（npm 包、Go module、crate、PyPI 发行包、
+/// Maven 构件），再与清单文件和 lockfile 中声明的依赖做关联，得到：
+/// 每个依赖被哪些模块使用、声明了却未使用的包、使用了却未声明的包。
+use serde::Serialize;
+use std::collections::{BTreeMap, BTreeSet};
+use std::path::Path;
+
+use crate::external::{PackageRef, PackageResolver};
+use crate::graph::CodeGraph;
+use crate::manifest::{
+    declared_dependencies, normalize_python_name, DeclaredDependency, Ecosystem,
+};
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 依赖的使用状态
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "lowercase")]
+pub enum DependencyStatus {
+    /// 已声明且被 import
+    Used,
+    /// 已声明但没有任何 import
+    Unused,
+    /// 被 import 但未在任何清单中声明
+    Undeclared,
+}
+
+/// 单个依赖的汇总
+#[derive(Debug, Clone, Serialize)]
+pub struct DependencyEntry {
+    pub ecosystem: Ecosystem,
+    /// 声明名（未声明时为 import 推断出的包名）
+    pub name: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub version: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub locked: Option<String>,
+    pub dev: bool,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub manifest: Option<String>,
+    pub status: DependencyStatus,
+    /// 使用该依赖的模块（排序）
+    pub modules: Vec<String>,
+    /// import 该依赖的文件数
+    #[serde(rename = "fileCount")]
+    pub file_count: usize,
+}
+
+/// `codegraph deps` 的完整结果
+#[derive(Debug, Clone, Serialize)]
+pub struct DependencyReport {
+    pub dependencies: Vec<DependencyEntry>,
+}
+
+impl DependencyReport {
+    pub fn with_status(&self, status: DependencyStatus) -> Vec<&DependencyEntry> {
+        self.dependencies
+            .iter()
+            .filter(|d| d.status == status)
+            .collect()
+    }
+
+    /// 声明了却未使用的运行时依赖（dev / 间接依赖不要求被直接 import，不计入）
+    pub fn unused(&self) -> Vec<&DependencyEntry> {
+        self.dependencies
+            .iter()
+            .filter(|d| d.status == DependencyStatus::Unused && !d.dev)
+            .collect()
+    }
+}
+
+/// import 某个包的位置汇总
+#[derive(Default)]
+struct Usage {
+    modules: BTreeSet<String>,
+    files: BTreeSet<String>,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 构建依赖清单报告
+pub fn build_report(root_dir: &Path, graph: &CodeGraph) -> DependencyReport {
+    build_report_with(
+        graph,
+        &PackageResolver::new(root_dir, graph),
+        declared_dependencies(root_dir),
+    )
+}
+
+/// 在给定的包解析器与声明列表上构建报告（便于测试）
+pub fn build_report_with(
+    graph: &CodeGraph,
+    resolver: &PackageResolver,
+    declared: Vec<DeclaredDependency>,
+) -> DependencyReport {
+    // 1. 按包汇总 import
+    let mut usages: BTreeMap<PackageRef, Usage> = BTreeMap::new();
+    for (path, file) in &graph.files {
+        for imp in file.imports.iter().filter(|i| i.is_external) {
+            if let Some(pkg) = resolver.resolve(&file.language, &imp.source) {
+                let usage = usages.entry(pkg).or_default();
+                usage.modules.insert(file.module.clone());
+                usage.files.insert(path.clone());
+            }
+        }
+    }
+
+    // 2. 逐个声明匹配使用情况
+    let mut matched: BTreeSet<PackageRef> = BTreeSet::new();
+    let mut dependencies = Vec::new();
+    for dep in declared {
+        let mut modules = BTreeSet::new();
+        let mut files = BTreeSet::new();
+        for (pkg, usage) in &usages {
+            if pkg.ecosystem == dep.ecosystem && declares(&dep, &pkg.name) {
+                matched.insert(pkg.clone());
+                modules.extend(usage.modules.iter().cloned());
+                files.extend(usage.files.iter().cloned());
+            }
+        }
+        let status = if files.is_empty() {
+            DependencyStatus::Unused
+        } else {
+            DependencyStatus::Used
+        };
+        // 类型声明包（@types/*）不会出现在 import 中，按 dev 依赖处理
+        let dev = dep.dev || is_implicit(&dep);
+        dependencies.push(DependencyEntry {
+            ecosystem: dep.ecosystem,
+            name: dep.name,
+            version: Some(dep.version).filter(|v| !v.is_empty()),
+            locked: dep.locked,
+            dev,
+            manifest: Some(dep.manifest),
+            status,
+            modules: modules.into_iter().collect(),
+            file_count: files.len(),
+        });
+    }
+
+    // 3. 未匹配到任何声明的 import
+    for (pkg, usage) in usages {
+        if matched.contains(&pkg) {
+            continue;
+        }
+        dependencies.push(DependencyEntry {
+            ecosystem: pkg.ecosystem,
+            name: pkg.name,
+            version: None,
+            locked: None,
+            dev: false,
+            manifest: None,
+            status: DependencyStatus::Undeclared,
+            modules: usage.modules.into_iter().collect(),
+            file_count: usage.files.len(),
+        });
+    }
+
+    dependencies.sort_by(|a, b| (a.ecosystem, &a.name).cmp(&(b.ecosystem, &b.name)));
+    DependencyReport { dependencies }
+}
+
+/// 文本格式输出
+pub fn format_report(report: &DependencyReport) -> String {
+    let mut out = String::new();
+    let used = report.with_status(DependencyStatus::Used);
+    out.push_str(&format!("Dependencies in use ({}):\n", used.len()));
+    for d in &used {
+        out.push_str(&format!("  [{}] {}", d.ecosystem.as_str(), d.name));
+        if let Some(v) = &d.version {
+            out.push_str(&format!(" {}", v));
+        }
+        if let Some(l) = &d.locked {
+            if d.version.as_deref() != Some(l.as_str()) {
+                out.push_str(&format!(" (locked {})", l));
+            }
+        }
+        if d.dev {
+            out.push_str(" [dev]");
+        }
+        out.push('\n');
+        out.push_str(&format!(
+            "      used by: {} ({} file(s))\n",
+            d.modules.join(", "),
+            d.file_count
+        ));
+    }
+
+    let unused = report.unused();
+    out.push_str(&format!("\nDeclared but unused ({}):\n", unused.len()));
+    for d in &unused {
+        out.push_str(&format!(
+            "  [{}] {} ({})\n",
+            d.ecosystem.as_str(),
+            d.name,
+            d.manifest.as_deref().unwrap_or("")
+        ));
+    }
+
+    let undeclared = report.with_status(DependencyStatus::Undeclared);
+    out.push_str(&format!("\nUsed but undeclared ({}):\n", undeclared.len()));
+    for d in &undeclared {
+        out.push_str(&format!(
+            "  [{}] {} — imported by: {}\n",
+            d.ecosystem.as_str(),
+            d.name,
+            d.modules.join(", ")
+        ));
+    }
+
+    let dev_only = report
+        .with_status(DependencyStatus::Unused)
+        .len()
+        .saturating_sub(unused.len());
+    if dev_only > 0 {
+        out.push_str(&format!(
+            "\n({} dev/indirect dependencies are not imported directly and not reported as unused)\n",
+            dev_only
+        ));
+    }
+    out.trim_end().to_string()
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+/// 不会出现在 import 中的依赖（TypeScript 类型声明包）
+fn is_implicit(dep: &DeclaredDependency) -> bool {
+    dep.ecosystem == Ecosystem::Npm && dep.name.starts_with("@types/")
+}
+
+/// 常见 PyPI 发行名与 import 名不一致的包（发行名已规范化）
+const PYTHON_IMPORT_NAMES: &[(&str, &str)] = &[
+    ("attrs", "attr"),
+    ("beautifulsoup4", "bs4"),
+    ("msgpack_python", "msgpack"),
+    ("opencv_python", "cv2"),
+    ("opencv_python_headless", "cv2"),
+    ("pillow", "pil"),
+    ("protobuf", "google"),
+    ("psycopg2_binary", "psycopg2"),
+    ("pyjwt", "jwt"),
+    ("python_dateutil", "dateutil"),
+    ("python_dotenv", "dotenv"),
+    ("pyyaml", "yaml"),
+    ("scikit_learn", "sklearn"),
+];
+
+/// 声明的依赖是否覆盖 import 推断出的包名
+fn declares(dep: &DeclaredDependency, used: &str) -> bool {
+    match dep.ecosystem {
+        Ecosystem::Npm | Ecosystem::Go => dep.name == used,
+        Ecosystem::Cargo => dep.name.replace('-', "_") == used,
+        Ecosystem::PyPI => {
+            let dist = normalize_python_name(&dep.name);
+            let import = PYTHON_IMPORT_NAMES
+                .iter()
+                .find(|(d, _)| *d == dist)
+                .map(|(_, i)| i.to_string())
+                .unwrap_or(dist);
+            import == used.to_lowercase()
+        }
+        Ecosystem::Maven => maven_declares(&dep.name, used),
+    }
+}
+
+/// Maven 坐标与 Java 包路径的匹配
+///
+/// 包路径以 groupId 开头即匹配；否则要求与 groupId 共享至少两段前缀，
+/// 且 artifactId 的末段出现在包路径中（如 `jackson-databind` ↔ `com.fasterxml.jackson.databind`）。
+fn maven_declares(coordinate: &str, package: &str) -> bool {
+    let Some((group, artifact)) = coordinate.split_once(':') else {
+        return false;
+    };
+    if package == group || package.starts_with(&format!("{}.", group)) {
+        return true;
+    }
+    let shared = group
+        .split('.')
+        .zip(package.split('.'))
+        .take_while(|(a, b)| a == b)
+        .count();
+    let tail = artifact.rsplit('-').next().unwrap_or(artifact);
+    shared >= 2 && package.split('.').any(|seg| seg == tail)
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::differ::merge_graph_update;
+    use crate::graph::create_empty_graph;
+    use crate::graph::fixtures::file_with_imports as file;
+    use std::collections::HashMap;
+
+    fn declared(ecosystem: Ecosystem, name: &str, dev: bool) -> DeclaredDependency {
+        DeclaredDependency {
+            ecosystem,
+            name: name.to_string(),
+            version: "^1.0.0".to_string(),
+            locked: Some("1.2.0".to_string()),
+            dev,
+            manifest: "package.json".to_string(),
+        }
+    }
+
+    #[test]
+    fn test_build_report_classifies_dependencies() {
+        let mut graph = create_empty_graph("demo", "");
+        let mut files = HashMap::new();
+        files.insert(
+            "src/server/app.ts".to_string(),
+            file("server", "typescript", &["express", "lodash/fp", "fs"]),
+        );
+        files.insert(
+            "src/api/client.ts".to_string(),
+            file("api", "typescript", &["axios", "express"]),
+        );
+        merge_graph_update(&mut graph, files, &[]);
+
+        let root = std::env::temp_dir().join("codegraph_deps_missing_root");
+        let resolver = PackageResolver::new(&root, &graph);
+        let report = build_report_with(
+            &graph,
+            &resolver,
+            vec![
+                declared(Ecosystem::Npm, "express", false),
+                declared(Ecosystem::Npm, "lodash", false),
+                declared(Ecosystem::Npm, "left-pad", false),
+                declared(Ecosystem::Npm, "jest", true),
+                declared(Ecosystem::Npm, "@types/express", true),
+            ],
+        );
+
+        let express = report
+            .dependencies
+            .iter()
+            .find(|d| d.name == "express")
+            .unwrap();
+        assert_eq!(express.status, DependencyStatus::Used);
+        assert_eq!(express.modules, vec!["api", "server"]);
+        assert_eq!(express.file_count, 2);
+
+        let unused: Vec<&str> = report.unused().iter().map(|d| d.name.as_str()).collect();
+        assert_eq!(unused, vec!["left-pad"]);
+
+        let undeclared: Vec<&str> = report
+            .with_status(DependencyStatus::Undeclared)
+            .iter()
+            .map(|d| d.name.as_str())
+            .collect();
+        assert_eq!(undeclared, vec!["axios"]);
+
+        let text = format_report(&report);
+        assert!(text.contains("Declared but unused (1):"));
+        assert!(text.contains("[npm] axios — imported by: api"));
+        assert!(text.contains("(2 dev/indirect dependencies"));
+    }
+
+    #[test]
+    fn test_declares_python_and_cargo_names() {
+        let dep = |eco, name: &str| DeclaredDependency {
+            ecosystem: eco,
+            name: name.to_string(),
+            version: String::new(),
+            locked: None,
+            dev: false,
+            manifest: String::new(),
+        };
+        assert!(declares(&dep(Ecosystem::PyPI, "PyYAML"), "yaml"));
+        assert!(declares(&dep(Ecosystem::PyPI, "Flask-Cors"), "flask_cors"));
+        assert!(!declares(&dep(Ecosystem::PyPI, "requests"), "httpx"));
+        assert!(declares(&dep(Ecosystem::Cargo, "serde-json"), "serde_json"));
+    }
+
+    #[test]
+    fn test_maven_declares() {
+        assert!(maven_declares(
+            "com.google.guava:guava",
+            "com.google.guava.collect"
+        ));
+        assert!(maven_declares(
+            "com.fasterxml.jackson.core:jackson-databind",
+            "com.fasterxml.jackson.databind"
+        ));
+        assert!(!maven_declares(
+            "org.slf4j:slf4j-api",
+            "com.fasterxml.jackson.databind"
+        ));
+    }
+}
@@ -1,6 +1,6 @@
-use crate::graph::{CodeGraph, FileEntry, ModuleEntry};
-use crate::path_utils::{posix_dirname, posix_normalize, strip_extension};
-use std::collections::{HashMap, HashSet};
+use crate::graph::{BrokenImport, CodeGraph, FileEntry, ModuleEntry};
+use crate::path_utils::{is_path_import, posix_dirname, posix_normalize, strip_extension};
+use std::collections::{BTreeMap, HashMap, HashSet};
 
 // ── 变更检测结果 ──────────────────────────────────────────────────────────────
 
@@ -116,12 +116,22 @@ pub fn merge_graph_update(
         graph.files.insert(file_path, file_data);
     }
 
-    // Step 3: 清理空模块
+    // Step 3: 清理空模块，模块内文件按路径排序（与 scan 的遍历顺序一致）
     graph.modules.retain(|_, m| !m.files.is_empty());
+    for module in graph.modules.values_mut() {
+        module.files.sort();
+    }
 
-    // Step 4: 重新计算 summary 和依赖
+    // Step 4: 重新计算 summary、依赖（含跨语言绑定边）与包成员
     recalculate_summary(graph);
     rebuild_dependencies(graph);
+    crate::bindings::link_bindings(graph);
+    crate::packages::refresh_packages(graph);
+}
+
+/// 断链的统一顺序：按 (文件, 行号)，同一行保持 import 顺序（scan 与 update 共用，输出一致）
+pub fn sort_broken_imports(broken_imports: &mut [BrokenImport]) {
+    broken_imports.sort_by(|a, b| (&a.file, a.line).cmp(&(&b.file, b.line)));
 }
 
 // ── 内部函数 ──────────────────────────────────────────────────────────────────
@@ -131,7 +141,7 @@ fn recalculate_summary(graph: &mut CodeGraph) {
     let mut total_files = 0u32;
     let mut total_functions = 0u32;
     let mut total_classes = 0u32;
-    let mut languages: HashMap<String, u32> = HashMap::new();
+    let mut languages: BTreeMap<String, u32> = BTreeMap::new();
 
     for file_data in graph.files.values() {
         total_files += 1;
@@ -163,7 +173,7 @@ fn recalculate_summary(graph: &mut CodeGraph) {
     graph.config.languages = lang_list;
 }
 
-/// 从文件级 import 数据重建模块级 dependsOn / dependedBy
+/// 从文件级 import 数据重建模块级 dependsOn / dependedBy，并记录无法解析的相对 import
 ///
 /// 注意：当前仅解析以 `.` 开头的相对路径导入（JS/TS），
 /// 非 JS/TS 语言的 import 被标记为 external 而跳过。
@@ -188,6 +198,7 @@ fn rebuild_dependencies(graph: &mut CodeGraph) {
         depended_by.insert(mod_name.clone(), HashSet::new());
     }
 
+    let mut broken_imports: Vec<BrokenImport> = Vec::new();
     for (rel_path, file_data) in &graph.files {
         let module_name = &file_data.module;
         let norm_path = rel_path.replace('\\', "/");
@@ -206,18 +217,30 @@ fn rebuild_dependencies(graph: &mut CodeGraph) {
                 .or_else(|| path_lookup.get(&format!("{}/index", resolved)))
                 .cloned();
 
-            if let Some(target_mod) = target {
-                if &target_mod != module_name {
-                    if let Some(set) = depends_on.get_mut(module_name) {
-                        set.insert(target_mod.clone());
-                    }
-                    if let Some(set) = depended_by.get_mut(&target_mod) {
-                        set.insert(module_name.clone());
+            match target {
+                Some(target_mod) => {
+                    if &target_mod != module_name {
+                        if let Some(set) = depends_on.get_mut(module_name) {
+                            set.insert(target_mod.clone());
+                        }
+                        if let Some(set) = depended_by.get_mut(&target_mod) {
+                            set.insert(module_name.clone());
+                        }
                     }
                 }
+                None if is_path_import(&file_data.language, &imp.source) => {
+                    broken_imports.push(BrokenImport {
+                        file: rel_path.clone(),
+                        line: imp.import_line,
+                        source: imp.source.clone(),
+                    });
+                }
+                None => {}
             }
         }
     }
+    sort_broken_imports(&mut broken_imports);
+    graph.broken_imports = broken_imports;
 
     // 写回图谱
     for (mod_name, module) in &mut graph.modules {
@@ -250,14 +273,7 @@ mod tests {
             module: module.to_string(),
             hash: "sha256:aabbccdd11223344".to_string(),
             lines: 10,
-            functions: vec![],
-            classes: vec![],
-            types: vec![],
-            variables: vec![],
-            imports: vec![],
-            exports: vec![],
-            is_entry_point: false,
-            symbol_refs: std::collections::BTreeMap::new(),
+            ..Default::default()
         }
     }
 
@@ -440,4 +456,60 @@ mod tests {
         assert_eq!(graph.modules["auth"].depends_on, vec!["utils"]);
         assert_eq!(graph.modules["utils"].depended_by, vec!["auth"]);
     }
+
+    #[test]
+    fn test_rebuild_dependencies_records_broken_imports() {
+        use crate::graph::ImportInfo;
+
+        let import = |source: &str, line: u32| ImportInfo {
+            source: source.to_string(),
+            symbols: vec![],
+            is_external: false,
+            import_line: line,
+        };
+        let mut graph = create_empty_graph("test", "/tmp/test");
+        let mut files = HashMap::new();
+        let mut api_file = make_file_entry("api");
+        // import 顺序与行号不一致时，断链仍按行号排列（与 scan 相同）；资源文件不算断链
+        api_file.imports = vec![
+            import("../auth/logn", 3),
+            import("../auth/login", 4),
+            import("./styles.css", 5),
+            import("./helpers", 1),
+        ];
+        files.insert("src/api/routes.ts".to_string(), api_file);
+        files.insert("src/auth/login.ts".to_string(), make_file_entry("auth"));
+        // Python 包内相对导入不按路径解析，不算断链
+        let mut py_file = make_file_entry("tools");
+        py_file.language = "python".to_string();
+        py_file.imports = vec![import(".models", 1)];
+        files.insert("tools/cli.py".to_string(), py_file);
+
+        merge_graph_update(&mut graph, files, &[]);
+
+        assert_eq!(
+            graph.broken_imports,
+            vec![
+                BrokenImport {
+                    file: "src/api/routes.ts".to_string(),
+                    line: 1,
+                    source: "./helpers".to_string(),
+                },
+                BrokenImport {
+                    file: "src/api/routes.ts".to_string(),
+                    line: 3,
+                    source: "../auth/logn".to_string(),
+                },
+            ]
+        );
+        assert_eq!(graph.modules["api"].depends_on, vec!["auth"]);
+
+        // 修复后断链消失
+        let mut fixed = graph.files["src/api/routes.ts"].clone();
+        fixed.imports.retain(|i| i.source == "../auth/login");
+        let mut updated = HashMap::new();
+        updated.insert("src/api/routes.ts".to_string(), fixed);
+        merge_graph_update(&mut graph, updated, &[]);
+        assert!(graph.broken_imports.is_empty());
+    }
 }
@@ -0,0 +1,330 @@
+/// 公开接口的文档覆盖率（doc-coverage）
+///
+/// 扫描时按注释区域判断函数、类与类型是否带文档注释（字符串中形似注释的文本不计）：
+/// - Rust：声明前的 `///`、`/** */` 或 `#[doc = ...]`
+/// - TypeScript / JavaScript / Java：声明前的 `/** */`（JSDoc / Javadoc）
+/// - Go：紧贴声明的 `//` 注释
+/// - C / C++：紧贴声明的任意注释（`///`、`//`、`/* */`）
+/// - Python：函数体或类体的第一条语句为 docstring
+///
+/// 声明与注释之间允许夹着属性、注解与装饰器。报告阶段以 `exports` 作为公开接口，
+/// 按模块统计覆盖率，并按引用文件数（fan-in）列出缺少文档的公开符号。
+use serde::Serialize;
+use std::collections::{BTreeMap, BTreeSet};
+
+use crate::graph::{CodeGraph, FileEntry};
+use crate::query::find_callers;
+use crate::source_text::{SourceLine, SourceText};
+use crate::traverser::Language;
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 缺少文档的公开符号
+#[derive(Debug, Clone, Serialize)]
+pub struct UndocumentedSymbol {
+    pub name: String,
+    pub kind: String,
+    pub file: String,
+    pub module: String,
+    pub line: u32,
+    /// 引用该符号的其他文件数
+    #[serde(rename = "fanIn")]
+    pub fan_in: usize,
+}
+
+/// 单个模块的覆盖率
+#[derive(Debug, Clone, Serialize)]
+pub struct ModuleCoverage {
+    pub module: String,
+    pub public: usize,
+    pub documented: usize,
+    pub percent: f64,
+}
+
+/// 覆盖率报告
+#[derive(Debug, Clone, Serialize)]
+pub struct CoverageReport {
+    pub public: usize,
+    pub documented: usize,
+    pub percent: f64,
+    pub modules: Vec<ModuleCoverage>,
+    /// 按 fan-in 降序排列
+    pub undocumented: Vec<UndocumentedSymbol>,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 返回带文档注释的函数、类与类型名（排序去重）
+pub fn extract_documented(lang: Language, source: &SourceText, entry: &FileEntry) -> Vec<String> {
+    let lines = source.lines();
+    let declarations = entry
+        .functions
+        .iter()
+        .map(|f| (f.name.as_str(), f.start_line))
+        .chain(
+            entry
+                .classes
+                .iter()
+                .map(|c| (c.name.as_str(), c.start_line)),
+        )
+        .chain(entry.types.iter().map(|t| (t.name.as_str(), t.start_line)));
+
+    let mut documented = BTreeSet::new();
+    for (name, start_line) in declarations {
+        let idx = start_line.saturating_sub(1) as usize;
+        if idx >= lines.len() {
+            continue;
+        }
+        let has_doc = if lang == Language::Python {
+            has_docstring(&lines, idx)
+        } else {
+            has_leading_doc(lang, &lines, idx)
+        };
+        if has_doc {
+            documented.insert(name.to_string());
+        }
+    }
+    documented.into_iter().collect()
+}
+
+/// 统计公开函数、类与类型的文档覆盖率；module 为 None 表示全部模块
+pub fn doc_coverage(graph: &CodeGraph, module: Option<&str>) -> CoverageReport {
+    let mut per_module: BTreeMap<&str, (usize, usize)> = BTreeMap::new();
+    let mut undocumented = Vec::new();
+    for (path, entry) in &graph.files {
+        if module.is_some_and(|m| m != entry.module) {
+            continue;
+        }
+        let counts = per_module.entry(entry.module.as_str()).or_default();
+        for (name, kind, line) in public_symbols(entry) {
+            counts.0 += 1;
+            if entry.documented.iter().any(|d| d == name) {
+                counts.1 += 1;
+                continue;
+            }
+            let (callers, _) = find_callers(graph, path, name);
+            undocumented.push(UndocumentedSymbol {
+                name: name.to_string(),
+                kind: kind.to_string(),
+                file: path.clone(),
+                module: entry.module.clone(),
+                line,
+                fan_in: callers.len(),
+            });
+        }
+    }
+    undocumented.sort_by(|a, b| {
+        b.fan_in
+            .cmp(&a.fan_in)
+            .then(a.file.cmp(&b.file))
+            .then(a.line.cmp(&b.line))
+    });
+
+    let modules: Vec<ModuleCoverage> = per_module
+        .into_iter()
+        .filter(|(_, (public, _))| *public > 0)
+        .map(|(module, (public, documented))| ModuleCoverage {
+            module: module.to_string(),
+            public,
+            documented,
+            percent: percent(documented, public),
+        })
+        .collect();
+    let public = modules.iter().map(|m| m.public).sum();
+    let documented = modules.iter().map(|m| m.documented).sum();
+    CoverageReport {
+        public,
+        documented,
+        percent: percent(documented, public),
+        modules,
+        undocumented,
+    }
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+/// 文件中导出的函数、类与类型：(名称, 类型, 起始行)，同名只取第一个
+fn public_symbols(entry: &FileEntry) -> Vec<(&str, &'static str, u32)> {
+    let mut seen = BTreeSet::new();
+    entry
+        .functions
+        .iter()
+        .map(|f| (f.name.as_str(), "function", f.start_line))
+        .chain(
+            entry
+                .classes
+                .iter()
+                .map(|c| (c.name.as_str(), "class", c.start_line)),
+        )
+        .chain(
+            entry
+                .types
+                .iter()
+                .map(|t| (t.name.as_str(), "type", t.start_line)),
+        )
+        .filter(|(name, _, _)| entry.exports.iter().any(|e| e == name) && seen.insert(*name))
+        .collect()
+}
+
+fn percent(documented: usize, total: usize) -> f64 {
+    if total == 0 {
+        return 100.0;
+    }
+    (documented as f64 * 1000.0 / total as f64).round() / 10.0
+}
+
+/// 声明前（跳过属性、注解与装饰器）是否紧贴文档注释
+fn has_leading_doc(lang: Language, lines: &[SourceLine], decl: usize) -> bool {
+    let mut i = decl;
+    while i > 0 {
+        let line = lines[i - 1].code.trim();
+        if line.starts_with("#[doc") {
+            return lang == Language::Rust;
+        }
+        if !(line.starts_with("#[") || line.starts_with('@') || line.starts_with("[[")) {
+            break;
+        }
+        i -= 1;
+    }
+    // 上一行须是纯注释行
+    if i == 0 || lines[i - 1].has_code() {
+        return false;
+    }
+    let Some(above) = lines[i - 1].comments().last().map(str::trim) else {
+        return false;
+    };
+    if above.ends_with("*/") {
+        // 向上找块注释的起始行
+        let Some(opener) = (0..i).rev().find_map(|j| {
+            lines[j]
+                .comments()
+                .map(str::trim_start)
+                .find(|c| c.starts_with("/*"))
+        }) else {
+            return false;
+        };
+        return match lang {
+            Language::C | Language::Cpp | Language::Go => true,
+            _ => opener.starts_with("/**"),
+        };
+    }
+    match lang {
+        Language::Rust => above.starts_with("///") && !above.starts_with("////"),
+        Language::Go | Language::C | Language::Cpp => above.starts_with("//"),
+        _ => false,
+    }
+}
+
+/// Python：声明头（可能跨行）之后的第一条语句是否为字符串
+fn has_docstring(lines: &[SourceLine], decl: usize) -> bool {
+    let Some(header_end) =
+        (decl..lines.len().min(decl + 20)).find(|&i| lines[i].code.trim_end().ends_with(':'))
+    else {
+        return false;
+    };
+    // 代码视图中字符串保留前缀与引号
+    let Some(first) = lines[header_end + 1..]
+        .iter()
+        .map(|l| l.code.trim())
+        .find(|l| !l.is_empty())
+    else {
+        return false;
+    };
+    let body = first.trim_start_matches(['r', 'R', 'u', 'U', 'b', 'B']);
+    body.starts_with("\"\"\"")
+        || body.starts_with("'''")
+        || body.starts_with('"')
+        || body.starts_with('\'')
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::fixtures::file_with_symbols as entry;
+    use crate::graph::{ClassInfo, FunctionInfo, SymbolRef};
+
+    fn func(name: &str, start: u32) -> FunctionInfo {
+        FunctionInfo {
+            name: name.into(),
+            signature: String::new(),
+            start_line: start,
+            end_line: start + 1,
+        }
+    }
+
+    fn documented(lang: Language, src: &str, e: &FileEntry) -> Vec<String> {
+        extract_documented(lang, &SourceText::scan(lang, src.as_bytes()), e)
+    }
+
+    #[test]
+    fn test_extract_documented_per_language() {
+        let rs = "/// Parses input.\n#[inline]\npub fn parse() {}\n\n// plain comment\npub fn raw() {}\n";
+        let e = entry("core", vec![func("parse", 3), func("raw", 6)], vec![]);
+        assert_eq!(documented(Language::Rust, rs, &e), vec!["parse"]);
+
+        let ts = "/**\n * Logs in.\n */\nexport function login() {}\n/* not jsdoc */\nexport function logout() {}\n";
+        let e = entry("auth", vec![func("login", 4), func("logout", 6)], vec![]);
+        assert_eq!(documented(Language::TypeScript, ts, &e), vec!["login"]);
+
+        let go = "// Serve starts the server.\nfunc Serve() {}\n\nfunc stop() {}\n";
+        let e = entry("srv", vec![func("Serve", 2), func("stop", 4)], vec![]);
+        assert_eq!(documented(Language::Go, go, &e), vec!["Serve"]);
+
+        let py = "@cache\ndef load(path,\n         mode):\n    \"\"\"Load a file.\"\"\"\n    return 1\n\nclass Store:\n    x = 1\n";
+        let e = entry(
+            "io",
+            vec![func("load", 2)],
+            vec![ClassInfo {
+                name: "Store".into(),
+                start_line: 7,
+                end_line: 8,
+            }],
+        );
+        assert_eq!(documented(Language::Python, py, &e), vec!["load"]);
+    }
+
+    #[test]
+    fn test_comment_markers_inside_strings_are_not_docs() {
+        let rs = "const USAGE: &str = \"usage:\n/// not a doc comment\";\npub fn run() {}\n";
+        let e = entry("cli", vec![func("run", 3)], vec![]);
+        assert!(documented(Language::Rust, rs, &e).is_empty());
+    }
+
+    #[test]
+    fn test_coverage_report_sorted_by_fan_in() {
+        let mut graph = crate::graph::create_empty_graph("p", "/tmp/p");
+        let mut core = entry(
+            "core",
+            vec![func("parse", 1), func("render", 5), func("helper", 9)],
+            vec![],
+        );
+        core.exports = vec!["parse".into(), "render".into()];
+        core.documented = vec!["parse".into(), "helper".into()];
+        graph.files.insert("src/core.rs".into(), core);
+        let mut app = entry("app", vec![func("main", 1)], vec![]);
+        app.symbol_refs.insert(
+            "render".into(),
+            SymbolRef {
+                symbol: "render".into(),
+                import_line: 1,
+                use_lines: vec![3],
+            },
+        );
+        graph.files.insert("src/app.rs".into(), app);
+
+        let report = doc_coverage(&graph, None);
+        assert_eq!((report.public, report.documented), (2, 1));
+        assert_eq!(report.percent, 50.0);
+        // app 没有公开符号，不计入模块列表
+        assert_eq!(report.modules.len(), 1);
+        assert_eq!(report.modules[0].module, "core");
+        assert_eq!(report.undocumented.len(), 1);
+        assert_eq!(report.undocumented[0].name, "render");
+        assert_eq!(report.undocumented[0].fan_in, 1);
+
+        let empty = doc_coverage(&graph, Some("app"));
+        assert_eq!(empty.percent, 100.0);
+    }
+}
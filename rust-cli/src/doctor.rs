@@ -0,0 +1,683 @@
+/// .codemap/ 完整性检查（doctor）
+///
+/// 校验 graph.json 与 meta.json 是否一致、模块表与文件表是否互相引用、依赖边是否悬空、
+/// slices/ 中是否有孤儿切片、schema 版本是否受支持，并抽样比对文件哈希与磁盘内容。
+/// 发现的问题可通过 [`repair`] 定点重扫修复；图谱无法读取或版本不受支持时退回全量扫描。
+use serde::Serialize;
+use std::collections::{BTreeSet, HashMap, HashSet};
+use std::path::{Path, PathBuf};
+
+use crate::differ::merge_graph_update;
+use crate::graph::{load_graph, load_meta, save_graph, CodeGraph, MetaInfo, ModuleEntry};
+use crate::merge::{disk_hash, rescan_from_disk};
+use crate::slicer::save_slices;
+
+/// 当前版本可读取的 graph.json schema 版本
+pub const SUPPORTED_VERSIONS: &[&str] = &["1.0"];
+
+/// 默认抽样比对哈希的文件数
+pub const DEFAULT_SAMPLE_SIZE: usize = 50;
+
+/// 插件包装脚本下载二进制的 GitHub 仓库
+const RELEASE_REPO: &str = "killvxk/CodeMap";
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 问题类别
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum IssueKind {
+    /// graph.json 缺失或无法解析
+    UnreadableGraph,
+    /// schema 版本不受支持
+    UnsupportedVersion,
+    /// meta.json 缺失，或其文件哈希 / commit 与 graph.json 不一致
+    MetaMismatch,
+    /// 模块引用的文件不在 files 中
+    ModuleFileMissing,
+    /// 文件的 module 字段与模块表不一致
+    ModuleMismatch,
+    /// dependsOn / dependedBy 指向不存在的模块
+    DanglingDependency,
+    /// summary 统计与实际条目数不一致
+    SummaryMismatch,
+    /// slices/ 中没有对应模块的切片
+    OrphanSlice,
+    /// 模块缺少切片
+    MissingSlice,
+    /// 图谱中的文件已不在磁盘上
+    FileMissing,
+    /// 文件哈希与磁盘内容不一致
+    HashMismatch,
+}
+
+/// 一个完整性问题
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct Issue {
+    pub kind: IssueKind,
+    pub message: String,
+    /// 涉及的源文件（定点重扫的对象）
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub file: Option<String>,
+}
+
+/// 检查结果
+#[derive(Debug, Clone, Serialize)]
+pub struct DoctorReport {
+    /// graph.json 的 schema 版本（无法读取时为空）
+    pub version: Option<String>,
+    #[serde(rename = "graphFiles")]
+    pub graph_files: usize,
+    #[serde(rename = "metaFiles")]
+    pub meta_files: usize,
+    /// 抽样比对哈希的文件数
+    #[serde(rename = "checkedHashes")]
+    pub checked_hashes: usize,
+    pub issues: Vec<Issue>,
+}
+
+/// 修复结果
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct RepairOutcome {
+    /// 是否退回了全量扫描
+    #[serde(rename = "fullRescan")]
+    pub full_rescan: bool,
+    /// 重新解析的文件
+    pub rescanned: Vec<String>,
+    /// 已从图谱中移除的文件
+    pub removed: Vec<String>,
+    /// 删除的孤儿切片
+    #[serde(rename = "removedSlices")]
+    pub removed_slices: Vec<String>,
+}
+
+/// 插件包装脚本（ccplugin/bin/codegraph）查找二进制的一个候选位置
+#[derive(Debug, Clone, Serialize)]
+pub struct BinaryCandidate {
+    /// 查找步骤说明
+    pub source: String,
+    /// 实际检查的路径（无法确定时为空，如未设置 CLAUDE_PLUGIN_ROOT）
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub path: Option<String>,
+    pub found: bool,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 检查 <root>/.codemap/ 的完整性
+///
+/// 哈希比对只抽样 `sample_size` 个文件（按路径均匀分布，结果可复现）；为 0 时跳过。
+pub fn diagnose(root_dir: &Path, sample_size: usize) -> DoctorReport {
+    let output_dir = root_dir.join(".codemap");
+    let graph = match load_graph(&output_dir) {
+        Ok(g) => g,
+        Err(e) => {
+            return DoctorReport {
+                version: None,
+                graph_files: 0,
+                meta_files: 0,
+                checked_hashes: 0,
+                issues: vec![issue(
+                    IssueKind::UnreadableGraph,
+                    format!("cannot read graph.json: {}", e),
+                    None,
+                )],
+            };
+        }
+    };
+    let meta = load_meta(&output_dir).ok();
+
+    let mut issues = check_graph(&graph, meta.as_ref());
+    issues.extend(check_slices(&output_dir, &graph));
+    let (hash_issues, checked_hashes) = check_hashes(root_dir, &graph, sample_size);
+    issues.extend(hash_issues);
+    issues.sort_by(|a, b| (a.kind, &a.file, &a.message).cmp(&(b.kind, &b.file, &b.message)));
+
+    DoctorReport {
+        version: Some(graph.version.clone()),
+        graph_files: graph.files.len(),
+        meta_files: meta.map(|m| m.file_hashes.len()).unwrap_or(0),
+        checked_hashes,
+        issues,
+    }
+}
+
+/// 检查图谱自身及其与 meta 的一致性（不访问磁盘）
+pub fn check_graph(graph: &CodeGraph, meta: Option<&MetaInfo>) -> Vec<Issue> {
+    let mut issues = Vec::new();
+
+    if !SUPPORTED_VERSIONS.contains(&graph.version.as_str()) {
+        issues.push(issue(
+            IssueKind::UnsupportedVersion,
+            format!(
+                "schema version '{}' is not supported (expected {})",
+                graph.version,
+                SUPPORTED_VERSIONS.join(", ")
+            ),
+            None,
+        ));
+    }
+
+    // graph ↔ meta
+    match meta {
+        None => issues.push(issue(
+            IssueKind::MetaMismatch,
+            "meta.json is missing or unreadable".to_string(),
+            None,
+        )),
+        Some(meta) => {
+            if meta.commit_hash != graph.commit_hash {
+                issues.push(issue(
+                    IssueKind::MetaMismatch,
+                    format!(
+                        "commit differs: graph {} vs meta {}",
+                        graph.commit_hash.as_deref().unwrap_or("(none)"),
+                        meta.commit_hash.as_deref().unwrap_or("(none)")
+                    ),
+                    None,
+                ));
+            }
+            for (path, file) in &graph.files {
+                match meta.file_hashes.get(path) {
+                    None => issues.push(issue(
+                        IssueKind::MetaMismatch,
+                        "in graph.json but not in meta.json".to_string(),
+                        Some(path),
+                    )),
+                    Some(hash) if *hash != file.hash => issues.push(issue(
+                        IssueKind::MetaMismatch,
+                        "hash in meta.json differs from graph.json".to_string(),
+                        Some(path),
+                    )),
+                    Some(_) => {}
+                }
+            }
+            for path in meta.file_hashes.keys() {
+                if !graph.files.contains_key(path) {
+                    issues.push(issue(
+                        IssueKind::MetaMismatch,
+                        "in meta.json but not in graph.json".to_string(),
+                        Some(path),
+                    ));
+                }
+            }
+        }
+    }
+
+    // 模块表 ↔ 文件表
+    for (name, module) in &graph.modules {
+        for path in &module.files {
+            if !graph.files.contains_key(path) {
+                issues.push(issue(
+                    IssueKind::ModuleFileMissing,
+                    format!("listed in module '{}' but missing from files", name),
+                    Some(path),
+                ));
+            }
+        }
+        for (field, targets) in [
+            ("dependsOn", &module.depends_on),
+            ("dependedBy", &module.depended_by),
+        ] {
+            for target in targets {
+                if !graph.modules.contains_key(target) {
+                    issues.push(issue(
+                        IssueKind::DanglingDependency,
+                        format!("module '{}' {} unknown module '{}'", name, field, target),
+                        None,
+                    ));
+                }
+            }
+        }
+    }
+    for (path, file) in &graph.files {
+        let listed = graph
+            .modules
+            .get(&file.module)
+            .is_some_and(|m| m.files.contains(path));
+        if !listed {
+            issues.push(issue(
+                IssueKind::ModuleMismatch,
+                format!("not listed in its module '{}'", file.module),
+                Some(path),
+            ));
+        }
+    }
+
+    if graph.summary.total_files as usize != graph.files.len() {
+        issues.push(issue(
+            IssueKind::SummaryMismatch,
+            format!(
+                "summary.totalFiles is {} but files has {} entries",
+                graph.summary.total_files,
+                graph.files.len()
+            ),
+            None,
+        ));
+    }
+
+    issues
+}
+
+/// 检查 slices/ 与模块表是否对应（未生成 slices/ 时跳过）
+pub fn check_slices(output_dir: &Path, graph: &CodeGraph) -> Vec<Issue> {
+    let slices_dir = output_dir.join("slices");
+    if !slices_dir.is_dir() {
+        return vec![];
+    }
+    let mut issues: Vec<Issue> = orphan_slices(&slices_dir, graph)
+        .into_iter()
+        .map(|name| {
+            issue(
+                IssueKind::OrphanSlice,
+                format!("slices/{} has no matching module", name),
+                None,
+            )
+        })
+        .collect();
+    for module in graph.modules.keys() {
+        let file = slice_file_name(module);
+        if !slices_dir.join(&file).is_file() {
+            issues.push(issue(
+                IssueKind::MissingSlice,
+                format!("module '{}' has no slices/{}", module, file),
+                None,
+            ));
+        }
+    }
+    issues
+}
+
+/// 抽样比对文件哈希与磁盘内容，返回 (问题, 实际比对的文件数)
+pub fn check_hashes(root_dir: &Path, graph: &CodeGraph, sample_size: usize) -> (Vec<Issue>, usize) {
+    let mut issues = Vec::new();
+    let sample = sample_paths(graph, sample_size);
+    for path in &sample {
+        let file = &graph.files[*path];
+        if !root_dir.join(path).is_file() {
+            issues.push(issue(
+                IssueKind::FileMissing,
+                "no longer exists on disk".to_string(),
+                Some(path),
+            ));
+            continue;
+        }
+        if disk_hash(root_dir, path).as_deref() != Some(file.hash.as_str()) {
+            issues.push(issue(
+                IssueKind::HashMismatch,
+                "content changed since the last scan".to_string(),
+                Some(path),
+            ));
+        }
+    }
+    (issues, sample.len())
+}
+
+/// 修复检查出的问题并写回 .codemap/
+///
+/// 涉及具体文件的问题逐个从磁盘重新解析（文件已删除则从图谱移除），
+/// 模块成员、依赖边、summary、meta.json 与 slices/ 随之重建，孤儿切片被删除。
+/// graph.json 无法读取或版本不受支持时执行全量扫描。
+pub fn repair(root_dir: &Path, report: &DoctorReport) -> anyhow::Result<RepairOutcome> {
+    let output_dir = root_dir.join(".codemap");
+    let mut outcome = RepairOutcome::default();
+    if report.issues.is_empty() {
+        return Ok(outcome);
+    }
+
+    let needs_full_scan = report.issues.iter().any(|i| {
+        matches!(
+            i.kind,
+            IssueKind::UnreadableGraph | IssueKind::UnsupportedVersion
+        )
+    });
+    if needs_full_scan {
+        crate::scanner::ScanOptions::new().scan_and_save(root_dir)?;
+        outcome.full_rescan = true;
+        return Ok(outcome);
+    }
+
+    let mut graph = load_graph(&output_dir)?;
+
+    // 模块成员以 files 表为准重建，清除指向不存在文件的引用
+    for module in graph.modules.values_mut() {
+        module.files.clear();
+    }
+    for (path, file) in &graph.files {
+        graph
+            .modules
+            .entry(file.module.clone())
+            .or_insert_with(|| ModuleEntry {
+                files: vec![],
+                depends_on: vec![],
+                depended_by: vec![],
+            })
+            .files
+            .push(path.clone());
+    }
+
+    // 定点重扫涉及的文件
+    let targets: BTreeSet<&String> = report
+        .issues
+        .iter()
+        .filter_map(|i| i.file.as_ref())
+        .collect();
+    let mut updated = HashMap::new();
+    for path in targets {
+        match rescan_from_disk(root_dir, path, &graph) {
+            Some(entry) => {
+                updated.insert(path.clone(), entry);
+                outcome.rescanned.push(path.clone());
+            }
+            None => {
+                if graph.files.contains_key(path) {
+                    outcome.removed.push(path.clone());
+                }
+            }
+        }
+    }
+    merge_graph_update(&mut graph, updated, &outcome.removed);
+    save_graph(&output_dir, &graph)?;
+
+    // 重新生成切片，并删除不再对应任何模块的旧切片（含本次修复移除的模块）
+    let slices_dir = output_dir.join("slices");
+    if slices_dir.is_dir() {
+        save_slices(&output_dir, &graph)?;
+        for name in orphan_slices(&slices_dir, &graph) {
+            std::fs::remove_file(slices_dir.join(&name))?;
+            outcome.removed_slices.push(name);
+        }
+    }
+    Ok(outcome)
+}
+
+/// 按 ccplugin/bin/codegraph 的查找顺序列出候选位置
+///
+/// 依次为：PATH 中的平台二进制、`$CODEMAP_HOME/bin/`（默认 `~/.codemap/bin/`）、
+/// 插件目录、开发构建（rust-cli/target/），都找不到时包装脚本从 GitHub Releases 自动下载。
+pub fn binary_lookup(cwd: &Path) -> Vec<BinaryCandidate> {
+    let Some(bin_name) = platform_binary_name(std::env::consts::OS, std::env::consts::ARCH) else {
+        return vec![BinaryCandidate {
+            source: format!(
+                "unsupported platform {}-{}",
+                std::env::consts::ARCH,
+                std::env::consts::OS
+            ),
+            path: None,
+            found: false,
+        }];
+    };
+    let mut candidates = Vec::new();
+    let mut push = |source: &str, path: Option<PathBuf>| {
+        let found = path.as_ref().is_some_and(|p| p.is_file());
+        candidates.push(BinaryCandidate {
+            source: source.to_string(),
+            path: path.map(|p| p.display().to_string()),
+            found,
+        });
+    };
+
+    let on_path = std::env::var_os("PATH").and_then(|paths| {
+        std::env::split_paths(&paths)
+            .map(|dir| dir.join(&bin_name))
+            .find(|p| p.is_file())
+    });
+    push(
+        "1. PATH",
+        on_path.or_else(|| Some(PathBuf::from(&bin_name))),
+    );
+
+    let codemap_home = crate::registry::codemap_home();
+    push(
+        "2. $CODEMAP_HOME/bin",
+        codemap_home.map(|h| h.join("bin").join(&bin_name)),
+    );
+
+    let plugin_root = std::env::var_os("CLAUDE_PLUGIN_ROOT").map(PathBuf::from);
+    push(
+        "3. plugin bin/ ($CLAUDE_PLUGIN_ROOT)",
+        plugin_root.as_ref().map(|r| r.join("bin").join(&bin_name)),
+    );
+
+    let dev_name = if std::env::consts::OS == "windows" {
+        "codegraph.exe"
+    } else {
+        "codegraph"
+    };
+    let mut dev_dirs: Vec<PathBuf> = Vec::new();
+    if let Some(root) = &plugin_root {
+        dev_dirs.push(root.join("..").join("rust-cli").join("target"));
+    }
+    dev_dirs.push(cwd.join("rust-cli").join("target"));
+    for dir in dev_dirs {
+        for profile in ["release", "debug"] {
+            push("4. dev build", Some(dir.join(profile).join(dev_name)));
+        }
+    }
+
+    candidates.push(BinaryCandidate {
+        source: "5. auto-download".to_string(),
+        path: Some(format!(
+            "https://github.com/{}/releases/latest/download/{}",
+            RELEASE_REPO, bin_name
+        )),
+        found: false,
+    });
+    candidates
+}
+
+/// 平台二进制文件名（与包装脚本一致：codegraph-<arch>-<os>[.exe]）
+pub fn platform_binary_name(os: &str, arch: &str) -> Option<String> {
+    let os = match os {
+        "linux" => "linux",
+        "macos" => "macos",
+        "windows" => "windows",
+        _ => return None,
+    };
+    let arch = match arch {
+        "x86_64" => "x86_64",
+        "aarch64" => "aarch64",
+        _ => return None,
+    };
+    let ext = if os == "windows" { ".exe" } else { "" };
+    Some(format!("codegraph-{}-{}{}", arch, os, ext))
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+fn issue(kind: IssueKind, message: String, file: Option<&String>) -> Issue {
+    Issue {
+        kind,
+        message,
+        file: file.cloned(),
+    }
+}
+
+/// 切片文件名（与 save_slices 的净化规则一致）
+fn slice_file_name(module: &str) -> String {
+    format!("{}.json", module.replace(['/', '\\', '.'], "_"))
+}
+
+/// slices/ 中不对应任何模块的切片文件名（已排序）
+fn orphan_slices(slices_dir: &Path, graph: &CodeGraph) -> Vec<String> {
+    let expected: HashSet<String> = graph.modules.keys().map(|m| slice_file_name(m)).collect();
+    let mut orphans: Vec<String> = std::fs::read_dir(slices_dir)
+        .into_iter()
+        .flatten()
+        .flatten()
+        .map(|entry| entry.file_name().to_string_lossy().to_string())
+        .filter(|name| {
+            name.ends_with(".json") && name != "_overview.json" && !expected.contains(name)
+        })
+        .collect();
+    orphans.sort();
+    orphans
+}
+
+/// 按路径均匀抽样（同一图谱总是得到同一组文件）
+fn sample_paths(graph: &CodeGraph, sample_size: usize) -> Vec<&String> {
+    if sample_size == 0 || graph.files.is_empty() {
+        return vec![];
+    }
+    let step = graph.files.len().div_ceil(sample_size).max(1);
+    graph.files.keys().step_by(step).collect()
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::{compute_file_hash, create_empty_graph, FileEntry};
+
+    fn file(module: &str, hash: &str) -> FileEntry {
+        FileEntry {
+            language: "typescript".to_string(),
+            module: module.to_string(),
+            hash: hash.to_string(),
+            lines: 1,
+            ..Default::default()
+        }
+    }
+
+    fn meta_for(graph: &CodeGraph) -> MetaInfo {
+        MetaInfo {
+            last_scan_at: graph.scanned_at.clone(),
+            commit_hash: graph.commit_hash.clone(),
+            scan_duration: 0,
+            file_hashes: graph
+                .files
+                .iter()
+                .map(|(p, f)| (p.clone(), f.hash.clone()))
+                .collect(),
+        }
+    }
+
+    fn sample_graph() -> CodeGraph {
+        let mut graph = create_empty_graph("demo", "/tmp/demo");
+        let mut files = HashMap::new();
+        files.insert("src/a/x.ts".to_string(), file("a", "sha256:1"));
+        files.insert("src/b/y.ts".to_string(), file("b", "sha256:2"));
+        merge_graph_update(&mut graph, files, &[]);
+        graph
+    }
+
+    #[test]
+    fn test_check_graph_clean() {
+        let graph = sample_graph();
+        assert!(check_graph(&graph, Some(&meta_for(&graph))).is_empty());
+    }
+
+    #[test]
+    fn test_check_graph_finds_inconsistencies() {
+        let mut graph = sample_graph();
+        let mut meta = meta_for(&graph);
+        meta.file_hashes.remove("src/b/y.ts");
+        meta.file_hashes
+            .insert("src/gone.ts".to_string(), "sha256:3".to_string());
+        graph.version = "9.0".to_string();
+        graph
+            .modules
+            .get_mut("a")
+            .unwrap()
+            .files
+            .push("src/a/ghost.ts".to_string());
+        graph
+            .modules
+            .get_mut("a")
+            .unwrap()
+            .depends_on
+            .push("nowhere".to_string());
+        graph.files.get_mut("src/b/y.ts").unwrap().module = "c".to_string();
+
+        let kinds: Vec<(IssueKind, Option<String>)> = check_graph(&graph, Some(&meta))
+            .into_iter()
+            .map(|i| (i.kind, i.file))
+            .collect();
+        let has = |kind: IssueKind, file: Option<&str>| {
+            kinds.contains(&(kind, file.map(|f| f.to_string())))
+        };
+        assert!(has(IssueKind::UnsupportedVersion, None));
+        assert!(has(IssueKind::MetaMismatch, Some("src/b/y.ts")));
+        assert!(has(IssueKind::MetaMismatch, Some("src/gone.ts")));
+        assert!(has(IssueKind::ModuleFileMissing, Some("src/a/ghost.ts")));
+        assert!(has(IssueKind::DanglingDependency, None));
+        assert!(has(IssueKind::ModuleMismatch, Some("src/b/y.ts")));
+        assert!(!has(IssueKind::SummaryMismatch, None));
+        assert!(check_graph(&graph, None)
+            .iter()
+            .any(|i| i.kind == IssueKind::MetaMismatch && i.file.is_none()));
+    }
+
+    #[test]
+    fn test_diagnose_and_repair_slices_and_hashes() {
+        let root = std::env::temp_dir().join(format!("codegraph_doctor_{}", std::process::id()));
+        let _ = std::fs::remove_dir_all(&root);
+        std::fs::create_dir_all(root.join("src/a")).unwrap();
+        std::fs::write(root.join("src/a/x.ts"), "export const x = 1;\n").unwrap();
+
+        let mut graph = create_empty_graph("demo", &root.to_string_lossy());
+        let mut files = HashMap::new();
+        let hash = compute_file_hash(b"export const x = 1;\n");
+        files.insert("src/a/x.ts".to_string(), file("a", &hash));
+        files.insert("src/b/deleted.ts".to_string(), file("b", "sha256:2"));
+        merge_graph_update(&mut graph, files, &[]);
+        let output_dir = root.join(".codemap");
+        save_graph(&output_dir, &graph).unwrap();
+        save_slices(&output_dir, &graph).unwrap();
+        std::fs::write(output_dir.join("slices/old_module.json"), "{}").unwrap();
+
+        let report = diagnose(&root, DEFAULT_SAMPLE_SIZE);
+        assert_eq!(report.version.as_deref(), Some("1.0"));
+        assert_eq!(report.checked_hashes, 2);
+        let kinds: Vec<IssueKind> = report.issues.iter().map(|i| i.kind).collect();
+        assert_eq!(kinds, vec![IssueKind::OrphanSlice, IssueKind::FileMissing]);
+
+        let outcome = repair(&root, &report).unwrap();
+        assert!(!outcome.full_rescan);
+        assert_eq!(outcome.removed, vec!["src/b/deleted.ts"]);
+        // 被移除模块 b 的旧切片一并清理
+        assert_eq!(outcome.removed_slices, vec!["b.json", "old_module.json"]);
+
+        let after = diagnose(&root, DEFAULT_SAMPLE_SIZE);
+        let _ = std::fs::remove_dir_all(&root);
+        assert!(after.issues.is_empty(), "{:?}", after.issues);
+        assert_eq!(after.graph_files, 1);
+    }
+
+    #[test]
+    fn test_diagnose_unreadable_graph() {
+        let root =
+            std::env::temp_dir().join(format!("codegraph_doctor_none_{}", std::process::id()));
+        let report = diagnose(&root, DEFAULT_SAMPLE_SIZE);
+        assert_eq!(report.issues.len(), 1);
+        assert_eq!(report.issues[0].kind, IssueKind::UnreadableGraph);
+    }
+
+    #[test]
+    fn test_platform_binary_name() {
+        assert_eq!(
+            platform_binary_name("linux", "x86_64").as_deref(),
+            Some("codegraph-x86_64-linux")
+        );
+        assert_eq!(
+            platform_binary_name("windows", "aarch64").as_deref(),
+            Some("codegraph-aarch64-windows.exe")
+        );
+        assert_eq!(platform_binary_name("freebsd", "x86_64"), None);
+    }
+
+    #[test]
+    fn test_sample_paths_is_evenly_spaced() {
+        let mut graph = create_empty_graph("demo", "");
+        for i in 0..10 {
+            graph
+                .files
+                .insert(format!("f{}.ts", i), file("m", "sha256:0"));
+        }
+        assert_eq!(sample_paths(&graph, 0).len(), 0);
+        assert_eq!(sample_paths(&graph, 100).len(), 10);
+        let sampled: Vec<&str> = sample_paths(&graph, 3).iter().map(|p| p.as_str()).collect();
+        assert_eq!(sampled, vec!["f0.ts", "f4.ts", "f8.ts"]);
+    }
+}
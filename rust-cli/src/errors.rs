@@ -0,0 +1,869 @@
+/// 错误传播图（errors）
+///
+/// 扫描时在代码视图（注释与字符串内容已屏蔽）中逐行提取每个函数可能抛出的异常或错误，
+/// 以及 try/catch 边界：
+/// - `throw`：TypeScript / JavaScript / Java / C++ 的 `throw`（`throw new X(...)` 记录类型 X）
+/// - `raise`：Python 的 `raise X(...)`；裸 `raise` 与 `raise err` 记为未知类型
+/// - `panic`：Rust `panic!` / `unreachable!` / `todo!` / `unimplemented!`，Go `panic(...)`
+/// - `unwrap`：Rust `.unwrap()` / `.expect(...)`
+/// - `throws`：Java 方法签名中的 `throws` 声明
+/// - 边界：`try { } catch (...)`、Python `try: ... except ...:`、Go 中调用 `recover()` 的函数
+///
+/// 查询阶段沿调用边传播：函数内使用的符号按名称解析到被调函数（同文件优先，其次为
+/// 本模块及其依赖模块中导出同名函数的文件），被调函数可逃逸的错误若在调用点处未被
+/// try 边界捕获，则继续向上逃逸。
+use serde::Serialize;
+use std::collections::{HashMap, HashSet};
+
+use crate::graph::{ClassInfo, CodeGraph, ErrorSite, FileEntry, FunctionInfo, TryBlock};
+use crate::source_text::{enclosing_symbol, SourceText};
+use crate::traverser::Language;
+
+/// 视为捕获全部异常的处理器类型
+const CATCH_ALL_TYPES: &[&str] = &[
+    "*",
+    "Exception",
+    "BaseException",
+    "Throwable",
+    "std::exception",
+    "exception",
+    "Error",
+];
+
+/// Rust 中会 panic 的宏
+const PANIC_MACROS: &[&str] = &["panic!", "unreachable!", "todo!", "unimplemented!"];
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 从某函数逃逸（或在其内部被捕获）的错误
+#[derive(Debug, Clone, Serialize)]
+pub struct PropagatedError {
+    pub kind: String,
+    pub error: String,
+    pub file: String,
+    pub line: u32,
+    /// 抛出错误的函数
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub symbol: Option<String>,
+    /// 从查询函数到抛出函数的调用链（仅本函数抛出时为空）
+    pub via: Vec<String>,
+}
+
+/// 单个函数定义的错误报告
+#[derive(Debug, Clone, Serialize)]
+pub struct ErrorReport {
+    pub symbol: String,
+    pub file: String,
+    pub line: u32,
+    /// 可逃逸到调用方的错误
+    pub escaping: Vec<PropagatedError>,
+    /// 在本函数内被 try 边界捕获的错误
+    pub caught: Vec<PropagatedError>,
+}
+
+/// 函数定义的标识：(文件, 函数名, 起始行)
+type FnKey = (String, String, u32);
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 从源码中提取错误点与 try 边界，均按行号排序
+pub fn extract_errors(
+    lang: Language,
+    source: &SourceText,
+    functions: &[FunctionInfo],
+    classes: &[ClassInfo],
+) -> (Vec<ErrorSite>, Vec<TryBlock>) {
+    let lines = source.code_lines();
+
+    let mut sites = Vec::new();
+    for (idx, line) in lines.iter().enumerate() {
+        let line_no = idx as u32 + 1;
+        for (kind, error) in line_error_sites(lang, line) {
+            sites.push(ErrorSite {
+                kind: kind.to_string(),
+                error,
+                line: line_no,
+                symbol: enclosing_symbol(functions, classes, line_no),
+            });
+        }
+    }
+
+    let mut blocks = match lang {
+        Language::Python => python_try_blocks(&lines),
+        Language::TypeScript | Language::JavaScript | Language::Java | Language::Cpp => {
+            brace_try_blocks(lang, &lines)
+        }
+        _ => vec![],
+    };
+    if lang == Language::Go {
+        // 调用 recover() 的函数（通常在 defer 中）捕获自身的 panic
+        for (idx, line) in lines.iter().enumerate() {
+            let line_no = idx as u32 + 1;
+            if !line.contains("recover()") {
+                continue;
+            }
+            if let Some(f) = functions
+                .iter()
+                .filter(|f| f.start_line <= line_no && line_no <= f.end_line)
+                .max_by_key(|f| f.end_line - f.start_line)
+            {
+                blocks.push(TryBlock {
+                    start_line: f.start_line,
+                    end_line: f.end_line,
+                    catches: vec!["*".to_string()],
+                });
+            }
+        }
+    }
+    blocks.sort_by_key(|b| (b.start_line, b.end_line));
+    blocks.dedup();
+    (sites, blocks)
+}
+
+/// 查找名为 symbol 的函数定义（file 为 Some 时限定文件），报告各定义可逃逸与被捕获的错误
+pub fn error_reports(graph: &CodeGraph, symbol: &str, file: Option<&str>) -> Vec<ErrorReport> {
+    let mut memo: HashMap<FnKey, Vec<PropagatedError>> = HashMap::new();
+    let mut reports = Vec::new();
+    for (path, entry) in &graph.files {
+        if file.is_some_and(|f| f != path) {
+            continue;
+        }
+        for func in entry.functions.iter().filter(|f| f.name == symbol) {
+            let mut stack = HashSet::new();
+            let (escaping, caught, _) = function_errors(graph, path, func, &mut memo, &mut stack);
+            reports.push(ErrorReport {
+                symbol: func.name.clone(),
+                file: path.clone(),
+                line: func.start_line,
+                escaping,
+                caught,
+            });
+        }
+    }
+    reports
+}
+
+/// 单行文本：`[kind] Error  file:line in symbol (via a → b)`
+pub fn format_error(e: &PropagatedError) -> String {
+    let error = if e.error.is_empty() {
+        "(unknown)"
+    } else {
+        e.error.as_str()
+    };
+    let mut out = format!("[{}] {}  {}:{}", e.kind, error, e.file, e.line);
+    if let Some(symbol) = &e.symbol {
+        out.push_str(&format!(" in {}", symbol));
+    }
+    if !e.via.is_empty() {
+        out.push_str(&format!(" (via {})", e.via.join(" → ")));
+    }
+    out
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+/// 计算函数的 (可逃逸, 被捕获, 被截断的调用链祖先) 错误；stack 为当前调用链，用于截断递归
+///
+/// 调用到 stack 中的祖先时跳过该边，结果因此依赖于调用链：第三项记录被跳过的祖先
+/// （不含函数自身）。只有不依赖任何祖先的结果才写入 memo，否则从环的另一处进入时
+/// 会读到缺少经由被截断边传来的错误的结果。
+fn function_errors(
+    graph: &CodeGraph,
+    path: &str,
+    func: &FunctionInfo,
+    memo: &mut HashMap<FnKey, Vec<PropagatedError>>,
+    stack: &mut HashSet<FnKey>,
+) -> (Vec<PropagatedError>, Vec<PropagatedError>, HashSet<FnKey>) {
+    let Some(entry) = graph.files.get(path) else {
+        return (vec![], vec![], HashSet::new());
+    };
+    let key: FnKey = (path.to_string(), func.name.clone(), func.start_line);
+    stack.insert(key.clone());
+
+    let mut escaping = Vec::new();
+    let mut caught = Vec::new();
+    let mut cut: HashSet<FnKey> = HashSet::new();
+    let in_func = |line: u32| func.start_line <= line && line <= func.end_line;
+
+    // 本函数内的错误点（嵌套函数中的错误点归属于嵌套函数）
+    for site in &entry.error_sites {
+        if !in_func(site.line) || site.symbol.as_deref() != Some(func.name.as_str()) {
+            continue;
+        }
+        let e = PropagatedError {
+            kind: site.kind.clone(),
+            error: site.error.clone(),
+            file: path.to_string(),
+            line: site.line,
+            symbol: site.symbol.clone(),
+            via: vec![],
+        };
+        if site.kind != "throws" && is_caught(&entry.try_blocks, site.line, &site.kind, &site.error)
+        {
+            caught.push(e);
+        } else {
+            escaping.push(e);
+        }
+    }
+
+    // 沿调用边传播
+    for (name, sym_ref) in &entry.symbol_refs {
+        let call_lines: Vec<u32> = sym_ref
+            .use_lines
+            .iter()
+            .copied()
+            .filter(|l| in_func(*l))
+            .collect();
+        if call_lines.is_empty() {
+            continue;
+        }
+        for (callee_path, callee) in resolve_callees(graph, path, entry, name, sym_ref.import_line)
+        {
+            let callee_key: FnKey = (callee_path.clone(), callee.name.clone(), callee.start_line);
+            if stack.contains(&callee_key) {
+                cut.insert(callee_key);
+                continue;
+            }
+            let callee_escaping = match memo.get(&callee_key) {
+                Some(v) => v.clone(),
+                None => {
+                    let (v, _, callee_cut) =
+                        function_errors(graph, &callee_path, callee, memo, stack);
+                    if callee_cut.is_empty() {
+                        memo.insert(callee_key, v.clone());
+                    }
+                    cut.extend(callee_cut);
+                    v
+                }
+            };
+            for mut e in callee_escaping {
+                e.via.insert(0, callee.name.clone());
+                // 任一调用点未捕获即可逃逸
+                let escapes = call_lines
+                    .iter()
+                    .any(|l| !is_caught(&entry.try_blocks, *l, &e.kind, &e.error));
+                if escapes {
+                    escaping.push(e);
+                } else {
+                    caught.push(e);
+                }
+            }
+        }
+    }
+
+    stack.remove(&key);
+    cut.remove(&key);
+    (dedup_errors(escaping), dedup_errors(caught), cut)
+}
+
+/// 按名称把函数内使用的符号解析到函数定义
+fn resolve_callees<'a>(
+    graph: &'a CodeGraph,
+    path: &str,
+    entry: &FileEntry,
+    name: &str,
+    import_line: u32,
+) -> Vec<(String, &'a FunctionInfo)> {
+    if import_line == 0 {
+        return graph
+            .files
+            .get(path)
+            .into_iter()
+            .flat_map(|e| e.functions.iter().filter(|f| f.name == name))
+            .map(|f| (path.to_string(), f))
+            .collect();
+    }
+    let reachable: HashSet<&str> = graph
+        .modules
+        .get(&entry.module)
+        .map(|m| m.depends_on.iter().map(String::as_str).collect())
+        .unwrap_or_default();
+    let mut callees = Vec::new();
+    for (other_path, other) in &graph.files {
+        if other_path == path
+            || !(other.module == entry.module || reachable.contains(other.module.as_str()))
+            || !other.exports.iter().any(|e| e == name)
+        {
+            continue;
+        }
+        callees.extend(
+            other
+                .functions
+                .iter()
+                .filter(|f| f.name == name)
+                .map(|f| (other_path.clone(), f)),
+        );
+    }
+    callees
+}
+
+/// 行 line 处抛出的错误是否被某个 try 边界捕获
+fn is_caught(blocks: &[TryBlock], line: u32, kind: &str, error: &str) -> bool {
+    blocks
+        .iter()
+        .filter(|b| b.start_line <= line && line <= b.end_line)
+        .any(|b| {
+            b.catches.iter().any(|c| {
+                // Rust 的 panic / unwrap 不会被 try 边界捕获；Go recover 记为 `*`
+                if kind == "unwrap" || (kind == "panic" && c != "*") {
+                    return false;
+                }
+                CATCH_ALL_TYPES.contains(&c.as_str())
+                    || error.is_empty()
+                    || last_segment(c) == last_segment(error)
+            })
+        })
+}
+
+/// 同一错误点只保留第一条（调用链最短的一条先被加入）
+fn dedup_errors(errors: Vec<PropagatedError>) -> Vec<PropagatedError> {
+    let mut seen = HashSet::new();
+    let mut result: Vec<PropagatedError> = errors
+        .into_iter()
+        .filter(|e| seen.insert((e.file.clone(), e.line, e.kind.clone(), e.error.clone())))
+        .collect();
+    result.sort_by(|a, b| {
+        a.via
+            .len()
+            .cmp(&b.via.len())
+            .then(a.file.cmp(&b.file))
+            .then(a.line.cmp(&b.line))
+    });
+    result
+}
+
+fn last_segment(name: &str) -> &str {
+    name.rsplit(['.', ':']).next().unwrap_or(name)
+}
+
+/// 单行中的错误点：(kind, 类型)
+fn line_error_sites(lang: Language, line: &str) -> Vec<(&'static str, String)> {
+    let mut found = Vec::new();
+    match lang {
+        Language::Rust => {
+            for m in PANIC_MACROS {
+                if find_word(line, m).is_some() {
+                    found.push(("panic", m.trim_end_matches('!').to_string()));
+                }
+            }
+            if line.contains(".unwrap()") {
+                found.push(("unwrap", "unwrap".to_string()));
+            }
+            if line.contains(".expect(") {
+                found.push(("unwrap", "expect".to_string()));
+            }
+        }
+        Language::Go => {
+            if find_word(line, "panic(").is_some() {
+                found.push(("panic", "panic".to_string()));
+            }
+        }
+        Language::Python => {
+            if let Some(pos) = find_word(line, "raise") {
+                let rest = &line[pos + "raise".len()..];
+                if rest.is_empty() || rest.starts_with([' ', ';']) {
+                    found.push(("raise", exception_type(rest.trim_start())));
+                }
+            }
+        }
+        Language::TypeScript | Language::JavaScript | Language::Java | Language::Cpp => {
+            if let Some(pos) = find_word(line, "throw") {
+                let rest = &line[pos + "throw".len()..];
+                if rest.is_empty() || rest.starts_with([' ', ';', '(']) {
+                    let rest = rest.trim_start();
+                    let rest = rest.strip_prefix("new ").unwrap_or(rest).trim_start();
+                    found.push(("throw", exception_type(rest)));
+                }
+            }
+            if lang == Language::Java {
+                if let Some(pos) = find_word(line, "throws ") {
+                    let clause = &line[pos + "throws ".len()..];
+                    let clause = clause.split(['{', ';']).next().unwrap_or("");
+                    for ty in clause.split(',').map(str::trim).filter(|t| !t.is_empty()) {
+                        found.push(("throws", ty.to_string()));
+                    }
+                }
+            }
+        }
+        Language::C => {}
+    }
+    found
+}
+
+/// `X(...)`、`pkg.X`、`std::runtime_error{...}` 中的类型名；小写开头的变量名视为未知
+fn exception_type(rest: &str) -> String {
+    let ident: String = rest
+        .chars()
+        .take_while(|c| c.is_alphanumeric() || matches!(c, '_' | '.' | ':'))
+        .collect();
+    let last = last_segment(&ident);
+    if ident.contains("::") || last.starts_with(|c: char| c.is_ascii_uppercase()) {
+        ident
+    } else {
+        String::new()
+    }
+}
+
+/// 关键字在行中的位置（前一个字符不是标识符字符或 `.`）
+fn find_word(line: &str, word: &str) -> Option<usize> {
+    line.match_indices(word).map(|(pos, _)| pos).find(|&pos| {
+        !line[..pos]
+            .chars()
+            .next_back()
+            .is_some_and(|c| c.is_alphanumeric() || c == '_' || c == '.')
+    })
+}
+
+/// 花括号语言的 try 块：受保护范围为 try 体，捕获类型取自后续的 catch 子句
+fn brace_try_blocks(lang: Language, lines: &[&str]) -> Vec<TryBlock> {
+    let mut blocks = Vec::new();
+    for (idx, line) in lines.iter().enumerate() {
+        let Some(pos) = find_word(line, "try") else {
+            continue;
+        };
+        let after = &line[pos + 3..];
+        if !(after.is_empty() || after.starts_with([' ', '{', '('])) {
+            continue;
+        }
+        let Some((body_end, mut cursor)) = match_block(lines, idx, pos + 3) else {
+            continue;
+        };
+        // 依次读取紧随其后的 catch 子句
+        let mut catches = Vec::new();
+        while let Some((line_idx, col)) = skip_whitespace(lines, cursor) {
+            let rest = &lines[line_idx][col..];
+            let Some(clause) = rest.strip_prefix("catch") else {
+                break;
+            };
+            let clause = clause.trim_start();
+            let param = clause
+                .strip_prefix('(')
+                .and_then(|c| c.find(')').map(|end| &c[..end]))
+                .unwrap_or("");
+            catches.extend(catch_types(lang, param));
+            match match_block(lines, line_idx, col + "catch".len()) {
+                Some((_, next)) => cursor = next,
+                None => break,
+            }
+        }
+        if !catches.is_empty() {
+            blocks.push(TryBlock {
+                start_line: idx as u32 + 1,
+                end_line: body_end as u32 + 1,
+                catches,
+            });
+        }
+    }
+    blocks
+}
+
+/// 从 (行, 列) 开始找到第一个 `{` 并匹配到对应的 `}`；返回 (`}` 所在行, `}` 之后的位置)
+fn match_block(lines: &[&str], start: usize, col: usize) -> Option<(usize, (usize, usize))> {
+    let mut depth = 0usize;
+    let mut opened = false;
+    for (i, line) in lines.iter().enumerate().skip(start) {
+        let from = if i == start { col } else { 0 };
+        for (offset, c) in line[from.min(line.len())..].char_indices() {
+            match c {
+                '{' => {
+                    depth += 1;
+                    opened = true;
+                }
+                '}' if opened => {
+                    depth -= 1;
+                    if depth == 0 {
+                        return Some((i, (i, from + offset + 1)));
+                    }
+                }
+                _ => {}
+            }
+        }
+    }
+    None
+}
+
+/// 跳过空白，返回下一个非空白字符的 (行, 列)
+fn skip_whitespace(lines: &[&str], (line, col): (usize, usize)) -> Option<(usize, usize)> {
+    for (i, text) in lines.iter().enumerate().skip(line) {
+        let from = if i == line { col.min(text.len()) } else { 0 };
+        if let Some(offset) = text[from..].find(|c: char| !c.is_whitespace()) {
+            return Some((i, from + offset));
+        }
+    }
+    None
+}
+
+/// catch 参数中的类型；JavaScript / TypeScript 的 catch 捕获全部
+fn catch_types(lang: Language, param: &str) -> Vec<String> {
+    if matches!(lang, Language::TypeScript | Language::JavaScript) || param.trim() == "..." {
+        return vec!["*".to_string()];
+    }
+    let types: Vec<String> = param
+        .split('|')
+        .filter_map(|part| {
+            part.split(|c: char| c.is_whitespace() || c == '&' || c == '*')
+                .find(|t| !t.is_empty() && *t != "const" && *t != "final")
+                .map(String::from)
+        })
+        .collect();
+    if types.is_empty() {
+        vec!["*".to_string()]
+    } else {
+        types
+    }
+}
+
+/// Python 的 try 块：受保护范围为 try 体，捕获类型取自同缩进的 except 子句
+fn python_try_blocks(lines: &[&str]) -> Vec<TryBlock> {
+    let indent = |l: &str| l.len() - l.trim_start().len();
+    let is_code = |l: &str| !l.trim().is_empty();
+    let mut blocks = Vec::new();
+    for (idx, line) in lines.iter().enumerate() {
+        if line.trim() != "try:" {
+            continue;
+        }
+        let level = indent(line);
+        let mut body_end = idx;
+        let mut catches = Vec::new();
+        let mut in_body = true;
+        for (j, next) in lines.iter().enumerate().skip(idx + 1) {
+            if !is_code(next) {
+                continue;
+            }
+            if indent(next) > level {
+                if in_body {
+                    body_end = j;
+                }
+                continue;
+            }
+            if indent(next) < level {
+                break;
+            }
+            let t = next.trim_start();
+            if let Some(clause) = t.strip_prefix("except") {
+                in_body = false;
+                catches.extend(except_types(clause));
+            } else if t.starts_with("else") || t.starts_with("finally") {
+                in_body = false;
+            } else {
+                break;
+            }
+        }
+        if !catches.is_empty() {
+            blocks.push(TryBlock {
+                start_line: idx as u32 + 1,
+                end_line: body_end as u32 + 1,
+                catches,
+            });
+        }
+    }
+    blocks
+}
+
+/// `except:`、`except KeyError as e:`、`except (A, B):`、`except* A:` 中的类型
+fn except_types(clause: &str) -> Vec<String> {
+    let clause = clause.trim_start_matches('*').trim();
+    let clause = clause.split(':').next().unwrap_or("");
+    let clause = clause.split(" as ").next().unwrap_or("").trim();
+    let clause = clause.trim_start_matches('(').trim_end_matches(')');
+    let types: Vec<String> = clause
+        .split(',')
+        .map(str::trim)
+        .filter(|t| !t.is_empty())
+        .map(String::from)
+        .collect();
+    if types.is_empty() {
+        vec!["*".to_string()]
+    } else {
+        types
+    }
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::{ModuleEntry, SymbolRef};
+    use std::collections::BTreeMap;
+
+    fn func(name: &str, start: u32, end: u32) -> FunctionInfo {
+        FunctionInfo {
+            name: name.into(),
+            signature: String::new(),
+            start_line: start,
+            end_line: end,
+        }
+    }
+
+    fn scan(lang: Language, src: &str) -> SourceText {
+        SourceText::scan(lang, src.as_bytes())
+    }
+
+    fn sites(lang: Language, src: &str, functions: &[FunctionInfo]) -> Vec<(u32, String, String)> {
+        extract_errors(lang, &scan(lang, src), functions, &[])
+            .0
+            .into_iter()
+            .map(|s| (s.line, s.kind, s.error))
+            .collect()
+    }
+
+    fn site(line: u32, kind: &str, error: &str) -> (u32, String, String) {
+        (line, kind.to_string(), error.to_string())
+    }
+
+    #[test]
+    fn test_extract_sites_per_language() {
+        let rs = "fn parse(s: &str) -> u32 {\n    let n = s.parse().unwrap();\n    if n == 0 { panic!(\"zero\") }\n    cfg.get(\"k\").expect(\"k\");\n    n\n}\n";
+        assert_eq!(
+            sites(Language::Rust, rs, &[func("parse", 1, 6)]),
+            vec![
+                site(2, "unwrap", "unwrap"),
+                site(3, "panic", "panic"),
+                site(4, "unwrap", "expect"),
+            ]
+        );
+
+        let java = "void load() throws IOException, SQLException {\n    throw new IllegalStateException(\"x\");\n}\n";
+        assert_eq!(
+            sites(Language::Java, java, &[func("load", 1, 3)]),
+            vec![
+                site(1, "throws", "IOException"),
+                site(1, "throws", "SQLException"),
+                site(2, "throw", "IllegalStateException"),
+            ]
+        );
+
+        let py = "def f(x):\n    if not x:\n        raise ValueError('x')\n    try:\n        g()\n    except KeyError as e:\n        raise\n";
+        assert_eq!(
+            sites(Language::Python, py, &[func("f", 1, 7)]),
+            vec![site(3, "raise", "ValueError"), site(7, "raise", "")]
+        );
+    }
+
+    #[test]
+    fn test_skips_strings_and_block_comments() {
+        let rs = "fn parse(s: &str) -> u32 {\n    /* s.parse().unwrap()\n       panic!(\"x\") */\n    let hint = \"never call .unwrap() here\";\n    s.len() as u32\n}\n";
+        assert!(sites(Language::Rust, rs, &[func("parse", 1, 6)]).is_empty());
+
+        // 字符串中的花括号不影响 try 块的范围
+        let ts = "function f() {\n  try {\n    log(\"}\");\n    g();\n  } catch (e) {}\n}\n";
+        let (_, blocks) = extract_errors(
+            Language::TypeScript,
+            &scan(Language::TypeScript, ts),
+            &[],
+            &[],
+        );
+        assert_eq!((blocks[0].start_line, blocks[0].end_line), (2, 5));
+    }
+
+    #[test]
+    fn test_extract_try_blocks() {
+        let ts = "function f() {\n  try {\n    g();\n  } catch (e) {\n    log(e);\n  }\n}\n";
+        let (_, blocks) = extract_errors(
+            Language::TypeScript,
+            &scan(Language::TypeScript, ts),
+            &[],
+            &[],
+        );
+        assert_eq!(
+            blocks,
+            vec![TryBlock {
+                start_line: 2,
+                end_line: 4,
+                catches: vec!["*".into()],
+            }]
+        );
+
+        let java = "try (var in = open()) {\n  read(in);\n}\ncatch (IOException | final SQLException e) {\n}\nfinally {\n}\n";
+        let (_, blocks) = extract_errors(Language::Java, &scan(Language::Java, java), &[], &[]);
+        assert_eq!(blocks[0].end_line, 3);
+        assert_eq!(blocks[0].catches, vec!["IOException", "SQLException"]);
+
+        let py = "try:\n    a()\n\n    b()\nexcept (KeyError, IndexError):\n    pass\nexcept:\n    pass\nc()\n";
+        let (_, blocks) = extract_errors(Language::Python, &scan(Language::Python, py), &[], &[]);
+        assert_eq!(
+            blocks,
+            vec![TryBlock {
+                start_line: 1,
+                end_line: 4,
+                catches: vec!["KeyError".into(), "IndexError".into(), "*".into()],
+            }]
+        );
+
+        let go = "func serve() {\n\tdefer func() { recover() }()\n\tpanic(\"x\")\n}\n";
+        let (sites, blocks) = extract_errors(
+            Language::Go,
+            &scan(Language::Go, go),
+            &[func("serve", 1, 4)],
+            &[],
+        );
+        assert_eq!(sites.len(), 1);
+        assert!(is_caught(&blocks, 3, "panic", "panic"));
+    }
+
+    #[test]
+    fn test_errors_propagate_along_calls() {
+        let mut graph = crate::graph::create_empty_graph("p", "/tmp/p");
+        let file = |module: &str, functions: Vec<FunctionInfo>| FileEntry {
+            language: "python".into(),
+            module: module.into(),
+            lines: 20,
+            functions,
+            ..Default::default()
+        };
+
+        // api.handle 调用 db.query（第 3 行，不受保护）与 db.lookup（第 6 行，在 try 中）
+        let mut api = file("api", vec![func("handle", 1, 10)]);
+        for (name, line) in [("query", 3), ("lookup", 6)] {
+            api.symbol_refs.insert(
+                name.into(),
+                SymbolRef {
+                    symbol: name.into(),
+                    import_line: 1,
+                    use_lines: vec![line],
+                },
+            );
+        }
+        api.try_blocks.push(TryBlock {
+            start_line: 5,
+            end_line: 6,
+            catches: vec!["KeyError".into()],
+        });
+        let mut db = file("db", vec![func("query", 1, 4), func("lookup", 6, 9)]);
+        db.exports = vec!["query".into(), "lookup".into()];
+        for (kind, error, line, symbol) in [
+            ("raise", "DbError", 2, "query"),
+            ("raise", "KeyError", 7, "lookup"),
+        ] {
+            db.error_sites.push(ErrorSite {
+                kind: kind.into(),
+                error: error.into(),
+                line,
+                symbol: Some(symbol.into()),
+            });
+        }
+        graph.files.insert("api.py".into(), api);
+        graph.files.insert("db.py".into(), db);
+        graph.modules.insert(
+            "api".into(),
+            ModuleEntry {
+                files: vec!["api.py".into()],
+                depends_on: vec!["db".into()],
+                depended_by: vec![],
+            },
+        );
+
+        let reports = error_reports(&graph, "handle", None);
+        assert_eq!(reports.len(), 1);
+        let report = &reports[0];
+        assert_eq!(report.escaping.len(), 1);
+        assert_eq!(
+            format_error(&report.escaping[0]),
+            "[raise] DbError  db.py:2 in query (via query)"
+        );
+        assert_eq!(report.caught.len(), 1);
+        assert_eq!(report.caught[0].error, "KeyError");
+    }
+
+    #[test]
+    fn test_errors_reach_private_helpers_from_scanned_source() {
+        use crate::scanner::build_file_entry;
+        use std::path::Path;
+
+        let mut graph = crate::graph::create_empty_graph("p", "/p");
+        let sources = [
+            (
+                "src/lib.rs",
+                Language::Rust,
+                "pub fn entry() -> u32 {\n    helper(\"1\")\n}\n\nfn helper(s: &str) -> u32 {\n    s.parse().unwrap()\n}\n",
+            ),
+            (
+                "cmd/main.go",
+                Language::Go,
+                "package main\n\nfunc Serve() {\n\tcheck()\n}\n\nfunc check() {\n\tpanic(\"boom\")\n}\n",
+            ),
+        ];
+        for (rel, lang, src) in sources {
+            let abs = Path::new("/p").join(rel);
+            let entry = build_file_entry(&abs, Path::new("/p"), lang, src.as_bytes()).unwrap();
+            graph.files.insert(rel.to_string(), entry);
+        }
+
+        // 入口函数只调用未导出的辅助函数，错误必须沿本地调用边逃逸
+        let reports = error_reports(&graph, "entry", None);
+        assert_eq!(reports.len(), 1);
+        let escaping: Vec<String> = reports[0].escaping.iter().map(format_error).collect();
+        assert_eq!(
+            escaping,
+            vec!["[unwrap] unwrap  src/lib.rs:6 in helper (via helper)"]
+        );
+
+        let reports = error_reports(&graph, "Serve", None);
+        assert_eq!(reports.len(), 1);
+        let escaping: Vec<String> = reports[0].escaping.iter().map(format_error).collect();
+        assert_eq!(
+            escaping,
+            vec!["[panic] panic  cmd/main.go:8 in check (via check)"]
+        );
+    }
+
+    #[test]
+    fn test_mutual_recursion_is_not_cached_partially() {
+        let mut graph = crate::graph::create_empty_graph("p", "/tmp/p");
+        let call = |line: u32, import_line: u32, name: &str| {
+            (
+                name.to_string(),
+                SymbolRef {
+                    symbol: name.into(),
+                    import_line,
+                    use_lines: vec![line],
+                },
+            )
+        };
+        let raise = |error: &str, line: u32, symbol: &str| ErrorSite {
+            kind: "raise".into(),
+            error: error.into(),
+            line,
+            symbol: Some(symbol.into()),
+        };
+
+        // x.run → a；a ↔ b 互相调用；y.run → b。a 抛 AError，b 抛 BError
+        let mut x = FileEntry {
+            language: "python".into(),
+            module: "m".into(),
+            lines: 13,
+            functions: vec![func("run", 1, 3), func("a", 5, 8), func("b", 10, 13)],
+            exports: vec!["b".into()],
+            ..Default::default()
+        };
+        x.symbol_refs = BTreeMap::from([call(2, 0, "a"), call(7, 0, "b")]);
+        x.symbol_refs.get_mut("a").unwrap().use_lines.push(12);
+        x.error_sites = vec![raise("AError", 6, "a"), raise("BError", 11, "b")];
+        let mut y = FileEntry {
+            language: "python".into(),
+            module: "m".into(),
+            lines: 3,
+            functions: vec![func("run", 1, 3)],
+            ..Default::default()
+        };
+        y.symbol_refs = BTreeMap::from([call(2, 1, "b")]);
+        graph.files.insert("x.py".into(), x);
+        graph.files.insert("y.py".into(), y);
+
+        // x.run 先经 a 进入 b（b → a 被截断）；y.run 随后直接调用 b，必须仍看到经 a 传来的 AError
+        let reports = error_reports(&graph, "run", None);
+        assert_eq!(reports.len(), 2);
+        for report in &reports {
+            let mut errors: Vec<&str> = report.escaping.iter().map(|e| e.error.as_str()).collect();
+            errors.sort();
+            assert_eq!(errors, vec!["AError", "BError"], "{}", report.file);
+        }
+        let via_b: Vec<String> = reports[1]
+            .escaping
+            .iter()
+            .map(|e| e.via.join(">"))
+            .collect();
+        assert!(via_b.contains(&"b>a".to_string()));
+    }
+}
@@ -0,0 +1,509 @@
+/// 表格化导出
+///
+/// 将 CodeGraph 拆分为规范化的关系表（files / symbols / imports / refs /
+/// module_edges / metrics），以稳定 ID 作为关联键，输出 CSV 或 Parquet，
+/// 便于在 DuckDB、pandas 等工具中分析代码结构。
+use crate::graph::{stable_id, CodeGraph};
+use crate::parquet::{self, ColumnType, Value};
+use crate::path_utils::{import_lookup, resolve_relative_import};
+use std::collections::HashMap;
+use std::io::Write;
+use std::path::Path;
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ExportFormat {
+    Csv,
+    Parquet,
+}
+
+impl ExportFormat {
+    pub fn parse(s: &str) -> Option<Self> {
+        match s.to_lowercase().as_str() {
+            "csv" => Some(ExportFormat::Csv),
+            "parquet" => Some(ExportFormat::Parquet),
+            _ => None,
+        }
+    }
+
+    pub fn extension(self) -> &'static str {
+        match self {
+            ExportFormat::Csv => "csv",
+            ExportFormat::Parquet => "parquet",
+        }
+    }
+}
+
+#[derive(Debug, Clone)]
+pub struct Table {
+    pub name: &'static str,
+    pub columns: Vec<(&'static str, ColumnType)>,
+    pub rows: Vec<Vec<Value>>,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 模块的稳定 ID
+pub fn module_id(name: &str) -> String {
+    stable_id(&["module", name])
+}
+
+/// 文件的稳定 ID
+pub fn file_id(path: &str) -> String {
+    stable_id(&["file", path])
+}
+
+/// 符号的稳定 ID（同文件同名同类符号按出现顺序追加序号）
+pub fn symbol_id(path: &str, kind: &str, name: &str, ordinal: usize) -> String {
+    let ord = ordinal.to_string();
+    stable_id(&["symbol", path, kind, name, &ord])
+}
+
+/// 从图谱构建全部规范化表，表内行按稳定顺序排列
+pub fn build_tables(graph: &CodeGraph) -> Vec<Table> {
+    let mut paths: Vec<&String> = graph.files.keys().collect();
+    paths.sort();
+
+    let mut files = Table {
+        name: "files",
+        columns: vec![
+            ("file_id", ColumnType::Utf8),
+            ("path", ColumnType::Utf8),
+            ("module_id", ColumnType::Utf8),
+            ("module", ColumnType::Utf8),
+            ("language", ColumnType::Utf8),
+            ("lines", ColumnType::Int64),
+            ("hash", ColumnType::Utf8),
+            ("is_entry_point", ColumnType::Boolean),
+        ],
+        rows: Vec::new(),
+    };
+    let mut symbols = Table {
+        name: "symbols",
+        columns: vec![
+            ("symbol_id", ColumnType::Utf8),
+            ("file_id", ColumnType::Utf8),
+            ("module_id", ColumnType::Utf8),
+            ("kind", ColumnType::Utf8),
+            ("name", ColumnType::Utf8),
+            ("signature", ColumnType::Utf8),
+            ("start_line", ColumnType::Int64),
+            ("end_line", ColumnType::Int64),
+            ("is_exported", ColumnType::Boolean),
+        ],
+        rows: Vec::new(),
+    };
+    let mut imports = Table {
+        name: "imports",
+        columns: vec![
+            ("import_id", ColumnType::Utf8),
+            ("file_id", ColumnType::Utf8),
+            ("source", ColumnType::Utf8),
+            ("symbol", ColumnType::Utf8),
+            ("is_external", ColumnType::Boolean),
+            ("line", ColumnType::Int64),
+            ("target_file_id", ColumnType::Utf8),
+        ],
+        rows: Vec::new(),
+    };
+    let mut refs = Table {
+        name: "refs",
+        columns: vec![
+            ("ref_id", ColumnType::Utf8),
+            ("file_id", ColumnType::Utf8),
+            ("symbol", ColumnType::Utf8),
+            ("target_symbol_id", ColumnType::Utf8),
+            ("import_line", ColumnType::Int64),
+            ("use_line", ColumnType::Int64),
+        ],
+        rows: Vec::new(),
+    };
+
+    // (文件路径, 符号名) → symbol_id，用于解析 refs 的目标
+    let mut symbol_lookup: HashMap<(String, String), String> = HashMap::new();
+    // 无扩展名路径 → 实际路径，用于解析相对 import
+    let path_lookup = import_lookup(paths.iter().copied());
+
+    for path in &paths {
+        let file = &graph.files[*path];
+        let fid = file_id(path);
+        let mid = module_id(&file.module);
+        files.rows.push(vec![
+            Value::Str(fid.clone()),
+            Value::Str(path.to_string()),
+            Value::Str(mid.clone()),
+            Value::Str(file.module.clone()),
+            Value::Str(file.language.clone()),
+            Value::Int(file.lines as i64),
+            Value::Str(file.hash.clone()),
+            Value::Bool(file.is_entry_point),
+        ]);
+
+        let mut entries: Vec<(&str, &str, String, u32, u32)> = Vec::new();
+        for f in &file.functions {
+            entries.push((
+                "function",
+                &f.name,
+                f.signature.clone(),
+                f.start_line,
+                f.end_line,
+            ));
+        }
+        for c in &file.classes {
+            entries.push(("class", &c.name, String::new(), c.start_line, c.end_line));
+        }
+        for t in &file.types {
+            entries.push(("type", &t.name, t.kind.clone(), t.start_line, t.end_line));
+        }
+        for v in &file.variables {
+            let sig = format!("{} {}", v.kind, v.name);
+            entries.push(("variable", &v.name, sig, v.start_line, v.start_line));
+        }
+
+        let mut seen: HashMap<(&str, &str), usize> = HashMap::new();
+        for (kind, name, signature, start, end) in entries {
+            let ordinal = seen.entry((kind, name)).or_insert(0);
+            let sid = symbol_id(path, kind, name, *ordinal);
+            *ordinal += 1;
+            symbol_lookup
+                .entry((path.to_string(), name.to_string()))
+                .or_insert_with(|| sid.clone());
+            symbols.rows.push(vec![
+                Value::Str(sid),
+                Value::Str(fid.clone()),
+                Value::Str(mid.clone()),
+                Value::Str(kind.to_string()),
+                Value::Str(name.to_string()),
+                Value::Str(signature),
+                Value::Int(start as i64),
+                Value::Int(end as i64),
+                Value::Bool(file.exports.iter().any(|e| e == name)),
+            ]);
+        }
+    }
+
+    for path in &paths {
+        let file = &graph.files[*path];
+        let fid = file_id(path);
+
+        // symbol → 导入来源文件（仅相对导入可解析）
+        let mut import_targets: HashMap<&str, String> = HashMap::new();
+        let mut seen: HashMap<(String, String), usize> = HashMap::new();
+        for imp in &file.imports {
+            let target = resolve_relative_import(path, &imp.source, &path_lookup);
+            let target_fid = target.as_deref().map(file_id).unwrap_or_default();
+            let symbols_or_blank: Vec<&str> = if imp.symbols.is_empty() {
+                vec![""]
+            } else {
+                imp.symbols.iter().map(|s| s.as_str()).collect()
+            };
+            for sym in symbols_or_blank {
+                if let Some(ref t) = target {
+                    import_targets.insert(sym, t.clone());
+                }
+                let ordinal = seen
+                    .entry((imp.source.clone(), sym.to_string()))
+                    .or_insert(0);
+                let ord = ordinal.to_string();
+                *ordinal += 1;
+                imports.rows.push(vec![
+                    Value::Str(stable_id(&["import", path, &imp.source, sym, &ord])),
+                    Value::Str(fid.clone()),
+                    Value::Str(imp.source.clone()),
+                    Value::Str(sym.to_string()),
+                    Value::Bool(imp.is_external),
+                    Value::Int(imp.import_line as i64),
+                    Value::Str(target_fid.clone()),
+                ]);
+            }
+        }
+
+        for (sym, sym_ref) in &file.symbol_refs {
+            let target_file = if sym_ref.import_line == 0 {
+                Some(path.to_string())
+            } else {
+                import_targets.get(sym.as_str()).cloned()
+            };
+            let target_sid = target_file
+                .and_then(|t| symbol_lookup.get(&(t, sym.clone())).cloned())
+                .unwrap_or_default();
+            for line in &sym_ref.use_lines {
+                let line_str = line.to_string();
+                refs.rows.push(vec![
+                    Value::Str(stable_id(&["ref", path, sym, &line_str])),
+                    Value::Str(fid.clone()),
+                    Value::Str(sym.clone()),
+                    Value::Str(target_sid.clone()),
+                    Value::Int(sym_ref.import_line as i64),
+                    Value::Int(*line as i64),
+                ]);
+            }
+        }
+    }
+
+    let mut module_names: Vec<&String> = graph.modules.keys().collect();
+    module_names.sort();
+
+    let mut module_edges = Table {
+        name: "module_edges",
+        columns: vec![
+            ("from_module_id", ColumnType::Utf8),
+            ("from_module", ColumnType::Utf8),
+            ("to_module_id", ColumnType::Utf8),
+            ("to_module", ColumnType::Utf8),
+        ],
+        rows: Vec::new(),
+    };
+    let mut metrics = Table {
+        name: "metrics",
+        columns: vec![
+            ("module_id", ColumnType::Utf8),
+            ("module", ColumnType::Utf8),
+            ("files", ColumnType::Int64),
+            ("lines", ColumnType::Int64),
+            ("functions", ColumnType::Int64),
+            ("classes", ColumnType::Int64),
+            ("types", ColumnType::Int64),
+            ("variables", ColumnType::Int64),
+            ("exports", ColumnType::Int64),
+            ("fan_in", ColumnType::Int64),
+            ("fan_out", ColumnType::Int64),
+        ],
+        rows: Vec::new(),
+    };
+
+    for name in module_names {
+        let module = &graph.modules[name];
+        let mid = module_id(name);
+        for dep in &module.depends_on {
+            module_edges.rows.push(vec![
+                Value::Str(mid.clone()),
+                Value::Str(name.clone()),
+                Value::Str(module_id(dep)),
+                Value::Str(dep.clone()),
+            ]);
+        }
+
+        let (mut lines, mut functions, mut classes, mut types, mut variables, mut exports) =
+            (0i64, 0i64, 0i64, 0i64, 0i64, 0i64);
+        for path in &module.files {
+            if let Some(file) = graph.files.get(path) {
+                lines += file.lines as i64;
+                functions += file.functions.len() as i64;
+                classes += file.classes.len() as i64;
+                types += file.types.len() as i64;
+                variables += file.variables.len() as i64;
+                exports += file.exports.len() as i64;
+            }
+        }
+        metrics.rows.push(vec![
+            Value::Str(mid),
+            Value::Str(name.clone()),
+            Value::Int(module.files.len() as i64),
+            Value::Int(lines),
+            Value::Int(functions),
+            Value::Int(classes),
+            Value::Int(types),
+            Value::Int(variables),
+            Value::Int(exports),
+            Value::Int(module.depended_by.len() as i64),
+            Value::Int(module.depends_on.len() as i64),
+        ]);
+    }
+
+    vec![files, symbols, imports, refs, module_edges, metrics]
+}
+
+/// 将表以 CSV（RFC 4180）写出，首行为列名
+pub fn write_csv<W: Write>(out: &mut W, table: &Table) -> anyhow::Result<()> {
+    let header: Vec<String> = table.columns.iter().map(|(n, _)| csv_field(n)).collect();
+    writeln!(out, "{}", header.join(","))?;
+    for row in &table.rows {
+        let cells: Vec<String> = row
+            .iter()
+            .map(|v| match v {
+                Value::Bool(b) => b.to_string(),
+                Value::Int(n) => n.to_string(),
+                Value::Str(s) => csv_field(s),
+            })
+            .collect();
+        writeln!(out, "{}", cells.join(","))?;
+    }
+    Ok(())
+}
+
+/// 导出全部表到 `out_dir/<table>.<ext>`，返回写出的文件路径
+pub fn export_tables(
+    graph: &CodeGraph,
+    out_dir: &Path,
+    format: ExportFormat,
+) -> anyhow::Result<Vec<std::path::PathBuf>> {
+    std::fs::create_dir_all(out_dir)?;
+    let mut written = Vec::new();
+    for table in build_tables(graph) {
+        let path = out_dir.join(format!("{}.{}", table.name, format.extension()));
+        let mut buf: Vec<u8> = Vec::new();
+        match format {
+            ExportFormat::Csv => write_csv(&mut buf, &table)?,
+            ExportFormat::Parquet => parquet::write_table(&mut buf, &table.columns, &table.rows)?,
+        }
+        std::fs::write(&path, buf)?;
+        written.push(path);
+    }
+    Ok(written)
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+fn csv_field(s: &str) -> String {
+    if s.contains([',', '"', '\n', '\r']) {
+        format!("\"{}\"", s.replace('"', "\"\""))
+    } else {
+        s.to_string()
+    }
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::{
+        create_empty_graph, FileEntry, FunctionInfo, ImportInfo, ModuleEntry, SymbolRef,
+    };
+
+    fn make_file(module: &str) -> FileEntry {
+        FileEntry {
+            language: "typescript".to_string(),
+            module: module.to_string(),
+            hash: "sha256:0011223344556677".to_string(),
+            lines: 12,
+            ..Default::default()
+        }
+    }
+
+    fn make_graph() -> CodeGraph {
+        let mut graph = create_empty_graph("test", "/tmp/test");
+
+        let mut helper = make_file("utils");
+        helper.functions.push(FunctionInfo {
+            name: "hash".to_string(),
+            signature: "hash(pw)".to_string(),
+            start_line: 1,
+            end_line: 3,
+        });
+        helper.exports.push("hash".to_string());
+        graph
+            .files
+            .insert("src/utils/helper.ts".to_string(), helper);
+
+        let mut login = make_file("auth");
+        login.imports.push(ImportInfo {
+            source: "../utils/helper".to_string(),
+            symbols: vec!["hash".to_string()],
+            is_external: false,
+            import_line: 1,
+        });
+        login.symbol_refs.insert(
+            "hash".to_string(),
+            SymbolRef {
+                symbol: "hash".to_string(),
+                import_line: 1,
+                use_lines: vec![4, 9],
+            },
+        );
+        graph.files.insert("src/auth/login.ts".to_string(), login);
+
+        graph.modules.insert(
+            "auth".to_string(),
+            ModuleEntry {
+                files: vec!["src/auth/login.ts".to_string()],
+                depends_on: vec!["utils".to_string()],
+                depended_by: vec![],
+            },
+        );
+        graph.modules.insert(
+            "utils".to_string(),
+            ModuleEntry {
+                files: vec!["src/utils/helper.ts".to_string()],
+                depends_on: vec![],
+                depended_by: vec!["auth".to_string()],
+            },
+        );
+        graph
+    }
+
+    fn table<'a>(tables: &'a [Table], name: &str) -> &'a Table {
+        tables.iter().find(|t| t.name == name).unwrap()
+    }
+
+    #[test]
+    fn test_build_tables_names() {
+        let tables = build_tables(&make_graph());
+        let names: Vec<&str> = tables.iter().map(|t| t.name).collect();
+        assert_eq!(
+            names,
+            vec![
+                "files",
+                "symbols",
+                "imports",
+                "refs",
+                "module_edges",
+                "metrics"
+            ]
+        );
+    }
+
+    #[test]
+    fn test_refs_resolve_target_symbol() {
+        let tables = build_tables(&make_graph());
+        let symbols = table(&tables, "symbols");
+        let refs = table(&tables, "refs");
+        assert_eq!(refs.rows.len(), 2);
+        let sid = symbols.rows[0][0].clone();
+        assert_eq!(refs.rows[0][3], sid);
+        assert_eq!(refs.rows[0][5], Value::Int(4));
+    }
+
+    #[test]
+    fn test_ids_are_stable() {
+        let a = build_tables(&make_graph());
+        let b = build_tables(&make_graph());
+        assert_eq!(table(&a, "files").rows, table(&b, "files").rows);
+        assert_eq!(
+            table(&a, "files").rows[0][0],
+            Value::Str(file_id("src/auth/login.ts"))
+        );
+    }
+
+    #[test]
+    fn test_metrics_fan_in_out() {
+        let tables = build_tables(&make_graph());
+        let metrics = table(&tables, "metrics");
+        // auth: fan_in 0, fan_out 1
+        assert_eq!(metrics.rows[0][1], Value::Str("auth".into()));
+        assert_eq!(metrics.rows[0][9], Value::Int(0));
+        assert_eq!(metrics.rows[0][10], Value::Int(1));
+        assert_eq!(table(&tables, "module_edges").rows.len(), 1);
+    }
+
+    #[test]
+    fn test_write_csv_quoting() {
+        let t = Table {
+            name: "t",
+            columns: vec![("a", ColumnType::Utf8), ("b", ColumnType::Int64)],
+            rows: vec![vec![Value::Str("x,\"y\"".into()), Value::Int(3)]],
+        };
+        let mut out = Vec::new();
+        write_csv(&mut out, &t).unwrap();
+        assert_eq!(String::from_utf8(out).unwrap(), "a,b\n\"x,\"\"y\"\"\",3\n");
+    }
+
+    #[test]
+    fn test_export_format_parse() {
+        assert_eq!(ExportFormat::parse("CSV"), Some(ExportFormat::Csv));
+        assert_eq!(ExportFormat::parse("parquet"), Some(ExportFormat::Parquet));
+        assert_eq!(ExportFormat::parse("xlsx"), None);
+    }
+}
@@ -0,0 +1,1048 @@
+/// 第三方依赖 API 层（opt-in）
+///
+/// 默认扫描会排除 node_modules、vendor 等目录，外部 import 只是一串字符串。
+/// `scan --external` 会额外定位项目实际导入的第三方包（node_modules、vendor、
+/// Go module 缓存、site-packages、~/.cargo/registry），只解析其对外导出的签名，
+/// 写入独立的只读层 `.codemap/external.json`。
+///
+/// 该层与项目图谱分开存放：`update` 不会修改它，项目模块的依赖关系也不受影响；
+/// query 会附带搜索该层，切片中列出模块用到的第三方 API。
+///
+/// `submodules = external` 时，git 子模块也以只读模块写入该层（模块名为子模块路径），
+/// 不需要 `--external`。
+use serde::{Deserialize, Serialize};
+use std::collections::{BTreeSet, HashMap};
+use std::path::{Path, PathBuf};
+
+use ignore::WalkBuilder;
+
+use crate::differ::merge_graph_update;
+use crate::graph::{create_empty_graph, to_record_lines, CodeGraph, FileEntry};
+use crate::manifest::{detect_package_aliases, go_requires, lock_packages, Ecosystem};
+use crate::path_utils::normalize_path;
+use crate::query::{CallerRef, QueryOptions, SymbolResult};
+use crate::scanner::build_file_entry;
+use crate::slicer::ModuleSlice;
+use crate::traverser::{detect_language, traverse_files_with, SubmodulePolicy, TraverseOptions};
+
+/// 只读层文件名（位于 .codemap/ 下）
+pub const EXTERNAL_FILE: &str = "external.json";
+
+/// 单个第三方包最多解析的文件数，避免超大依赖拖慢扫描
+const MAX_FILES_PER_PACKAGE: usize = 400;
+
+/// 第三方包内不属于对外 API 的目录
+const SKIP_DIRS: &[&str] = &[
+    "node_modules",
+    "test",
+    "tests",
+    "__tests__",
+    "testdata",
+    "examples",
+    "example",
+    "benches",
+    "docs",
+    "__pycache__",
+    ".git",
+];
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 外部 import 所属的第三方包
+#[derive(Debug, Clone, PartialEq, Eq, PartialOrd, Ord, Hash)]
+pub struct PackageRef {
+    pub ecosystem: Ecosystem,
+    /// npm 包名（含 scope）、Go module 路径、crate 名、Python 顶层包、Java 包路径
+    pub name: String,
+}
+
+/// 切片中列出的第三方 API
+#[derive(Debug, Clone, PartialEq, Eq, PartialOrd, Ord, Serialize, Deserialize)]
+pub struct ExternalApi {
+    pub package: String,
+    pub symbol: String,
+    pub kind: String,
+    #[serde(skip_serializing_if = "Option::is_none", default)]
+    pub signature: Option<String>,
+    pub file: String,
+    pub line: u32,
+}
+
+/// 将外部 import 源归类为第三方包
+///
+/// 排除标准库与项目自身（同名包、Go module 前缀、本地 Python 包、本地 Java 包），
+/// 剩下的才是真正的第三方依赖。
+pub struct PackageResolver {
+    own_packages: Vec<String>,
+    go_requires: Vec<(String, String)>,
+    local_python: BTreeSet<String>,
+    local_java: BTreeSet<String>,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+impl PackageResolver {
+    pub fn new(root_dir: &Path, graph: &CodeGraph) -> Self {
+        let go_requires = std::fs::read_to_string(root_dir.join("go.mod"))
+            .map(|t| go_requires(&t))
+            .unwrap_or_default();
+
+        let mut local_python = BTreeSet::new();
+        let mut local_java = BTreeSet::new();
+        for (path, file) in &graph.files {
+            let stem = crate::path_utils::strip_extension(path);
+            match file.language.as_str() {
+                "python" => local_python.extend(stem.split('/').map(str::to_string)),
+                "java" => {
+                    let dir = crate::path_utils::posix_dirname(path);
+                    local_java.insert(dir.replace('/', "."));
+                }
+                _ => {}
+            }
+        }
+
+        Self {
+            own_packages: detect_package_aliases(root_dir),
+            go_requires,
+            local_python,
+            local_java,
+        }
+    }
+
+    /// 解析 import 源所属的第三方包；标准库、项目内部 import 返回 None
+    pub fn resolve(&self, language: &str, source: &str) -> Option<PackageRef> {
+        let (ecosystem, name) = match language {
+            "typescript" | "javascript" => (Ecosystem::Npm, npm_package(source)?),
+            "python" => (Ecosystem::PyPI, python_package(source, &self.local_python)?),
+            "go" => (Ecosystem::Go, go_package(source, &self.go_requires)?),
+            "rust" => (Ecosystem::Cargo, rust_crate(source)?),
+            "java" => (Ecosystem::Maven, java_package(source, &self.local_java)?),
+            _ => return None,
+        };
+        if self.is_own(&name) {
+            return None;
+        }
+        Some(PackageRef { ecosystem, name })
+    }
+
+    /// go.mod 中声明的版本
+    pub fn go_version(&self, module: &str) -> Option<&str> {
+        self.go_requires
+            .iter()
+            .find(|(m, _)| m == module)
+            .map(|(_, v)| v.as_str())
+    }
+
+    fn is_own(&self, name: &str) -> bool {
+        self.own_packages.iter().any(|own| {
+            let own = own.split(':').next().unwrap_or(own);
+            name == own || name.starts_with(&format!("{}/", own))
+        })
+    }
+}
+
+/// 收集项目图谱中用到的全部第三方包（去重排序）
+pub fn used_packages(root_dir: &Path, graph: &CodeGraph) -> Vec<PackageRef> {
+    let resolver = PackageResolver::new(root_dir, graph);
+    let mut packages = BTreeSet::new();
+    for file in graph.files.values() {
+        for imp in file.imports.iter().filter(|i| i.is_external) {
+            if let Some(pkg) = resolver.resolve(&file.language, &imp.source) {
+                packages.insert(pkg);
+            }
+        }
+    }
+    packages.into_iter().collect()
+}
+
+/// 扫描项目用到的第三方包的导出 API，返回只读层图谱
+///
+/// 找不到源码的包（未安装、Java jar 等）会被跳过。
+pub fn scan_external(root_dir: &Path, graph: &CodeGraph) -> CodeGraph {
+    let resolver = PackageResolver::new(root_dir, graph);
+    let cargo_versions: HashMap<String, String> =
+        std::fs::read_to_string(root_dir.join("Cargo.lock"))
+            .map(|t| lock_packages(&t).into_iter().collect())
+            .unwrap_or_default();
+
+    let mut files: HashMap<String, FileEntry> = HashMap::new();
+    for pkg in used_packages(root_dir, graph) {
+        let version = match pkg.ecosystem {
+            Ecosystem::Go => resolver.go_version(&pkg.name).map(str::to_string),
+            Ecosystem::Cargo => cargo_versions
+                .get(&pkg.name)
+                .or_else(|| cargo_versions.get(&pkg.name.replace('_', "-")))
+                .cloned(),
+            _ => None,
+        };
+        for dir in locate_package(root_dir, &pkg, version.as_deref()) {
+            for (rel, entry) in scan_package_dir(&dir, &pkg) {
+                files.insert(format!("{}/{}", pkg.name, rel), entry);
+            }
+        }
+    }
+    files.extend(submodule_files(root_dir, graph));
+
+    build_layer(root_dir, files)
+}
+
+/// 只含 git 子模块的只读层（`submodules = external` 且未启用 `--external` 时）
+pub fn scan_submodules(root_dir: &Path, graph: &CodeGraph) -> CodeGraph {
+    build_layer(
+        root_dir,
+        submodule_files(root_dir, graph).into_iter().collect(),
+    )
+}
+
+/// 写入 .codemap/external.json
+///
+/// 除扫描时间外与已有文件相同时保留原文件，重扫不改动依赖就不会产生 diff。
+pub fn save(output_dir: &Path, layer: &CodeGraph) -> anyhow::Result<()> {
+    std::fs::create_dir_all(output_dir)?;
+    if let Ok(Some(previous)) = load(output_dir) {
+        let mut same_time = layer.clone();
+        same_time.scanned_at = previous.scanned_at.clone();
+        if to_record_lines(&same_time)? == to_record_lines(&previous)? {
+            return Ok(());
+        }
+    }
+    std::fs::write(output_dir.join(EXTERNAL_FILE), to_record_lines(layer)?)?;
+    Ok(())
+}
+
+/// 读取 .codemap/external.json；未启用（文件不存在）时返回 Ok(None)
+pub fn load(output_dir: &Path) -> anyhow::Result<Option<CodeGraph>> {
+    let path = output_dir.join(EXTERNAL_FILE);
+    if !path.exists() {
+        return Ok(None);
+    }
+    let data = std::fs::read_to_string(&path)?;
+    Ok(Some(serde_json::from_str(&data)?))
+}
+
+/// 在只读层中查找 import 源对应的包（按包名最长前缀匹配）
+pub fn resolve_import<'a>(layer: &'a CodeGraph, source: &str) -> Option<&'a str> {
+    layer
+        .modules
+        .keys()
+        .filter(|pkg| {
+            source == pkg.as_str()
+                || ["/", ".", "::"]
+                    .iter()
+                    .any(|sep| source.starts_with(&format!("{}{}", pkg, sep)))
+        })
+        .max_by_key(|pkg| pkg.len())
+        .map(|s| s.as_str())
+}
+
+/// 列出模块中 import 的、能在只读层解析到定义的第三方 API
+pub fn apis_used_by(graph: &CodeGraph, module: &str, layer: &CodeGraph) -> Vec<ExternalApi> {
+    let mut apis = BTreeSet::new();
+    let Some(mod_data) = graph.modules.get(module) else {
+        return vec![];
+    };
+    for file in mod_data.files.iter().filter_map(|f| graph.files.get(f)) {
+        for imp in file.imports.iter().filter(|i| i.is_external) {
+            let Some(pkg) = resolve_import(layer, &imp.source) else {
+                continue;
+            };
+            for symbol in &imp.symbols {
+                if let Some(api) = find_definition(layer, pkg, symbol) {
+                    apis.insert(api);
+                }
+            }
+        }
+    }
+    apis.into_iter().collect()
+}
+
+/// 在只读层中搜索符号，并补全项目内 import 了该符号的文件
+pub fn query_symbol(
+    layer: &CodeGraph,
+    project: &CodeGraph,
+    symbol: &str,
+    opts: &QueryOptions,
+) -> Vec<SymbolResult> {
+    let mut results = crate::query::query_symbol(layer, symbol, opts);
+    for r in &mut results {
+        for (path, file) in &project.files {
+            for imp in file.imports.iter().filter(|i| i.is_external) {
+                if resolve_import(layer, &imp.source) != Some(r.module.as_str())
+                    || !imp.symbols.contains(&r.name)
+                {
+                    continue;
+                }
+                let use_lines = file
+                    .symbol_refs
+                    .get(&r.name)
+                    .map(|sr| sr.use_lines.clone())
+                    .unwrap_or_default();
+                r.imported_by.push(format!("{}:{}", file.module, path));
+                r.imported_by_refs.push(CallerRef {
+                    file: path.clone(),
+                    module: file.module.clone(),
+                    import_line: imp.import_line,
+                    use_lines,
+                });
+            }
+        }
+    }
+    results
+}
+
+/// 为切片附加第三方 API 列表
+pub fn attach_apis(slice: &mut ModuleSlice, graph: &CodeGraph, layer: &CodeGraph) {
+    slice.external_apis = apis_used_by(graph, &slice.module, layer);
+}
+
+// ── 包名归类 ──────────────────────────────────────────────────────────────────
+
+/// Node.js 内置模块（不属于 npm 依赖）
+const NODE_BUILTINS: &[&str] = &[
+    "assert",
+    "buffer",
+    "child_process",
+    "cluster",
+    "crypto",
+    "dns",
+    "events",
+    "fs",
+    "http",
+    "http2",
+    "https",
+    "module",
+    "net",
+    "os",
+    "path",
+    "perf_hooks",
+    "process",
+    "querystring",
+    "readline",
+    "stream",
+    "timers",
+    "tls",
+    "url",
+    "util",
+    "v8",
+    "vm",
+    "worker_threads",
+    "zlib",
+];
+
+/// 常见 Python 标准库顶层模块
+const PYTHON_STDLIB: &[&str] = &[
+    "__future__",
+    "abc",
+    "argparse",
+    "asyncio",
+    "base64",
+    "bisect",
+    "collections",
+    "contextlib",
+    "copy",
+    "csv",
+    "dataclasses",
+    "datetime",
+    "decimal",
+    "email",
+    "enum",
+    "functools",
+    "glob",
+    "hashlib",
+    "heapq",
+    "http",
+    "importlib",
+    "inspect",
+    "io",
+    "itertools",
+    "json",
+    "logging",
+    "math",
+    "multiprocessing",
+    "operator",
+    "os",
+    "pathlib",
+    "pickle",
+    "platform",
+    "queue",
+    "random",
+    "re",
+    "shutil",
+    "signal",
+    "socket",
+    "sqlite3",
+    "string",
+    "struct",
+    "subprocess",
+    "sys",
+    "tempfile",
+    "textwrap",
+    "threading",
+    "time",
+    "traceback",
+    "types",
+    "typing",
+    "unittest",
+    "urllib",
+    "uuid",
+    "warnings",
+    "weakref",
+    "xml",
+];
+
+/// Rust 内置 crate 与路径关键字
+const RUST_BUILTINS: &[&str] = &[
+    "std",
+    "core",
+    "alloc",
+    "crate",
+    "self",
+    "super",
+    "proc_macro",
+    "test",
+];
+
+/// Java 平台包前缀
+const JAVA_BUILTINS: &[&str] = &["java.", "javax.", "jdk.", "sun.", "com.sun.", "kotlin."];
+
+pub(crate) fn npm_package(source: &str) -> Option<String> {
+    if source.starts_with("node:") || source.starts_with('#') || source.starts_with('/') {
+        return None;
+    }
+    let mut parts = source.split('/');
+    let first = parts.next()?;
+    let name = if let Some(scope) = first.strip_prefix('@') {
+        // "@/..." 与 "~/..." 是 tsconfig 路径别名
+        if scope.is_empty() {
+            return None;
+        }
+        format!("{}/{}", first, parts.next()?)
+    } else {
+        if first.is_empty() || first == "~" || NODE_BUILTINS.contains(&first) {
+            return None;
+        }
+        first.to_string()
+    };
+    Some(name)
+}
+
+fn python_package(source: &str, local: &BTreeSet<String>) -> Option<String> {
+    let top = source.split('.').next()?;
+    if top.is_empty() || PYTHON_STDLIB.contains(&top) || local.contains(top) {
+        return None;
+    }
+    Some(top.to_string())
+}
+
+fn go_package(source: &str, requires: &[(String, String)]) -> Option<String> {
+    let first = source.split('/').next()?;
+    // 标准库路径的首段不含域名
+    if !first.contains('.') {
+        return None;
+    }
+    if let Some((module, _)) = requires
+        .iter()
+        .filter(|(m, _)| source == m || source.starts_with(&format!("{}/", m)))
+        .max_by_key(|(m, _)| m.len())
+    {
+        return Some(module.clone());
+    }
+    let segments: Vec<&str> = source.split('/').collect();
+    let take = match first {
+        "gopkg.in" => 2,
+        _ => 3,
+    };
+    Some(segments[..take.min(segments.len())].join("/"))
+}
+
+pub(crate) fn rust_crate(source: &str) -> Option<String> {
+    let first = source.trim_start_matches("::").split("::").next()?;
+    if first.is_empty() || RUST_BUILTINS.contains(&first) {
+        return None;
+    }
+    Some(first.to_string())
+}
+
+fn java_package(source: &str, local: &BTreeSet<String>) -> Option<String> {
+    if JAVA_BUILTINS.iter().any(|p| source.starts_with(p)) {
+        return None;
+    }
+    // 去掉类名 / 通配符，保留包路径
+    let package = match source.rsplit_once('.') {
+        Some((pkg, last)) if last == "*" || last.starts_with(|c: char| c.is_ascii_uppercase()) => {
+            pkg
+        }
+        _ => source,
+    };
+    if local.iter().any(|dir| dir.ends_with(package)) {
+        return None;
+    }
+    Some(package.to_string())
+}
+
+// ── 定位与解析第三方包 ────────────────────────────────────────────────────────
+
+/// 定位第三方包的源码目录（可能有多个，如 npm 包与其 @types 声明包）
+fn locate_package(root_dir: &Path, pkg: &PackageRef, version: Option<&str>) -> Vec<PathBuf> {
+    let mut dirs = Vec::new();
+    match pkg.ecosystem {
+        Ecosystem::Npm => {
+            let node_modules = root_dir.join("node_modules");
+            dirs.push(node_modules.join(&pkg.name));
+            let types_name = pkg.name.trim_start_matches('@').replace('/', "__");
+            dirs.push(node_modules.join("@types").join(types_name));
+        }
+        Ecosystem::PyPI => {
+            for site in site_packages_dirs(root_dir) {
+                dirs.push(site.join(&pkg.name));
+                dirs.push(site.join(format!("{}.py", pkg.name)));
+            }
+        }
+        Ecosystem::Go => {
+            dirs.push(root_dir.join("vendor").join(&pkg.name));
+            if let (Some(cache), Some(version)) = (go_mod_cache(), version) {
+                dirs.push(cache.join(format!("{}@{}", escape_go_path(&pkg.name), version)));
+            }
+        }
+        Ecosystem::Cargo => dirs.extend(cargo_registry_dir(&pkg.name, version)),
+        // Maven 依赖以 jar 分发，没有可解析的源码
+        Ecosystem::Maven => {}
+    }
+    dirs.retain(|d| d.exists());
+    // vendor 与缓存同时存在时只取第一个（vendor 优先）
+    if pkg.ecosystem == Ecosystem::Go {
+        dirs.truncate(1);
+    }
+    dirs
+}
+
+/// 虚拟环境中的 site-packages 目录
+fn site_packages_dirs(root_dir: &Path) -> Vec<PathBuf> {
+    let mut envs: Vec<PathBuf> = [".venv", "venv", "env"]
+        .iter()
+        .map(|d| root_dir.join(d))
+        .collect();
+    if let Ok(venv) = std::env::var("VIRTUAL_ENV") {
+        envs.push(PathBuf::from(venv));
+    }
+
+    let mut dirs = Vec::new();
+    for env in envs {
+        let Ok(entries) = std::fs::read_dir(env.join("lib")) else {
+            continue;
+        };
+        let mut pythons: Vec<PathBuf> = entries
+            .flatten()
+            .map(|e| e.path())
+            .filter(|p| {
+                p.file_name()
+                    .map(|n| n.to_string_lossy().starts_with("python"))
+                    .unwrap_or(false)
+            })
+            .collect();
+        pythons.sort();
+        dirs.extend(pythons.into_iter().map(|p| p.join("site-packages")));
+    }
+    dirs
+}
+
+/// Go module 缓存目录：$GOMODCACHE → $GOPATH/pkg/mod → ~/go/pkg/mod
+fn go_mod_cache() -> Option<PathBuf> {
+    if let Ok(cache) = std::env::var("GOMODCACHE") {
+        return Some(PathBuf::from(cache));
+    }
+    if let Ok(gopath) = std::env::var("GOPATH") {
+        let first = std::env::split_paths(&gopath).next()?;
+        return Some(first.join("pkg").join("mod"));
+    }
+    crate::registry::home_dir().map(|h| h.join("go").join("pkg").join("mod"))
+}
+
+/// Go module 缓存路径中大写字母转义为 `!` + 小写
+fn escape_go_path(module: &str) -> String {
+    let mut out = String::with_capacity(module.len());
+    for c in module.chars() {
+        if c.is_ascii_uppercase() {
+            out.push('!');
+            out.push(c.to_ascii_lowercase());
+        } else {
+            out.push(c);
+        }
+    }
+    out
+}
+
+/// ~/.cargo/registry/src/<index>/<crate>-<version>；未锁定版本时取最新的目录
+fn cargo_registry_dir(crate_name: &str, version: Option<&str>) -> Option<PathBuf> {
+    let cargo_home = std::env::var("CARGO_HOME")
+        .map(PathBuf::from)
+        .ok()
+        .or_else(|| crate::registry::home_dir().map(|h| h.join(".cargo")))?;
+    let names = [crate_name.to_string(), crate_name.replace('_', "-")];
+
+    let mut candidates: Vec<(VersionKey, PathBuf)> = Vec::new();
+    for index in std::fs::read_dir(cargo_home.join("registry").join("src"))
+        .ok()?
+        .flatten()
+    {
+        for entry in std::fs::read_dir(index.path())
+            .into_iter()
+            .flatten()
+            .flatten()
+        {
+            let dir_name = entry.file_name().to_string_lossy().to_string();
+            let matched = names.iter().find_map(|n| {
+                let rest = dir_name.strip_prefix(&format!("{}-", n))?;
+                match version {
+                    Some(v) => (rest == v).then_some(rest),
+                    None => rest
+                        .starts_with(|c: char| c.is_ascii_digit())
+                        .then_some(rest),
+                }
+            });
+            if let Some(rest) = matched {
+                candidates.push((version_key(rest), entry.path()));
+            }
+        }
+    }
+    candidates.sort();
+    candidates.pop().map(|(_, path)| path)
+}
+
+/// 版本排序键：数字段逐段按数值比较，同版本的预发布版本（`-beta.1` 等）排在正式版之前
+type VersionKey = (Vec<u64>, bool);
+
+fn version_key(version: &str) -> VersionKey {
+    let (core, pre) = match version.split_once('-') {
+        Some((core, pre)) => (core, Some(pre)),
+        None => (version, None),
+    };
+    let core = core.split('+').next().unwrap_or(core);
+    let numbers = core
+        .split('.')
+        .map(|part| part.parse().unwrap_or(0))
+        .collect();
+    (numbers, pre.is_none())
+}
+
+fn build_layer(root_dir: &Path, files: HashMap<String, FileEntry>) -> CodeGraph {
+    let root_str = root_dir.to_string_lossy().replace('\\', "/");
+    let mut layer = create_empty_graph("external", &root_str);
+    merge_graph_update(&mut layer, files, &[]);
+    layer.scanned_at = crate::graph::chrono_now();
+    layer
+}
+
+/// 图谱记录为 external 的子模块：按项目内路径解析，每个子模块是一个只保留导出 API 的模块
+fn submodule_files(root_dir: &Path, graph: &CodeGraph) -> Vec<(String, FileEntry)> {
+    if graph.config.submodules != SubmodulePolicy::External {
+        return vec![];
+    }
+    let opts = TraverseOptions {
+        follow_symlinks: graph.config.follow_symlinks,
+        submodules: SubmodulePolicy::Scan,
+    };
+    let mut entries = Vec::new();
+    for sub in &graph.config.submodule_paths {
+        let dir = root_dir.join(sub);
+        let paths = traverse_files_with(&dir, &graph.config.exclude_patterns, &opts);
+        for path in paths {
+            let Some(lang) = detect_language(&path) else {
+                continue;
+            };
+            let Ok(content) = std::fs::read(&path) else {
+                continue;
+            };
+            if let Some(mut entry) = build_file_entry(&path, root_dir, lang, &content) {
+                entry.module = sub.clone();
+                let rel = path
+                    .strip_prefix(root_dir)
+                    .map(normalize_path)
+                    .unwrap_or_else(|_| normalize_path(&path));
+                entries.push((rel, api_surface(entry)));
+            }
+        }
+    }
+    entries
+}
+
+/// 解析一个第三方包目录，返回 (包内相对路径, 仅含导出 API 的 FileEntry)
+fn scan_package_dir(dir: &Path, pkg: &PackageRef) -> Vec<(String, FileEntry)> {
+    let files = api_files(dir, pkg.ecosystem);
+    let base = if dir.is_file() {
+        dir.parent().unwrap_or(dir)
+    } else {
+        dir
+    };
+
+    let mut entries = Vec::new();
+    for path in files {
+        let Some(lang) = detect_language(&path) else {
+            continue;
+        };
+        let Ok(content) = std::fs::read(&path) else {
+            continue;
+        };
+        if let Some(mut entry) = build_file_entry(&path, base, lang, &content) {
+            entry.module = pkg.name.clone();
+            let rel = path
+                .strip_prefix(base)
+                .map(normalize_path)
+                .unwrap_or_else(|_| normalize_path(&path));
+            entries.push((rel, api_surface(entry)));
+        }
+    }
+    entries
+}
+
+/// 列出包内构成对外 API 的源文件
+///
+/// 不读取 .gitignore（node_modules 等通常被忽略），跳过测试与示例目录；
+/// npm 包带有 .d.ts 声明时只解析声明文件。
+fn api_files(dir: &Path, ecosystem: Ecosystem) -> Vec<PathBuf> {
+    if dir.is_file() {
+        return vec![dir.to_path_buf()];
+    }
+
+    let walker = WalkBuilder::new(dir)
+        .hidden(true)
+        .git_ignore(false)
+        .git_global(false)
+        .git_exclude(false)
+        .ignore(false)
+        .parents(false)
+        .build();
+
+    let mut files: Vec<PathBuf> = walker
+        .flatten()
+        .map(|e| e.into_path())
+        .filter(|p| p.is_file() && detect_language(p).is_some())
+        .filter(|p| {
+            let rel = p.strip_prefix(dir).unwrap_or(p);
+            !rel.components().any(|c| {
+                let name = c.as_os_str().to_string_lossy();
+                SKIP_DIRS.contains(&name.as_ref())
+            })
+        })
+        .filter(|p| !p.to_string_lossy().ends_with("_test.go"))
+        .collect();
+
+    if ecosystem == Ecosystem::Npm && files.iter().any(|p| is_declaration_file(p)) {
+        files.retain(|p| is_declaration_file(p));
+    }
+    files.sort();
+    files.truncate(MAX_FILES_PER_PACKAGE);
+    files
+}
+
+fn is_declaration_file(path: &Path) -> bool {
+    let name = path.to_string_lossy();
+    name.ends_with(".d.ts") || name.ends_with(".d.mts") || name.ends_with(".d.cts")
+}
+
+/// 只保留导出的符号及其弃用标记，去掉 import、引用与其余逐文件分析结果
+///
+/// 有显式导出列表的语言按导出列表过滤；否则按约定排除 `_` 开头的私有名称。
+/// 第三方代码中的 TODO、审查点、错误位置等对调用方没有意义，不进入外部层；
+/// 新增的逐文件字段默认同样被丢弃。
+fn api_surface(mut entry: FileEntry) -> FileEntry {
+    let exports: BTreeSet<String> = entry.exports.iter().cloned().collect();
+    let public = |name: &str| {
+        if exports.is_empty() {
+            !name.starts_with('_')
+        } else {
+            exports.contains(name)
+        }
+    };
+    entry.functions.retain(|f| public(&f.name));
+    entry.classes.retain(|c| public(&c.name));
+    entry.types.retain(|t| public(&t.name));
+    entry
+        .variables
+        .retain(|v| (v.is_exported || exports.is_empty()) && public(&v.name));
+    entry.deprecations.retain(|d| public(&d.name));
+    FileEntry {
+        language: entry.language,
+        module: entry.module,
+        hash: entry.hash,
+        lines: entry.lines,
+        functions: entry.functions,
+        classes: entry.classes,
+        types: entry.types,
+        variables: entry.variables,
+        exports: entry.exports,
+        deprecations: entry.deprecations,
+        ..Default::default()
+    }
+}
+
+/// 在包内查找符号定义
+fn find_definition(layer: &CodeGraph, package: &str, symbol: &str) -> Option<ExternalApi> {
+    let module = layer.modules.get(package)?;
+    for path in &module.files {
+        let Some(file) = layer.files.get(path) else {
+            continue;
+        };
+        let api = |kind: &str, signature: Option<String>, line: u32| ExternalApi {
+            package: package.to_string(),
+            symbol: symbol.to_string(),
+            kind: kind.to_string(),
+            signature,
+            file: path.clone(),
+            line,
+        };
+        if let Some(f) = file.functions.iter().find(|f| f.name == symbol) {
+            return Some(api("function", Some(f.signature.clone()), f.start_line));
+        }
+        if let Some(c) = file.classes.iter().find(|c| c.name == symbol) {
+            return Some(api("class", None, c.start_line));
+        }
+        if let Some(t) = file.types.iter().find(|t| t.name == symbol) {
+            return Some(api("type", None, t.start_line));
+        }
+        if let Some(v) = file.variables.iter().find(|v| v.name == symbol) {
+            return Some(api(
+                "variable",
+                Some(format!("{} {}", v.kind, v.name)),
+                v.start_line,
+            ));
+        }
+    }
+    None
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::{FunctionInfo, ImportInfo, VariableInfo};
+
+    fn entry(module: &str, language: &str) -> FileEntry {
+        FileEntry {
+            language: language.to_string(),
+            module: module.to_string(),
+            hash: "sha256:0000000000000000".to_string(),
+            lines: 10,
+            ..Default::default()
+        }
+    }
+
+    fn func(name: &str) -> FunctionInfo {
+        FunctionInfo {
+            name: name.to_string(),
+            signature: format!("{}(path, handler)", name),
+            start_line: 3,
+            end_line: 3,
+        }
+    }
+
+    fn import(source: &str, symbols: &[&str]) -> ImportInfo {
+        ImportInfo {
+            source: source.to_string(),
+            symbols: symbols.iter().map(|s| s.to_string()).collect(),
+            is_external: true,
+            import_line: 1,
+        }
+    }
+
+    #[test]
+    fn test_npm_package() {
+        assert_eq!(npm_package("express").as_deref(), Some("express"));
+        assert_eq!(npm_package("lodash/fp").as_deref(), Some("lodash"));
+        assert_eq!(
+            npm_package("@nestjs/core/di").as_deref(),
+            Some("@nestjs/core")
+        );
+        assert_eq!(npm_package("fs"), None);
+        assert_eq!(npm_package("node:path"), None);
+        assert_eq!(npm_package("@/components/Button"), None);
+    }
+
+    #[test]
+    fn test_go_package_uses_go_mod_requires() {
+        let requires = vec![("github.com/gin-gonic/gin".to_string(), "v1.9.1".to_string())];
+        assert_eq!(
+            go_package("github.com/gin-gonic/gin/binding", &requires).as_deref(),
+            Some("github.com/gin-gonic/gin")
+        );
+        assert_eq!(
+            go_package("github.com/pkg/errors", &[]).as_deref(),
+            Some("github.com/pkg/errors")
+        );
+        assert_eq!(go_package("net/http", &requires), None);
+    }
+
+    #[test]
+    fn test_python_rust_java_packages() {
+        let local: BTreeSet<String> = ["myapp".to_string()].into_iter().collect();
+        assert_eq!(
+            python_package("requests.adapters", &local).as_deref(),
+            Some("requests")
+        );
+        assert_eq!(python_package("os.path", &local), None);
+        assert_eq!(python_package("myapp.models", &local), None);
+        assert_eq!(rust_crate("serde::de").as_deref(), Some("serde"));
+        assert_eq!(rust_crate("crate::graph"), None);
+        let java_local: BTreeSet<String> = ["src.main.java.com.acme.billing".to_string()]
+            .into_iter()
+            .collect();
+        assert_eq!(
+            java_package("com.fasterxml.jackson.databind.ObjectMapper", &java_local).as_deref(),
+            Some("com.fasterxml.jackson.databind")
+        );
+        assert_eq!(java_package("com.acme.billing.Invoice", &java_local), None);
+        assert_eq!(java_package("java.util.List", &java_local), None);
+    }
+
+    #[test]
+    fn test_escape_go_path() {
+        assert_eq!(
+            escape_go_path("github.com/BurntSushi/toml"),
+            "github.com/!burnt!sushi/toml"
+        );
+    }
+
+    #[test]
+    fn test_version_key_compares_numerically() {
+        assert!(version_key("1.10.0") > version_key("1.9.3"));
+        assert!(version_key("0.2.100") > version_key("0.2.99"));
+        assert!(version_key("1.0.0") > version_key("1.0.0-rc.1"));
+        assert!(version_key("2.0.0-alpha") > version_key("1.99.0"));
+    }
+
+    #[test]
+    fn test_api_surface_keeps_exports_only() {
+        let mut e = entry("express", "javascript");
+        e.functions = vec![func("Router"), func("internalHelper")];
+        e.exports = vec!["Router".to_string()];
+        e.imports = vec![import("./lib", &[])];
+        let api = api_surface(e);
+        assert_eq!(api.functions.len(), 1);
+        assert_eq!(api.functions[0].name, "Router");
+        assert!(api.imports.is_empty());
+
+        let mut py = entry("requests", "python");
+        py.functions = vec![func("get"), func("_merge")];
+        py.variables = vec![VariableInfo {
+            name: "__version__".to_string(),
+            kind: "var".to_string(),
+            start_line: 1,
+            is_exported: false,
+        }];
+        let api = api_surface(py);
+        assert_eq!(api.functions.len(), 1);
+        assert!(api.variables.is_empty());
+    }
+
+    #[test]
+    fn test_api_surface_drops_per_file_analysis() {
+        let mut e = entry("left-pad", "javascript");
+        e.functions = vec![func("pad"), func("legacyPad"), func("helper")];
+        e.exports = vec!["pad".to_string(), "legacyPad".to_string()];
+        e.is_entry_point = true;
+        e.symbol_refs.insert(
+            "x".to_string(),
+            crate::graph::SymbolRef {
+                symbol: "x".to_string(),
+                import_line: 1,
+                use_lines: vec![2],
+            },
+        );
+        e.config_keys = vec![crate::graph::ConfigKey {
+            name: "PAD_CHAR".to_string(),
+            kind: "env".to_string(),
+            lines: vec![3],
+        }];
+        e.todos = vec![crate::graph::Todo {
+            tag: "TODO".to_string(),
+            text: "unicode".to_string(),
+            line: 4,
+            owner: None,
+            ticket: None,
+            symbol: None,
+        }];
+        e.documented = vec!["pad".to_string()];
+        e.deprecations = ["legacyPad", "helper"]
+            .iter()
+            .map(|name| crate::graph::Deprecation {
+                name: name.to_string(),
+                kind: "function".to_string(),
+                line: 1,
+                message: Some("use pad".to_string()),
+            })
+            .collect();
+
+        let api = api_surface(e);
+        assert!(!api.is_entry_point);
+        assert!(api.symbol_refs.is_empty());
+        assert!(api.config_keys.is_empty());
+        assert!(api.todos.is_empty());
+        assert!(api.documented.is_empty());
+        assert!(api.audit_sites.is_empty() && api.error_sites.is_empty());
+        // 导出符号的弃用标记保留给调用方
+        let deprecated: Vec<&str> = api.deprecations.iter().map(|d| d.name.as_str()).collect();
+        assert_eq!(deprecated, vec!["legacyPad"]);
+    }
+
+    #[test]
+    fn test_resolve_import_and_apis_used_by() {
+        let mut layer = create_empty_graph("external", "");
+        let mut express = entry("express", "typescript");
+        express.functions = vec![func("Router")];
+        let mut files = HashMap::new();
+        files.insert("express/index.d.ts".to_string(), express);
+        merge_graph_update(&mut layer, files, &[]);
+
+        assert_eq!(resolve_import(&layer, "express"), Some("express"));
+        assert_eq!(
+            resolve_import(&layer, "express/lib/router"),
+            Some("express")
+        );
+        assert_eq!(resolve_import(&layer, "expressive"), None);
+
+        let mut graph = create_empty_graph("demo", "");
+        let mut server = entry("server", "typescript");
+        server.imports = vec![import("express", &["Router", "json"])];
+        let mut files = HashMap::new();
+        files.insert("src/server/app.ts".to_string(), server);
+        merge_graph_update(&mut graph, files, &[]);
+
+        let hits = query_symbol(&layer, &graph, "Router", &QueryOptions::default());
+        assert_eq!(hits.len(), 1);
+        assert_eq!(hits[0].imported_by, vec!["server:src/server/app.ts"]);
+        assert_eq!(hits[0].imported_by_refs[0].import_line, 1);
+
+        let apis = apis_used_by(&graph, "server", &layer);
+        assert_eq!(apis.len(), 1);
+        assert_eq!(apis[0].symbol, "Router");
+        assert_eq!(apis[0].file, "express/index.d.ts");
+        assert_eq!(apis[0].signature.as_deref(), Some("Router(path, handler)"));
+    }
+
+    #[test]
+    fn test_locate_package_skips_missing_dirs() {
+        let pkg = PackageRef {
+            ecosystem: Ecosystem::Npm,
+            name: "left-pad".to_string(),
+        };
+        let root = std::env::temp_dir().join(format!("codegraph_ext_{}", std::process::id()));
+        assert!(locate_package(&root, &pkg, None).is_empty());
+    }
+
+    #[test]
+    fn test_api_files_prefers_declarations_and_skips_tests() {
+        let dir = std::env::temp_dir().join(format!("codegraph_ext_files_{}", std::process::id()));
+        std::fs::create_dir_all(dir.join("lib")).unwrap();
+        std::fs::create_dir_all(dir.join("test")).unwrap();
+        std::fs::write(dir.join("index.js"), "module.exports = {}").unwrap();
+        std::fs::write(dir.join("index.d.ts"), "export declare function f(): void;").unwrap();
+        std::fs::write(dir.join("lib/util.js"), "").unwrap();
+        std::fs::write(dir.join("test/index.d.ts"), "").unwrap();
+
+        let files = api_files(&dir, Ecosystem::Npm);
+        assert_eq!(files, vec![dir.join("index.d.ts")]);
+        assert_eq!(api_files(&dir, Ecosystem::PyPI).len(), 3);
+        let _ = std::fs::remove_dir_all(&dir);
+    }
+}
@@ -0,0 +1,177 @@
+/// 图谱新鲜度检查（status --check）
+///
+/// 不解析源码：先比对文件大小与修改时间（本机缓存 .codemap/.stat-cache），
+/// 只有 stat 不一致的文件才计算哈希，因此可以在会话开始时快速判断 .codemap/ 是否需要 update。
+use serde::Serialize;
+use std::collections::HashSet;
+use std::path::Path;
+
+use crate::graph::{compute_file_hash, load_stat_cache, FileStat, GraphConfig, MetaInfo};
+use crate::scanner::git_head;
+use crate::traverser::traverse_files_with;
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 磁盘相对上次扫描的变化
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct Freshness {
+    pub added: Vec<String>,
+    pub modified: Vec<String>,
+    pub removed: Vec<String>,
+    /// 扫描时记录的 HEAD
+    #[serde(rename = "scannedCommit")]
+    pub scanned_commit: Option<String>,
+    /// 当前 HEAD
+    #[serde(rename = "headCommit")]
+    pub head_commit: Option<String>,
+    /// stat 不一致、需要计算哈希的文件数
+    #[serde(rename = "hashedFiles")]
+    pub hashed_files: usize,
+}
+
+impl Freshness {
+    /// 有文件新增、修改或删除（需要 `codegraph update`）
+    pub fn is_stale(&self) -> bool {
+        !self.added.is_empty() || !self.modified.is_empty() || !self.removed.is_empty()
+    }
+
+    /// HEAD 相对扫描时发生了移动
+    ///
+    /// 仅作提示：HEAD 变化但文件内容未变（如提交了已扫描的改动）时图谱仍是准确的。
+    pub fn head_moved(&self) -> bool {
+        matches!((&self.scanned_commit, &self.head_commit), (Some(a), Some(b)) if a != b)
+    }
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 对比磁盘当前源文件与 meta.json，得到新增 / 修改 / 删除的文件
+///
+/// 文件遍历规则与 scan / update 相同（排除模式、符号链接与子模块策略取自图谱配置）。
+pub fn check_freshness(root_dir: &Path, meta: &MetaInfo, config: &GraphConfig) -> Freshness {
+    let mut freshness = Freshness {
+        scanned_commit: meta.commit_hash.clone(),
+        head_commit: git_head(root_dir),
+        ..Default::default()
+    };
+
+    let stats = load_stat_cache(&root_dir.join(".codemap"));
+    let mut on_disk: HashSet<String> = HashSet::new();
+    let opts = config.traverse_options();
+    for abs_path in traverse_files_with(root_dir, &config.exclude_patterns, &opts) {
+        let rel_path = match abs_path.strip_prefix(root_dir) {
+            Ok(r) => r.to_string_lossy().replace('\\', "/"),
+            Err(_) => continue,
+        };
+        on_disk.insert(rel_path.clone());
+
+        let Some(hash) = meta.file_hashes.get(&rel_path) else {
+            freshness.added.push(rel_path);
+            continue;
+        };
+        // 缓存哈希须与 meta.json 一致：合并或检出另一版本的图谱后，旧 stat 不再可信
+        let stat_matches = stats.get(&rel_path).is_some_and(|cached| {
+            cached.hash == *hash && FileStat::of(&abs_path) == Some(cached.stat)
+        });
+        if stat_matches {
+            continue;
+        }
+        // stat 变化（或未记录）时以内容哈希为准：仅 touch 过的文件不算修改
+        freshness.hashed_files += 1;
+        match std::fs::read(&abs_path) {
+            Ok(content) if compute_file_hash(&content) == *hash => {}
+            _ => freshness.modified.push(rel_path),
+        }
+    }
+
+    freshness.removed = meta
+        .file_hashes
+        .keys()
+        .filter(|p| !on_disk.contains(*p))
+        .cloned()
+        .collect();
+    freshness.added.sort();
+    freshness.modified.sort();
+    freshness
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::{create_empty_graph, save_stat_cache, CachedStat, StatCache};
+    use std::collections::BTreeMap;
+
+    #[test]
+    fn test_check_freshness() {
+        let root = std::env::temp_dir().join(format!("codegraph_fresh_{}", std::process::id()));
+        let _ = std::fs::remove_dir_all(&root);
+        std::fs::create_dir_all(root.join("src")).unwrap();
+        let write = |rel: &str, text: &str| std::fs::write(root.join(rel), text).unwrap();
+        write("src/same.ts", "export const a = 1;\n");
+        write("src/touched.ts", "export const b = 2;\n");
+        write("src/edited.ts", "export const c = 3;\n");
+        write("src/merged.ts", "export const e = 5;\n");
+        write("src/new.ts", "export const d = 4;\n");
+
+        let mut file_hashes = BTreeMap::new();
+        for (path, text) in [
+            ("src/same.ts", "export const a = 1;\n"),
+            ("src/touched.ts", "export const b = 2;\n"),
+            ("src/edited.ts", "export const c = 3 ;\n"),
+            ("src/merged.ts", "export const e = 6;\n"),
+            ("src/deleted.ts", ""),
+        ] {
+            file_hashes.insert(path.to_string(), compute_file_hash(text.as_bytes()));
+        }
+        // same.ts 缓存了当前 stat；touched.ts 的 stat 过期但内容未变；
+        // merged.ts 的 stat 仍一致，但 meta.json 的哈希已被合并换掉，不能跳过
+        let cached = |rel: &str, stat: FileStat| CachedStat {
+            stat,
+            hash: compute_file_hash(&std::fs::read(root.join(rel)).unwrap()),
+        };
+        let mut stats = StatCache::new();
+        for rel in ["src/same.ts", "src/merged.ts"] {
+            let stat = FileStat::of(&root.join(rel)).unwrap();
+            stats.insert(rel.to_string(), cached(rel, stat));
+        }
+        let stale = FileStat { size: 1, mtime: 1 };
+        stats.insert(
+            "src/touched.ts".to_string(),
+            cached("src/touched.ts", stale),
+        );
+        save_stat_cache(&root.join(".codemap"), &stats).unwrap();
+        let meta = MetaInfo {
+            last_scan_at: "2026-01-01T00:00:00.000Z".to_string(),
+            commit_hash: None,
+            scan_duration: 0,
+            file_hashes,
+        };
+
+        let config = create_empty_graph("p", "/").config;
+        let freshness = check_freshness(&root, &meta, &config);
+        let ignore = std::fs::read_to_string(root.join(".codemap/.gitignore")).unwrap();
+        let _ = std::fs::remove_dir_all(&root);
+
+        assert_eq!(ignore, ".stat-cache\n");
+        assert_eq!(freshness.added, vec!["src/new.ts"]);
+        assert_eq!(freshness.modified, vec!["src/edited.ts", "src/merged.ts"]);
+        assert_eq!(freshness.removed, vec!["src/deleted.ts"]);
+        assert_eq!(freshness.hashed_files, 3);
+        assert!(freshness.is_stale());
+        assert!(!freshness.head_moved());
+    }
+
+    #[test]
+    fn test_head_moved() {
+        let mut f = Freshness::default();
+        assert!(!f.head_moved());
+        f.scanned_commit = Some("abc".to_string());
+        f.head_commit = Some("abc".to_string());
+        assert!(!f.head_moved());
+        f.head_commit = Some("def".to_string());
+        assert!(f.head_moved());
+        assert!(!f.is_stale());
+    }
+}
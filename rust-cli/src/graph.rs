@@ -1,9 +1,10 @@
 use serde::{Deserialize, Serialize};
 use sha2::{Digest, Sha256};
 use std::collections::BTreeMap;
-use std::collections::HashMap;
 use std::path::Path;
 
+use crate::traverser::{SubmodulePolicy, TraverseOptions};
+
 // ── 数据结构（与 Node.js JSON schema 完全兼容）────────────────────────────────
 
 #[derive(Debug, Clone, Serialize, Deserialize)]
@@ -64,7 +65,124 @@ pub struct SymbolRef {
     pub use_lines: Vec<u32>,
 }
 
-#[derive(Debug, Clone, Serialize, Deserialize)]
+/// 环境变量或配置键的读取（见 config_keys.rs）
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct ConfigKey {
+    pub name: String,
+    pub kind: String, // "env" | "config"
+    pub lines: Vec<u32>,
+}
+
+/// 安全审查点（见 audit_sites.rs）
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct AuditSite {
+    pub tag: String, // "unsafe" | "ffi" | "exec" | "sql"
+    pub detail: String,
+    pub line: u32,
+    /// 所在函数（或类）
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub symbol: Option<String>,
+}
+
+/// 跨语言绑定声明（见 bindings.rs）
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct BindingDecl {
+    pub role: String, // "export" | "import"
+    pub abi: String,  // "pyo3" | "pyo3-module" | "wasm" | "napi" | "c" | "jni"
+    pub name: String,
+    pub line: u32,
+}
+
+/// 跨语言绑定边：调用方文件 → 导出方文件
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct BindingEdge {
+    pub from: String,
+    pub to: String,
+    pub abi: String,
+    pub symbol: String,
+    /// 调用方中的声明或 import 行
+    pub line: u32,
+}
+
+/// Makefile 目标、just 配方或 package.json 脚本（见 targets.rs）
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct BuildTarget {
+    pub runner: String, // "make" | "just" | "npm"
+    pub name: String,
+    /// 定义所在的构建文件
+    pub file: String,
+    pub line: u32,
+    /// 依赖的同文件目标（先决条件、`$(MAKE) x`、`npm run x` 等）
+    #[serde(rename = "dependsOn", default, skip_serializing_if = "Vec::is_empty")]
+    pub depends_on: Vec<String>,
+    pub commands: Vec<String>,
+    /// 命令中运行的源文件（脚本路径、`python -m`、`go run`、`cargo run` 等）
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub runs: Vec<String>,
+}
+
+/// 弃用符号（见 deprecations.rs）
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Deprecation {
+    pub name: String,
+    pub kind: String, // "function" | "class" | "type" | "variable"
+    /// 符号的起始行
+    pub line: u32,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub message: Option<String>,
+}
+
+/// TODO / FIXME / HACK / XXX 注释（见 todos.rs）
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Todo {
+    pub tag: String, // "TODO" | "FIXME" | "HACK" | "XXX"
+    pub text: String,
+    pub line: u32,
+    /// `TODO(owner)` 中的负责人
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub owner: Option<String>,
+    /// 工单号（`ABC-123` 或 `#123`）
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub ticket: Option<String>,
+    /// 所在函数（或类）
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub symbol: Option<String>,
+}
+
+/// 并发原语使用点（见 concurrency.rs）
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct ConcurrencySite {
+    pub kind: String, // "spawn" | "channel" | "async" | "await" | "lock"
+    pub detail: String,
+    pub line: u32,
+    /// 所在函数（或类）
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub symbol: Option<String>,
+}
+
+/// 可能抛出的异常或错误（见 errors.rs）
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct ErrorSite {
+    pub kind: String, // "throw" | "raise" | "panic" | "unwrap" | "throws"
+    /// 异常类型或宏名；空字符串表示无法确定（如重新抛出变量）
+    pub error: String,
+    pub line: u32,
+    /// 所在函数（或类）
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub symbol: Option<String>,
+}
+
+/// try/catch 边界：受保护的行范围及捕获的类型（`*` 表示全部）
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct TryBlock {
+    #[serde(rename = "startLine")]
+    pub start_line: u32,
+    #[serde(rename = "endLine")]
+    pub end_line: u32,
+    pub catches: Vec<String>,
+}
+
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
 pub struct FileEntry {
     pub language: String,
     pub module: String,
@@ -81,6 +199,25 @@ pub struct FileEntry {
     pub is_entry_point: bool,
     #[serde(rename = "symbolRefs", default)]
     pub symbol_refs: BTreeMap<String, SymbolRef>,
+    #[serde(rename = "configKeys", default, skip_serializing_if = "Vec::is_empty")]
+    pub config_keys: Vec<ConfigKey>,
+    #[serde(rename = "auditSites", default, skip_serializing_if = "Vec::is_empty")]
+    pub audit_sites: Vec<AuditSite>,
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub bindings: Vec<BindingDecl>,
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub deprecations: Vec<Deprecation>,
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub todos: Vec<Todo>,
+    /// 带文档注释的函数、类与类型名（见 doc_coverage.rs）
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub documented: Vec<String>,
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub concurrency: Vec<ConcurrencySite>,
+    #[serde(rename = "errorSites", default, skip_serializing_if = "Vec::is_empty")]
+    pub error_sites: Vec<ErrorSite>,
+    #[serde(rename = "tryBlocks", default, skip_serializing_if = "Vec::is_empty")]
+    pub try_blocks: Vec<TryBlock>,
 }
 
 #[derive(Debug, Clone, Serialize, Deserialize)]
@@ -92,6 +229,33 @@ pub struct ModuleEntry {
     pub depended_by: Vec<String>,
 }
 
+/// 清单定义的包（构建单元）：Cargo crate、npm 包、Go module、Python 项目、Maven / Gradle 模块或 .csproj 项目
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct PackageEntry {
+    /// cargo / npm / go / python / maven / gradle / dotnet
+    pub kind: String,
+    /// 包目录（相对项目根，根目录为 `.`）
+    pub path: String,
+    /// 定义该包的清单文件
+    pub manifest: String,
+    pub modules: Vec<String>,
+    pub files: Vec<String>,
+    /// 清单中声明依赖的同仓库包
+    #[serde(rename = "declaredDependsOn")]
+    pub declared_depends_on: Vec<String>,
+    /// 源码 import 实际依赖的同仓库包
+    #[serde(rename = "observedDependsOn")]
+    pub observed_depends_on: Vec<String>,
+}
+
+/// 无法解析到项目内文件的相对 import（拼写错误、文件被删除或移动、扩展名错误）
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct BrokenImport {
+    pub file: String,
+    pub line: u32,
+    pub source: String,
+}
+
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct ProjectInfo {
     pub name: String,
@@ -108,7 +272,7 @@ pub struct GraphSummary {
     pub total_classes: u32,
     #[serde(rename = "totalVariables", default)]
     pub total_variables: u32,
-    pub languages: HashMap<String, u32>,
+    pub languages: BTreeMap<String, u32>,
     pub modules: Vec<String>,
     #[serde(rename = "entryPoints")]
     pub entry_points: Vec<String>,
@@ -119,6 +283,33 @@ pub struct GraphConfig {
     pub languages: Vec<String>,
     #[serde(rename = "excludePatterns")]
     pub exclude_patterns: Vec<String>,
+    /// 扫描时是否跟随符号链接（见 traverser.rs）
+    #[serde(
+        rename = "followSymlinks",
+        default,
+        skip_serializing_if = "std::ops::Not::not"
+    )]
+    pub follow_symlinks: bool,
+    /// git 子模块的处理方式：skip / external / scan
+    #[serde(default, skip_serializing_if = "SubmodulePolicy::is_default")]
+    pub submodules: SubmodulePolicy,
+    /// 扫描时 .gitmodules 声明的子模块路径
+    #[serde(
+        rename = "submodulePaths",
+        default,
+        skip_serializing_if = "Vec::is_empty"
+    )]
+    pub submodule_paths: Vec<String>,
+}
+
+impl GraphConfig {
+    /// 扫描时使用的遍历选项，update 与新鲜度检查沿用
+    pub fn traverse_options(&self) -> TraverseOptions {
+        TraverseOptions {
+            follow_symlinks: self.follow_symlinks,
+            submodules: self.submodules,
+        }
+    }
 }
 
 #[derive(Debug, Clone, Serialize, Deserialize)]
@@ -131,8 +322,42 @@ pub struct CodeGraph {
     pub commit_hash: Option<String>,
     pub config: GraphConfig,
     pub summary: GraphSummary,
-    pub modules: HashMap<String, ModuleEntry>,
-    pub files: HashMap<String, FileEntry>,
+    pub modules: BTreeMap<String, ModuleEntry>,
+    pub files: BTreeMap<String, FileEntry>,
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub packages: BTreeMap<String, PackageEntry>,
+    #[serde(
+        rename = "brokenImports",
+        default,
+        skip_serializing_if = "Vec::is_empty"
+    )]
+    pub broken_imports: Vec<BrokenImport>,
+    #[serde(
+        rename = "bindingEdges",
+        default,
+        skip_serializing_if = "Vec::is_empty"
+    )]
+    pub binding_edges: Vec<BindingEdge>,
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub targets: Vec<BuildTarget>,
+}
+
+impl CodeGraph {
+    /// 从 .codemap/ 目录加载图谱（等价于 [`load_graph`]，附带路径上下文）
+    pub fn load(output_dir: &Path) -> anyhow::Result<Self> {
+        load_graph(output_dir).map_err(|e| {
+            anyhow::anyhow!(
+                "failed to load {}: {}",
+                output_dir.join("graph.json").display(),
+                e
+            )
+        })
+    }
+
+    /// 保存图谱到 .codemap/ 目录（等价于 [`save_graph`]）
+    pub fn save(&self, output_dir: &Path) -> anyhow::Result<()> {
+        save_graph(output_dir, self)
+    }
 }
 
 /// meta.json 格式与 Node.js 版本完全兼容：
@@ -153,6 +378,46 @@ pub struct MetaInfo {
     pub file_hashes: BTreeMap<String, String>,
 }
 
+/// 文件的大小与修改时间（Unix 毫秒）
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+pub struct FileStat {
+    pub size: u64,
+    pub mtime: u64,
+}
+
+/// 本机 stat 缓存（.codemap/.stat-cache，不入库）：relPath → 读取文件计算哈希前的 stat 与该次哈希
+///
+/// `status --check` 只在 stat 与缓存一致、且缓存哈希仍等于 meta.json 中的哈希时跳过该文件。
+pub type StatCache = BTreeMap<String, CachedStat>;
+
+/// stat 缓存项
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct CachedStat {
+    #[serde(flatten)]
+    pub stat: FileStat,
+    pub hash: String,
+}
+
+impl FileStat {
+    /// 读取磁盘文件的 stat；文件不存在或无法读取修改时间时返回 None
+    pub fn of(path: &Path) -> Option<Self> {
+        let metadata = std::fs::metadata(path).ok()?;
+        let mtime = metadata
+            .modified()
+            .ok()?
+            .duration_since(std::time::UNIX_EPOCH)
+            .ok()?
+            .as_millis() as u64;
+        Some(FileStat {
+            size: metadata.len(),
+            mtime,
+        })
+    }
+}
+
+/// 本机 stat 缓存文件名（位于 .codemap/ 下）
+pub const STAT_CACHE_FILE: &str = ".stat-cache";
+
 // ── 入口点文件名集合 ──────────────────────────────────────────────────────────
 
 const ENTRY_POINT_NAMES: &[&str] = &["main", "index", "server", "app", "entry", "bootstrap"];
@@ -173,18 +438,25 @@ pub fn create_empty_graph(project_name: &str, root_dir: &str) -> CodeGraph {
         config: GraphConfig {
             languages: vec![],
             exclude_patterns: vec![],
+            follow_symlinks: false,
+            submodules: Default::default(),
+            submodule_paths: vec![],
         },
         summary: GraphSummary {
             total_files: 0,
             total_functions: 0,
             total_classes: 0,
             total_variables: 0,
-            languages: HashMap::new(),
+            languages: BTreeMap::new(),
             modules: vec![],
             entry_points: vec![],
         },
-        modules: HashMap::new(),
-        files: HashMap::new(),
+        modules: BTreeMap::new(),
+        files: BTreeMap::new(),
+        packages: BTreeMap::new(),
+        broken_imports: vec![],
+        binding_edges: vec![],
+        targets: vec![],
     }
 }
 
@@ -197,6 +469,21 @@ pub fn compute_file_hash(content: &[u8]) -> String {
     format!("sha256:{}", &hex[..16])
 }
 
+/// 计算稳定 ID（各部分以 `\0` 连接后取 sha256 前 16 个十六进制字符）
+///
+/// 同样的输入在不同运行间得到同样的 ID，可作为导出表之间的关联键。
+pub fn stable_id(parts: &[&str]) -> String {
+    let mut hasher = Sha256::new();
+    for (i, part) in parts.iter().enumerate() {
+        if i > 0 {
+            hasher.update([0u8]);
+        }
+        hasher.update(part.as_bytes());
+    }
+    let hex = hex_encode(&hasher.finalize());
+    hex[..16].to_string()
+}
+
 /// 判断文件是否为入口点
 pub fn is_entry_point(file_path: &Path) -> bool {
     let stem = file_path
@@ -210,7 +497,7 @@ pub fn is_entry_point(file_path: &Path) -> bool {
 /// 保存图谱到 .codemap/ 目录，meta.json 格式与 Node.js 完全兼容
 pub fn save_graph(output_dir: &Path, graph: &CodeGraph) -> anyhow::Result<()> {
     std::fs::create_dir_all(output_dir)?;
-    let graph_json = serde_json::to_string_pretty(graph)?;
+    let graph_json = to_record_lines(graph)?;
     std::fs::write(output_dir.join("graph.json"), graph_json)?;
 
     // 构建 fileHashes 映射（与 Node.js scan.js 逻辑一致），BTreeMap 自动按键排序
@@ -221,16 +508,52 @@ pub fn save_graph(output_dir: &Path, graph: &CodeGraph) -> anyhow::Result<()> {
         .collect();
 
     let meta = MetaInfo {
-        last_scan_at: chrono_now(),
+        last_scan_at: graph.scanned_at.clone(),
         commit_hash: graph.commit_hash.clone(),
         scan_duration: 0,
         file_hashes,
     };
-    let meta_json = serde_json::to_string_pretty(&meta)?;
+    let meta_json = to_record_lines(&meta)?;
     std::fs::write(output_dir.join("meta.json"), meta_json)?;
     Ok(())
 }
 
+/// 写入本机 stat 缓存，并确保 .codemap/.gitignore 忽略它
+///
+/// stat 随机器与检出时间变化，提交后只会制造无意义的 diff 与合并冲突。
+pub fn save_stat_cache(output_dir: &Path, cache: &StatCache) -> anyhow::Result<()> {
+    std::fs::create_dir_all(output_dir)?;
+    std::fs::write(
+        output_dir.join(STAT_CACHE_FILE),
+        serde_json::to_string(cache)?,
+    )?;
+    ensure_git_ignored(output_dir, STAT_CACHE_FILE)
+}
+
+/// 确保 .codemap/.gitignore 中忽略 `name`（只属于本机的状态文件）
+pub fn ensure_git_ignored(output_dir: &Path, name: &str) -> anyhow::Result<()> {
+    let ignore_path = output_dir.join(".gitignore");
+    let existing = std::fs::read_to_string(&ignore_path).unwrap_or_default();
+    if !existing.lines().any(|l| l.trim() == name) {
+        let mut content = existing;
+        if !content.is_empty() && !content.ends_with('\n') {
+            content.push('\n');
+        }
+        content.push_str(name);
+        content.push('\n');
+        std::fs::write(&ignore_path, content)?;
+    }
+    Ok(())
+}
+
+/// 读取本机 stat 缓存；不存在或无法解析时返回空缓存（退化为逐个计算哈希）
+pub fn load_stat_cache(output_dir: &Path) -> StatCache {
+    std::fs::read_to_string(output_dir.join(STAT_CACHE_FILE))
+        .ok()
+        .and_then(|data| serde_json::from_str(&data).ok())
+        .unwrap_or_default()
+}
+
 /// 从 .codemap/ 目录加载图谱
 pub fn load_graph(output_dir: &Path) -> anyhow::Result<CodeGraph> {
     let data = std::fs::read_to_string(output_dir.join("graph.json"))?;
@@ -243,6 +566,68 @@ pub fn load_meta(output_dir: &Path) -> anyhow::Result<MetaInfo> {
     Ok(serde_json::from_str(&data)?)
 }
 
+/// 文件哈希与 commit 都与上次保存的一致时沿用上次的扫描时间
+///
+/// 重扫未改动的代码不应只因时间戳不同而改写 graph.json / meta.json。
+pub fn keep_scan_time_if_unchanged(output_dir: &Path, graph: &mut CodeGraph) {
+    let Ok(meta) = load_meta(output_dir) else {
+        return;
+    };
+    let unchanged = meta.commit_hash == graph.commit_hash
+        && meta.file_hashes.len() == graph.files.len()
+        && graph
+            .files
+            .iter()
+            .all(|(path, f)| meta.file_hashes.get(path) == Some(&f.hash));
+    if unchanged && !meta.last_scan_at.is_empty() {
+        graph.scanned_at = meta.last_scan_at;
+    }
+}
+
+/// 序列化为"一条记录一行"的 JSON（.codemap/ 下所有落盘文件共用）
+///
+/// 顶层每个字段占一行；字段值为非空对象或数组时再展开一层，每个子项占一行。
+/// 对象键一律按字典序输出，同样的图谱总是得到逐字节相同的文件，
+/// 修改一个源文件只会改动 graph.json 中对应的那一行，便于 git diff / merge。
+pub fn to_record_lines<T: Serialize>(value: &T) -> anyhow::Result<String> {
+    // serde_json::Map 默认基于 BTreeMap，to_value 后键即有序
+    let root = match serde_json::to_value(value)? {
+        serde_json::Value::Object(map) => map,
+        other => return Ok(format!("{}\n", serde_json::to_string(&other)?)),
+    };
+
+    let mut out = String::from("{\n");
+    for (i, (key, val)) in root.iter().enumerate() {
+        out.push_str(&format!("  {}: ", serde_json::to_string(key)?));
+        match val {
+            serde_json::Value::Object(children) if !children.is_empty() => {
+                out.push_str("{\n");
+                for (j, (child_key, child)) in children.iter().enumerate() {
+                    out.push_str(&format!(
+                        "    {}: {}",
+                        serde_json::to_string(child_key)?,
+                        serde_json::to_string(child)?
+                    ));
+                    out.push_str(if j + 1 < children.len() { ",\n" } else { "\n" });
+                }
+                out.push_str("  }");
+            }
+            serde_json::Value::Array(items) if !items.is_empty() => {
+                out.push_str("[\n");
+                for (j, item) in items.iter().enumerate() {
+                    out.push_str(&format!("    {}", serde_json::to_string(item)?));
+                    out.push_str(if j + 1 < items.len() { ",\n" } else { "\n" });
+                }
+                out.push_str("  ]");
+            }
+            _ => out.push_str(&serde_json::to_string(val)?),
+        }
+        out.push_str(if i + 1 < root.len() { ",\n" } else { "\n" });
+    }
+    out.push_str("}\n");
+    Ok(out)
+}
+
 // ── 内部工具函数 ──────────────────────────────────────────────────────────────
 
 fn hex_encode(bytes: &[u8]) -> String {
@@ -316,6 +701,45 @@ fn is_leap(year: u64) -> bool {
 
 // ── 测试 ──────────────────────────────────────────────────────────────────────
 
+/// 各模块测试共用的 FileEntry 构造：只填写与默认值不同的字段
+#[cfg(test)]
+pub(crate) mod fixtures {
+    use super::{ClassInfo, FileEntry, FunctionInfo, ImportInfo};
+
+    /// 带 import 的文件：不以 `.` 开头的来源为外部依赖，导入行依次为 1、2、…
+    pub fn file_with_imports(module: &str, language: &str, sources: &[&str]) -> FileEntry {
+        FileEntry {
+            language: language.to_string(),
+            module: module.to_string(),
+            imports: sources
+                .iter()
+                .enumerate()
+                .map(|(i, s)| ImportInfo {
+                    source: s.to_string(),
+                    symbols: vec![],
+                    is_external: !s.starts_with('.'),
+                    import_line: i as u32 + 1,
+                })
+                .collect(),
+            ..Default::default()
+        }
+    }
+
+    /// 带函数与类的文件
+    pub fn file_with_symbols(
+        module: &str,
+        functions: Vec<FunctionInfo>,
+        classes: Vec<ClassInfo>,
+    ) -> FileEntry {
+        FileEntry {
+            module: module.to_string(),
+            functions,
+            classes,
+            ..Default::default()
+        }
+    }
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -327,6 +751,15 @@ mod tests {
         assert_eq!(hash.len(), 7 + 16); // "sha256:" + 16 hex chars
     }
 
+    #[test]
+    fn test_stable_id() {
+        let a = stable_id(&["file", "src/a.ts"]);
+        assert_eq!(a.len(), 16);
+        assert_eq!(a, stable_id(&["file", "src/a.ts"]));
+        // 分隔符避免 "ab"+"c" 与 "a"+"bc" 碰撞
+        assert_ne!(stable_id(&["ab", "c"]), stable_id(&["a", "bc"]));
+    }
+
     #[test]
     fn test_is_entry_point() {
         assert!(is_entry_point(Path::new("main.rs")));
@@ -350,4 +783,74 @@ mod tests {
         let parsed: CodeGraph = serde_json::from_str(&json).unwrap();
         assert_eq!(parsed.version, "1.0");
     }
+
+    #[test]
+    fn test_to_record_lines_one_file_per_line() {
+        let mut g = create_empty_graph("test", "/tmp/test");
+        for path in ["src/b.ts", "src/a.ts"] {
+            g.files.insert(
+                path.to_string(),
+                FileEntry {
+                    language: "typescript".to_string(),
+                    module: "_root".to_string(),
+                    hash: compute_file_hash(path.as_bytes()),
+                    lines: 1,
+                    ..Default::default()
+                },
+            );
+        }
+        let out = to_record_lines(&g).unwrap();
+        let lines: Vec<&str> = out.lines().collect();
+        let a = lines
+            .iter()
+            .position(|l| l.starts_with("    \"src/a.ts\": {"))
+            .unwrap();
+        let b = lines
+            .iter()
+            .position(|l| l.starts_with("    \"src/b.ts\": {"))
+            .unwrap();
+        assert_eq!(b, a + 1, "files should be sorted, one per line");
+        assert!(out.ends_with("}\n"));
+
+        let parsed: CodeGraph = serde_json::from_str(&out).unwrap();
+        assert_eq!(parsed.files.len(), 2);
+        // 同样的输入得到逐字节相同的输出
+        assert_eq!(to_record_lines(&parsed).unwrap(), out);
+    }
+
+    #[test]
+    fn test_keep_scan_time_if_unchanged() {
+        let dir = std::env::temp_dir().join(format!("codegraph_scan_time_{}", std::process::id()));
+        let mut g = create_empty_graph("test", "/tmp/test");
+        g.scanned_at = "2026-01-01T00:00:00.000Z".to_string();
+        g.files.insert(
+            "src/a.ts".to_string(),
+            FileEntry {
+                hash: compute_file_hash(b"a"),
+                ..Default::default()
+            },
+        );
+        save_graph(&dir, &g).unwrap();
+        let graph_json = std::fs::read(dir.join("graph.json")).unwrap();
+        let meta_json = std::fs::read(dir.join("meta.json")).unwrap();
+
+        // 内容未变：沿用上次时间，重写后的文件逐字节相同
+        let mut rescanned = g.clone();
+        rescanned.scanned_at = chrono_now();
+        keep_scan_time_if_unchanged(&dir, &mut rescanned);
+        assert_eq!(rescanned.scanned_at, g.scanned_at);
+        save_graph(&dir, &rescanned).unwrap();
+        assert_eq!(std::fs::read(dir.join("graph.json")).unwrap(), graph_json);
+        assert_eq!(std::fs::read(dir.join("meta.json")).unwrap(), meta_json);
+
+        // 文件哈希变化：使用新的扫描时间
+        let mut changed = rescanned.clone();
+        changed.scanned_at = chrono_now();
+        changed.files.get_mut("src/a.ts").unwrap().hash = compute_file_hash(b"b");
+        let now = changed.scanned_at.clone();
+        keep_scan_time_if_unchanged(&dir, &mut changed);
+        assert_eq!(changed.scanned_at, now);
+
+        let _ = std::fs::remove_dir_all(&dir);
+    }
 }
@@ -1,19 +1,36 @@
-use std::collections::{HashMap, HashSet, VecDeque};
+use std::collections::{BTreeMap, HashSet, VecDeque};
 
+use serde::Serialize;
+
+use crate::audit_sites::{collect_findings, AuditFinding};
 use crate::graph::{CodeGraph, ModuleEntry};
+use crate::targets::{targets_affected_by, AffectedTarget};
 
 /// 影响分析结果
-#[derive(Debug)]
+#[derive(Debug, Serialize)]
 pub struct ImpactResult {
+    #[serde(rename = "targetType")]
     pub target_type: TargetType,
+    #[serde(rename = "targetModule")]
     pub target_module: String,
+    #[serde(rename = "directDependants")]
     pub direct_dependants: Vec<String>,
+    #[serde(rename = "transitiveDependants")]
     pub transitive_dependants: Vec<String>,
+    #[serde(rename = "impactedModules")]
     pub impacted_modules: Vec<String>,
+    #[serde(rename = "impactedFiles")]
     pub impacted_files: Vec<String>,
+    /// 目标范围内的安全审查点及能到达它们的入口（见 audit_sites.rs）
+    #[serde(rename = "auditSites", skip_serializing_if = "Vec::is_empty")]
+    pub audit_sites: Vec<AuditFinding>,
+    /// 运行受影响文件的构建目标（见 targets.rs）
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub targets: Vec<AffectedTarget>,
 }
 
-#[derive(Debug, PartialEq, Eq)]
+#[derive(Debug, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "lowercase")]
 pub enum TargetType {
     Module,
     File,
@@ -57,6 +74,21 @@ pub fn analyze_impact(graph: &CodeGraph, target: &str, max_depth: u32) -> Impact
         .collect();
     impacted_files.sort();
 
+    // 6. 目标范围（文件或模块内的文件）中的审查点
+    let target_files: Vec<String> = match target_type {
+        TargetType::File => find_target_file(graph, target).into_iter().collect(),
+        TargetType::Module => graph
+            .modules
+            .get(&target_module)
+            .map(|m| m.files.clone())
+            .unwrap_or_default(),
+    };
+    let audit_sites = collect_findings(graph, &target_files, None);
+
+    // 7. 运行受影响文件的构建目标
+    let file_set: HashSet<&str> = impacted_files.iter().map(|f| f.as_str()).collect();
+    let targets = targets_affected_by(graph, &file_set);
+
     ImpactResult {
         target_type,
         target_module,
@@ -64,33 +96,41 @@ pub fn analyze_impact(graph: &CodeGraph, target: &str, max_depth: u32) -> Impact
         transitive_dependants,
         impacted_modules,
         impacted_files,
+        audit_sites,
+        targets,
     }
 }
 
-fn resolve_target(graph: &CodeGraph, target: &str) -> (TargetType, String) {
+/// 将目标解析为（类型, 所属模块）；模块名和文件路径都不匹配时返回 None。
+pub fn find_target(graph: &CodeGraph, target: &str) -> Option<(TargetType, String)> {
     // 优先匹配模块名
     if graph.modules.contains_key(target) {
-        return (TargetType::Module, target.to_string());
+        return Some((TargetType::Module, target.to_string()));
     }
 
+    find_target_file(graph, target)
+        .map(|file| (TargetType::File, graph.files[&file].module.clone()))
+}
+
+/// 目标对应的文件：精确路径优先，其次部分路径匹配
+fn find_target_file(graph: &CodeGraph, target: &str) -> Option<String> {
     // 精确文件路径匹配
-    if let Some(file) = graph.files.get(target) {
-        return (TargetType::File, file.module.clone());
+    if graph.files.contains_key(target) {
+        return Some(target.to_string());
     }
 
     // 部分文件路径匹配
-    if let Some(matched) = graph.files.keys().find(|f| f.contains(target)) {
-        let module = graph.files[matched].module.clone();
-        return (TargetType::File, module);
-    }
+    graph.files.keys().find(|f| f.contains(target)).cloned()
+}
 
+fn resolve_target(graph: &CodeGraph, target: &str) -> (TargetType, String) {
     // 未找到 — 返回空结果
-    (TargetType::Module, target.to_string())
+    find_target(graph, target).unwrap_or((TargetType::Module, target.to_string()))
 }
 
 /// BFS 遍历 dependedBy 边，返回所有传递依赖方（不含起始模块），按名称排序。
 fn bfs_dependants(
-    modules: &HashMap<String, ModuleEntry>,
+    modules: &BTreeMap<String, ModuleEntry>,
     start: &str,
     max_depth: u32,
 ) -> Vec<String> {
@@ -121,20 +161,59 @@ fn bfs_dependants(
     result
 }
 
+/// 沿 dependsOn 边查找从 from 到 to 的最短依赖路径（含两端模块）。
+///
+/// 同层按模块名顺序扩展，结果确定；不可达时返回 None。
+pub fn dependency_path(graph: &CodeGraph, from: &str, to: &str) -> Option<Vec<String>> {
+    if !graph.modules.contains_key(from) || !graph.modules.contains_key(to) {
+        return None;
+    }
+
+    let mut parent: BTreeMap<String, String> = BTreeMap::new();
+    let mut visited: HashSet<String> = HashSet::new();
+    visited.insert(from.to_string());
+    let mut queue: VecDeque<String> = VecDeque::new();
+    queue.push_back(from.to_string());
+
+    while let Some(current) = queue.pop_front() {
+        if current == to {
+            let mut path = vec![current];
+            while let Some(prev) = parent.get(path.last().unwrap()) {
+                path.push(prev.clone());
+            }
+            path.reverse();
+            return Some(path);
+        }
+        let Some(mod_entry) = graph.modules.get(&current) else {
+            continue;
+        };
+        let mut next: Vec<&String> = mod_entry.depends_on.iter().collect();
+        next.sort();
+        for dep in next {
+            if visited.insert(dep.clone()) {
+                parent.insert(dep.clone(), current.clone());
+                queue.push_back(dep.clone());
+            }
+        }
+    }
+
+    None
+}
+
 // ── 测试 ──────────────────────────────────────────────────────────────────────
 
 #[cfg(test)]
 mod tests {
     use super::*;
     use crate::graph::{CodeGraph, FileEntry, GraphConfig, GraphSummary, ModuleEntry, ProjectInfo};
-    use std::collections::HashMap;
+    use std::collections::BTreeMap;
 
     fn make_graph() -> CodeGraph {
         // 模块依赖关系：
         //   core  ← utils ← app
         //   core  ← app
         // 即 core.dependedBy = [utils, app], utils.dependedBy = [app]
-        let mut modules = HashMap::new();
+        let mut modules = BTreeMap::new();
         modules.insert(
             "core".to_string(),
             ModuleEntry {
@@ -160,7 +239,7 @@ mod tests {
             },
         );
 
-        let mut files = HashMap::new();
+        let mut files = BTreeMap::new();
         files.insert(
             "src/core/mod.rs".to_string(),
             FileEntry {
@@ -168,14 +247,7 @@ mod tests {
                 module: "core".to_string(),
                 hash: "sha256:abc".to_string(),
                 lines: 10,
-                functions: vec![],
-                classes: vec![],
-                types: vec![],
-                variables: vec![],
-                imports: vec![],
-                exports: vec![],
-                is_entry_point: false,
-                symbol_refs: std::collections::BTreeMap::new(),
+                ..Default::default()
             },
         );
 
@@ -190,21 +262,55 @@ mod tests {
             config: GraphConfig {
                 languages: vec![],
                 exclude_patterns: vec![],
+                follow_symlinks: false,
+                submodules: Default::default(),
+                submodule_paths: vec![],
             },
             summary: GraphSummary {
                 total_files: 3,
                 total_functions: 0,
                 total_classes: 0,
                 total_variables: 0,
-                languages: HashMap::new(),
+                languages: BTreeMap::new(),
                 modules: vec!["core".to_string(), "utils".to_string(), "app".to_string()],
                 entry_points: vec![],
             },
             modules,
             files,
+            packages: BTreeMap::new(),
+            broken_imports: vec![],
+            binding_edges: vec![],
+            targets: vec![],
         }
     }
 
+    #[test]
+    fn test_impact_audit_sites_with_entry_points() {
+        let mut graph = make_graph();
+        let mut main = graph.files["src/core/mod.rs"].clone();
+        main.module = "app".to_string();
+        main.is_entry_point = true;
+        graph.files.insert("src/main.rs".to_string(), main);
+        graph
+            .files
+            .get_mut("src/core/mod.rs")
+            .unwrap()
+            .audit_sites
+            .push(crate::graph::AuditSite {
+                tag: "unsafe".to_string(),
+                detail: "unsafe block".to_string(),
+                line: 4,
+                symbol: Some("read_raw".to_string()),
+            });
+
+        let result = analyze_impact(&graph, "src/core/mod.rs", 1);
+        assert_eq!(result.audit_sites.len(), 1);
+        assert_eq!(result.audit_sites[0].symbol.as_deref(), Some("read_raw"));
+        // app 依赖 core，其入口文件可到达该审查点
+        assert_eq!(result.audit_sites[0].entry_points, vec!["src/main.rs"]);
+        assert!(analyze_impact(&graph, "utils", 3).audit_sites.is_empty());
+    }
+
     #[test]
     fn test_impact_module_core() {
         let graph = make_graph();
@@ -283,4 +389,20 @@ mod tests {
         };
         assert_eq!(result.impacted_files, sorted);
     }
+
+    #[test]
+    fn test_dependency_path() {
+        let graph = make_graph();
+        // app 直接依赖 core，最短路径不经过 utils
+        assert_eq!(
+            dependency_path(&graph, "app", "core"),
+            Some(vec!["app".to_string(), "core".to_string()])
+        );
+        assert_eq!(
+            dependency_path(&graph, "utils", "utils"),
+            Some(vec!["utils".to_string()])
+        );
+        assert_eq!(dependency_path(&graph, "core", "app"), None);
+        assert_eq!(dependency_path(&graph, "nowhere", "core"), None);
+    }
 }
@@ -1,10 +1,38 @@
+pub mod api;
+pub mod audit_sites;
+pub mod bindings;
+pub mod brief;
+pub mod concurrency;
+pub mod config_keys;
+pub mod context;
+pub mod deprecations;
+pub mod deps;
 pub mod differ;
+pub mod doc_coverage;
+pub mod doctor;
+pub mod errors;
+pub mod export;
+pub mod external;
+pub mod freshness;
 pub mod graph;
 pub mod impact;
 pub mod languages;
+pub mod manifest;
+pub mod merge;
+pub mod notes;
+pub mod packages;
+pub mod parquet;
 pub mod parser;
 pub mod path_utils;
 pub mod query;
+pub mod registry;
 pub mod scanner;
 pub mod slicer;
+pub mod source_text;
+pub mod targets;
+pub mod todos;
 pub mod traverser;
+pub mod tui;
+pub mod workspace;
+
+pub use api::{Graph, ScanOptions, ScanProgress};
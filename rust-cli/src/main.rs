@@ -1,16 +1,14 @@
 use clap::{Parser, Subcommand};
 
 mod commands;
-mod differ;
 mod grammar_tests;
-mod graph;
-pub mod impact;
-pub mod languages;
-mod path_utils;
-pub mod query;
-mod scanner;
-mod slicer;
-mod traverser;
+
+// 命令层通过库 crate 调用核心逻辑，与嵌入方使用同一套 API
+use codegraph::{
+    api, audit_sites, brief, concurrency, config_keys, context, deprecations, deps, doc_coverage,
+    doctor, errors, export, external, freshness, graph, impact, merge, notes, packages, path_utils,
+    query, registry, scanner, slicer, todos, traverser, tui, workspace,
+};
 
 #[derive(Parser)]
 #[command(
@@ -33,10 +31,52 @@ enum Commands {
     Query(commands::query::QueryArgs),
     /// Analyze the impact of changes to a module or file
     Impact(commands::impact::ImpactArgs),
+    /// Show the shortest dependency path between two modules
+    Path(commands::path::PathArgs),
     /// Show the status of the code graph for a project
     Status(commands::status::StatusArgs),
     /// Output module slice or overview as JSON
     Slice(commands::slice::SliceArgs),
+    /// List external dependencies joined with manifests and lockfiles
+    Deps(commands::deps::DepsArgs),
+    /// Report imports that bypass declared package dependencies
+    Check(commands::check::CheckArgs),
+    /// List relative imports that resolve to no file in the project
+    BrokenImports(commands::broken_imports::BrokenImportsArgs),
+    /// Validate .codemap/ integrity and optionally repair it
+    Doctor(commands::doctor::DoctorArgs),
+    /// Digest of graph changes since the previous session (--record saves the state)
+    Brief(commands::brief::BriefArgs),
+    /// Persistent notes on modules and symbols (add, list, remove)
+    Note(commands::note::NoteArgs),
+    /// Inventory unsafe, FFI, process-exec and SQL-building sites with the entry points that reach them
+    AuditSites(commands::audit_sites::AuditSitesArgs),
+    /// List deprecated symbols with their remaining use sites grouped by module and owner
+    Deprecated(commands::deprecated::DeprecatedArgs),
+    /// List TODO, FIXME, HACK and XXX comments with their owner, ticket and enclosing symbol
+    Todos(commands::todos::TodosArgs),
+    /// Report documentation coverage of exported symbols per module, with a CI threshold
+    DocCoverage(commands::doc_coverage::DocCoverageArgs),
+    /// Summarize concurrency constructs (spawns, channels, async/await, locks) per function in a module
+    Concurrency(commands::concurrency::ConcurrencyArgs),
+    /// Show which errors, panics and exceptions can escape from a function, following calls
+    Errors(commands::errors::ErrorsArgs),
+    /// List Makefile, justfile and package.json targets with their dependencies and the files they run
+    Targets(commands::targets::TargetsArgs),
+    /// List every project scanned on this machine with its freshness ($CODEMAP_HOME/projects.json)
+    Projects(commands::projects::ProjectsArgs),
+    /// Search for a symbol in this project, or with --all in every registered project
+    Search(commands::search::SearchArgs),
+    /// Browse modules, dependencies, file outlines and references in an interactive terminal UI
+    Tui(commands::tui::TuiArgs),
+    /// List environment variables and config keys with their read sites and modules
+    Env(commands::env::EnvArgs),
+    /// Summarize files before editing: outline, importers, dependants, owners, tests
+    Context(commands::context::ContextArgs),
+    /// Export the code graph as CSV or Parquet tables
+    Export(commands::export::ExportArgs),
+    /// Git merge driver for .codemap/ files (register with --install)
+    MergeDriver(commands::merge_driver::MergeDriverArgs),
 }
 
 fn main() {
@@ -47,7 +87,28 @@ fn main() {
         Commands::Update(args) => commands::update::run(args),
         Commands::Query(args) => commands::query::run(args),
         Commands::Impact(args) => commands::impact::run(args),
+        Commands::Path(args) => commands::path::run(args),
         Commands::Status(args) => commands::status::run(args),
         Commands::Slice(args) => commands::slice::run(args),
+        Commands::Deps(args) => commands::deps::run(args),
+        Commands::Check(args) => commands::check::run(args),
+        Commands::BrokenImports(args) => commands::broken_imports::run(args),
+        Commands::Doctor(args) => commands::doctor::run(args),
+        Commands::Brief(args) => commands::brief::run(args),
+        Commands::Note(args) => commands::note::run(args),
+        Commands::AuditSites(args) => commands::audit_sites::run(args),
+        Commands::Deprecated(args) => commands::deprecated::run(args),
+        Commands::Todos(args) => commands::todos::run(args),
+        Commands::DocCoverage(args) => commands::doc_coverage::run(args),
+        Commands::Concurrency(args) => commands::concurrency::run(args),
+        Commands::Errors(args) => commands::errors::run(args),
+        Commands::Targets(args) => commands::targets::run(args),
+        Commands::Projects(args) => commands::projects::run(args),
+        Commands::Search(args) => commands::search::run(args),
+        Commands::Tui(args) => commands::tui::run(args),
+        Commands::Env(args) => commands::env::run(args),
+        Commands::Context(args) => commands::context::run(args),
+        Commands::Export(args) => commands::export::run(args),
+        Commands::MergeDriver(args) => commands::merge_driver::run(args),
     }
 }
@@ -0,0 +1,963 @@
+/// 构建清单解析（go.mod / package.json / pom.xml / build.gradle / Cargo.toml / pyproject.toml / .csproj）
+///
+/// 只做轻量的文本解析，不引入各格式的完整解析器。
+use serde::Serialize;
+use std::collections::HashMap;
+use std::path::Path;
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 包管理生态
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Hash, Serialize)]
+#[serde(rename_all = "lowercase")]
+pub enum Ecosystem {
+    Npm,
+    Go,
+    Cargo,
+    PyPI,
+    Maven,
+}
+
+impl Ecosystem {
+    pub fn as_str(self) -> &'static str {
+        match self {
+            Ecosystem::Npm => "npm",
+            Ecosystem::Go => "go",
+            Ecosystem::Cargo => "cargo",
+            Ecosystem::PyPI => "pypi",
+            Ecosystem::Maven => "maven",
+        }
+    }
+}
+
+/// 清单文件中声明的一个依赖
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct DeclaredDependency {
+    pub ecosystem: Ecosystem,
+    /// npm 包名、Go module 路径、crate 名、PyPI 发行名、Maven `groupId:artifactId`
+    pub name: String,
+    /// 清单中声明的版本约束（可能为空）
+    pub version: String,
+    /// lockfile 中锁定的版本
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub locked: Option<String>,
+    /// 开发 / 构建 / 测试 / 间接依赖：不要求被源码直接 import
+    pub dev: bool,
+    /// 声明所在的清单文件
+    pub manifest: String,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 检测项目对外发布的包名（其他仓库 import 本项目时使用的名称）
+///
+/// - go.mod：`module` 路径
+/// - package.json：`name`
+/// - pom.xml：`groupId:artifactId` Maven 坐标
+/// - Cargo.toml：`[package] name`（`-` 转为 `_`，与 `use` 路径一致）
+/// - pyproject.toml：`[project] name`（`-` 转为 `_`，与 import 名一致）
+pub fn detect_package_aliases(root_dir: &Path) -> Vec<String> {
+    let mut aliases = Vec::new();
+
+    if let Ok(text) = std::fs::read_to_string(root_dir.join("go.mod")) {
+        if let Some(module) = go_module_path(&text) {
+            aliases.push(module);
+        }
+    }
+    if let Ok(text) = std::fs::read_to_string(root_dir.join("package.json")) {
+        if let Ok(json) = serde_json::from_str::<serde_json::Value>(&text) {
+            if let Some(name) = json.get("name").and_then(|n| n.as_str()) {
+                aliases.push(name.to_string());
+            }
+        }
+    }
+    if let Ok(text) = std::fs::read_to_string(root_dir.join("pom.xml")) {
+        if let Some(coord) = maven_coordinate(&text) {
+            aliases.push(coord);
+        }
+    }
+    if let Ok(text) = std::fs::read_to_string(root_dir.join("Cargo.toml")) {
+        if let Some(name) = toml_string(&text, "package", "name") {
+            aliases.push(name.replace('-', "_"));
+        }
+    }
+    if let Ok(text) = std::fs::read_to_string(root_dir.join("pyproject.toml")) {
+        if let Some(name) = toml_string(&text, "project", "name") {
+            aliases.push(name.replace('-', "_").to_lowercase());
+        }
+    }
+
+    aliases.sort();
+    aliases.dedup();
+    aliases
+}
+
+/// go.mod 中的 `module` 路径
+pub fn go_module_path(text: &str) -> Option<String> {
+    text.lines()
+        .map(str::trim)
+        .find_map(|l| l.strip_prefix("module "))
+        .map(|m| m.trim().trim_matches('"').to_string())
+        .filter(|m| !m.is_empty())
+}
+
+/// pom.xml 中项目自身的 `groupId:artifactId`（跳过 `<parent>` 与依赖块）
+pub fn maven_coordinate(text: &str) -> Option<String> {
+    let own = strip_xml_blocks(
+        text,
+        &[
+            "parent",
+            "dependencies",
+            "dependencyManagement",
+            "build",
+            "profiles",
+        ],
+    );
+    let artifact = xml_tag(&own, "artifactId")?;
+    // 未声明 groupId 时继承 parent 的 groupId
+    let group = xml_tag(&own, "groupId").or_else(|| {
+        let parent = xml_block(text, "parent")?;
+        xml_tag(parent, "groupId")
+    })?;
+    Some(format!("{}:{}", group, artifact))
+}
+
+/// go.mod 中 `require` 声明的 (module 路径, 版本)，含 `require ( ... )` 块
+pub fn go_requires(text: &str) -> Vec<(String, String)> {
+    parse_go_requires(text)
+        .into_iter()
+        .map(|(module, version, _)| (module, version))
+        .collect()
+}
+
+/// `[[package]]` 格式 lockfile（Cargo.lock / poetry.lock / uv.lock）中锁定的 (包名, 版本)
+pub fn lock_packages(text: &str) -> Vec<(String, String)> {
+    let mut packages = Vec::new();
+    let mut name: Option<String> = None;
+    for line in text.lines().map(str::trim) {
+        if line == "[[package]]" {
+            name = None;
+            continue;
+        }
+        if let Some((k, v)) = line.split_once('=') {
+            let v = v.trim().trim_matches('"').to_string();
+            match k.trim() {
+                "name" => name = Some(v),
+                "version" => {
+                    if let Some(n) = name.take() {
+                        packages.push((n, v));
+                    }
+                }
+                _ => {}
+            }
+        }
+    }
+    packages
+}
+
+/// 读取项目根目录下所有清单与 lockfile 中声明的依赖
+///
+/// 支持 package.json（+ package-lock.json / yarn.lock）、go.mod、Cargo.toml（+ Cargo.lock）、
+/// pyproject.toml / requirements*.txt（+ poetry.lock / uv.lock）、pom.xml 与 build.gradle(.kts)。
+pub fn declared_dependencies(root_dir: &Path) -> Vec<DeclaredDependency> {
+    let read = |name: &str| std::fs::read_to_string(root_dir.join(name)).ok();
+    let mut deps = Vec::new();
+    let mut push = |ecosystem, name: String, version: String, dev, manifest: &str| {
+        deps.push(DeclaredDependency {
+            ecosystem,
+            name,
+            version,
+            locked: None,
+            dev,
+            manifest: manifest.to_string(),
+        })
+    };
+
+    if let Some(text) = read("package.json") {
+        for (name, version, dev) in npm_dependencies(&text) {
+            push(Ecosystem::Npm, name, version, dev, "package.json");
+        }
+    }
+    if let Some(text) = read("go.mod") {
+        for (module, version, indirect) in parse_go_requires(&text) {
+            push(Ecosystem::Go, module, version, indirect, "go.mod");
+        }
+    }
+    if let Some(text) = read("Cargo.toml") {
+        for (name, version, dev) in cargo_dependencies(&text) {
+            push(Ecosystem::Cargo, name, version, dev, "Cargo.toml");
+        }
+    }
+    if let Some(text) = read("pyproject.toml") {
+        for (name, version, dev) in pyproject_dependencies(&text) {
+            push(Ecosystem::PyPI, name, version, dev, "pyproject.toml");
+        }
+    }
+    for (file, dev) in [
+        ("requirements.txt", false),
+        ("requirements-dev.txt", true),
+        ("requirements-test.txt", true),
+    ] {
+        if let Some(text) = read(file) {
+            for line in text.lines() {
+                if let Some((name, version)) = parse_python_requirement(line) {
+                    push(Ecosystem::PyPI, name, version, dev, file);
+                }
+            }
+        }
+    }
+    if let Some(text) = read("pom.xml") {
+        for (name, version, dev) in maven_dependencies(&text) {
+            push(Ecosystem::Maven, name, version, dev, "pom.xml");
+        }
+    }
+    for file in ["build.gradle", "build.gradle.kts"] {
+        if let Some(text) = read(file) {
+            for (name, version, dev) in gradle_dependencies(&text) {
+                push(Ecosystem::Maven, name, version, dev, file);
+            }
+        }
+    }
+
+    // 关联 lockfile 中的锁定版本
+    let mut locked: HashMap<(Ecosystem, String), String> = HashMap::new();
+    if let Some(text) = read("package-lock.json") {
+        for (name, version) in npm_lock_packages(&text) {
+            locked.insert((Ecosystem::Npm, name), version);
+        }
+    } else if let Some(text) = read("yarn.lock") {
+        for (name, version) in yarn_lock_packages(&text) {
+            locked.entry((Ecosystem::Npm, name)).or_insert(version);
+        }
+    }
+    if let Some(text) = read("Cargo.lock") {
+        for (name, version) in lock_packages(&text) {
+            locked.insert((Ecosystem::Cargo, name), version);
+        }
+    }
+    for file in ["poetry.lock", "uv.lock"] {
+        if let Some(text) = read(file) {
+            for (name, version) in lock_packages(&text) {
+                locked.insert((Ecosystem::PyPI, normalize_python_name(&name)), version);
+            }
+        }
+    }
+    for dep in &mut deps {
+        dep.locked = match dep.ecosystem {
+            // go.mod 中的版本即为 MVS 选定版本（go.sum 只做校验）
+            Ecosystem::Go => Some(dep.version.clone()),
+            Ecosystem::PyPI => locked
+                .get(&(Ecosystem::PyPI, normalize_python_name(&dep.name)))
+                .cloned(),
+            // Maven / Gradle 没有通用 lockfile，声明的固定版本即解析结果
+            Ecosystem::Maven => Some(dep.version.clone()).filter(|v| !v.is_empty()),
+            eco => locked.get(&(eco, dep.name.clone())).cloned(),
+        };
+    }
+
+    deps.sort_by(|a, b| (a.ecosystem, &a.name).cmp(&(b.ecosystem, &b.name)));
+    deps
+}
+
+/// PyPI 发行名规范化（PEP 503：小写，`-` `_` `.` 统一为 `_`）
+pub fn normalize_python_name(name: &str) -> String {
+    name.to_lowercase().replace(['-', '.'], "_")
+}
+
+/// build.gradle(.kts) 中 `project(':libs:core')` 形式的项目依赖，返回 Gradle 项目路径
+pub fn gradle_project_dependencies(text: &str) -> Vec<String> {
+    let mut projects = Vec::new();
+    for line in text.lines().map(str::trim) {
+        if line.starts_with("//") {
+            continue;
+        }
+        let mut rest = line;
+        while let Some(i) = rest.find("project(") {
+            rest = &rest[i + "project(".len()..];
+            let inner = rest.trim_start().trim_start_matches("path:").trim_start();
+            let Some(quote) = inner.chars().next().filter(|c| *c == '\'' || *c == '"') else {
+                continue;
+            };
+            if let Some(end) = inner[1..].find(quote) {
+                let path = &inner[1..1 + end];
+                if path.starts_with(':') {
+                    projects.push(path.to_string());
+                }
+            }
+        }
+    }
+    projects
+}
+
+/// .csproj 中 `<ProjectReference Include="..\Core\Core.csproj" />` 引用的项目名（文件名去扩展名）
+pub fn csproj_project_references(text: &str) -> Vec<String> {
+    let mut refs = Vec::new();
+    let mut rest = text;
+    while let Some(i) = rest.find("<ProjectReference") {
+        rest = &rest[i + "<ProjectReference".len()..];
+        let tag = rest.split('>').next().unwrap_or("");
+        let Some(start) = tag.find("Include=\"") else {
+            continue;
+        };
+        let value = &tag[start + "Include=\"".len()..];
+        let path = value.split('"').next().unwrap_or("").replace('\\', "/");
+        let file = path.rsplit('/').next().unwrap_or("");
+        if let Some(stem) = file.strip_suffix(".csproj") {
+            refs.push(stem.to_string());
+        }
+    }
+    refs
+}
+
+/// 读取简单 TOML 中 `[section]` 下的字符串键（仅支持 `key = "value"` 形式）
+pub fn toml_string(text: &str, section: &str, key: &str) -> Option<String> {
+    let header = format!("[{}]", section);
+    let mut in_section = false;
+    for line in text.lines() {
+        let line = line.trim();
+        if line.starts_with('[') {
+            in_section = line == header;
+            continue;
+        }
+        if !in_section {
+            continue;
+        }
+        if let Some((k, v)) = line.split_once('=') {
+            if k.trim() == key {
+                let v = v.trim();
+                let v = v.split(" #").next().unwrap_or(v).trim();
+                return Some(v.trim_matches('"').trim_matches('\'').to_string());
+            }
+        }
+    }
+    None
+}
+
+/// 提取第一个 `<tag>...</tag>` 的文本内容
+pub fn xml_tag(text: &str, tag: &str) -> Option<String> {
+    let block = xml_block(text, tag)?;
+    let value = block.trim();
+    if value.is_empty() || value.contains('<') {
+        None
+    } else {
+        Some(value.to_string())
+    }
+}
+
+/// 提取第一个 `<tag>...</tag>` 的原始内部文本
+pub fn xml_block<'a>(text: &'a str, tag: &str) -> Option<&'a str> {
+    let open = format!("<{}>", tag);
+    let close = format!("</{}>", tag);
+    let start = text.find(&open)? + open.len();
+    let end = text[start..].find(&close)? + start;
+    Some(&text[start..end])
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+/// go.mod require 解析：(module, 版本, 是否 `// indirect`)
+fn parse_go_requires(text: &str) -> Vec<(String, String, bool)> {
+    let mut requires = Vec::new();
+    let mut in_block = false;
+    for raw in text.lines() {
+        let indirect = raw.contains("// indirect");
+        let line = raw.split("//").next().unwrap_or("").trim();
+        let spec = if in_block {
+            if line == ")" {
+                in_block = false;
+                continue;
+            }
+            line
+        } else if line == "require (" {
+            in_block = true;
+            continue;
+        } else if let Some(rest) = line.strip_prefix("require ") {
+            rest.trim()
+        } else {
+            continue;
+        };
+        let mut parts = spec.split_whitespace();
+        if let (Some(module), Some(version)) = (parts.next(), parts.next()) {
+            requires.push((module.to_string(), version.to_string(), indirect));
+        }
+    }
+    requires
+}
+
+/// package.json 的 dependencies / devDependencies / peerDependencies / optionalDependencies
+fn npm_dependencies(text: &str) -> Vec<(String, String, bool)> {
+    let Ok(json) = serde_json::from_str::<serde_json::Value>(text) else {
+        return vec![];
+    };
+    let mut deps = Vec::new();
+    for (section, dev) in [
+        ("dependencies", false),
+        ("peerDependencies", false),
+        ("optionalDependencies", false),
+        ("devDependencies", true),
+    ] {
+        if let Some(map) = json.get(section).and_then(|v| v.as_object()) {
+            for (name, version) in map {
+                if deps
+                    .iter()
+                    .any(|(n, _, _): &(String, String, bool)| n == name)
+                {
+                    continue;
+                }
+                let version = version.as_str().unwrap_or("").to_string();
+                deps.push((name.clone(), version, dev));
+            }
+        }
+    }
+    deps
+}
+
+/// package-lock.json（v2/v3 的 `packages`，v1 的 `dependencies`）中的顶层包版本
+fn npm_lock_packages(text: &str) -> Vec<(String, String)> {
+    let Ok(json) = serde_json::from_str::<serde_json::Value>(text) else {
+        return vec![];
+    };
+    let mut packages = Vec::new();
+    if let Some(map) = json.get("packages").and_then(|v| v.as_object()) {
+        for (path, info) in map {
+            // 只取顶层安装的包，跳过嵌套的 node_modules/a/node_modules/b
+            let Some(name) = path.strip_prefix("node_modules/") else {
+                continue;
+            };
+            if name.contains("/node_modules/") {
+                continue;
+            }
+            if let Some(v) = info.get("version").and_then(|v| v.as_str()) {
+                packages.push((name.to_string(), v.to_string()));
+            }
+        }
+    } else if let Some(map) = json.get("dependencies").and_then(|v| v.as_object()) {
+        for (name, info) in map {
+            if let Some(v) = info.get("version").and_then(|v| v.as_str()) {
+                packages.push((name.clone(), v.to_string()));
+            }
+        }
+    }
+    packages
+}
+
+/// yarn.lock（v1 与 berry）中的 (包名, 版本)
+fn yarn_lock_packages(text: &str) -> Vec<(String, String)> {
+    let mut packages = Vec::new();
+    let mut current: Vec<String> = Vec::new();
+    for line in text.lines() {
+        if !line.starts_with(' ') && line.ends_with(':') {
+            // "@scope/a@^1.0.0, @scope/a@^1.2.0":
+            current = line
+                .trim_end_matches(':')
+                .split(',')
+                .filter_map(|spec| {
+                    let spec = spec.trim().trim_matches('"');
+                    let at = spec.get(1..)?.find('@')? + 1;
+                    Some(spec[..at].to_string())
+                })
+                .collect();
+            current.dedup();
+            continue;
+        }
+        let trimmed = line.trim();
+        let version = trimmed
+            .strip_prefix("version ")
+            .or_else(|| trimmed.strip_prefix("version: "));
+        if let Some(v) = version {
+            let v = v.trim().trim_matches('"');
+            for name in current.drain(..) {
+                packages.push((name, v.to_string()));
+            }
+        }
+    }
+    packages
+}
+
+/// Cargo.toml 的 [dependencies] / [dev-dependencies] / [build-dependencies]，
+/// 含 `[target.'cfg(..)'.dependencies]` 与 `[dependencies.foo]` 表
+fn cargo_dependencies(text: &str) -> Vec<(String, String, bool)> {
+    let mut deps = Vec::new();
+    // (section 是否为依赖表, 是否 dev, `[dependencies.foo]` 中的 foo)
+    let mut section: Option<(bool, Option<String>)> = None;
+    for line in text.lines() {
+        let line = line.split(" #").next().unwrap_or("").trim();
+        if line.starts_with('[') {
+            let header = line.trim_matches(|c| c == '[' || c == ']');
+            let (table, name) = match header.rsplit_once('.') {
+                Some((t, n)) if t.ends_with("dependencies") => (t, Some(n.to_string())),
+                _ => (header, None),
+            };
+            let table = table.rsplit('.').next().unwrap_or(table);
+            section = match table {
+                "dependencies" => Some((false, name)),
+                "dev-dependencies" | "build-dependencies" => Some((true, name)),
+                _ => None,
+            };
+            if let Some((dev, Some(name))) = &section {
+                deps.push((name.clone(), String::new(), *dev));
+            }
+            continue;
+        }
+        let Some((dev, table_name)) = &section else {
+            continue;
+        };
+        let Some((key, value)) = line.split_once('=') else {
+            continue;
+        };
+        let key = key.trim();
+        let value = value.trim();
+        match table_name {
+            // [dependencies.foo] 表内的 version 键
+            Some(name) => {
+                if key == "version" {
+                    if let Some(dep) = deps.iter_mut().rev().find(|d| &d.0 == name) {
+                        dep.1 = value.trim_matches('"').to_string();
+                    }
+                }
+            }
+            None => {
+                let version = if value.starts_with('{') {
+                    inline_table_value(value, "version").unwrap_or_default()
+                } else {
+                    value.trim_matches('"').to_string()
+                };
+                deps.push((key.to_string(), version, *dev));
+            }
+        }
+    }
+    deps
+}
+
+/// 读取 TOML 内联表 `{ version = "1", features = [..] }` 中的字符串键
+fn inline_table_value(value: &str, key: &str) -> Option<String> {
+    let inner = value.trim().trim_start_matches('{').trim_end_matches('}');
+    inner.split(',').find_map(|pair| {
+        let (k, v) = pair.split_once('=')?;
+        (k.trim() == key).then(|| v.trim().trim_matches('"').to_string())
+    })
+}
+
+/// pyproject.toml：PEP 621 `[project] dependencies` / optional-dependencies，
+/// 以及 Poetry 的 `[tool.poetry.*dependencies]` 表
+fn pyproject_dependencies(text: &str) -> Vec<(String, String, bool)> {
+    let mut deps = Vec::new();
+    let mut section = String::new();
+    let mut in_array: Option<bool> = None;
+    for line in text.lines() {
+        let line = line.split(" #").next().unwrap_or("").trim();
+        if let Some(dev) = in_array {
+            for item in line.split(',') {
+                let item = item.trim().trim_end_matches(']').trim().trim_matches('"');
+                if let Some((name, version)) = parse_python_requirement(item) {
+                    deps.push((name, version, dev));
+                }
+            }
+            if closes_array(line) {
+                in_array = None;
+            }
+            continue;
+        }
+        if line.starts_with('[') {
+            section = line.trim_matches(|c| c == '[' || c == ']').to_string();
+            continue;
+        }
+        let Some((key, value)) = line.split_once('=') else {
+            continue;
+        };
+        let (key, value) = (key.trim(), value.trim());
+        let array_dev = match section.as_str() {
+            "project" if key == "dependencies" => Some(false),
+            "project.optional-dependencies" | "dependency-groups" => Some(true),
+            _ => None,
+        };
+        if let Some(dev) = array_dev {
+            if let Some(rest) = value.strip_prefix('[') {
+                for item in rest.split(',') {
+                    let item = item.trim().trim_end_matches(']').trim().trim_matches('"');
+                    if let Some((name, version)) = parse_python_requirement(item) {
+                        deps.push((name, version, dev));
+                    }
+                }
+                if !closes_array(rest) {
+                    in_array = Some(dev);
+                }
+            }
+            continue;
+        }
+        let poetry_dev = match section.as_str() {
+            "tool.poetry.dependencies" => Some(false),
+            "tool.poetry.dev-dependencies" => Some(true),
+            s if s.starts_with("tool.poetry.group.") && s.ends_with(".dependencies") => Some(true),
+            _ => None,
+        };
+        if let Some(dev) = poetry_dev {
+            if key == "python" {
+                continue;
+            }
+            let version = if value.starts_with('{') {
+                inline_table_value(value, "version").unwrap_or_default()
+            } else {
+                value.trim_matches('"').to_string()
+            };
+            deps.push((key.to_string(), version, dev));
+        }
+    }
+    deps
+}
+
+/// 行内（引号外）是否出现数组结束符 `]`；`"requests[socks]"` 中的 extras 不算
+fn closes_array(line: &str) -> bool {
+    let mut in_quote = false;
+    for c in line.chars() {
+        match c {
+            '"' | '\'' => in_quote = !in_quote,
+            ']' if !in_quote => return true,
+            _ => {}
+        }
+    }
+    false
+}
+
+/// 解析一条 PEP 508 需求（`requests[socks]>=2.31; python_version>"3.8"`）
+fn parse_python_requirement(line: &str) -> Option<(String, String)> {
+    let line = line.split('#').next()?.trim();
+    if line.is_empty() || line.starts_with('-') {
+        return None;
+    }
+    let line = line.split(';').next()?.trim();
+    let end = line
+        .find(|c: char| !(c.is_ascii_alphanumeric() || matches!(c, '-' | '_' | '.')))
+        .unwrap_or(line.len());
+    let name = &line[..end];
+    if name.is_empty() {
+        return None;
+    }
+    let mut rest = line[end..].trim();
+    if rest.starts_with('[') {
+        rest = rest.split_once(']').map(|(_, r)| r.trim()).unwrap_or("");
+    }
+    Some((name.to_string(), rest.to_string()))
+}
+
+/// pom.xml `<dependencies>` 中的依赖；scope 为 test / provided 视为 dev
+fn maven_dependencies(text: &str) -> Vec<(String, String, bool)> {
+    // 跳过 dependencyManagement（只声明版本，不引入依赖）与插件依赖
+    let text = strip_xml_blocks(text, &["dependencyManagement", "build", "profiles"]);
+    let mut deps = Vec::new();
+    let mut rest = text.as_str();
+    while let Some(block) = xml_block(rest, "dependency") {
+        let group = xml_tag(block, "groupId");
+        let artifact = xml_tag(block, "artifactId");
+        if let (Some(g), Some(a)) = (group, artifact) {
+            let version = xml_tag(block, "version").unwrap_or_default();
+            let scope = xml_tag(block, "scope").unwrap_or_default();
+            let dev = matches!(scope.as_str(), "test" | "provided");
+            deps.push((format!("{}:{}", g, a), version, dev));
+        }
+        let consumed = rest
+            .find("</dependency>")
+            .map(|i| i + "</dependency>".len());
+        match consumed {
+            Some(i) => rest = &rest[i..],
+            None => break,
+        }
+    }
+    deps
+}
+
+/// build.gradle(.kts) 中 `implementation 'g:a:v'` 形式的依赖
+fn gradle_dependencies(text: &str) -> Vec<(String, String, bool)> {
+    const CONFIGS: &[(&str, bool)] = &[
+        ("implementation", false),
+        ("api", false),
+        ("compileOnly", true),
+        ("runtimeOnly", false),
+        ("testImplementation", true),
+        ("testRuntimeOnly", true),
+        ("testCompileOnly", true),
+        ("annotationProcessor", true),
+        ("kapt", true),
+    ];
+    let mut deps = Vec::new();
+    for line in text.lines().map(str::trim) {
+        let Some((_, dev)) = CONFIGS.iter().find(|(c, _)| {
+            line.strip_prefix(c)
+                .map(|r| r.starts_with([' ', '(', '\'', '"']))
+                .unwrap_or(false)
+        }) else {
+            continue;
+        };
+        let Some(start) = line.find(['\'', '"']) else {
+            continue;
+        };
+        let quote = &line[start..start + 1];
+        let inner = &line[start + 1..];
+        let Some(end) = inner.find(quote) else {
+            continue;
+        };
+        let parts: Vec<&str> = inner[..end].split(':').collect();
+        if parts.len() >= 2 {
+            let version = parts.get(2).copied().unwrap_or("").to_string();
+            deps.push((format!("{}:{}", parts[0], parts[1]), version, *dev));
+        }
+    }
+    deps
+}
+
+/// 删除指定标签块（含标签本身），用于只保留 pom.xml 顶层字段
+fn strip_xml_blocks(text: &str, tags: &[&str]) -> String {
+    let mut out = text.to_string();
+    for tag in tags {
+        let open = format!("<{}>", tag);
+        let close = format!("</{}>", tag);
+        while let Some(start) = out.find(&open) {
+            match out[start..].find(&close) {
+                Some(end) => out.replace_range(start..start + end + close.len(), ""),
+                None => break,
+            }
+        }
+    }
+    out
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_go_module_path() {
+        let text = "module github.com/acme/api\n\ngo 1.22\n";
+        assert_eq!(go_module_path(text).as_deref(), Some("github.com/acme/api"));
+        assert_eq!(go_module_path("go 1.22\n"), None);
+    }
+
+    #[test]
+    fn test_maven_coordinate_skips_parent_and_dependencies() {
+        let pom = r#"<project>
+  <parent><groupId>com.acme.parent</groupId><artifactId>base</artifactId></parent>
+  <artifactId>billing</artifactId>
+  <dependencies>
+    <dependency><groupId>junit</groupId><artifactId>junit</artifactId></dependency>
+  </dependencies>
+</project>"#;
+        assert_eq!(
+            maven_coordinate(pom).as_deref(),
+            Some("com.acme.parent:billing")
+        );
+    }
+
+    #[test]
+    fn test_go_requires() {
+        let text = "module x\n\nrequire github.com/pkg/errors v0.9.1\n\nrequire (\n\tgithub.com/gin-gonic/gin v1.9.1\n\tgolang.org/x/sync v0.5.0 // indirect\n)\n";
+        assert_eq!(
+            go_requires(text),
+            vec![
+                ("github.com/pkg/errors".to_string(), "v0.9.1".to_string()),
+                ("github.com/gin-gonic/gin".to_string(), "v1.9.1".to_string()),
+                ("golang.org/x/sync".to_string(), "v0.5.0".to_string()),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_lock_packages() {
+        let text = "version = 3\n\n[[package]]\nname = \"serde\"\nversion = \"1.0.190\"\nsource = \"registry+https://github.com/rust-lang/crates.io-index\"\n\n[[package]]\nname = \"anyhow\"\nversion = \"1.0.75\"\n";
+        assert_eq!(
+            lock_packages(text),
+            vec![
+                ("serde".to_string(), "1.0.190".to_string()),
+                ("anyhow".to_string(), "1.0.75".to_string()),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_toml_string() {
+        let text = "[workspace]\nname = \"nope\"\n\n[package]\nname = \"my-lib\" # comment\nversion = \"0.1.0\"\n";
+        assert_eq!(
+            toml_string(text, "package", "name").as_deref(),
+            Some("my-lib")
+        );
+        assert_eq!(toml_string(text, "project", "name"), None);
+    }
+
+    #[test]
+    fn test_detect_package_aliases() {
+        let dir = std::env::temp_dir().join(format!("codegraph_manifest_{}", std::process::id()));
+        std::fs::create_dir_all(&dir).unwrap();
+        std::fs::write(dir.join("package.json"), r#"{"name": "@acme/ui"}"#).unwrap();
+        std::fs::write(dir.join("Cargo.toml"), "[package]\nname = \"acme-core\"\n").unwrap();
+        assert_eq!(detect_package_aliases(&dir), vec!["@acme/ui", "acme_core"]);
+        let _ = std::fs::remove_dir_all(&dir);
+    }
+
+    fn names(deps: &[(String, String, bool)]) -> Vec<(&str, &str, bool)> {
+        deps.iter()
+            .map(|(n, v, d)| (n.as_str(), v.as_str(), *d))
+            .collect()
+    }
+
+    #[test]
+    fn test_cargo_dependencies() {
+        let text = r#"[package]
+name = "demo"
+
+[dependencies]
+anyhow = "1.0"
+serde = { version = "1", features = ["derive"] }
+
+[dependencies.tokio]
+version = "1.35"
+features = ["full"]
+
+[target.'cfg(unix)'.dependencies]
+libc = "0.2"
+
+[dev-dependencies]
+tempfile = "3"
+"#;
+        assert_eq!(
+            names(&cargo_dependencies(text)),
+            vec![
+                ("anyhow", "1.0", false),
+                ("serde", "1", false),
+                ("tokio", "1.35", false),
+                ("libc", "0.2", false),
+                ("tempfile", "3", true),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_pyproject_dependencies() {
+        let text = r#"[project]
+name = "demo"
+dependencies = [
+    "requests[socks]>=2.31",
+    "PyYAML",
+]
+
+[project.optional-dependencies]
+test = ["pytest>=7"]
+
+[tool.poetry.dependencies]
+python = "^3.10"
+httpx = { version = "^0.25", extras = ["http2"] }
+"#;
+        assert_eq!(
+            names(&pyproject_dependencies(text)),
+            vec![
+                ("requests", ">=2.31", false),
+                ("PyYAML", "", false),
+                ("pytest", ">=7", true),
+                ("httpx", "^0.25", false),
+            ]
+        );
+        assert_eq!(
+            parse_python_requirement("django-cors-headers==4.3.1 ; python_version >= '3.8'"),
+            Some(("django-cors-headers".to_string(), "==4.3.1".to_string()))
+        );
+        assert_eq!(parse_python_requirement("-r base.txt"), None);
+    }
+
+    #[test]
+    fn test_npm_locks() {
+        let lock = r#"{"packages": {"": {}, "node_modules/express": {"version": "4.18.2"},
+            "node_modules/express/node_modules/debug": {"version": "2.6.9"}}}"#;
+        assert_eq!(
+            npm_lock_packages(lock),
+            vec![("express".to_string(), "4.18.2".to_string())]
+        );
+        let yarn = "# yarn lockfile v1\n\n\"@babel/core@^7.0.0\", \"@babel/core@^7.1.0\":\n  version \"7.23.0\"\n\nlodash@^4.17.21:\n  version \"4.17.21\"\n";
+        assert_eq!(
+            yarn_lock_packages(yarn),
+            vec![
+                ("@babel/core".to_string(), "7.23.0".to_string()),
+                ("lodash".to_string(), "4.17.21".to_string()),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_maven_and_gradle_dependencies() {
+        let pom = r#"<project>
+  <dependencies>
+    <dependency><groupId>com.google.guava</groupId><artifactId>guava</artifactId><version>32.1.3-jre</version></dependency>
+    <dependency><groupId>junit</groupId><artifactId>junit</artifactId><version>4.13.2</version><scope>test</scope></dependency>
+  </dependencies>
+  <build><plugins><plugin><dependencies><dependency><groupId>x</groupId><artifactId>y</artifactId></dependency></dependencies></plugin></plugins></build>
+</project>"#;
+        assert_eq!(
+            names(&maven_dependencies(pom)),
+            vec![
+                ("com.google.guava:guava", "32.1.3-jre", false),
+                ("junit:junit", "4.13.2", true),
+            ]
+        );
+        let gradle = "dependencies {\n    implementation 'org.slf4j:slf4j-api:2.0.9'\n    testImplementation(\"org.junit.jupiter:junit-jupiter:5.10.0\")\n}\n";
+        assert_eq!(
+            names(&gradle_dependencies(gradle)),
+            vec![
+                ("org.slf4j:slf4j-api", "2.0.9", false),
+                ("org.junit.jupiter:junit-jupiter", "5.10.0", true),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_project_references() {
+        let gradle = "dependencies {\n    implementation project(':libs:core')\n    api(project(path: \":model\"))\n    // implementation project(':old')\n}\n";
+        assert_eq!(
+            gradle_project_dependencies(gradle),
+            vec![":libs:core".to_string(), ":model".to_string()]
+        );
+
+        let csproj = r#"<Project Sdk="Microsoft.NET.Sdk">
+  <ItemGroup>
+    <ProjectReference Include="..\Acme.Core\Acme.Core.csproj" />
+    <PackageReference Include="Newtonsoft.Json" Version="13.0.1" />
+  </ItemGroup>
+</Project>"#;
+        assert_eq!(csproj_project_references(csproj), vec!["Acme.Core"]);
+    }
+
+    #[test]
+    fn test_declared_dependencies_joins_lockfiles() {
+        let dir = std::env::temp_dir().join(format!("codegraph_declared_{}", std::process::id()));
+        std::fs::create_dir_all(&dir).unwrap();
+        std::fs::write(
+            dir.join("package.json"),
+            r#"{"dependencies": {"express": "^4.18.0"}, "devDependencies": {"jest": "^29"}}"#,
+        )
+        .unwrap();
+        std::fs::write(
+            dir.join("package-lock.json"),
+            r#"{"packages": {"node_modules/express": {"version": "4.18.2"}}}"#,
+        )
+        .unwrap();
+        std::fs::write(
+            dir.join("go.mod"),
+            "module x\n\nrequire (\n\tgithub.com/pkg/errors v0.9.1\n\tgolang.org/x/sys v0.15.0 // indirect\n)\n",
+        )
+        .unwrap();
+
+        let deps = declared_dependencies(&dir);
+        let summary: Vec<(&str, Option<&str>, bool)> = deps
+            .iter()
+            .map(|d| (d.name.as_str(), d.locked.as_deref(), d.dev))
+            .collect();
+        assert_eq!(
+            summary,
+            vec![
+                ("express", Some("4.18.2"), false),
+                ("jest", None, true),
+                ("github.com/pkg/errors", Some("v0.9.1"), false),
+                ("golang.org/x/sys", Some("v0.15.0"), true),
+            ]
+        );
+        let _ = std::fs::remove_dir_all(&dir);
+    }
+}
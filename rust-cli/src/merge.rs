@@ -0,0 +1,363 @@
+/// .codemap/ 三方合并（git merge driver 的核心逻辑）
+///
+/// graph.json / meta.json 以文件条目为单位做三方合并：
+/// 只有一侧改动的条目直接采用该侧；两侧都改动且结果不同的条目视为冲突，
+/// 交给调用方从工作区重新解析（解析不了就删除，留给 `codegraph update` 补齐）。
+/// slices/ 是由 graph.json 派生的数据，两侧都改动时保留 ours 并标记为过期，
+/// 下次 `codegraph update` 会重新生成。
+use std::collections::HashMap;
+use std::path::{Path, PathBuf};
+
+use crate::differ::merge_graph_update;
+use crate::graph::{compute_file_hash, CodeGraph, FileEntry, MetaInfo};
+use crate::scanner::build_file_entry;
+use crate::traverser::{detect_language, effective_language};
+
+/// slices 过期标记文件名（位于 .codemap/ 下，由 update 清除）
+pub const SLICES_STALE_MARKER: &str = "slices.stale";
+
+/// 合并目标类型（由 .codemap/ 内的相对路径决定）
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum MergeKind {
+    Graph,
+    Meta,
+    Slice,
+}
+
+/// 单个条目的三方合并结果
+#[derive(Debug, PartialEq, Eq)]
+enum Pick<T> {
+    Keep(T),
+    Remove,
+    Conflict,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 根据仓库内路径判断合并类型，并返回其所在的项目根目录（.codemap 的父目录）
+///
+/// 非 .codemap/ 下的文件返回 None。
+pub fn classify(repo_path: &str) -> Option<(MergeKind, PathBuf)> {
+    let norm = repo_path.replace('\\', "/");
+    let (root, rest) = match norm.rfind(".codemap/") {
+        Some(0) => (".", &norm[".codemap/".len()..]),
+        Some(i) if norm[..i].ends_with('/') => (&norm[..i - 1], &norm[i + ".codemap/".len()..]),
+        _ => return None,
+    };
+    let kind = match rest {
+        "graph.json" => MergeKind::Graph,
+        "meta.json" => MergeKind::Meta,
+        r if r.starts_with("slices/") && r.ends_with(".json") => MergeKind::Slice,
+        _ => return None,
+    };
+    Some((kind, PathBuf::from(root)))
+}
+
+/// 三方合并 graph.json
+///
+/// `rescan` 为冲突条目提供工作区中的最新解析结果（返回 None 表示删除该条目）。
+/// 返回合并后的图谱以及发生冲突的文件列表（已排序）。
+pub fn merge_graphs(
+    base: &CodeGraph,
+    ours: &CodeGraph,
+    theirs: &CodeGraph,
+    rescan: &dyn Fn(&str) -> Option<FileEntry>,
+) -> (CodeGraph, Vec<String>) {
+    let mut merged = ours.clone();
+    let mut updated: HashMap<String, FileEntry> = HashMap::new();
+    let mut removed: Vec<String> = Vec::new();
+    let mut conflicts: Vec<String> = Vec::new();
+
+    let mut paths: Vec<&String> = ours.files.keys().chain(theirs.files.keys()).collect();
+    paths.sort();
+    paths.dedup();
+
+    for path in paths {
+        let pick = three_way(
+            base.files.get(path),
+            ours.files.get(path),
+            theirs.files.get(path),
+            |a, b| a.hash == b.hash,
+        );
+        let resolved = match pick {
+            Pick::Keep(entry) => Some(entry.clone()),
+            Pick::Remove => None,
+            Pick::Conflict => {
+                conflicts.push(path.clone());
+                rescan(path)
+            }
+        };
+        match resolved {
+            Some(entry) => {
+                // 与 ours 相同的条目无需改动
+                let same_as_ours = ours.files.get(path).is_some_and(|o| o.hash == entry.hash);
+                if !same_as_ours {
+                    updated.insert(path.clone(), entry);
+                }
+            }
+            None => {
+                if ours.files.contains_key(path) {
+                    removed.push(path.clone());
+                }
+            }
+        }
+    }
+
+    merge_graph_update(&mut merged, updated, &removed);
+    // ISO 8601 字符串可直接按字典序比较
+    merged.scanned_at = later(&ours.scanned_at, &theirs.scanned_at);
+    (merged, conflicts)
+}
+
+/// 三方合并 meta.json
+///
+/// `disk_hash` 为冲突条目提供工作区文件的当前哈希（None 表示删除）。
+pub fn merge_meta(
+    base: &MetaInfo,
+    ours: &MetaInfo,
+    theirs: &MetaInfo,
+    disk_hash: &dyn Fn(&str) -> Option<String>,
+) -> MetaInfo {
+    let mut merged = ours.clone();
+    merged.file_hashes.clear();
+
+    let mut paths: Vec<&String> = ours
+        .file_hashes
+        .keys()
+        .chain(theirs.file_hashes.keys())
+        .collect();
+    paths.sort();
+    paths.dedup();
+
+    for path in paths {
+        let pick = three_way(
+            base.file_hashes.get(path),
+            ours.file_hashes.get(path),
+            theirs.file_hashes.get(path),
+            |a, b| a == b,
+        );
+        let resolved = match pick {
+            Pick::Keep(hash) => Some(hash.clone()),
+            Pick::Remove => None,
+            Pick::Conflict => disk_hash(path),
+        };
+        if let Some(hash) = resolved {
+            merged.file_hashes.insert(path.clone(), hash);
+        }
+    }
+
+    merged.last_scan_at = later(&ours.last_scan_at, &theirs.last_scan_at);
+    merged.scan_duration = ours.scan_duration.max(theirs.scan_duration);
+    merged
+}
+
+/// 三方合并派生文件（slices/）：一侧未改动则取另一侧，否则返回 None 交由调用方处理
+pub fn merge_derived<'a>(base: &str, ours: &'a str, theirs: &'a str) -> Option<&'a str> {
+    if ours == theirs || theirs == base {
+        Some(ours)
+    } else if ours == base {
+        Some(theirs)
+    } else {
+        None
+    }
+}
+
+/// 从工作区重新解析单个文件，用于解决冲突条目
+///
+/// 文件不存在、语言不支持或仍带有冲突标记时返回 None。
+pub fn rescan_from_disk(root_dir: &Path, rel_path: &str, graph: &CodeGraph) -> Option<FileEntry> {
+    let abs_path = root_dir.join(rel_path);
+    let content = read_clean(&abs_path)?;
+    let base_lang = detect_language(&abs_path)?;
+    let has_cpp = graph.files.keys().any(|p| {
+        Path::new(p)
+            .extension()
+            .and_then(|e| e.to_str())
+            .is_some_and(|e| matches!(e.to_lowercase().as_str(), "cpp" | "cc" | "cxx"))
+    });
+    let lang = effective_language(&abs_path, base_lang, has_cpp);
+    build_file_entry(&abs_path, root_dir, lang, &content)
+}
+
+/// 工作区文件的当前哈希（规则同 [`rescan_from_disk`]）
+pub fn disk_hash(root_dir: &Path, rel_path: &str) -> Option<String> {
+    read_clean(&root_dir.join(rel_path)).map(|c| compute_file_hash(&c))
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+fn three_way<'a, T>(
+    base: Option<&'a T>,
+    ours: Option<&'a T>,
+    theirs: Option<&'a T>,
+    same: impl Fn(&T, &T) -> bool,
+) -> Pick<&'a T> {
+    let eq = |a: Option<&T>, b: Option<&T>| match (a, b) {
+        (Some(x), Some(y)) => same(x, y),
+        (None, None) => true,
+        _ => false,
+    };
+    let take = |v: Option<&'a T>| match v {
+        Some(x) => Pick::Keep(x),
+        None => Pick::Remove,
+    };
+    if eq(ours, theirs) || eq(theirs, base) {
+        take(ours)
+    } else if eq(ours, base) {
+        take(theirs)
+    } else {
+        Pick::Conflict
+    }
+}
+
+fn later(a: &str, b: &str) -> String {
+    if b > a {
+        b.to_string()
+    } else {
+        a.to_string()
+    }
+}
+
+/// 读取文件内容；带有 git 冲突标记的文件视为不可用
+fn read_clean(path: &Path) -> Option<Vec<u8>> {
+    let content = std::fs::read(path).ok()?;
+    let text = String::from_utf8_lossy(&content);
+    let conflicted = text
+        .lines()
+        .any(|l| l.starts_with("<<<<<<< ") || l.starts_with(">>>>>>> ") || l == "=======");
+    if conflicted {
+        None
+    } else {
+        Some(content)
+    }
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::create_empty_graph;
+
+    fn entry(module: &str, hash: &str) -> FileEntry {
+        FileEntry {
+            language: "typescript".to_string(),
+            module: module.to_string(),
+            hash: hash.to_string(),
+            lines: 1,
+            ..Default::default()
+        }
+    }
+
+    fn graph(files: &[(&str, &str, &str)]) -> CodeGraph {
+        let mut g = create_empty_graph("demo", "/tmp/demo");
+        let mut updated = HashMap::new();
+        for (path, module, hash) in files {
+            updated.insert(path.to_string(), entry(module, hash));
+        }
+        merge_graph_update(&mut g, updated, &[]);
+        g
+    }
+
+    #[test]
+    fn test_classify() {
+        assert_eq!(
+            classify(".codemap/graph.json"),
+            Some((MergeKind::Graph, PathBuf::from(".")))
+        );
+        assert_eq!(
+            classify("web/.codemap/meta.json"),
+            Some((MergeKind::Meta, PathBuf::from("web")))
+        );
+        assert_eq!(
+            classify(".codemap/slices/auth.json").map(|c| c.0),
+            Some(MergeKind::Slice)
+        );
+        assert_eq!(classify("src/graph.json"), None);
+        assert_eq!(classify("x.codemap/graph.json"), None);
+    }
+
+    #[test]
+    fn test_merge_graphs_takes_one_sided_changes() {
+        let base = graph(&[("src/a/x.ts", "a", "h1"), ("src/b/y.ts", "b", "h2")]);
+        // ours 修改 x，theirs 删除 y 并新增 z
+        let ours = graph(&[("src/a/x.ts", "a", "h1-ours"), ("src/b/y.ts", "b", "h2")]);
+        let theirs = graph(&[("src/a/x.ts", "a", "h1"), ("src/c/z.ts", "c", "h3")]);
+
+        let (merged, conflicts) = merge_graphs(&base, &ours, &theirs, &|_| None);
+        assert!(conflicts.is_empty());
+        assert_eq!(merged.files["src/a/x.ts"].hash, "h1-ours");
+        assert!(!merged.files.contains_key("src/b/y.ts"));
+        assert_eq!(merged.files["src/c/z.ts"].hash, "h3");
+        assert_eq!(merged.summary.modules, vec!["a", "c"]);
+    }
+
+    #[test]
+    fn test_merge_graphs_rescans_conflicts() {
+        let base = graph(&[("src/a/x.ts", "a", "h1"), ("src/a/w.ts", "a", "h0")]);
+        let ours = graph(&[("src/a/x.ts", "a", "ours"), ("src/a/w.ts", "a", "ours")]);
+        let theirs = graph(&[("src/a/x.ts", "a", "theirs"), ("src/a/w.ts", "a", "theirs")]);
+
+        let rescan = |path: &str| {
+            if path == "src/a/x.ts" {
+                Some(entry("a", "disk"))
+            } else {
+                None
+            }
+        };
+        let (merged, conflicts) = merge_graphs(&base, &ours, &theirs, &rescan);
+        assert_eq!(conflicts, vec!["src/a/w.ts", "src/a/x.ts"]);
+        assert_eq!(merged.files["src/a/x.ts"].hash, "disk");
+        // 无法重新解析的冲突条目被删除，留给 update 补齐
+        assert!(!merged.files.contains_key("src/a/w.ts"));
+    }
+
+    #[test]
+    fn test_merge_meta() {
+        let meta = |pairs: &[(&str, &str)], at: &str| MetaInfo {
+            last_scan_at: at.to_string(),
+            commit_hash: None,
+            scan_duration: 0,
+            file_hashes: pairs
+                .iter()
+                .map(|(k, v)| (k.to_string(), v.to_string()))
+                .collect(),
+        };
+        let base = meta(&[("a", "1"), ("b", "1")], "2026-01-01T00:00:00.000Z");
+        let ours = meta(&[("a", "2"), ("b", "2")], "2026-01-02T00:00:00.000Z");
+        let theirs = meta(
+            &[("a", "1"), ("b", "3"), ("c", "1")],
+            "2026-01-03T00:00:00.000Z",
+        );
+
+        let merged = merge_meta(&base, &ours, &theirs, &|p| Some(format!("disk-{}", p)));
+        assert_eq!(merged.file_hashes["a"], "2");
+        assert_eq!(merged.file_hashes["b"], "disk-b");
+        assert_eq!(merged.file_hashes["c"], "1");
+        assert_eq!(merged.last_scan_at, "2026-01-03T00:00:00.000Z");
+    }
+
+    #[test]
+    fn test_merge_derived() {
+        assert_eq!(merge_derived("a", "a", "b"), Some("b"));
+        assert_eq!(merge_derived("a", "b", "a"), Some("b"));
+        assert_eq!(merge_derived("a", "b", "b"), Some("b"));
+        assert_eq!(merge_derived("a", "b", "c"), None);
+    }
+
+    #[test]
+    fn test_read_clean_rejects_conflict_markers() {
+        let dir = std::env::temp_dir().join(format!("codegraph_merge_{}", std::process::id()));
+        std::fs::create_dir_all(&dir).unwrap();
+        std::fs::write(dir.join("ok.ts"), "export const a = 1;\n").unwrap();
+        std::fs::write(
+            dir.join("bad.ts"),
+            "<<<<<<< HEAD\nconst a = 1;\n=======\nconst a = 2;\n>>>>>>> other\n",
+        )
+        .unwrap();
+        assert!(disk_hash(&dir, "ok.ts").is_some());
+        assert!(disk_hash(&dir, "bad.ts").is_none());
+        assert!(disk_hash(&dir, "missing.ts").is_none());
+        let _ = std::fs::remove_dir_all(&dir);
+    }
+}
@@ -0,0 +1,583 @@
+/// 模块与符号的持久注释（note 命令）
+///
+/// 每个注释目标一个文件：`.codemap/notes/<id>.json`，便于在 git 中逐条合并。
+/// 目标 ID 由种类与名称计算，不含文件路径，因此文件移动后注释仍能找到目标；
+/// 同名符号以最近一次解析到的文件消歧。目标消失或无法唯一确定时在列表中标出；
+/// 有歧义的目标仍附加到每个候选符号上并标记 `ambiguous`，不会从输出中消失。
+/// 配置键（`config`）是全项目范围的名称，不记录文件，只要仍有文件读取它即可解析。
+use serde::{Deserialize, Serialize};
+use std::path::Path;
+
+use crate::graph::{chrono_now, stable_id, to_record_lines, CodeGraph};
+use crate::query::{ModuleResult, SymbolResult};
+use crate::slicer::{ModuleSlice, Overview};
+
+/// 注释目录名（位于 .codemap/ 下）
+pub const NOTES_DIR: &str = "notes";
+
+/// 可注释的符号种类（与 query --type 一致）
+pub const SYMBOL_KINDS: &[&str] = &["function", "class", "type", "variable", "config"];
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 注释目标：模块或符号
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct NoteTarget {
+    /// module / function / class / type / variable / config
+    pub kind: String,
+    pub name: String,
+    /// 符号所在文件（最近一次解析结果，仅用于同名符号消歧）
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub file: Option<String>,
+}
+
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Note {
+    pub text: String,
+    #[serde(rename = "createdAt")]
+    pub created_at: String,
+}
+
+/// 一个目标的全部注释（对应 notes/ 下的一个文件）
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct NoteEntry {
+    pub id: String,
+    pub target: NoteTarget,
+    pub notes: Vec<Note>,
+}
+
+/// 注释目标在当前图谱中的解析结果
+#[derive(Debug, Clone, PartialEq, Serialize)]
+#[serde(tag = "state", rename_all = "lowercase")]
+pub enum Resolution {
+    /// 找到目标；符号目标附带当前所在文件
+    Found { file: Option<String> },
+    /// 同名符号有多个且都不在记录的文件中
+    Ambiguous { candidates: Vec<String> },
+    /// 目标已不存在
+    Missing,
+}
+
+/// 附加到 query / slice / overview 输出中的注释
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct AttachedNote {
+    pub kind: String,
+    pub name: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub file: Option<String>,
+    pub text: String,
+    /// 目标有歧义：同名符号有多个，注释附加到了每个候选上
+    #[serde(default, skip_serializing_if = "std::ops::Not::not")]
+    pub ambiguous: bool,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 新目标的稳定 ID：`kind + name` 的哈希，不含路径，文件移动后保持不变；
+/// 仅当同名同类符号有多个时才加入文件路径。已有注释的目标沿用原 ID（见 [`add_note`]）
+pub fn note_id(graph: &CodeGraph, target: &NoteTarget) -> String {
+    match &target.file {
+        Some(file) if symbol_files(graph, &target.kind, &target.name).len() > 1 => {
+            stable_id(&["note", &target.kind, &target.name, file])
+        }
+        _ => stable_id(&["note", &target.kind, &target.name]),
+    }
+}
+
+/// 按名称查找可注释的目标：模块名精确匹配，或指定种类（None 为全部种类）的同名符号
+pub fn find_targets(graph: &CodeGraph, name: &str, kind: Option<&str>) -> Vec<NoteTarget> {
+    let mut targets = Vec::new();
+    if matches!(kind, None | Some("module")) && graph.modules.contains_key(name) {
+        targets.push(NoteTarget {
+            kind: "module".to_string(),
+            name: name.to_string(),
+            file: None,
+        });
+    }
+    for k in SYMBOL_KINDS {
+        if kind.is_some_and(|want| want != *k) {
+            continue;
+        }
+        let files = symbol_files(graph, k, name);
+        if *k == "config" {
+            // 配置键与读取它的文件无关，只产生一个目标
+            if !files.is_empty() {
+                targets.push(NoteTarget {
+                    kind: k.to_string(),
+                    name: name.to_string(),
+                    file: None,
+                });
+            }
+            continue;
+        }
+        for file in files {
+            targets.push(NoteTarget {
+                kind: k.to_string(),
+                name: name.to_string(),
+                file: Some(file),
+            });
+        }
+    }
+    targets
+}
+
+/// 在当前图谱中解析注释目标
+pub fn resolve(graph: &CodeGraph, target: &NoteTarget) -> Resolution {
+    if target.kind == "module" {
+        return if graph.modules.contains_key(&target.name) {
+            Resolution::Found { file: None }
+        } else {
+            Resolution::Missing
+        };
+    }
+    let files = symbol_files(graph, &target.kind, &target.name);
+    if target.kind == "config" {
+        return if files.is_empty() {
+            Resolution::Missing
+        } else {
+            Resolution::Found { file: None }
+        };
+    }
+    match (target.file.as_ref(), files.len()) {
+        (_, 0) => Resolution::Missing,
+        (Some(f), _) if files.contains(f) => Resolution::Found {
+            file: Some(f.clone()),
+        },
+        (_, 1) => Resolution::Found {
+            file: files.into_iter().next(),
+        },
+        _ => Resolution::Ambiguous { candidates: files },
+    }
+}
+
+/// 读取 .codemap/notes/ 下的全部注释（目录不存在时返回空），按目标排序
+///
+/// 单个注释文件无法读取或解析时在 stderr 警告并跳过，不影响其余注释。
+pub fn load_notes(output_dir: &Path) -> anyhow::Result<Vec<NoteEntry>> {
+    let dir = output_dir.join(NOTES_DIR);
+    if !dir.exists() {
+        return Ok(vec![]);
+    }
+    let mut entries = Vec::new();
+    for item in std::fs::read_dir(&dir)? {
+        let path = item?.path();
+        if path.extension().and_then(|e| e.to_str()) != Some("json") {
+            continue;
+        }
+        let parsed = std::fs::read_to_string(&path)
+            .map_err(anyhow::Error::from)
+            .and_then(|data| Ok(serde_json::from_str::<NoteEntry>(&data)?));
+        match parsed {
+            Ok(entry) => entries.push(entry),
+            Err(e) => eprintln!(
+                "Warning: skipping invalid note file {}: {}",
+                path.display(),
+                e
+            ),
+        }
+    }
+    entries.sort_by(|a, b| {
+        (&a.target.name, &a.target.kind, &a.target.file).cmp(&(
+            &b.target.name,
+            &b.target.kind,
+            &b.target.file,
+        ))
+    });
+    Ok(entries)
+}
+
+/// 为目标追加一条注释并写回其注释文件；返回注释文件 ID
+pub fn add_note(
+    output_dir: &Path,
+    graph: &CodeGraph,
+    target: &NoteTarget,
+    text: &str,
+) -> anyhow::Result<String> {
+    let id = existing_id(output_dir, graph, target)?.unwrap_or_else(|| note_id(graph, target));
+    let dir = output_dir.join(NOTES_DIR);
+    std::fs::create_dir_all(&dir)?;
+    let path = dir.join(format!("{}.json", id));
+    let mut entry = match std::fs::read_to_string(&path) {
+        Ok(data) => serde_json::from_str(&data)?,
+        Err(_) => NoteEntry {
+            id: id.clone(),
+            target: target.clone(),
+            notes: vec![],
+        },
+    };
+    // 记录最新解析到的文件，移动后的符号在下次消歧时优先匹配
+    entry.target = target.clone();
+    entry.notes.push(Note {
+        text: text.to_string(),
+        created_at: chrono_now(),
+    });
+    std::fs::write(&path, to_record_lines(&entry)?)?;
+    Ok(id)
+}
+
+/// 删除一个目标的全部注释；ID 不存在时返回 false
+pub fn remove_notes(output_dir: &Path, id: &str) -> anyhow::Result<bool> {
+    let path = output_dir.join(NOTES_DIR).join(format!("{}.json", id));
+    if !path.exists() {
+        return Ok(false);
+    }
+    std::fs::remove_file(path)?;
+    Ok(true)
+}
+
+/// 当前图谱中能解析到的全部注释
+///
+/// 已消失的目标不附加；有歧义的目标附加到每个候选符号上并标记 `ambiguous`
+pub fn attached_notes(graph: &CodeGraph, entries: &[NoteEntry]) -> Vec<AttachedNote> {
+    let mut attached = Vec::new();
+    for entry in entries {
+        let (files, ambiguous) = match resolve(graph, &entry.target) {
+            Resolution::Found { file } => (vec![file], false),
+            Resolution::Ambiguous { candidates } => {
+                (candidates.into_iter().map(Some).collect(), true)
+            }
+            Resolution::Missing => continue,
+        };
+        for file in files {
+            for note in &entry.notes {
+                attached.push(AttachedNote {
+                    kind: entry.target.kind.clone(),
+                    name: entry.target.name.clone(),
+                    file: file.clone(),
+                    text: note.text.clone(),
+                    ambiguous,
+                });
+            }
+        }
+    }
+    attached
+}
+
+/// 为符号查询结果附加注释
+pub fn attach_to_symbols(results: &mut [SymbolResult], notes: &[AttachedNote]) {
+    for r in results {
+        r.notes = notes
+            .iter()
+            .filter(|n| {
+                n.kind == r.kind
+                    && n.name == r.name
+                    && (n.file.is_none() || n.file.as_ref() == Some(&r.file))
+            })
+            .map(|n| {
+                if n.ambiguous {
+                    format!("{} (ambiguous target)", n.text)
+                } else {
+                    n.text.clone()
+                }
+            })
+            .collect();
+    }
+}
+
+/// 为模块查询结果附加模块注释
+pub fn attach_to_module(result: &mut ModuleResult, notes: &[AttachedNote]) {
+    result.notes = module_notes(notes, &result.name)
+        .into_iter()
+        .map(|n| n.text.clone())
+        .collect();
+}
+
+/// 为模块切片附加模块注释及其文件中符号的注释
+pub fn attach_to_slice(slice: &mut ModuleSlice, graph: &CodeGraph, notes: &[AttachedNote]) {
+    slice.notes = notes_in_module(graph, notes, &slice.module);
+}
+
+/// 为概览中的每个模块附加注释
+pub fn attach_to_overview(overview: &mut Overview, graph: &CodeGraph, notes: &[AttachedNote]) {
+    for module in &mut overview.modules {
+        module.notes = notes_in_module(graph, notes, &module.name);
+    }
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+/// 已有注释中指向同一目标的条目 ID
+///
+/// 先按记录的文件精确匹配；否则唯一一个同名同类条目仍解析到该文件（符号移动过）时沿用它。
+/// 这样目标后来出现同名符号时，新注释仍写入原来的文件，而不是按新的歧义状态另起一个 ID。
+fn existing_id(
+    output_dir: &Path,
+    graph: &CodeGraph,
+    target: &NoteTarget,
+) -> anyhow::Result<Option<String>> {
+    let same: Vec<NoteEntry> = load_notes(output_dir)?
+        .into_iter()
+        .filter(|e| e.target.kind == target.kind && e.target.name == target.name)
+        .collect();
+    if let Some(e) = same.iter().find(|e| e.target.file == target.file) {
+        return Ok(Some(e.id.clone()));
+    }
+    if let [only] = same.as_slice() {
+        if resolve(graph, &only.target)
+            == (Resolution::Found {
+                file: target.file.clone(),
+            })
+        {
+            return Ok(Some(only.id.clone()));
+        }
+    }
+    Ok(None)
+}
+
+/// 定义了指定种类、名称符号的文件（按路径排序）
+fn symbol_files(graph: &CodeGraph, kind: &str, name: &str) -> Vec<String> {
+    graph
+        .files
+        .iter()
+        .filter(|(_, f)| match kind {
+            "function" => f.functions.iter().any(|s| s.name == name),
+            "class" => f.classes.iter().any(|s| s.name == name),
+            "type" => f.types.iter().any(|s| s.name == name),
+            "variable" => f.variables.iter().any(|s| s.name == name),
+            "config" => f.config_keys.iter().any(|k| k.name == name),
+            _ => false,
+        })
+        .map(|(path, _)| path.clone())
+        .collect()
+}
+
+fn module_notes<'a>(notes: &'a [AttachedNote], module: &str) -> Vec<&'a AttachedNote> {
+    notes
+        .iter()
+        .filter(|n| n.kind == "module" && n.name == module)
+        .collect()
+}
+
+fn notes_in_module(graph: &CodeGraph, notes: &[AttachedNote], module: &str) -> Vec<AttachedNote> {
+    notes
+        .iter()
+        .filter(|n| match &n.file {
+            None if n.kind == "config" => graph
+                .files
+                .values()
+                .any(|f| f.module == module && f.config_keys.iter().any(|k| k.name == n.name)),
+            None => n.kind == "module" && n.name == module,
+            Some(f) => graph.files.get(f).is_some_and(|e| e.module == module),
+        })
+        .cloned()
+        .collect()
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::{create_empty_graph, FileEntry, FunctionInfo, ModuleEntry};
+
+    fn add_file(graph: &mut CodeGraph, path: &str, module: &str, function: &str) {
+        graph.files.insert(
+            path.to_string(),
+            FileEntry {
+                language: "typescript".to_string(),
+                module: module.to_string(),
+                lines: 10,
+                functions: vec![FunctionInfo {
+                    name: function.to_string(),
+                    signature: format!("{}()", function),
+                    start_line: 1,
+                    end_line: 3,
+                }],
+                ..Default::default()
+            },
+        );
+        graph
+            .modules
+            .entry(module.to_string())
+            .or_insert_with(|| ModuleEntry {
+                files: vec![],
+                depends_on: vec![],
+                depended_by: vec![],
+            })
+            .files
+            .push(path.to_string());
+    }
+
+    #[test]
+    fn test_note_survives_move_and_flags_missing() {
+        let root = std::env::temp_dir().join(format!("codegraph_notes_{}", std::process::id()));
+        let _ = std::fs::remove_dir_all(&root);
+        let mut graph = create_empty_graph("p", "/p");
+        add_file(
+            &mut graph,
+            "src/legacy_auth/login.ts",
+            "legacy_auth",
+            "login",
+        );
+
+        let targets = find_targets(&graph, "legacy_auth", None);
+        assert_eq!(targets.len(), 1);
+        add_note(&root, &graph, &targets[0], "deprecated, use auth2").unwrap();
+        let func = find_targets(&graph, "login", Some("function")).remove(0);
+        let id = add_note(&root, &graph, &func, "called by the SSO bridge").unwrap();
+        assert_eq!(id, note_id(&graph, &func));
+
+        // 文件移动到新模块：符号注释仍可解析，模块注释的目标消失
+        graph.files.clear();
+        graph.modules.clear();
+        add_file(&mut graph, "src/auth2/login.ts", "auth2", "login");
+        let entries = load_notes(&root).unwrap();
+        assert_eq!(entries.len(), 2);
+        let states: Vec<Resolution> = entries.iter().map(|e| resolve(&graph, &e.target)).collect();
+        assert!(states.contains(&Resolution::Missing));
+        assert!(states.contains(&Resolution::Found {
+            file: Some("src/auth2/login.ts".to_string())
+        }));
+
+        let notes = attached_notes(&graph, &entries);
+        assert_eq!(notes.len(), 1);
+        let mut overview = crate::slicer::generate_overview(&graph);
+        attach_to_overview(&mut overview, &graph, &notes);
+        assert_eq!(
+            overview.modules[0].notes[0].text,
+            "called by the SSO bridge"
+        );
+
+        assert!(remove_notes(&root, &id).unwrap());
+        assert_eq!(load_notes(&root).unwrap().len(), 1);
+
+        // 损坏的注释文件只跳过它自己
+        std::fs::write(root.join(NOTES_DIR).join("broken.json"), "{ not json").unwrap();
+        assert_eq!(load_notes(&root).unwrap().len(), 1);
+        let _ = std::fs::remove_dir_all(&root);
+    }
+
+    #[test]
+    fn test_config_key_note() {
+        let mut graph = create_empty_graph("p", "/p");
+        add_file(&mut graph, "a/db.ts", "a", "connect");
+        add_file(&mut graph, "b/db.ts", "b", "migrate");
+        for path in ["a/db.ts", "b/db.ts"] {
+            graph.files.get_mut(path).unwrap().config_keys = vec![crate::graph::ConfigKey {
+                name: "DATABASE_URL".to_string(),
+                kind: "env".to_string(),
+                lines: vec![2],
+            }];
+        }
+
+        // 多个文件读取同一配置键时仍只有一个目标，不会被判为歧义
+        let targets = find_targets(&graph, "DATABASE_URL", None);
+        assert_eq!(targets.len(), 1);
+        assert_eq!(targets[0].kind, "config");
+        assert_eq!(targets[0].file, None);
+        assert_eq!(
+            resolve(&graph, &targets[0]),
+            Resolution::Found { file: None }
+        );
+
+        let entries = vec![NoteEntry {
+            id: note_id(&graph, &targets[0]),
+            target: targets[0].clone(),
+            notes: vec![Note {
+                text: "rotated monthly".to_string(),
+                created_at: String::new(),
+            }],
+        }];
+        let notes = attached_notes(&graph, &entries);
+        let opts = crate::query::QueryOptions {
+            type_filter: Some("config".to_string()),
+        };
+        let mut results = crate::query::query_symbol(&graph, "DATABASE_URL", &opts);
+        attach_to_symbols(&mut results, &notes);
+        assert_eq!(results[0].notes, vec!["rotated monthly"]);
+        assert_eq!(notes_in_module(&graph, &notes, "b").len(), 1);
+
+        for f in graph.files.values_mut() {
+            f.config_keys.clear();
+        }
+        assert_eq!(resolve(&graph, &targets[0]), Resolution::Missing);
+    }
+
+    #[test]
+    fn test_note_keeps_id_when_name_becomes_ambiguous() {
+        let root = std::env::temp_dir().join(format!("codegraph_notes_id_{}", std::process::id()));
+        let _ = std::fs::remove_dir_all(&root);
+        let mut graph = create_empty_graph("p", "/p");
+        add_file(&mut graph, "a/util.ts", "a", "parse");
+        let target = find_targets(&graph, "parse", None).remove(0);
+        let id = add_note(&root, &graph, &target, "first").unwrap();
+
+        // 注释文件与 .codemap/ 其他文件一样一条记录一行
+        let written = std::fs::read_to_string(root.join(NOTES_DIR).join(format!("{}.json", id)));
+        assert!(written.unwrap().contains("\n    {\"createdAt\":"));
+
+        // 出现同名符号后再次为原符号添加注释：沿用原 ID，不拆成两个文件
+        add_file(&mut graph, "b/util.ts", "b", "parse");
+        let targets = find_targets(&graph, "parse", None);
+        assert_eq!(targets.len(), 2);
+        assert_ne!(note_id(&graph, &targets[0]), id);
+        assert_eq!(add_note(&root, &graph, &targets[0], "second").unwrap(), id);
+        let entries = load_notes(&root).unwrap();
+        assert_eq!(entries.len(), 1);
+        assert_eq!(entries[0].notes.len(), 2);
+
+        // 另一个同名符号得到自己的 ID
+        assert_ne!(add_note(&root, &graph, &targets[1], "other").unwrap(), id);
+        let _ = std::fs::remove_dir_all(&root);
+    }
+
+    #[test]
+    fn test_ambiguous_notes_are_attached_and_flagged() {
+        let mut graph = create_empty_graph("p", "/p");
+        add_file(&mut graph, "a/util.ts", "a", "parse");
+        add_file(&mut graph, "b/util.ts", "b", "parse");
+        let entries = vec![NoteEntry {
+            id: "n1".to_string(),
+            target: NoteTarget {
+                kind: "function".to_string(),
+                name: "parse".to_string(),
+                file: Some("c/util.ts".to_string()),
+            },
+            notes: vec![Note {
+                text: "handles legacy input".to_string(),
+                created_at: String::new(),
+            }],
+        }];
+        let notes = attached_notes(&graph, &entries);
+        assert_eq!(notes.len(), 2);
+        assert!(notes.iter().all(|n| n.ambiguous));
+        let mut results = crate::query::query_symbol(&graph, "parse", &Default::default());
+        attach_to_symbols(&mut results, &notes);
+        assert_eq!(
+            results[0].notes,
+            vec!["handles legacy input (ambiguous target)"]
+        );
+    }
+
+    #[test]
+    fn test_resolve_ambiguous() {
+        let mut graph = create_empty_graph("p", "/p");
+        add_file(&mut graph, "a/util.ts", "a", "parse");
+        add_file(&mut graph, "b/util.ts", "b", "parse");
+        let target = NoteTarget {
+            kind: "function".to_string(),
+            name: "parse".to_string(),
+            file: Some("c/util.ts".to_string()),
+        };
+        assert_eq!(
+            resolve(&graph, &target),
+            Resolution::Ambiguous {
+                candidates: vec!["a/util.ts".to_string(), "b/util.ts".to_string()]
+            }
+        );
+        let pinned = NoteTarget {
+            file: Some("b/util.ts".to_string()),
+            ..target
+        };
+        assert_ne!(
+            note_id(&graph, &pinned),
+            stable_id(&["note", "function", "parse"])
+        );
+        assert_eq!(
+            resolve(&graph, &pinned),
+            Resolution::Found {
+                file: Some("b/util.ts".to_string())
+            }
+        );
+    }
+}
@@ -0,0 +1,591 @@
+/// 清单定义的包（构建单元）
+///
+/// 扫描 Cargo.toml、package.json、go.mod、pyproject.toml、pom.xml、build.gradle(.kts) 与 *.csproj，
+/// 每个清单所在目录构成一个包节点，源文件归属最内层的包目录。
+/// 清单声明的包间依赖（declaredDependsOn）与源码 import 观察到的包间依赖（observedDependsOn）
+/// 并列保存；两者的差集即 `codegraph check` 报告的越界 import。
+use serde::Serialize;
+use std::collections::{BTreeMap, BTreeSet, HashMap};
+use std::path::Path;
+
+use crate::external::{npm_package, rust_crate};
+use crate::graph::{CodeGraph, PackageEntry};
+use crate::manifest::{
+    csproj_project_references, declared_dependencies, go_module_path, gradle_project_dependencies,
+    maven_coordinate, toml_string,
+};
+use crate::path_utils::{import_lookup, posix_dirname, resolve_relative_import};
+use crate::traverser::{traverse_manifests, TraverseOptions};
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 一条跨包 import
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct PackageEdge {
+    /// 发起 import 的包
+    pub from: String,
+    /// 被 import 的包
+    pub to: String,
+    pub file: String,
+    pub line: u32,
+    /// import 语句中的原始来源
+    pub source: String,
+}
+
+/// 同一目录存在多个清单时按此顺序取第一个（`*.csproj` 排在最后）
+///
+/// package.json 常作为其他生态项目的前端 / 工具链配置出现，因此优先级最低。
+const MANIFEST_PRIORITY: &[&str] = &[
+    "Cargo.toml",
+    "go.mod",
+    "pyproject.toml",
+    "pom.xml",
+    "build.gradle.kts",
+    "build.gradle",
+    "package.json",
+];
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 检测项目中由清单定义的包，并解析清单声明的同仓库包依赖
+///
+/// 返回的包尚未填充 files / modules / observedDependsOn，需再调用 [`refresh_packages`]。
+/// 无 `[package]` 的 Cargo 虚拟清单、无 name 的 package.json 等不构成包。
+/// 清单的遍历与源文件一样遵循 opts 中的符号链接与子模块策略。
+pub fn detect_packages(
+    root_dir: &Path,
+    exclude: &[String],
+    opts: &TraverseOptions,
+) -> BTreeMap<String, PackageEntry> {
+    // 包目录 → (优先级, 清单文件名)
+    let mut by_dir: BTreeMap<String, (usize, String)> = BTreeMap::new();
+    for manifest in traverse_manifests(root_dir, exclude, opts) {
+        let Some(file) = manifest.file_name().and_then(|n| n.to_str()) else {
+            continue;
+        };
+        let priority = MANIFEST_PRIORITY
+            .iter()
+            .position(|name| *name == file)
+            .unwrap_or(MANIFEST_PRIORITY.len());
+        let dir = manifest
+            .parent()
+            .and_then(|p| p.strip_prefix(root_dir).ok())
+            .map(|p| p.to_string_lossy().replace('\\', "/"))
+            .filter(|d| !d.is_empty())
+            .unwrap_or_else(|| ".".to_string());
+        match by_dir.get(&dir) {
+            Some((existing, _)) if *existing <= priority => {}
+            _ => {
+                by_dir.insert(dir, (priority, file.to_string()));
+            }
+        }
+    }
+
+    // 第一遍：包身份与清单中声明的依赖名
+    let mut found: Vec<(String, PackageEntry, Vec<String>)> = Vec::new();
+    for (dir, (_, file)) in by_dir {
+        let abs_dir = if dir == "." {
+            root_dir.to_path_buf()
+        } else {
+            root_dir.join(&dir)
+        };
+        let Ok(text) = std::fs::read_to_string(abs_dir.join(&file)) else {
+            continue;
+        };
+        let Some((kind, name)) = package_identity(&file, &text, &abs_dir) else {
+            continue;
+        };
+        let declared = declared_names(kind, &text, &abs_dir);
+        let manifest = if dir == "." {
+            file.clone()
+        } else {
+            format!("{}/{}", dir, file)
+        };
+        let entry = PackageEntry {
+            kind: kind.to_string(),
+            path: dir,
+            manifest,
+            modules: vec![],
+            files: vec![],
+            declared_depends_on: vec![],
+            observed_depends_on: vec![],
+        };
+        found.push((name, entry, declared));
+    }
+
+    // 同名包（如多个 Gradle 子项目都叫 core）以 "名称 (目录)" 区分
+    let mut name_counts: HashMap<String, usize> = HashMap::new();
+    for (name, _, _) in &found {
+        *name_counts.entry(name.clone()).or_insert(0) += 1;
+    }
+    let mut by_name: HashMap<String, String> = HashMap::new();
+    let mut keyed: Vec<(String, PackageEntry, Vec<String>)> = Vec::new();
+    for (name, entry, declared) in found {
+        let key = if name_counts[&name] > 1 {
+            format!("{} ({})", name, entry.path)
+        } else {
+            by_name.insert(name_key(&name), name.clone());
+            name
+        };
+        keyed.push((key, entry, declared));
+    }
+
+    // 第二遍：声明的依赖名 → 同仓库包
+    let mut packages = BTreeMap::new();
+    for (key, mut entry, declared) in keyed {
+        let deps: BTreeSet<String> = declared
+            .iter()
+            .filter_map(|d| by_name.get(&name_key(d)))
+            .filter(|d| **d != key)
+            .cloned()
+            .collect();
+        entry.declared_depends_on = deps.into_iter().collect();
+        packages.insert(key, entry);
+    }
+    packages
+}
+
+/// 按当前图谱文件重新计算各包的 files / modules / observedDependsOn
+///
+/// 包目录本身不变时无需重新遍历清单，增量更新与合并直接调用此函数。
+pub fn refresh_packages(graph: &mut CodeGraph) {
+    if graph.packages.is_empty() {
+        return;
+    }
+
+    let dirs = package_dirs(graph);
+    let mut files: HashMap<String, Vec<String>> = HashMap::new();
+    let mut modules: HashMap<String, BTreeSet<String>> = HashMap::new();
+    for (path, file) in &graph.files {
+        if let Some(key) = owning_package(&dirs, path) {
+            files.entry(key.to_string()).or_default().push(path.clone());
+            modules
+                .entry(key.to_string())
+                .or_default()
+                .insert(file.module.clone());
+        }
+    }
+    for (key, pkg) in graph.packages.iter_mut() {
+        pkg.files = files.remove(key).unwrap_or_default();
+        pkg.modules = modules
+            .remove(key)
+            .map(|m| m.into_iter().collect())
+            .unwrap_or_default();
+    }
+
+    let mut observed: HashMap<String, BTreeSet<String>> = HashMap::new();
+    for edge in package_edges(graph) {
+        observed.entry(edge.from).or_default().insert(edge.to);
+    }
+    for (key, pkg) in graph.packages.iter_mut() {
+        pkg.observed_depends_on = observed
+            .remove(key)
+            .map(|s| s.into_iter().collect())
+            .unwrap_or_default();
+    }
+}
+
+/// 所有跨包 import（按文件路径、import 顺序排列）
+///
+/// 相对 import 按目标文件所属的包判定；裸 import 按语言匹配同仓库包名：
+/// JS/TS 对应 npm 包名、Go 对应 module 路径前缀、Rust 对应 crate 名、
+/// Python 对应顶层包名、Java 对应包内源码目录。
+pub fn package_edges(graph: &CodeGraph) -> Vec<PackageEdge> {
+    let mut owner: HashMap<&str, &str> = HashMap::new();
+    for (key, pkg) in &graph.packages {
+        for file in &pkg.files {
+            owner.insert(file.as_str(), key.as_str());
+        }
+    }
+    let lookup = import_lookup(graph.files.keys());
+    let index = BareImportIndex::new(graph, &owner);
+
+    let mut edges = Vec::new();
+    for (path, file) in &graph.files {
+        let Some(&from) = owner.get(path.as_str()) else {
+            continue;
+        };
+        for imp in &file.imports {
+            let to = match resolve_relative_import(path, &imp.source, &lookup) {
+                Some(target) => owner.get(target.as_str()).copied(),
+                None => index.resolve(&file.language, &imp.source),
+            };
+            if let Some(to) = to.filter(|to| *to != from) {
+                edges.push(PackageEdge {
+                    from: from.to_string(),
+                    to: to.to_string(),
+                    file: path.clone(),
+                    line: imp.import_line,
+                    source: imp.source.clone(),
+                });
+            }
+        }
+    }
+    edges
+}
+
+/// 绕过声明依赖的跨包 import：源码依赖了某个包，清单却没有声明
+pub fn check_boundaries(graph: &CodeGraph) -> Vec<PackageEdge> {
+    package_edges(graph)
+        .into_iter()
+        .filter(|e| {
+            graph
+                .packages
+                .get(&e.from)
+                .is_some_and(|p| !p.declared_depends_on.contains(&e.to))
+        })
+        .collect()
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+/// 清单中的包类型与包名
+fn package_identity(file: &str, text: &str, dir: &Path) -> Option<(&'static str, String)> {
+    let dir_name = || {
+        dir.file_name()
+            .and_then(|n| n.to_str())
+            .map(|n| n.to_string())
+    };
+    match file {
+        "Cargo.toml" => toml_string(text, "package", "name").map(|n| ("cargo", n)),
+        "go.mod" => go_module_path(text).map(|m| ("go", m)),
+        "pyproject.toml" => toml_string(text, "project", "name")
+            .or_else(|| toml_string(text, "tool.poetry", "name"))
+            .map(|n| ("python", n)),
+        "pom.xml" => maven_coordinate(text).map(|c| ("maven", c)),
+        // Gradle 子项目以目录名作为项目名（与 settings.gradle 的 include 一致）
+        "build.gradle" | "build.gradle.kts" => dir_name().map(|n| ("gradle", n)),
+        "package.json" => serde_json::from_str::<serde_json::Value>(text)
+            .ok()?
+            .get("name")
+            .and_then(|n| n.as_str())
+            .map(|n| ("npm", n.to_string())),
+        _ => file
+            .strip_suffix(".csproj")
+            .map(|stem| ("dotnet", stem.to_string())),
+    }
+}
+
+/// 包目录下清单声明的全部依赖名（含第三方包，稍后只保留同仓库包）
+fn declared_names(kind: &str, text: &str, dir: &Path) -> Vec<String> {
+    if kind == "dotnet" {
+        return csproj_project_references(text);
+    }
+    let mut names: Vec<String> = declared_dependencies(dir)
+        .into_iter()
+        .map(|d| d.name)
+        .collect();
+    // Gradle `project(':libs:core')` 按最后一段匹配子项目目录名
+    for file in ["build.gradle", "build.gradle.kts"] {
+        if let Ok(gradle) = std::fs::read_to_string(dir.join(file)) {
+            names.extend(
+                gradle_project_dependencies(&gradle)
+                    .iter()
+                    .filter_map(|p| p.rsplit(':').next())
+                    .map(|p| p.to_string()),
+            );
+        }
+    }
+    names
+}
+
+/// 包名比较键：忽略大小写，`-` `_` `.` 视为相同（crate 与 PyPI 名在 import 时均写作 `_`）
+fn name_key(name: &str) -> String {
+    name.to_lowercase().replace(['-', '.'], "_")
+}
+
+/// (包目录, 包名) 列表，目录越深越靠前，便于取最内层的包
+fn package_dirs(graph: &CodeGraph) -> Vec<(String, String)> {
+    let mut dirs: Vec<(String, String)> = graph
+        .packages
+        .iter()
+        .map(|(key, pkg)| (pkg.path.clone(), key.clone()))
+        .collect();
+    dirs.sort_by(|a, b| b.0.len().cmp(&a.0.len()).then_with(|| a.0.cmp(&b.0)));
+    dirs
+}
+
+fn owning_package<'a>(dirs: &'a [(String, String)], path: &str) -> Option<&'a str> {
+    dirs.iter()
+        .find(|(dir, _)| {
+            dir == "." || (path.starts_with(dir.as_str()) && path[dir.len()..].starts_with('/'))
+        })
+        .map(|(_, key)| key.as_str())
+}
+
+/// 裸 import → 同仓库包的查找表
+struct BareImportIndex<'a> {
+    /// (包类型, 包名比较键) → 包
+    names: HashMap<(&'a str, String), &'a str>,
+    /// Go module 路径（按长度降序，取最长前缀）
+    go_modules: Vec<(&'a str, &'a str)>,
+    /// Java 源码目录 → 包
+    java_dirs: BTreeMap<&'a str, &'a str>,
+}
+
+impl<'a> BareImportIndex<'a> {
+    fn new(graph: &'a CodeGraph, owner: &HashMap<&'a str, &'a str>) -> Self {
+        let mut names = HashMap::new();
+        let mut go_modules = Vec::new();
+        for (key, pkg) in &graph.packages {
+            names.insert((pkg.kind.as_str(), name_key(key)), key.as_str());
+            if pkg.kind == "go" {
+                go_modules.push((key.as_str(), key.as_str()));
+            }
+        }
+        go_modules.sort_by_key(|(module, _)| std::cmp::Reverse(module.len()));
+
+        let mut java_dirs = BTreeMap::new();
+        for (path, file) in &graph.files {
+            if file.language == "java" {
+                if let Some(&key) = owner.get(path.as_str()) {
+                    java_dirs.insert(posix_dirname(path), key);
+                }
+            }
+        }
+        BareImportIndex {
+            names,
+            go_modules,
+            java_dirs,
+        }
+    }
+
+    fn resolve(&self, language: &str, source: &str) -> Option<&'a str> {
+        let by_name = |kind: &'a str, name: &str| self.names.get(&(kind, name_key(name))).copied();
+        match language {
+            "typescript" | "javascript" => by_name("npm", &npm_package(source)?),
+            "rust" => by_name("cargo", &rust_crate(source)?),
+            "python" => by_name("python", source.split('.').next()?),
+            "go" => self
+                .go_modules
+                .iter()
+                .find(|(module, _)| {
+                    source == *module
+                        || (source.starts_with(module) && source[module.len()..].starts_with('/'))
+                })
+                .map(|(_, key)| *key),
+            "java" => {
+                // 去掉类名 / 通配符，按包路径匹配源码目录后缀
+                let package = match source.rsplit_once('.') {
+                    Some((pkg, last))
+                        if last == "*" || last.starts_with(|c: char| c.is_ascii_uppercase()) =>
+                    {
+                        pkg
+                    }
+                    _ => source,
+                };
+                let suffix = package.replace('.', "/");
+                self.java_dirs
+                    .iter()
+                    .find(|(dir, _)| {
+                        **dir == suffix || dir.ends_with(&format!("/{}", suffix).as_str())
+                    })
+                    .map(|(_, key)| *key)
+            }
+            _ => None,
+        }
+    }
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::differ::merge_graph_update;
+    use crate::graph::create_empty_graph;
+    use crate::graph::fixtures::file_with_imports as file;
+
+    fn package(kind: &str, path: &str, declared: &[&str]) -> PackageEntry {
+        PackageEntry {
+            kind: kind.to_string(),
+            path: path.to_string(),
+            manifest: format!("{}/package.json", path),
+            modules: vec![],
+            files: vec![],
+            declared_depends_on: declared.iter().map(|d| d.to_string()).collect(),
+            observed_depends_on: vec![],
+        }
+    }
+
+    #[test]
+    fn test_detect_packages() {
+        let root = std::env::temp_dir().join(format!("codegraph_pkgs_{}", std::process::id()));
+        let write = |rel: &str, text: &str| {
+            let path = root.join(rel);
+            std::fs::create_dir_all(path.parent().unwrap()).unwrap();
+            std::fs::write(path, text).unwrap();
+        };
+        write("Cargo.toml", "[workspace]\nmembers = [\"crates/*\"]\n");
+        write(
+            "crates/acme-util/Cargo.toml",
+            "[package]\nname = \"acme-util\"\nversion = \"0.1.0\"\n",
+        );
+        write(
+            "crates/acme-cli/Cargo.toml",
+            "[package]\nname = \"acme-cli\"\n\n[dependencies]\nacme-util = { path = \"../acme-util\" }\nserde = \"1\"\n",
+        );
+        write("web/core/package.json", r#"{"name": "@acme/core"}"#);
+        write(
+            "web/app/package.json",
+            r#"{"name": "@acme/app", "dependencies": {"@acme/core": "workspace:*", "react": "^18"}}"#,
+        );
+        write(
+            "web/app/node_modules/react/package.json",
+            r#"{"name": "react"}"#,
+        );
+        write(
+            "dotnet/Api/Api.csproj",
+            r#"<Project><ItemGroup><ProjectReference Include="..\Domain\Domain.csproj" /></ItemGroup></Project>"#,
+        );
+        write("dotnet/Domain/Domain.csproj", "<Project></Project>");
+
+        let packages = detect_packages(&root, &[], &TraverseOptions::default());
+        let _ = std::fs::remove_dir_all(&root);
+
+        let names: Vec<&str> = packages.keys().map(|k| k.as_str()).collect();
+        assert_eq!(
+            names,
+            vec![
+                "@acme/app",
+                "@acme/core",
+                "Api",
+                "Domain",
+                "acme-cli",
+                "acme-util"
+            ]
+        );
+        assert_eq!(packages["acme-cli"].kind, "cargo");
+        assert_eq!(packages["acme-cli"].path, "crates/acme-cli");
+        assert_eq!(packages["acme-cli"].manifest, "crates/acme-cli/Cargo.toml");
+        assert_eq!(packages["acme-cli"].declared_depends_on, vec!["acme-util"]);
+        assert_eq!(
+            packages["@acme/app"].declared_depends_on,
+            vec!["@acme/core"]
+        );
+        assert_eq!(packages["Api"].kind, "dotnet");
+        assert_eq!(packages["Api"].declared_depends_on, vec!["Domain"]);
+        assert!(packages["Domain"].declared_depends_on.is_empty());
+    }
+
+    #[test]
+    fn test_refresh_packages_and_check_boundaries() {
+        let mut graph = create_empty_graph("demo", "");
+        graph.packages.insert(
+            "@acme/app".to_string(),
+            package("npm", "web/app", &["@acme/core"]),
+        );
+        graph
+            .packages
+            .insert("@acme/core".to_string(), package("npm", "web/core", &[]));
+        graph
+            .packages
+            .insert("@acme/utils".to_string(), package("npm", "web/utils", &[]));
+        graph
+            .packages
+            .insert("web".to_string(), package("npm", "web", &[]));
+
+        let mut files = std::collections::HashMap::new();
+        files.insert(
+            "web/app/src/main.ts".to_string(),
+            file(
+                "app",
+                "typescript",
+                &[
+                    "@acme/core/button",
+                    "../../utils/src/fmt",
+                    "./local",
+                    "react",
+                ],
+            ),
+        );
+        files.insert(
+            "web/app/src/local.ts".to_string(),
+            file("app", "typescript", &[]),
+        );
+        files.insert(
+            "web/core/src/button.ts".to_string(),
+            file("core", "typescript", &[]),
+        );
+        files.insert(
+            "web/utils/src/fmt.ts".to_string(),
+            file("utils", "typescript", &[]),
+        );
+        files.insert(
+            "web/scripts/build.ts".to_string(),
+            file("scripts", "typescript", &[]),
+        );
+        merge_graph_update(&mut graph, files, &[]);
+
+        let app = &graph.packages["@acme/app"];
+        assert_eq!(
+            app.files,
+            vec!["web/app/src/local.ts", "web/app/src/main.ts"]
+        );
+        assert_eq!(app.modules, vec!["app"]);
+        assert_eq!(app.observed_depends_on, vec!["@acme/core", "@acme/utils"]);
+        // 不属于任何子包的文件归入外层包
+        assert_eq!(graph.packages["web"].files, vec!["web/scripts/build.ts"]);
+
+        let violations = check_boundaries(&graph);
+        assert_eq!(violations.len(), 1);
+        assert_eq!(violations[0].from, "@acme/app");
+        assert_eq!(violations[0].to, "@acme/utils");
+        assert_eq!(violations[0].file, "web/app/src/main.ts");
+        assert_eq!(violations[0].line, 2);
+    }
+
+    #[test]
+    fn test_bare_imports_by_language() {
+        let mut graph = create_empty_graph("demo", "");
+        graph.packages.insert(
+            "example.com/mono/core".to_string(),
+            package("go", "core", &[]),
+        );
+        graph
+            .packages
+            .insert("acme_util".to_string(), package("cargo", "util", &[]));
+        graph
+            .packages
+            .insert("com.acme:model".to_string(), package("maven", "model", &[]));
+        graph
+            .packages
+            .insert("svc".to_string(), package("go", "svc", &[]));
+
+        let mut files = std::collections::HashMap::new();
+        files.insert(
+            "svc/main.go".to_string(),
+            file("svc", "go", &["example.com/mono/core/db", "fmt"]),
+        );
+        files.insert("core/db/db.go".to_string(), file("core", "go", &[]));
+        files.insert("util/src/lib.rs".to_string(), file("util", "rust", &[]));
+        files.insert(
+            "model/src/main/java/com/acme/model/User.java".to_string(),
+            file("model", "java", &[]),
+        );
+        merge_graph_update(&mut graph, files, &[]);
+
+        let index_owner: HashMap<&str, &str> = graph
+            .packages
+            .iter()
+            .flat_map(|(k, p)| p.files.iter().map(move |f| (f.as_str(), k.as_str())))
+            .collect();
+        let index = BareImportIndex::new(&graph, &index_owner);
+        assert_eq!(index.resolve("rust", "acme_util::fmt"), Some("acme_util"));
+        assert_eq!(
+            index.resolve("java", "com.acme.model.User"),
+            Some("com.acme:model")
+        );
+        assert_eq!(
+            index.resolve("java", "com.acme.model.*"),
+            Some("com.acme:model")
+        );
+        assert_eq!(index.resolve("java", "java.util.List"), None);
+        assert_eq!(index.resolve("python", "acme_util"), None);
+
+        assert_eq!(
+            graph.packages["svc"].observed_depends_on,
+            vec!["example.com/mono/core"]
+        );
+    }
+}
@@ -0,0 +1,591 @@
+/// 最小化 Parquet 写入器
+///
+/// 仅支持导出所需的子集：单 row group、每列单个 PLAIN 编码的 v1 数据页、
+/// 无压缩、所有列均为 REQUIRED。元数据使用 Thrift compact protocol 手工编码，
+/// 不依赖 arrow/parquet crate（与 chrono_now 等实现保持同样的零依赖取向）。
+use std::io::Write;
+
+// ── 列定义 ────────────────────────────────────────────────────────────────────
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ColumnType {
+    Boolean,
+    Int64,
+    Utf8,
+}
+
+#[derive(Debug, Clone, PartialEq)]
+pub enum Value {
+    Bool(bool),
+    Int(i64),
+    Str(String),
+}
+
+/// Parquet 物理类型编号（parquet.thrift `Type`）
+const TYPE_BOOLEAN: i32 = 0;
+const TYPE_INT64: i32 = 2;
+const TYPE_BYTE_ARRAY: i32 = 6;
+
+const REPETITION_REQUIRED: i32 = 0;
+const CONVERTED_UTF8: i32 = 0;
+const ENCODING_PLAIN: i32 = 0;
+const ENCODING_RLE: i32 = 3;
+const CODEC_UNCOMPRESSED: i32 = 0;
+const PAGE_DATA: i32 = 0;
+
+const MAGIC: &[u8] = b"PAR1";
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 将一张表写成 Parquet 文件。
+///
+/// `rows` 中每行的值数量和类型必须与 `columns` 一致，否则返回错误。
+pub fn write_table<W: Write>(
+    out: &mut W,
+    columns: &[(&str, ColumnType)],
+    rows: &[Vec<Value>],
+) -> anyhow::Result<()> {
+    for (i, row) in rows.iter().enumerate() {
+        if row.len() != columns.len() {
+            anyhow::bail!(
+                "row {} has {} values, expected {}",
+                i,
+                row.len(),
+                columns.len()
+            );
+        }
+        for ((name, ty), value) in columns.iter().zip(row) {
+            if !value_matches(*ty, value) {
+                anyhow::bail!("row {}: value for column '{}' has wrong type", i, name);
+            }
+        }
+    }
+
+    let mut buf: Vec<u8> = Vec::new();
+    buf.extend_from_slice(MAGIC);
+
+    // 每列一个 column chunk（无数据时不写 row group）
+    let mut chunks: Vec<ChunkMeta> = Vec::new();
+    if !rows.is_empty() {
+        for (col_idx, (name, ty)) in columns.iter().enumerate() {
+            let data = encode_plain(*ty, rows.iter().map(|r| &r[col_idx]));
+            let header = encode_page_header(data.len(), rows.len());
+            let offset = buf.len() as i64;
+            buf.extend_from_slice(&header);
+            buf.extend_from_slice(&data);
+            chunks.push(ChunkMeta {
+                name: name.to_string(),
+                ty: *ty,
+                offset,
+                size: (header.len() + data.len()) as i64,
+            });
+        }
+    }
+
+    let footer = encode_file_metadata(columns, &chunks, rows.len() as i64);
+    buf.extend_from_slice(&footer);
+    buf.extend_from_slice(&(footer.len() as u32).to_le_bytes());
+    buf.extend_from_slice(MAGIC);
+
+    out.write_all(&buf)?;
+    Ok(())
+}
+
+// ── 内部实现 ──────────────────────────────────────────────────────────────────
+
+struct ChunkMeta {
+    name: String,
+    ty: ColumnType,
+    offset: i64,
+    size: i64,
+}
+
+fn value_matches(ty: ColumnType, value: &Value) -> bool {
+    matches!(
+        (ty, value),
+        (ColumnType::Boolean, Value::Bool(_))
+            | (ColumnType::Int64, Value::Int(_))
+            | (ColumnType::Utf8, Value::Str(_))
+    )
+}
+
+fn physical_type(ty: ColumnType) -> i32 {
+    match ty {
+        ColumnType::Boolean => TYPE_BOOLEAN,
+        ColumnType::Int64 => TYPE_INT64,
+        ColumnType::Utf8 => TYPE_BYTE_ARRAY,
+    }
+}
+
+/// PLAIN 编码：布尔按位打包（LSB 优先），INT64 小端 8 字节，BYTE_ARRAY 为 4 字节长度前缀
+fn encode_plain<'a>(ty: ColumnType, values: impl Iterator<Item = &'a Value>) -> Vec<u8> {
+    let mut out = Vec::new();
+    match ty {
+        ColumnType::Boolean => {
+            let mut byte = 0u8;
+            let mut bit = 0;
+            for v in values {
+                if let Value::Bool(true) = v {
+                    byte |= 1 << bit;
+                }
+                bit += 1;
+                if bit == 8 {
+                    out.push(byte);
+                    byte = 0;
+                    bit = 0;
+                }
+            }
+            if bit > 0 {
+                out.push(byte);
+            }
+        }
+        ColumnType::Int64 => {
+            for v in values {
+                if let Value::Int(n) = v {
+                    out.extend_from_slice(&n.to_le_bytes());
+                }
+            }
+        }
+        ColumnType::Utf8 => {
+            for v in values {
+                if let Value::Str(s) = v {
+                    out.extend_from_slice(&(s.len() as u32).to_le_bytes());
+                    out.extend_from_slice(s.as_bytes());
+                }
+            }
+        }
+    }
+    out
+}
+
+fn encode_page_header(data_len: usize, num_values: usize) -> Vec<u8> {
+    let mut w = CompactWriter::new();
+    w.field_i32(1, PAGE_DATA);
+    w.field_i32(2, data_len as i32);
+    w.field_i32(3, data_len as i32);
+    // data_page_header
+    w.field_struct_begin(5);
+    w.field_i32(1, num_values as i32);
+    w.field_i32(2, ENCODING_PLAIN);
+    w.field_i32(3, ENCODING_RLE);
+    w.field_i32(4, ENCODING_RLE);
+    w.struct_end();
+    w.struct_end();
+    w.into_bytes()
+}
+
+fn encode_file_metadata(
+    columns: &[(&str, ColumnType)],
+    chunks: &[ChunkMeta],
+    num_rows: i64,
+) -> Vec<u8> {
+    let mut w = CompactWriter::new();
+    w.field_i32(1, 1); // version
+
+    // schema：根节点 + 每列一个叶子节点
+    w.field_list_begin(2, CT_STRUCT, columns.len() + 1);
+    w.list_struct_begin();
+    w.field_binary(4, b"schema");
+    w.field_i32(5, columns.len() as i32);
+    w.struct_end();
+    for (name, ty) in columns {
+        w.list_struct_begin();
+        w.field_i32(1, physical_type(*ty));
+        w.field_i32(3, REPETITION_REQUIRED);
+        w.field_binary(4, name.as_bytes());
+        if *ty == ColumnType::Utf8 {
+            w.field_i32(6, CONVERTED_UTF8);
+        }
+        w.struct_end();
+    }
+
+    w.field_i64(3, num_rows);
+
+    // row_groups
+    if chunks.is_empty() {
+        w.field_list_begin(4, CT_STRUCT, 0);
+    } else {
+        w.field_list_begin(4, CT_STRUCT, 1);
+        w.list_struct_begin();
+        w.field_list_begin(1, CT_STRUCT, chunks.len());
+        for chunk in chunks {
+            w.list_struct_begin();
+            w.field_i64(2, chunk.offset);
+            // meta_data
+            w.field_struct_begin(3);
+            w.field_i32(1, physical_type(chunk.ty));
+            w.field_list_begin(2, CT_I32, 2);
+            w.list_i32(ENCODING_PLAIN);
+            w.list_i32(ENCODING_RLE);
+            w.field_list_begin(3, CT_BINARY, 1);
+            w.list_binary(chunk.name.as_bytes());
+            w.field_i32(4, CODEC_UNCOMPRESSED);
+            w.field_i64(5, num_rows);
+            w.field_i64(6, chunk.size);
+            w.field_i64(7, chunk.size);
+            w.field_i64(9, chunk.offset);
+            w.struct_end();
+            w.struct_end();
+        }
+        let total: i64 = chunks.iter().map(|c| c.size).sum();
+        w.field_i64(2, total);
+        w.field_i64(3, num_rows);
+        w.struct_end();
+    }
+
+    w.field_binary(6, b"codegraph");
+    w.struct_end();
+    w.into_bytes()
+}
+
+// ── Thrift compact protocol ───────────────────────────────────────────────────
+
+const CT_I32: u8 = 5;
+const CT_I64: u8 = 6;
+const CT_BINARY: u8 = 8;
+const CT_LIST: u8 = 9;
+const CT_STRUCT: u8 = 12;
+
+/// 仅实现写入 Parquet 元数据所需的 compact protocol 子集
+struct CompactWriter {
+    buf: Vec<u8>,
+    /// 每层 struct 中上一个字段 id（用于字段 id 差值编码）
+    last_field: Vec<i16>,
+}
+
+impl CompactWriter {
+    fn new() -> Self {
+        Self {
+            buf: Vec::new(),
+            last_field: vec![0],
+        }
+    }
+
+    fn into_bytes(self) -> Vec<u8> {
+        self.buf
+    }
+
+    fn field_header(&mut self, id: i16, ty: u8) {
+        let last = self.last_field.last_mut().expect("struct stack empty");
+        let delta = id - *last;
+        if delta > 0 && delta <= 15 {
+            self.buf.push(((delta as u8) << 4) | ty);
+        } else {
+            self.buf.push(ty);
+            write_varint(&mut self.buf, zigzag(id as i64));
+        }
+        *last = id;
+    }
+
+    fn field_i32(&mut self, id: i16, v: i32) {
+        self.field_header(id, CT_I32);
+        write_varint(&mut self.buf, zigzag(v as i64));
+    }
+
+    fn field_i64(&mut self, id: i16, v: i64) {
+        self.field_header(id, CT_I64);
+        write_varint(&mut self.buf, zigzag(v));
+    }
+
+    fn field_binary(&mut self, id: i16, v: &[u8]) {
+        self.field_header(id, CT_BINARY);
+        self.list_binary(v);
+    }
+
+    fn field_struct_begin(&mut self, id: i16) {
+        self.field_header(id, CT_STRUCT);
+        self.last_field.push(0);
+    }
+
+    fn field_list_begin(&mut self, id: i16, elem: u8, size: usize) {
+        self.field_header(id, CT_LIST);
+        if size < 15 {
+            self.buf.push(((size as u8) << 4) | elem);
+        } else {
+            self.buf.push(0xF0 | elem);
+            write_varint(&mut self.buf, size as u64);
+        }
+    }
+
+    fn list_struct_begin(&mut self) {
+        self.last_field.push(0);
+    }
+
+    fn list_i32(&mut self, v: i32) {
+        write_varint(&mut self.buf, zigzag(v as i64));
+    }
+
+    fn list_binary(&mut self, v: &[u8]) {
+        write_varint(&mut self.buf, v.len() as u64);
+        self.buf.extend_from_slice(v);
+    }
+
+    fn struct_end(&mut self) {
+        self.buf.push(0);
+        self.last_field.pop();
+    }
+}
+
+fn zigzag(v: i64) -> u64 {
+    ((v << 1) ^ (v >> 63)) as u64
+}
+
+fn write_varint(buf: &mut Vec<u8>, mut v: u64) {
+    loop {
+        if v < 0x80 {
+            buf.push(v as u8);
+            return;
+        }
+        buf.push((v as u8 & 0x7F) | 0x80);
+        v >>= 7;
+    }
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn footer_len(bytes: &[u8]) -> usize {
+        let n = bytes.len();
+        u32::from_le_bytes([bytes[n - 8], bytes[n - 7], bytes[n - 6], bytes[n - 5]]) as usize
+    }
+
+    #[test]
+    fn test_zigzag_varint() {
+        let mut buf = Vec::new();
+        write_varint(&mut buf, zigzag(1));
+        write_varint(&mut buf, zigzag(-1));
+        write_varint(&mut buf, zigzag(300));
+        assert_eq!(buf, vec![0x02, 0x01, 0xD8, 0x04]);
+    }
+
+    #[test]
+    fn test_write_table_layout() {
+        let columns = [
+            ("id", ColumnType::Utf8),
+            ("lines", ColumnType::Int64),
+            ("entry", ColumnType::Boolean),
+        ];
+        let rows = vec![
+            vec![Value::Str("a".into()), Value::Int(10), Value::Bool(true)],
+            vec![Value::Str("bc".into()), Value::Int(-2), Value::Bool(false)],
+        ];
+        let mut out = Vec::new();
+        write_table(&mut out, &columns, &rows).unwrap();
+        assert_eq!(&out[..4], MAGIC);
+        assert_eq!(&out[out.len() - 4..], MAGIC);
+        let flen = footer_len(&out);
+        assert!(flen > 0 && flen < out.len() - 12);
+    }
+
+    /// 测试用 compact protocol 解码结果（只覆盖写入器用到的类型）
+    #[derive(Debug, Clone, PartialEq)]
+    enum Thrift {
+        Int(i64),
+        Binary(Vec<u8>),
+        List(Vec<Thrift>),
+        Struct(std::collections::BTreeMap<i16, Thrift>),
+    }
+
+    impl Thrift {
+        fn field(&self, id: i16) -> &Thrift {
+            match self {
+                Thrift::Struct(fields) => fields.get(&id).expect("missing field"),
+                other => panic!("not a struct: {:?}", other),
+            }
+        }
+
+        fn int(&self) -> i64 {
+            match self {
+                Thrift::Int(v) => *v,
+                other => panic!("not an int: {:?}", other),
+            }
+        }
+
+        fn text(&self) -> String {
+            match self {
+                Thrift::Binary(b) => String::from_utf8(b.clone()).unwrap(),
+                other => panic!("not binary: {:?}", other),
+            }
+        }
+
+        fn items(&self) -> &[Thrift] {
+            match self {
+                Thrift::List(items) => items,
+                other => panic!("not a list: {:?}", other),
+            }
+        }
+    }
+
+    struct Reader<'a> {
+        bytes: &'a [u8],
+        pos: usize,
+    }
+
+    impl Reader<'_> {
+        fn byte(&mut self) -> u8 {
+            self.pos += 1;
+            self.bytes[self.pos - 1]
+        }
+
+        fn varint(&mut self) -> u64 {
+            let (mut v, mut shift) = (0u64, 0);
+            loop {
+                let b = self.byte();
+                v |= ((b & 0x7F) as u64) << shift;
+                if b & 0x80 == 0 {
+                    return v;
+                }
+                shift += 7;
+            }
+        }
+
+        fn zigzag(&mut self) -> i64 {
+            let v = self.varint();
+            ((v >> 1) as i64) ^ -((v & 1) as i64)
+        }
+
+        fn value(&mut self, ty: u8) -> Thrift {
+            match ty {
+                CT_I32 | CT_I64 => Thrift::Int(self.zigzag()),
+                CT_BINARY => {
+                    let len = self.varint() as usize;
+                    self.pos += len;
+                    Thrift::Binary(self.bytes[self.pos - len..self.pos].to_vec())
+                }
+                CT_LIST => {
+                    let head = self.byte();
+                    let size = match head >> 4 {
+                        15 => self.varint() as usize,
+                        n => n as usize,
+                    };
+                    Thrift::List((0..size).map(|_| self.value(head & 0x0F)).collect())
+                }
+                CT_STRUCT => {
+                    let mut fields = std::collections::BTreeMap::new();
+                    let mut last = 0i16;
+                    loop {
+                        let head = self.byte();
+                        if head == 0 {
+                            return Thrift::Struct(fields);
+                        }
+                        let id = match head >> 4 {
+                            0 => self.zigzag() as i16,
+                            delta => last + delta as i16,
+                        };
+                        fields.insert(id, self.value(head & 0x0F));
+                        last = id;
+                    }
+                }
+                other => panic!("unexpected thrift type {}", other),
+            }
+        }
+    }
+
+    /// 解码 bytes[pos..] 处的一个 struct，返回解码结果与结束位置
+    fn read_struct(bytes: &[u8], pos: usize) -> (Thrift, usize) {
+        let mut r = Reader { bytes, pos };
+        let value = r.value(CT_STRUCT);
+        (value, r.pos)
+    }
+
+    #[test]
+    fn test_footer_metadata_roundtrip() {
+        let columns = [
+            ("id", ColumnType::Utf8),
+            ("lines", ColumnType::Int64),
+            ("entry", ColumnType::Boolean),
+        ];
+        let rows = vec![
+            vec![Value::Str("a".into()), Value::Int(10), Value::Bool(true)],
+            vec![Value::Str("bc".into()), Value::Int(-2), Value::Bool(false)],
+            vec![Value::Str("".into()), Value::Int(7), Value::Bool(true)],
+        ];
+        let mut out = Vec::new();
+        write_table(&mut out, &columns, &rows).unwrap();
+
+        let footer_start = out.len() - 8 - footer_len(&out);
+        let (meta, end) = read_struct(&out, footer_start);
+        assert_eq!(end, out.len() - 8);
+        assert_eq!(meta.field(1).int(), 1);
+        assert_eq!(meta.field(3).int(), 3);
+        assert_eq!(meta.field(6).text(), "codegraph");
+
+        // schema：根节点记录子节点数，叶子节点依次为各列
+        let schema = meta.field(2).items();
+        assert_eq!(schema.len(), 4);
+        assert_eq!(schema[0].field(4).text(), "schema");
+        assert_eq!(schema[0].field(5).int(), 3);
+        let leaves: Vec<(String, i64)> = schema[1..]
+            .iter()
+            .map(|s| (s.field(4).text(), s.field(1).int()))
+            .collect();
+        assert_eq!(
+            leaves,
+            vec![
+                ("id".to_string(), TYPE_BYTE_ARRAY as i64),
+                ("lines".to_string(), TYPE_INT64 as i64),
+                ("entry".to_string(), TYPE_BOOLEAN as i64),
+            ]
+        );
+        assert_eq!(schema[1].field(6).int(), CONVERTED_UTF8 as i64);
+
+        // row group：column chunk 首尾相接，覆盖 magic 与 footer 之间的全部字节
+        let row_groups = meta.field(4).items();
+        assert_eq!(row_groups.len(), 1);
+        assert_eq!(row_groups[0].field(3).int(), 3);
+        let chunks = row_groups[0].field(1).items();
+        assert_eq!(chunks.len(), 3);
+        let mut expected_offset = MAGIC.len() as i64;
+        let mut total = 0;
+        for (chunk, (name, ty)) in chunks.iter().zip(&columns) {
+            let cm = chunk.field(3);
+            assert_eq!(chunk.field(2).int(), expected_offset);
+            assert_eq!(cm.field(9).int(), expected_offset);
+            assert_eq!(cm.field(1).int(), physical_type(*ty) as i64);
+            assert_eq!(cm.field(3).items()[0].text(), *name);
+            assert_eq!(cm.field(4).int(), CODEC_UNCOMPRESSED as i64);
+            assert_eq!(cm.field(5).int(), 3);
+
+            // 数据页：页头记录的大小与值个数，页体为 PLAIN 编码的列值
+            let (page, data_start) = read_struct(&out, expected_offset as usize);
+            let data_len = page.field(2).int();
+            assert_eq!(page.field(1).int(), PAGE_DATA as i64);
+            assert_eq!(page.field(5).field(1).int(), 3);
+            let col = columns.iter().position(|(n, _)| n == name).unwrap();
+            let data = &out[data_start..data_start + data_len as usize];
+            assert_eq!(data, encode_plain(*ty, rows.iter().map(|r| &r[col])));
+            let size = (data_start as i64 - expected_offset) + data_len;
+            assert_eq!(cm.field(6).int(), size);
+            expected_offset += size;
+            total += size;
+        }
+        assert_eq!(expected_offset as usize, footer_start);
+        assert_eq!(row_groups[0].field(2).int(), total);
+    }
+
+    #[test]
+    fn test_write_empty_table() {
+        let columns = [("id", ColumnType::Utf8)];
+        let mut out = Vec::new();
+        write_table(&mut out, &columns, &[]).unwrap();
+        // 空表只有 magic + footer
+        assert_eq!(out.len(), 4 + footer_len(&out) + 8);
+    }
+
+    #[test]
+    fn test_write_table_rejects_mismatched_row() {
+        let columns = [("id", ColumnType::Utf8)];
+        let rows = vec![vec![Value::Int(1)]];
+        let mut out = Vec::new();
+        assert!(write_table(&mut out, &columns, &rows).is_err());
+    }
+
+    #[test]
+    fn test_boolean_bit_packing() {
+        let vals = [Value::Bool(true), Value::Bool(false), Value::Bool(true)];
+        assert_eq!(encode_plain(ColumnType::Boolean, vals.iter()), vec![0b101]);
+    }
+}
@@ -1,3 +1,4 @@
+use std::collections::HashMap;
 use std::path::Path;
 
 /// 去掉路径的文件扩展名（posix 风格字符串）
@@ -40,9 +41,61 @@ pub fn posix_normalize(path: &str) -> String {
 }
 
 /// 将 Path 规范化为 posix 风格字符串（解析 `..` 和 `.`，统一为 `/` 分隔符）
+///
+/// 绝对路径保留开头的 `/`，与 scan 中以绝对路径为键的查找表一致。
 pub fn normalize_path(path: &Path) -> String {
     let raw = path.to_string_lossy().replace('\\', "/");
-    posix_normalize(&raw)
+    let normalized = posix_normalize(&raw);
+    if raw.starts_with('/') {
+        format!("/{}", normalized)
+    } else {
+        normalized
+    }
+}
+
+/// 构建相对 import 的查找表：文件路径及其无扩展名形式 → 实际文件路径
+pub fn import_lookup<'a>(paths: impl IntoIterator<Item = &'a String>) -> HashMap<String, String> {
+    let mut lookup: HashMap<String, String> = HashMap::new();
+    for path in paths {
+        lookup.insert(path.to_string(), path.to_string());
+        lookup
+            .entry(strip_extension(path))
+            .or_insert_with(|| path.to_string());
+    }
+    lookup
+}
+
+/// 是否为按文件路径解析的相对 import（JS/TS 的 `./x`、C/C++ 的 `"../x.h"`）
+///
+/// Python 的 `.models` / `..pkg` 是包内相对导入，按包而非路径解析，不属于此类。
+/// 带非源码扩展名的导入（`./styles.css`、`./logo.svg`、`./data.json`）指向图谱之外的资源，同样不算。
+pub fn is_path_import(language: &str, source: &str) -> bool {
+    if !source.starts_with('.') || language == "python" {
+        return false;
+    }
+    let name = source.rsplit('/').next().unwrap_or(source);
+    if name == "." || name == ".." || !name.contains('.') {
+        return true;
+    }
+    crate::traverser::detect_language(Path::new(name)).is_some()
+}
+
+/// 解析相对导入到图谱内的文件路径（与 differ 的解析规则一致：直接匹配，其次 `/index`）
+///
+/// 非 `.` 开头的导入或找不到目标文件时返回 None。
+pub fn resolve_relative_import(
+    importer: &str,
+    source: &str,
+    lookup: &HashMap<String, String>,
+) -> Option<String> {
+    if !source.starts_with('.') {
+        return None;
+    }
+    let resolved = posix_normalize(&format!("{}/{}", posix_dirname(importer), source));
+    lookup
+        .get(&resolved)
+        .or_else(|| lookup.get(&format!("{}/index", resolved)))
+        .cloned()
 }
 
 // ── 测试 ──────────────────────────────────────────────────────────────────────
@@ -79,5 +132,43 @@ mod tests {
     fn test_normalize_path() {
         let p = std::path::Path::new("src/auth/../utils/helper");
         assert_eq!(normalize_path(p), "src/utils/helper");
+        let abs = std::path::Path::new("/repo/src/auth/../utils/helper");
+        assert_eq!(normalize_path(abs), "/repo/src/utils/helper");
+    }
+
+    #[test]
+    fn test_is_path_import() {
+        assert!(is_path_import("typescript", "./utils"));
+        assert!(is_path_import("typescript", "../auth/login.js"));
+        assert!(is_path_import("typescript", ".."));
+        assert!(is_path_import("cpp", "../include/x.h"));
+        assert!(!is_path_import("typescript", "./styles.css"));
+        assert!(!is_path_import("typescript", "./logo.svg"));
+        assert!(!is_path_import("javascript", "../data.json"));
+        assert!(!is_path_import("typescript", "./pkg_bg.wasm"));
+        assert!(!is_path_import("python", ".models"));
+        assert!(!is_path_import("typescript", "react"));
+    }
+
+    #[test]
+    fn test_resolve_relative_import() {
+        let paths = vec![
+            "src/auth/login.ts".to_string(),
+            "src/utils/index.ts".to_string(),
+        ];
+        let lookup = import_lookup(&paths);
+        assert_eq!(
+            resolve_relative_import("src/auth/login.ts", "../utils", &lookup).as_deref(),
+            Some("src/utils/index.ts")
+        );
+        assert_eq!(
+            resolve_relative_import("src/utils/index.ts", "../auth/login", &lookup).as_deref(),
+            Some("src/auth/login.ts")
+        );
+        assert_eq!(
+            resolve_relative_import("src/auth/login.ts", "./missing", &lookup),
+            None
+        );
+        assert_eq!(resolve_relative_import("src/a.ts", "react", &lookup), None);
     }
 }
@@ -2,61 +2,77 @@
 ///
 /// 在 CodeGraph 中按名称搜索函数、类、类型，支持模糊匹配和类型过滤。
 /// 逻辑与 ccplugin/cli/src/query.js 保持一致。
+use crate::config_keys::collect_config_symbols;
 use crate::graph::{CodeGraph, FileEntry};
+use serde::Serialize;
 
 // ── 查询结果结构 ──────────────────────────────────────────────────────────────
 
-#[derive(Debug, Clone)]
+#[derive(Debug, Clone, Serialize)]
 pub struct LineRange {
     pub start: u32,
     pub end: u32,
 }
 
-#[derive(Debug, Clone)]
+#[derive(Debug, Clone, Serialize)]
 pub struct CallerRef {
     pub file: String,
     pub module: String,
+    #[serde(rename = "importLine")]
     pub import_line: u32,
+    #[serde(rename = "useLines")]
     pub use_lines: Vec<u32>,
 }
 
-#[derive(Debug, Clone)]
+#[derive(Debug, Clone, Serialize)]
 pub struct SymbolResult {
-    pub kind: String, // "function" | "class" | "type" | "variable"
+    pub kind: String, // "function" | "class" | "type" | "variable" | "config"
     pub name: String,
     pub signature: Option<String>,
     pub file: String,
     pub module: String,
     pub lines: LineRange,
     /// 同文件中导入的其他符号（非自身）
+    #[serde(rename = "fileImports")]
     pub file_imports: Vec<String>,
     /// 导入了该符号的其他文件（"module:file" 格式）— 向后兼容
+    #[serde(rename = "importedBy")]
     pub imported_by: Vec<String>,
     /// 行号级引用详情
+    #[serde(rename = "importedByRefs")]
     pub imported_by_refs: Vec<CallerRef>,
+    /// 持久注释（`codegraph note add`）
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub notes: Vec<String>,
 }
 
-#[derive(Debug, Clone)]
+#[derive(Debug, Clone, Serialize)]
 pub struct ModuleResult {
     pub name: String,
     pub files: Vec<String>,
+    #[serde(rename = "dependsOn")]
     pub depends_on: Vec<String>,
+    #[serde(rename = "dependedBy")]
     pub depended_by: Vec<String>,
+    /// 持久注释（`codegraph note add`）
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub notes: Vec<String>,
 }
 
 // ── 查询选项 ──────────────────────────────────────────────────────────────────
 
 #[derive(Debug, Default)]
 pub struct QueryOptions {
-    /// 限制搜索类型："function" | "class" | "type"，None 表示全部
+    /// 限制搜索类型："function" | "class" | "type" | "variable" | "config"，None 表示全部
     pub type_filter: Option<String>,
 }
 
 // ── 核心查询函数 ──────────────────────────────────────────────────────────────
 
-/// 在图谱中搜索匹配的符号（函数、类、类型）。
+/// 在图谱中搜索匹配的符号（函数、类、类型、变量、配置键）。
 ///
 /// 匹配规则：符号名称等于 symbol_name，或包含 symbol_name（子串匹配）。
+/// 配置键（环境变量等）没有定义位置：结果指向第一个读取文件，全部读取位置列在 usedAt 中。
 pub fn query_symbol(
     graph: &CodeGraph,
     symbol_name: &str,
@@ -86,6 +102,7 @@ pub fn query_symbol(
                         file_imports,
                         imported_by,
                         imported_by_refs,
+                        notes: vec![],
                     });
                 }
             }
@@ -109,6 +126,7 @@ pub fn query_symbol(
                         file_imports: vec![],
                         imported_by,
                         imported_by_refs,
+                        notes: vec![],
                     });
                 }
             }
@@ -132,6 +150,7 @@ pub fn query_symbol(
                         file_imports: vec![],
                         imported_by,
                         imported_by_refs,
+                        notes: vec![],
                     });
                 }
             }
@@ -155,12 +174,47 @@ pub fn query_symbol(
                         file_imports: vec![],
                         imported_by,
                         imported_by_refs,
+                        notes: vec![],
                     });
                 }
             }
         }
     }
 
+    // 搜索配置键
+    if type_filter.is_none() || type_filter == Some("config") {
+        for sym in collect_config_symbols(graph) {
+            if !matches_symbol(&sym.name, symbol_name) {
+                continue;
+            }
+            let first = &sym.sites[0];
+            results.push(SymbolResult {
+                kind: "config".into(),
+                name: sym.name.clone(),
+                signature: Some(format!("{} {}", sym.kind, sym.name)),
+                file: first.file.clone(),
+                module: first.module.clone(),
+                lines: LineRange {
+                    start: first.lines.first().copied().unwrap_or(0),
+                    end: first.lines.last().copied().unwrap_or(0),
+                },
+                file_imports: vec![],
+                imported_by: vec![],
+                imported_by_refs: sym
+                    .sites
+                    .iter()
+                    .map(|site| CallerRef {
+                        file: site.file.clone(),
+                        module: site.module.clone(),
+                        import_line: 0,
+                        use_lines: site.lines.clone(),
+                    })
+                    .collect(),
+                notes: vec![],
+            });
+        }
+    }
+
     // 按文件路径排序，保证输出稳定
     results.sort_by(|a, b| a.file.cmp(&b.file).then(a.name.cmp(&b.name)));
     results
@@ -174,6 +228,7 @@ pub fn query_module(graph: &CodeGraph, module_name: &str) -> Option<ModuleResult
         files: mod_data.files.clone(),
         depends_on: mod_data.depends_on.clone(),
         depended_by: mod_data.depended_by.clone(),
+        notes: vec![],
     })
 }
 
@@ -215,7 +270,7 @@ fn collect_file_imports(file_data: &FileEntry, self_name: &str) -> Vec<String> {
 
 /// 查找导入了指定符号的其他文件
 /// 返回 (旧格式 "module:file" 列表, 新格式 CallerRef 列表)
-fn find_callers(
+pub fn find_callers(
     graph: &CodeGraph,
     source_file: &str,
     symbol_name: &str,
@@ -286,6 +341,9 @@ pub fn format_symbol_results(results: &[SymbolResult]) -> String {
             }
         }
         out.push_str(&format!("  module:    {}\n", r.module));
+        for note in &r.notes {
+            out.push_str(&format!("  note:      {}\n", note));
+        }
         out.push_str(&format!("  lines:     {}-{}\n", r.lines.start, r.lines.end));
         // 同文件使用引用（import_line == 0）
         let local_refs: Vec<&CallerRef> = r
@@ -328,6 +386,9 @@ pub fn format_symbol_results(results: &[SymbolResult]) -> String {
 /// 将模块查询结果格式化为人类可读的文本
 pub fn format_module_result(result: &ModuleResult) -> String {
     let mut out = format!("module: {}\n", result.name);
+    for note in &result.notes {
+        out.push_str(&format!("  note: {}\n", note));
+    }
     out.push_str(&format!("  files ({}):\n", result.files.len()));
     for f in &result.files {
         out.push_str(&format!("    {}\n", f));
@@ -353,10 +414,10 @@ mod tests {
         ClassInfo, CodeGraph, FileEntry, FunctionInfo, GraphConfig, GraphSummary, ImportInfo,
         ModuleEntry, ProjectInfo, TypeInfo, VariableInfo,
     };
-    use std::collections::HashMap;
+    use std::collections::BTreeMap;
 
     fn make_graph() -> CodeGraph {
-        let mut files = HashMap::new();
+        let mut files = BTreeMap::new();
 
         // auth/login.ts
         files.insert(
@@ -404,8 +465,7 @@ mod tests {
                     import_line: 0,
                 }],
                 exports: vec!["login".into(), "logout".into(), "AuthService".into()],
-                is_entry_point: false,
-                symbol_refs: std::collections::BTreeMap::new(),
+                ..Default::default()
             },
         );
 
@@ -423,17 +483,12 @@ mod tests {
                     start_line: 1,
                     end_line: 8,
                 }],
-                classes: vec![],
-                types: vec![],
-                variables: vec![],
-                imports: vec![],
                 exports: vec!["hashPassword".into()],
-                is_entry_point: false,
-                symbol_refs: std::collections::BTreeMap::new(),
+                ..Default::default()
             },
         );
 
-        let mut modules = HashMap::new();
+        let mut modules = BTreeMap::new();
         modules.insert(
             "auth".into(),
             ModuleEntry {
@@ -462,18 +517,25 @@ mod tests {
             config: GraphConfig {
                 languages: vec![],
                 exclude_patterns: vec![],
+                follow_symlinks: false,
+                submodules: Default::default(),
+                submodule_paths: vec![],
             },
             summary: GraphSummary {
                 total_files: 2,
                 total_functions: 3,
                 total_classes: 1,
                 total_variables: 0,
-                languages: HashMap::new(),
+                languages: BTreeMap::new(),
                 modules: vec!["auth".into(), "utils".into()],
                 entry_points: vec![],
             },
             modules,
             files,
+            packages: BTreeMap::new(),
+            broken_imports: vec![],
+            binding_edges: vec![],
+            targets: vec![],
         }
     }
 
@@ -534,6 +596,29 @@ mod tests {
         assert_eq!(results[0].kind, "type");
     }
 
+    #[test]
+    fn test_query_config_key() {
+        let mut graph = make_graph();
+        for (file, lines) in [("auth/login.ts", vec![8, 12]), ("utils/helper.ts", vec![3])] {
+            graph.files.get_mut(file).unwrap().config_keys = vec![crate::graph::ConfigKey {
+                name: "AUTH_SECRET".into(),
+                kind: "env".into(),
+                lines,
+            }];
+        }
+        let opts = QueryOptions {
+            type_filter: Some("config".into()),
+        };
+        let results = query_symbol(&graph, "SECRET", &opts);
+        assert_eq!(results.len(), 1);
+        assert_eq!(results[0].kind, "config");
+        assert_eq!(results[0].signature.as_deref(), Some("env AUTH_SECRET"));
+        assert_eq!(results[0].file, "auth/login.ts");
+        assert_eq!(results[0].lines.end, 12);
+        assert_eq!(results[0].imported_by_refs.len(), 2);
+        assert_eq!(results[0].imported_by_refs[1].use_lines, vec![3]);
+    }
+
     #[test]
     fn test_query_no_match() {
         let graph = make_graph();
@@ -0,0 +1,372 @@
+/// 本机项目注册表
+///
+/// 每次 `scan` / `update` 完成后，把项目的路径、名称、扫描时间与语言记录到
+/// `$CODEMAP_HOME/projects.json`（默认 `~/.codemap/projects.json`）。
+/// `projects` 据此列出本机全部已扫描项目及其新鲜度，`search --all` 在所有项目的图谱中查找符号。
+///
+/// 注册表只是索引：图谱仍保存在各项目自己的 `.codemap/` 中，目录被删除的项目显示为 missing。
+use serde::{Deserialize, Serialize};
+use std::path::{Path, PathBuf};
+use std::time::{Duration, Instant};
+
+use crate::freshness::check_freshness;
+use crate::graph::{load_graph, load_meta, CodeGraph};
+use crate::query::{query_symbol, QueryOptions, SymbolResult};
+
+/// 注册表文件名（位于 $CODEMAP_HOME 下）
+pub const REGISTRY_FILE: &str = "projects.json";
+
+/// 注册表锁文件名：存在即表示有进程正在改写注册表
+const LOCK_FILE: &str = "projects.json.lock";
+
+/// 等待注册表锁的最长时间；超过 [`STALE_LOCK`] 未释放的锁视为崩溃进程遗留，直接清除
+const LOCK_TIMEOUT: Duration = Duration::from_secs(10);
+const STALE_LOCK: Duration = Duration::from_secs(30);
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 注册表中的一个项目
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct ProjectRecord {
+    /// 项目根目录的绝对路径（注册表主键）
+    pub path: String,
+    pub name: String,
+    /// 最近一次 scan / update 的时间
+    #[serde(rename = "lastScan")]
+    pub last_scan: String,
+    #[serde(
+        rename = "commitHash",
+        default,
+        skip_serializing_if = "Option::is_none"
+    )]
+    pub commit_hash: Option<String>,
+    #[serde(default)]
+    pub languages: Vec<String>,
+    #[serde(default)]
+    pub files: u32,
+}
+
+#[derive(Debug, Default, Serialize, Deserialize)]
+struct Registry {
+    projects: Vec<ProjectRecord>,
+}
+
+/// 项目当前状态
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "lowercase")]
+pub enum ProjectState {
+    /// 磁盘与图谱一致
+    Fresh,
+    /// 有文件增删改，需要 update
+    Stale,
+    /// 目录或 .codemap/ 已不存在
+    Missing,
+}
+
+impl ProjectState {
+    pub fn as_str(self) -> &'static str {
+        match self {
+            ProjectState::Fresh => "fresh",
+            ProjectState::Stale => "stale",
+            ProjectState::Missing => "missing",
+        }
+    }
+}
+
+/// `projects` 的一行：注册信息 + 新鲜度
+#[derive(Debug, Clone, Serialize)]
+pub struct ProjectStatus {
+    #[serde(flatten)]
+    pub record: ProjectRecord,
+    pub state: ProjectState,
+    /// 自上次扫描以来的文件变化（missing 时为 None）
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub changes: Option<ChangeCounts>,
+    /// HEAD 是否已离开扫描时的 commit
+    #[serde(rename = "headMoved")]
+    pub head_moved: bool,
+}
+
+/// 新增 / 修改 / 删除的文件数
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+pub struct ChangeCounts {
+    pub added: usize,
+    pub modified: usize,
+    pub removed: usize,
+}
+
+/// 单个项目中的搜索结果
+#[derive(Debug, Clone, Serialize)]
+pub struct ProjectMatches {
+    pub project: String,
+    pub path: String,
+    pub results: Vec<SymbolResult>,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+impl ProjectRecord {
+    pub fn from_graph(root_dir: &Path, graph: &CodeGraph) -> Self {
+        ProjectRecord {
+            path: root_dir.to_string_lossy().replace('\\', "/"),
+            name: graph.project.name.clone(),
+            last_scan: graph.scanned_at.clone(),
+            commit_hash: graph.commit_hash.clone(),
+            languages: graph.config.languages.clone(),
+            files: graph.summary.total_files,
+        }
+    }
+}
+
+/// `$CODEMAP_HOME`，未设置时为 `~/.codemap`
+pub fn codemap_home() -> Option<PathBuf> {
+    std::env::var_os("CODEMAP_HOME")
+        .map(PathBuf::from)
+        .or_else(|| home_dir().map(|h| h.join(".codemap")))
+}
+
+/// 用户主目录：$HOME，Windows 上为 %USERPROFILE%
+pub fn home_dir() -> Option<PathBuf> {
+    std::env::var_os("HOME")
+        .or_else(|| std::env::var_os("USERPROFILE"))
+        .map(PathBuf::from)
+}
+
+/// 读取注册表（按路径排序）；文件不存在时返回空列表
+pub fn load_registry(home: &Path) -> anyhow::Result<Vec<ProjectRecord>> {
+    let path = home.join(REGISTRY_FILE);
+    if !path.exists() {
+        return Ok(vec![]);
+    }
+    let data = std::fs::read_to_string(&path)?;
+    let registry: Registry = serde_json::from_str(&data)
+        .map_err(|e| anyhow::anyhow!("invalid registry {}: {}", path.display(), e))?;
+    Ok(registry.projects)
+}
+
+/// 写入注册表：先写临时文件再改名，避免并发扫描留下半截文件
+pub fn save_registry(home: &Path, projects: &[ProjectRecord]) -> anyhow::Result<()> {
+    std::fs::create_dir_all(home)?;
+    let mut projects = projects.to_vec();
+    projects.sort_by(|a, b| a.path.cmp(&b.path));
+    let json = serde_json::to_string_pretty(&Registry { projects })?;
+    let tmp = home.join(format!("{}.{}.tmp", REGISTRY_FILE, std::process::id()));
+    std::fs::write(&tmp, json + "\n")?;
+    std::fs::rename(&tmp, home.join(REGISTRY_FILE))?;
+    Ok(())
+}
+
+/// 登记（或刷新）一次扫描结果
+///
+/// 读取—修改—写回期间持有注册表锁，并发扫描不同项目时不会互相覆盖对方的记录。
+pub fn record_scan(home: &Path, root_dir: &Path, graph: &CodeGraph) -> anyhow::Result<()> {
+    let record = ProjectRecord::from_graph(root_dir, graph);
+    let _lock = RegistryLock::acquire(home)?;
+    let mut projects = load_registry(home)?;
+    match projects.iter_mut().find(|p| p.path == record.path) {
+        Some(existing) => *existing = record,
+        None => projects.push(record),
+    }
+    save_registry(home, &projects)
+}
+
+/// 在 `$CODEMAP_HOME` 的注册表中登记项目（无法确定 home 目录时跳过）
+pub fn register_project(root_dir: &Path, graph: &CodeGraph) -> anyhow::Result<()> {
+    match codemap_home() {
+        Some(home) => record_scan(&home, root_dir, graph),
+        None => Ok(()),
+    }
+}
+
+/// 从注册表移除目录已不存在的项目，返回被移除的记录
+pub fn prune_missing(home: &Path) -> anyhow::Result<Vec<ProjectRecord>> {
+    let _lock = RegistryLock::acquire(home)?;
+    let (kept, removed): (Vec<ProjectRecord>, Vec<ProjectRecord>) = load_registry(home)?
+        .into_iter()
+        .partition(|p| codemap_dir(p).is_dir());
+    if !removed.is_empty() {
+        save_registry(home, &kept)?;
+    }
+    Ok(removed)
+}
+
+/// 检查项目的新鲜度（只比对文件 stat，必要时计算哈希，不解析源码）
+pub fn project_status(record: &ProjectRecord) -> ProjectStatus {
+    let dir = codemap_dir(record);
+    let checked = load_graph(&dir).ok().and_then(|graph| {
+        let meta = load_meta(&dir).ok()?;
+        Some(check_freshness(
+            Path::new(&record.path),
+            &meta,
+            &graph.config,
+        ))
+    });
+    match checked {
+        Some(f) => ProjectStatus {
+            record: record.clone(),
+            state: if f.is_stale() {
+                ProjectState::Stale
+            } else {
+                ProjectState::Fresh
+            },
+            changes: Some(ChangeCounts {
+                added: f.added.len(),
+                modified: f.modified.len(),
+                removed: f.removed.len(),
+            }),
+            head_moved: f.head_moved(),
+        },
+        None => missing(record),
+    }
+}
+
+/// 在多个项目的图谱中搜索符号；无法加载图谱的项目被跳过，没有匹配的项目不出现在结果中
+pub fn search_projects(
+    projects: &[ProjectRecord],
+    symbol: &str,
+    opts: &QueryOptions,
+) -> Vec<ProjectMatches> {
+    let mut matches = Vec::new();
+    for project in projects {
+        let Ok(graph) = load_graph(&codemap_dir(project)) else {
+            continue;
+        };
+        let results = query_symbol(&graph, symbol, opts);
+        if !results.is_empty() {
+            matches.push(ProjectMatches {
+                project: project.name.clone(),
+                path: project.path.clone(),
+                results,
+            });
+        }
+    }
+    matches
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+/// 注册表的进程间锁：以 create_new 创建锁文件，释放时删除
+struct RegistryLock {
+    path: PathBuf,
+}
+
+impl RegistryLock {
+    fn acquire(home: &Path) -> anyhow::Result<Self> {
+        std::fs::create_dir_all(home)?;
+        let path = home.join(LOCK_FILE);
+        let start = Instant::now();
+        loop {
+            match std::fs::OpenOptions::new()
+                .write(true)
+                .create_new(true)
+                .open(&path)
+            {
+                Ok(_) => return Ok(RegistryLock { path }),
+                Err(e) if e.kind() == std::io::ErrorKind::AlreadyExists => {
+                    let stale = std::fs::metadata(&path)
+                        .and_then(|m| m.modified())
+                        .ok()
+                        .and_then(|t| t.elapsed().ok())
+                        .is_some_and(|age| age > STALE_LOCK);
+                    if stale {
+                        let _ = std::fs::remove_file(&path);
+                        continue;
+                    }
+                    if start.elapsed() > LOCK_TIMEOUT {
+                        anyhow::bail!("registry is locked by another process: {}", path.display());
+                    }
+                    std::thread::sleep(Duration::from_millis(20));
+                }
+                Err(e) => return Err(e.into()),
+            }
+        }
+    }
+}
+
+impl Drop for RegistryLock {
+    fn drop(&mut self) {
+        let _ = std::fs::remove_file(&self.path);
+    }
+}
+
+fn codemap_dir(record: &ProjectRecord) -> PathBuf {
+    Path::new(&record.path).join(".codemap")
+}
+
+fn missing(record: &ProjectRecord) -> ProjectStatus {
+    ProjectStatus {
+        record: record.clone(),
+        state: ProjectState::Missing,
+        changes: None,
+        head_moved: false,
+    }
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::create_empty_graph;
+
+    #[test]
+    fn test_record_and_prune() {
+        let base = std::env::temp_dir().join(format!("codegraph_registry_{}", std::process::id()));
+        let _ = std::fs::remove_dir_all(&base);
+        let home = base.join("home");
+        let project = base.join("proj");
+        std::fs::create_dir_all(project.join(".codemap")).unwrap();
+
+        let mut graph = create_empty_graph("proj", &project.to_string_lossy());
+        graph.config.languages = vec!["rust".to_string()];
+        record_scan(&home, &project, &graph).unwrap();
+        graph.summary.total_files = 3;
+        record_scan(&home, &project, &graph).unwrap();
+        record_scan(&home, &base.join("gone"), &graph).unwrap();
+
+        let projects = load_registry(&home).unwrap();
+        assert_eq!(projects.len(), 2);
+        assert_eq!(projects[1].path, project.to_string_lossy());
+        assert_eq!(projects[1].files, 3);
+        assert_eq!(projects[1].languages, vec!["rust"]);
+        assert_eq!(project_status(&projects[0]).state, ProjectState::Missing);
+
+        let removed = prune_missing(&home).unwrap();
+        let remaining = load_registry(&home).unwrap();
+        let _ = std::fs::remove_dir_all(&base);
+
+        assert_eq!(removed.len(), 1);
+        assert!(removed[0].path.ends_with("/gone"));
+        assert_eq!(remaining.len(), 1);
+    }
+
+    #[test]
+    fn test_concurrent_record_scan_keeps_every_project() {
+        let base = std::env::temp_dir().join(format!(
+            "codegraph_registry_concurrent_{}",
+            std::process::id()
+        ));
+        let _ = std::fs::remove_dir_all(&base);
+        let home = base.join("home");
+
+        let handles: Vec<_> = (0..8)
+            .map(|i| {
+                let home = home.clone();
+                let project = base.join(format!("proj{}", i));
+                std::thread::spawn(move || {
+                    let graph = create_empty_graph("proj", &project.to_string_lossy());
+                    record_scan(&home, &project, &graph).unwrap();
+                })
+            })
+            .collect();
+        for h in handles {
+            h.join().unwrap();
+        }
+
+        let projects = load_registry(&home).unwrap();
+        let lock_left = home.join(LOCK_FILE).exists();
+        let _ = std::fs::remove_dir_all(&base);
+        assert_eq!(projects.len(), 8);
+        assert!(!lock_left);
+    }
+}
@@ -1,12 +1,26 @@
+use crate::audit_sites::extract_audit_sites;
+use crate::bindings::{extract_bindings, link_bindings};
+use crate::concurrency::extract_concurrency;
+use crate::config_keys::extract_config_keys;
+use crate::deprecations::extract_deprecations;
+use crate::differ::{detect_changed_files, merge_graph_update, sort_broken_imports, ChangeSet};
+use crate::doc_coverage::extract_documented;
+use crate::errors::extract_errors;
 use crate::graph::{
-    compute_file_hash, create_empty_graph, is_entry_point, save_graph, ClassInfo as GraphClassInfo,
-    CodeGraph, FileEntry, FunctionInfo as GraphFunctionInfo, ImportInfo as GraphImportInfo,
-    ModuleEntry, TypeInfo as GraphTypeInfo,
+    chrono_now, compute_file_hash, create_empty_graph, is_entry_point, keep_scan_time_if_unchanged,
+    load_graph, load_meta, save_graph, save_stat_cache, BrokenImport, CachedStat,
+    ClassInfo as GraphClassInfo, CodeGraph, FileEntry, FileStat, FunctionInfo as GraphFunctionInfo,
+    ImportInfo as GraphImportInfo, ModuleEntry, StatCache, TypeInfo as GraphTypeInfo,
 };
 use crate::languages;
-use crate::path_utils::{normalize_path, strip_extension};
+use crate::merge::SLICES_STALE_MARKER;
+use crate::path_utils::{is_path_import, normalize_path, strip_extension};
+use crate::slicer::save_slices;
+use crate::source_text::SourceText;
+use crate::todos::extract_todos;
 use crate::traverser::{
-    detect_language, effective_language, has_cpp_source_files, traverse_files, Language,
+    detect_language, detect_submodules, effective_language, has_cpp_source_files,
+    traverse_files_with, Language, SubmodulePolicy, TraverseOptions,
 };
 use std::collections::{BTreeMap, HashMap, HashSet};
 use std::path::{Path, PathBuf};
@@ -158,204 +172,506 @@ pub fn detect_module_name(file_path: &Path, root_dir: &Path) -> String {
     }
 }
 
-/// 扫描整个项目，构建 CodeGraph
-pub fn scan_project(root_dir: &Path, exclude: &[String]) -> anyhow::Result<CodeGraph> {
-    let project_name = root_dir
-        .file_name()
-        .and_then(|n| n.to_str())
-        .unwrap_or("unknown");
-    let root_str = root_dir.to_string_lossy().replace('\\', "/");
-    let mut graph = create_empty_graph(project_name, &root_str);
+// ── 扫描选项与进度回调 ────────────────────────────────────────────────────────
 
-    // Step 1: 遍历文件
-    let files = traverse_files(root_dir, exclude);
-    let has_cpp = has_cpp_source_files(&files);
+/// 扫描进度（每处理一个文件回调一次）
+#[derive(Debug, Clone)]
+pub struct ScanProgress {
+    /// 当前文件序号（从 1 开始）
+    pub current: usize,
+    /// 本次需要处理的文件总数
+    pub total: usize,
+    /// 当前文件的相对路径（posix 风格）
+    pub path: String,
+}
 
-    // Step 2: 解析每个文件
-    struct FileInfo {
-        rel_path: String,
-        language: String,
-        module_name: String,
-        hash: String,
-        lines: u32,
-        functions: Vec<crate::graph::FunctionInfo>,
-        classes: Vec<crate::graph::ClassInfo>,
-        types: Vec<crate::graph::TypeInfo>,
-        imports: Vec<crate::graph::ImportInfo>,
-        exports: Vec<String>,
-        variables: Vec<crate::graph::VariableInfo>,
-        is_entry_point: bool,
-        symbol_refs: BTreeMap<String, crate::graph::SymbolRef>,
-    }
-
-    let mut file_infos: Vec<(PathBuf, FileInfo)> = Vec::new();
-    let mut language_counts: HashMap<String, u32> = HashMap::new();
-    let mut total_functions = 0u32;
-    let mut total_classes = 0u32;
-    let mut total_variables = 0u32;
-    let mut module_set: HashSet<String> = HashSet::new();
+/// 进度回调类型
+pub type ProgressCallback = Box<dyn Fn(&ScanProgress) + Send + Sync>;
 
-    for abs_path in &files {
-        let base_lang = match detect_language(abs_path) {
-            Some(l) => l,
-            None => continue,
-        };
-        let lang = effective_language(abs_path, base_lang, has_cpp);
+/// 扫描选项（builder 风格）
+///
+/// ```ignore
+/// let graph = ScanOptions::new()
+///     .exclude("vendor/**")
+///     .on_progress(|p| eprintln!("[{}/{}] {}", p.current, p.total, p.path))
+///     .scan(Path::new("/path/to/project"))?;
+/// ```
+#[derive(Default)]
+pub struct ScanOptions {
+    exclude: Vec<String>,
+    skip_slices: bool,
+    external: bool,
+    follow_symlinks: Option<bool>,
+    submodules: Option<SubmodulePolicy>,
+    progress: Option<ProgressCallback>,
+}
 
-        let content = match std::fs::read(abs_path) {
-            Ok(c) => c,
-            Err(_) => continue,
-        };
+/// 增量更新结果
+#[derive(Debug, Clone)]
+pub struct UpdateOutcome {
+    /// 更新后的图谱（无变更时为原图谱）
+    pub graph: CodeGraph,
+    /// 检测到的文件变更
+    pub changes: ChangeSet,
+}
 
-        let hash = compute_file_hash(&content);
-        let adapter = languages::get_adapter(lang);
+impl ScanOptions {
+    pub fn new() -> Self {
+        Self::default()
+    }
 
-        // 用语言适配器解析
-        let mut ts_parser = tree_sitter::Parser::new();
-        if ts_parser.set_language(&adapter.language()).is_err() {
-            eprintln!(
-                "Warning: failed to set language for {:?}, skipping",
-                abs_path
-            );
-            continue;
+    /// 追加一个排除 glob
+    pub fn exclude(mut self, pattern: impl Into<String>) -> Self {
+        self.exclude.push(pattern.into());
+        self
+    }
+
+    /// 追加多个排除 glob
+    pub fn excludes<I, S>(mut self, patterns: I) -> Self
+    where
+        I: IntoIterator<Item = S>,
+        S: Into<String>,
+    {
+        self.exclude.extend(patterns.into_iter().map(Into::into));
+        self
+    }
+
+    /// 保存时是否同时生成 slices/（默认生成）
+    pub fn write_slices(mut self, enabled: bool) -> Self {
+        self.skip_slices = !enabled;
+        self
+    }
+
+    /// 保存时是否同时扫描第三方依赖的导出 API，写入只读层 external.json（默认关闭）
+    pub fn scan_external(mut self, enabled: bool) -> Self {
+        self.external = enabled;
+        self
+    }
+
+    /// 是否跟随符号链接（默认不跟随；update 未设置时沿用图谱记录的选择）
+    pub fn follow_symlinks(mut self, enabled: bool) -> Self {
+        self.follow_symlinks = Some(enabled);
+        self
+    }
+
+    /// git 子模块的处理方式（默认 scan；update 未设置时沿用图谱记录的选择）
+    pub fn submodules(mut self, policy: SubmodulePolicy) -> Self {
+        self.submodules = Some(policy);
+        self
+    }
+
+    /// 设置进度回调
+    pub fn on_progress<F>(mut self, callback: F) -> Self
+    where
+        F: Fn(&ScanProgress) + Send + Sync + 'static,
+    {
+        self.progress = Some(Box::new(callback));
+        self
+    }
+
+    pub fn exclude_patterns(&self) -> &[String] {
+        &self.exclude
+    }
+
+    /// 本次遍历使用的选项：显式设置优先，否则取 recorded（update 时为图谱中记录的选择）
+    fn traverse_options(&self, recorded: TraverseOptions) -> TraverseOptions {
+        TraverseOptions {
+            follow_symlinks: self.follow_symlinks.unwrap_or(recorded.follow_symlinks),
+            submodules: self.submodules.unwrap_or(recorded.submodules),
         }
-        let tree = match ts_parser.parse(&content, None) {
-            Some(t) => t,
-            None => continue,
-        };
+    }
 
-        let lang_functions = adapter.extract_functions(&tree, &content);
-        let lang_imports = adapter.extract_imports(&tree, &content);
-        let lang_exports = adapter.extract_exports(&tree, &content);
-        let lang_classes = adapter.extract_classes(&tree, &content);
-        let lang_variables = adapter.extract_variables(&tree, &content);
-        let lines = content.iter().filter(|&&b| b == b'\n').count() as u32 + 1;
+    fn report(&self, current: usize, total: usize, path: &str) {
+        if let Some(cb) = &self.progress {
+            cb(&ScanProgress {
+                current,
+                total,
+                path: path.to_string(),
+            });
+        }
+    }
 
-        // 转换为 graph 数据结构
-        let functions = convert_functions(&lang_functions);
-        let classes = convert_classes(&lang_classes);
-        let types = convert_types(&lang_classes, lang);
-        let imports = convert_imports(&lang_imports);
-        let exports = convert_exports(&lang_exports);
-        let variables = convert_variables(&lang_variables);
+    /// 全量扫描项目，仅返回图谱，不写磁盘
+    pub fn scan(&self, root_dir: &Path) -> anyhow::Result<CodeGraph> {
+        if !root_dir.is_dir() {
+            anyhow::bail!("'{}' is not a directory", root_dir.display());
+        }
+        Ok(build_graph(root_dir, self).0)
+    }
 
-        // 扫描导入符号的使用位置，构建 symbol_refs
-        let imported_symbols: HashSet<String> = imports
-            .iter()
-            .flat_map(|imp| imp.symbols.iter().cloned())
-            .collect();
+    /// 全量扫描并写入 <root>/.codemap/（graph.json、meta.json 与 slices/）
+    pub fn scan_and_save(&self, root_dir: &Path) -> anyhow::Result<CodeGraph> {
+        if !root_dir.is_dir() {
+            anyhow::bail!("'{}' is not a directory", root_dir.display());
+        }
+        let (mut graph, stats) = build_graph(root_dir, self);
+        let output_dir = root_dir.join(".codemap");
+        keep_scan_time_if_unchanged(&output_dir, &mut graph);
+        save_graph(&output_dir, &graph)?;
+        save_stat_cache(&output_dir, &stats)?;
+        // external 层需先于 slices 写出，切片才能列出用到的第三方 API
+        if self.external {
+            let layer = crate::external::scan_external(root_dir, &graph);
+            crate::external::save(&output_dir, &layer)?;
+        } else if graph.config.submodules == SubmodulePolicy::External
+            && !graph.config.submodule_paths.is_empty()
+        {
+            let layer = crate::external::scan_submodules(root_dir, &graph);
+            crate::external::save(&output_dir, &layer)?;
+        }
+        if !self.skip_slices {
+            save_slices(&output_dir, &graph)?;
+        }
+        Ok(graph)
+    }
 
-        // 也追踪同文件内定义的变量/函数/类的使用位置
-        let mut all_tracked_symbols = imported_symbols.clone();
-        for var in &variables {
-            all_tracked_symbols.insert(var.name.clone());
+    /// 增量更新：对比 meta.json 中的文件哈希，仅重新解析新增/修改的文件
+    ///
+    /// 有变更时写回 <root>/.codemap/；图谱不存在时返回错误。
+    pub fn update(&self, root_dir: &Path) -> anyhow::Result<UpdateOutcome> {
+        let codemap_dir = root_dir.join(".codemap");
+        let mut graph = load_graph(&codemap_dir).map_err(|e| {
+            anyhow::anyhow!("could not load graph from {}: {}", codemap_dir.display(), e)
+        })?;
+
+        // 遍历磁盘当前文件，计算哈希（符号链接与子模块策略沿用图谱记录）
+        let recorded = recorded_traverse_state(&graph);
+        let traverse = self.traverse_options(graph.config.traverse_options());
+        record_traverse_options(&mut graph, root_dir, traverse);
+        let options_changed = recorded_traverse_state(&graph) != recorded;
+        let files = traverse_files_with(root_dir, &self.exclude, &traverse);
+        let has_cpp = has_cpp_source_files(&files);
+
+        let mut new_hashes: HashMap<String, String> = HashMap::new();
+        let mut file_contents: HashMap<String, (PathBuf, Vec<u8>)> = HashMap::new();
+        let mut stats = StatCache::new();
+        for abs_path in &files {
+            if detect_language(abs_path).is_none() {
+                continue;
+            }
+            let rel_path = relative_path(abs_path, root_dir);
+            let Some((content, hash)) = read_for_hash(abs_path, &rel_path, &mut stats) else {
+                continue;
+            };
+            new_hashes.insert(rel_path.clone(), hash);
+            file_contents.insert(rel_path, (abs_path.clone(), content));
         }
-        for func in &functions {
-            if exports.contains(&func.name) {
-                all_tracked_symbols.insert(func.name.clone());
+
+        // 从 meta.fileHashes 读取旧哈希（与 Node.js update 逻辑一致）
+        // 若 meta.json 不存在或无 fileHashes，回退到从 graph.files 提取
+        let old_hashes: HashMap<String, String> = match load_meta(&codemap_dir) {
+            Ok(meta) if !meta.file_hashes.is_empty() => meta.file_hashes.into_iter().collect(),
+            _ => graph
+                .files
+                .iter()
+                .map(|(p, f)| (p.clone(), f.hash.clone()))
+                .collect(),
+        };
+
+        let changes = detect_changed_files(&old_hashes, &new_hashes);
+        // 清单与构建脚本不在源文件哈希中：重新检测，只改了 Makefile / package.json 等时也要写回
+        let packages = crate::packages::detect_packages(root_dir, &self.exclude, &traverse);
+        let build_files_changed = packages != graph.packages
+            || crate::targets::detect_targets(root_dir, &self.exclude, &graph) != graph.targets;
+        // merge-driver 合并 slices 冲突时会留下过期标记，即使没有文件变更也需重新生成
+        let stale_marker = codemap_dir.join(SLICES_STALE_MARKER);
+        if changes.is_empty() && !build_files_changed && !options_changed {
+            // 内容未变时仍刷新 stat，仅 touch 过的文件下次 status --check 不必再计算哈希
+            save_stat_cache(&codemap_dir, &stats)?;
+            if !self.skip_slices && stale_marker.exists() {
+                save_slices(&codemap_dir, &graph)?;
+                std::fs::remove_file(&stale_marker)?;
             }
+            return Ok(UpdateOutcome { graph, changes });
         }
-        for cls in &classes {
-            if exports.contains(&cls.name) {
-                all_tracked_symbols.insert(cls.name.clone());
+
+        // 解析变更文件（新增 + 修改）
+        let to_parse: Vec<&String> = changes
+            .added
+            .iter()
+            .chain(changes.modified.iter())
+            .collect();
+        let mut updated_files: HashMap<String, FileEntry> = HashMap::new();
+        for (i, rel_path) in to_parse.iter().enumerate() {
+            self.report(i + 1, to_parse.len(), rel_path);
+            let (abs_path, content) = match file_contents.get(*rel_path) {
+                Some(c) => c,
+                None => continue,
+            };
+            let base_lang = match detect_language(abs_path) {
+                Some(l) => l,
+                None => continue,
+            };
+            let lang = effective_language(abs_path, base_lang, has_cpp);
+            if let Some(entry) = build_file_entry(abs_path, root_dir, lang, content) {
+                updated_files.insert((*rel_path).clone(), entry);
             }
         }
 
-        let symbol_uses = scan_symbol_uses(&tree, &content, &all_tracked_symbols);
-        let mut symbol_refs: BTreeMap<String, crate::graph::SymbolRef> = BTreeMap::new();
-        // 先处理导入符号（保持原有逻辑）
-        for imp in &imports {
-            for sym in &imp.symbols {
-                let use_lines = symbol_uses.get(sym).cloned().unwrap_or_default();
-                symbol_refs.insert(
-                    sym.clone(),
-                    crate::graph::SymbolRef {
-                        symbol: sym.clone(),
-                        import_line: imp.import_line,
-                        use_lines,
-                    },
-                );
+        // 清单可能随源码一起增删，使用重新检测的包目录合并（合并时刷新包成员）
+        graph.packages = packages;
+        merge_graph_update(&mut graph, updated_files, &changes.removed);
+        graph.targets = crate::targets::detect_targets(root_dir, &self.exclude, &graph);
+        graph.scanned_at = chrono_now();
+        graph.commit_hash = git_head(root_dir);
+        keep_scan_time_if_unchanged(&codemap_dir, &mut graph);
+
+        save_graph(&codemap_dir, &graph)?;
+        save_stat_cache(&codemap_dir, &stats)?;
+        if !self.skip_slices {
+            save_slices(&codemap_dir, &graph)?;
+            if stale_marker.exists() {
+                std::fs::remove_file(&stale_marker)?;
             }
         }
-        // 再处理本地定义的导出符号（import_line = 0 表示本地定义）
-        for sym_name in &all_tracked_symbols {
-            if !symbol_refs.contains_key(sym_name) {
-                if let Some(use_lines) = symbol_uses.get(sym_name) {
-                    if !use_lines.is_empty() {
-                        symbol_refs.insert(
-                            sym_name.clone(),
-                            crate::graph::SymbolRef {
-                                symbol: sym_name.clone(),
-                                import_line: 0,
-                                use_lines: use_lines.clone(),
-                            },
-                        );
-                    }
+        Ok(UpdateOutcome { graph, changes })
+    }
+}
+
+// ── 单文件解析 ────────────────────────────────────────────────────────────────
+
+/// 解析单个源文件，构建 FileEntry（scan 与 update 共用）
+///
+/// 无法加载语法或解析失败时返回 None。
+pub fn build_file_entry(
+    abs_path: &Path,
+    root_dir: &Path,
+    lang: Language,
+    content: &[u8],
+) -> Option<FileEntry> {
+    let adapter = languages::get_adapter(lang);
+
+    // 用语言适配器解析
+    let mut ts_parser = tree_sitter::Parser::new();
+    if ts_parser.set_language(&adapter.language()).is_err() {
+        eprintln!(
+            "Warning: failed to set language for {:?}, skipping",
+            abs_path
+        );
+        return None;
+    }
+    let tree = ts_parser.parse(content, None)?;
+
+    let lang_functions = adapter.extract_functions(&tree, content);
+    let lang_imports = adapter.extract_imports(&tree, content);
+    let lang_exports = adapter.extract_exports(&tree, content);
+    let lang_classes = adapter.extract_classes(&tree, content);
+    let lang_variables = adapter.extract_variables(&tree, content);
+    let lines = content.iter().filter(|&&b| b == b'\n').count() as u32 + 1;
+
+    // 转换为 graph 数据结构
+    let functions = convert_functions(&lang_functions);
+    let classes = convert_classes(&lang_classes);
+    let types = convert_types(&lang_classes, lang);
+    let imports = convert_imports(&lang_imports);
+    let exports = convert_exports(&lang_exports);
+    let variables = convert_variables(&lang_variables);
+
+    // 扫描导入符号的使用位置，构建 symbol_refs
+    let imported_symbols: HashSet<String> = imports
+        .iter()
+        .flat_map(|imp| imp.symbols.iter().cloned())
+        .collect();
+
+    // 也追踪同文件内定义的变量/函数/类的使用位置
+    // 函数不论是否导出都追踪：私有辅助函数的调用边是错误传播（errors）的主要路径
+    let mut all_tracked_symbols = imported_symbols.clone();
+    for var in &variables {
+        all_tracked_symbols.insert(var.name.clone());
+    }
+    for func in &functions {
+        all_tracked_symbols.insert(func.name.clone());
+    }
+    for cls in &classes {
+        if exports.contains(&cls.name) {
+            all_tracked_symbols.insert(cls.name.clone());
+        }
+    }
+
+    let symbol_uses = scan_symbol_uses(&tree, content, &all_tracked_symbols);
+    let mut symbol_refs: BTreeMap<String, crate::graph::SymbolRef> = BTreeMap::new();
+    // 先处理导入符号（保持原有逻辑）
+    for imp in &imports {
+        for sym in &imp.symbols {
+            let use_lines = symbol_uses.get(sym).cloned().unwrap_or_default();
+            symbol_refs.insert(
+                sym.clone(),
+                crate::graph::SymbolRef {
+                    symbol: sym.clone(),
+                    import_line: imp.import_line,
+                    use_lines,
+                },
+            );
+        }
+    }
+    // 再处理本地定义的符号（import_line = 0 表示本地定义）
+    for sym_name in &all_tracked_symbols {
+        if !symbol_refs.contains_key(sym_name) {
+            if let Some(use_lines) = symbol_uses.get(sym_name) {
+                if !use_lines.is_empty() {
+                    symbol_refs.insert(
+                        sym_name.clone(),
+                        crate::graph::SymbolRef {
+                            symbol: sym_name.clone(),
+                            import_line: 0,
+                            use_lines: use_lines.clone(),
+                        },
+                    );
                 }
             }
         }
-        // 过滤掉定义行本身（避免把变量/函数/类的定义处算作使用）
-        for var in &variables {
-            if let Some(ref_entry) = symbol_refs.get_mut(&var.name) {
-                if ref_entry.import_line == 0 {
-                    ref_entry.use_lines.retain(|&line| line != var.start_line);
-                }
+    }
+    // 过滤掉定义行本身（避免把变量/函数/类的定义处算作使用）
+    for var in &variables {
+        if let Some(ref_entry) = symbol_refs.get_mut(&var.name) {
+            if ref_entry.import_line == 0 {
+                ref_entry.use_lines.retain(|&line| line != var.start_line);
             }
         }
-        for func in &functions {
-            if let Some(ref_entry) = symbol_refs.get_mut(&func.name) {
-                if ref_entry.import_line == 0 {
-                    ref_entry
-                        .use_lines
-                        .retain(|&line| line < func.start_line || line > func.end_line);
-                }
+    }
+    for func in &functions {
+        if let Some(ref_entry) = symbol_refs.get_mut(&func.name) {
+            if ref_entry.import_line == 0 {
+                ref_entry
+                    .use_lines
+                    .retain(|&line| line < func.start_line || line > func.end_line);
             }
         }
-        for cls in &classes {
-            if let Some(ref_entry) = symbol_refs.get_mut(&cls.name) {
-                if ref_entry.import_line == 0 {
-                    ref_entry.use_lines.retain(|&line| line != cls.start_line);
-                }
+    }
+    for cls in &classes {
+        if let Some(ref_entry) = symbol_refs.get_mut(&cls.name) {
+            if ref_entry.import_line == 0 {
+                ref_entry.use_lines.retain(|&line| line != cls.start_line);
             }
         }
-        // 移除过滤后 use_lines 为空的本地符号条目
-        symbol_refs.retain(|_, v| v.import_line != 0 || !v.use_lines.is_empty());
-
-        let module_name = detect_module_name(abs_path, root_dir);
-        module_set.insert(module_name.clone());
-
-        let lang_str = lang.as_str().to_string();
-        *language_counts.entry(lang_str.clone()).or_insert(0) += 1;
-        total_functions += functions.len() as u32;
-        total_classes += classes.len() as u32;
-        total_variables += variables.len() as u32;
-
-        let rel_path = abs_path
-            .strip_prefix(root_dir)
-            .unwrap_or(abs_path)
-            .to_string_lossy()
-            .replace('\\', "/");
-
-        let entry = FileInfo {
-            rel_path,
-            language: lang_str,
-            module_name,
-            hash,
-            lines,
-            functions,
-            classes,
-            types,
-            imports,
-            exports,
-            variables,
-            is_entry_point: is_entry_point(abs_path),
-            symbol_refs,
+    }
+    // 移除过滤后 use_lines 为空的本地符号条目
+    symbol_refs.retain(|_, v| v.import_line != 0 || !v.use_lines.is_empty());
+    // 文本分析器共用：按语法树屏蔽注释与字符串内容
+    let source = SourceText::from_tree(lang, content, &tree);
+    let audit_sites = extract_audit_sites(lang, &source, &functions, &classes);
+    let bindings = extract_bindings(lang, abs_path, &source, &functions, &classes);
+    let todos = extract_todos(&source, &functions, &classes);
+    let concurrency = extract_concurrency(lang, &source, &functions, &classes);
+    let (error_sites, try_blocks) = extract_errors(lang, &source, &functions, &classes);
+
+    let mut entry = FileEntry {
+        language: lang.as_str().to_string(),
+        module: detect_module_name(abs_path, root_dir),
+        hash: compute_file_hash(content),
+        lines,
+        functions,
+        classes,
+        types,
+        variables,
+        imports,
+        exports,
+        is_entry_point: is_entry_point(abs_path),
+        symbol_refs,
+        config_keys: extract_config_keys(lang, &source),
+        audit_sites,
+        bindings,
+        deprecations: vec![],
+        todos,
+        documented: vec![],
+        concurrency,
+        error_sites,
+        try_blocks,
+    };
+    entry.deprecations = extract_deprecations(lang, &source, &entry);
+    entry.documented = extract_documented(lang, &source, &entry);
+    Some(entry)
+}
+
+/// 文件绝对路径 → 相对根目录的 posix 路径
+fn relative_path(abs_path: &Path, root_dir: &Path) -> String {
+    abs_path
+        .strip_prefix(root_dir)
+        .unwrap_or(abs_path)
+        .to_string_lossy()
+        .replace('\\', "/")
+}
+
+// ── 全量扫描 ──────────────────────────────────────────────────────────────────
+
+/// 扫描整个项目，构建 CodeGraph
+pub fn scan_project(root_dir: &Path, exclude: &[String]) -> anyhow::Result<CodeGraph> {
+    ScanOptions::new()
+        .excludes(exclude.iter().cloned())
+        .scan(root_dir)
+}
+
+/// 读取文件并计算哈希，同时把 stat 记入缓存
+///
+/// stat 在读取之前记录：读取期间被修改的文件下次 stat 比对失败，会重新计算哈希。
+fn read_for_hash(path: &Path, rel_path: &str, stats: &mut StatCache) -> Option<(Vec<u8>, String)> {
+    let stat = FileStat::of(path);
+    let content = std::fs::read(path).ok()?;
+    let hash = compute_file_hash(&content);
+    if let Some(stat) = stat {
+        let cached = CachedStat {
+            stat,
+            hash: hash.clone(),
         };
-        file_infos.push((abs_path.clone(), entry));
+        stats.insert(rel_path.to_string(), cached);
     }
+    Some((content, hash))
+}
+
+fn build_graph(root_dir: &Path, opts: &ScanOptions) -> (CodeGraph, StatCache) {
+    let project_name = root_dir
+        .file_name()
+        .and_then(|n| n.to_str())
+        .unwrap_or("unknown");
+    let root_str = root_dir.to_string_lossy().replace('\\', "/");
+    let mut graph = create_empty_graph(project_name, &root_str);
+    graph.commit_hash = git_head(root_dir);
+    graph.config.exclude_patterns = opts.exclude.clone();
+    let traverse = opts.traverse_options(TraverseOptions::default());
+    record_traverse_options(&mut graph, root_dir, traverse);
+
+    // Step 1: 遍历文件
+    let files = traverse_files_with(root_dir, &opts.exclude, &traverse);
+    let has_cpp = has_cpp_source_files(&files);
+
+    // Step 2: 解析每个文件
+    let mut file_infos: Vec<(PathBuf, String, FileEntry)> = Vec::new();
+    let mut language_counts: BTreeMap<String, u32> = BTreeMap::new();
+    let mut total_functions = 0u32;
+    let mut total_classes = 0u32;
+    let mut total_variables = 0u32;
+    let mut module_set: HashSet<String> = HashSet::new();
+    let mut stats = StatCache::new();
+
+    for (i, abs_path) in files.iter().enumerate() {
+        let rel_path = relative_path(abs_path, root_dir);
+        opts.report(i + 1, files.len(), &rel_path);
+
+        let base_lang = match detect_language(abs_path) {
+            Some(l) => l,
+            None => continue,
+        };
+        let lang = effective_language(abs_path, base_lang, has_cpp);
 
+        let Some((content, _)) = read_for_hash(abs_path, &rel_path, &mut stats) else {
+            continue;
+        };
+
+        let entry = match build_file_entry(abs_path, root_dir, lang, &content) {
+            Some(e) => e,
+            None => continue,
+        };
+
+        module_set.insert(entry.module.clone());
+        *language_counts.entry(entry.language.clone()).or_insert(0) += 1;
+        total_functions += entry.functions.len() as u32;
+        total_classes += entry.classes.len() as u32;
+        total_variables += entry.variables.len() as u32;
+
+        file_infos.push((abs_path.clone(), rel_path, entry));
+    }
     // Step 3: 初始化模块表
-    let mut modules: HashMap<String, ModuleEntry> = HashMap::new();
+    let mut modules: BTreeMap<String, ModuleEntry> = BTreeMap::new();
     for mod_name in &module_set {
         modules.insert(
             mod_name.clone(),
@@ -369,14 +685,14 @@ pub fn scan_project(root_dir: &Path, exclude: &[String]) -> anyhow::Result<CodeG
 
     // 构建路径 → 模块名的查找表（O(1) 导入解析）
     let mut path_lookup: HashMap<String, String> = HashMap::new();
-    for (abs_path, info) in &file_infos {
+    for (abs_path, _, entry) in &file_infos {
         let norm = abs_path.to_string_lossy().replace('\\', "/");
-        path_lookup.insert(norm.clone(), info.module_name.clone());
+        path_lookup.insert(norm.clone(), entry.module.clone());
         // 无扩展名版本
         let without_ext = strip_extension(&norm);
         path_lookup
             .entry(without_ext)
-            .or_insert_with(|| info.module_name.clone());
+            .or_insert_with(|| entry.module.clone());
     }
 
     // Step 4: 填充 graph.files 并解析跨模块依赖
@@ -387,51 +703,46 @@ pub fn scan_project(root_dir: &Path, exclude: &[String]) -> anyhow::Result<CodeG
         depended_by_map.insert(mod_name.clone(), HashSet::new());
     }
 
-    for (abs_path, info) in &file_infos {
+    let total_files = file_infos.len() as u32;
+    let mut broken_imports: Vec<BrokenImport> = Vec::new();
+    for (abs_path, rel_path, entry) in file_infos {
         // 解析导入依赖
-        for imp in &info.imports {
+        for imp in &entry.imports {
             if imp.is_external {
                 continue;
             }
-            if let Some(target_mod) =
-                resolve_import_module(abs_path, &imp.source, &path_lookup, &info.module_name)
-            {
-                if target_mod != info.module_name {
-                    depends_on_map
-                        .entry(info.module_name.clone())
-                        .or_default()
-                        .insert(target_mod.clone());
-                    depended_by_map
-                        .entry(target_mod)
-                        .or_default()
-                        .insert(info.module_name.clone());
+            match resolve_import_module(&abs_path, &imp.source, &path_lookup, &entry.module) {
+                Some(target_mod) => {
+                    if target_mod != entry.module {
+                        depends_on_map
+                            .entry(entry.module.clone())
+                            .or_default()
+                            .insert(target_mod.clone());
+                        depended_by_map
+                            .entry(target_mod)
+                            .or_default()
+                            .insert(entry.module.clone());
+                    }
                 }
+                // 解析失败的相对 import 记录为断链，而不是静默丢弃
+                None if is_path_import(&entry.language, &imp.source) => {
+                    broken_imports.push(BrokenImport {
+                        file: rel_path.clone(),
+                        line: imp.import_line,
+                        source: imp.source.clone(),
+                    });
+                }
+                None => {}
             }
         }
 
-        // 写入 graph.files
-        graph.files.insert(
-            info.rel_path.clone(),
-            FileEntry {
-                language: info.language.clone(),
-                module: info.module_name.clone(),
-                hash: info.hash.clone(),
-                lines: info.lines,
-                functions: info.functions.clone(),
-                classes: info.classes.clone(),
-                types: info.types.clone(),
-                variables: info.variables.clone(),
-                imports: info.imports.clone(),
-                exports: info.exports.clone(),
-                is_entry_point: info.is_entry_point,
-                symbol_refs: info.symbol_refs.clone(),
-            },
-        );
-
         // 将文件加入模块
-        if let Some(m) = modules.get_mut(&info.module_name) {
-            m.files.push(info.rel_path.clone());
+        if let Some(m) = modules.get_mut(&entry.module) {
+            m.files.push(rel_path.clone());
         }
+
+        // 写入 graph.files
+        graph.files.insert(rel_path, entry);
     }
 
     // Step 5: 填充 graph.modules（Set → 排序数组）
@@ -451,9 +762,13 @@ pub fn scan_project(root_dir: &Path, exclude: &[String]) -> anyhow::Result<CodeG
         mod_entry.depended_by = dep_by;
     }
     graph.modules = modules;
+    sort_broken_imports(&mut broken_imports);
+    graph.broken_imports = broken_imports;
+    // 跨语言绑定边补充模块依赖
+    link_bindings(&mut graph);
 
     // Step 6: 构建 summary
-    graph.summary.total_files = file_infos.len() as u32;
+    graph.summary.total_files = total_files;
     graph.summary.total_functions = total_functions;
     graph.summary.total_classes = total_classes;
     graph.summary.total_variables = total_variables;
@@ -474,7 +789,30 @@ pub fn scan_project(root_dir: &Path, exclude: &[String]) -> anyhow::Result<CodeG
         langs
     };
 
-    Ok(graph)
+    // Step 7: 清单定义的包
+    graph.packages = crate::packages::detect_packages(root_dir, &opts.exclude, &traverse);
+    crate::packages::refresh_packages(&mut graph);
+
+    // Step 8: 构建目标（Makefile / justfile / package.json scripts）
+    graph.targets = crate::targets::detect_targets(root_dir, &opts.exclude, &graph);
+
+    (graph, stats)
+}
+
+/// 图谱中记录的遍历选择，用于判断 update 是否需要写回
+fn recorded_traverse_state(graph: &CodeGraph) -> (bool, SubmodulePolicy, Vec<String>) {
+    (
+        graph.config.follow_symlinks,
+        graph.config.submodules,
+        graph.config.submodule_paths.clone(),
+    )
+}
+
+/// 在图谱配置中记录本次的符号链接与子模块策略
+fn record_traverse_options(graph: &mut CodeGraph, root_dir: &Path, opts: TraverseOptions) {
+    graph.config.follow_symlinks = opts.follow_symlinks;
+    graph.config.submodules = opts.submodules;
+    graph.config.submodule_paths = detect_submodules(root_dir);
 }
 
 /// 解析相对导入，返回目标模块名
@@ -512,10 +850,28 @@ fn resolve_import_module(
     None
 }
 
+/// 当前 git HEAD commit（不在 git 仓库中或 git 不可用时为 None）
+pub fn git_head(root_dir: &Path) -> Option<String> {
+    let output = std::process::Command::new("git")
+        .arg("-C")
+        .arg(root_dir)
+        .args(["rev-parse", "HEAD"])
+        .output()
+        .ok()?;
+    if !output.status.success() {
+        return None;
+    }
+    let head = String::from_utf8_lossy(&output.stdout).trim().to_string();
+    (!head.is_empty()).then_some(head)
+}
+
 /// 扫描并保存到 .codemap/ 目录
+///
+/// 仅写入 graph.json 与 meta.json；需要同时生成 slices/ 时使用 [`ScanOptions::scan_and_save`]。
 pub fn scan_and_save(root_dir: &Path, exclude: &[String]) -> anyhow::Result<CodeGraph> {
-    let graph = scan_project(root_dir, exclude)?;
+    let mut graph = scan_project(root_dir, exclude)?;
     let output_dir = root_dir.join(".codemap");
+    keep_scan_time_if_unchanged(&output_dir, &mut graph);
     save_graph(&output_dir, &graph)?;
     Ok(graph)
 }
@@ -661,4 +1017,94 @@ mod tests {
         assert_eq!(imports[0].is_external, false);
         assert_eq!(imports[1].is_external, true);
     }
+
+    #[test]
+    fn test_scan_options_reports_progress() {
+        use std::sync::{Arc, Mutex};
+
+        let dir = std::env::temp_dir().join(format!("codegraph_progress_{}", std::process::id()));
+        std::fs::create_dir_all(dir.join("src")).unwrap();
+        std::fs::write(dir.join("src/a.ts"), "export const a = 1;\n").unwrap();
+        std::fs::write(dir.join("src/b.py"), "def b():\n    pass\n").unwrap();
+
+        let seen: Arc<Mutex<Vec<(usize, usize, String)>>> = Arc::new(Mutex::new(Vec::new()));
+        let sink = Arc::clone(&seen);
+        ScanOptions::new()
+            .on_progress(move |p| {
+                sink.lock()
+                    .unwrap()
+                    .push((p.current, p.total, p.path.clone()))
+            })
+            .scan(&dir)
+            .unwrap();
+
+        let seen = seen.lock().unwrap().clone();
+        let currents: Vec<usize> = seen.iter().map(|(c, _, _)| *c).collect();
+        let mut paths: Vec<String> = seen.iter().map(|(_, _, p)| p.clone()).collect();
+        paths.sort();
+        assert_eq!(currents, vec![1, 2]);
+        assert!(seen.iter().all(|(_, total, _)| *total == 2));
+        assert_eq!(paths, vec!["src/a.ts", "src/b.py"]);
+        let _ = std::fs::remove_dir_all(&dir);
+    }
+
+    #[test]
+    fn test_stat_cache_is_local_and_recorded_on_read() {
+        let root = std::env::temp_dir().join(format!("codegraph_statcache_{}", std::process::id()));
+        let _ = std::fs::remove_dir_all(&root);
+        std::fs::create_dir_all(root.join("src")).unwrap();
+        std::fs::write(root.join("src/a.ts"), "export const a = 1;\n").unwrap();
+
+        let opts = ScanOptions::new().write_slices(false);
+        opts.scan_and_save(&root).unwrap();
+        let codemap = root.join(".codemap");
+        let meta = std::fs::read_to_string(codemap.join("meta.json")).unwrap();
+        let stats = crate::graph::load_stat_cache(&codemap);
+        let ignore = std::fs::read_to_string(codemap.join(".gitignore")).unwrap();
+
+        // 仅 touch（内容不变）后 update 刷新 stat 缓存
+        let file = std::fs::File::options()
+            .write(true)
+            .open(root.join("src/a.ts"))
+            .unwrap();
+        let epoch = std::time::UNIX_EPOCH + std::time::Duration::from_secs(1_000);
+        file.set_modified(epoch).unwrap();
+        let touched = FileStat::of(&root.join("src/a.ts")).unwrap();
+        opts.update(&root).unwrap();
+        let refreshed = crate::graph::load_stat_cache(&codemap);
+        let _ = std::fs::remove_dir_all(&root);
+
+        // meta.json 只含可提交的内容，stat 写入被忽略的本机缓存
+        assert!(!meta.contains("mtime"), "meta.json: {}", meta);
+        assert_eq!(ignore.lines().collect::<Vec<_>>(), vec![".stat-cache"]);
+        let cached = &stats["src/a.ts"];
+        assert_eq!(cached.hash, compute_file_hash(b"export const a = 1;\n"));
+        assert_eq!(cached.stat.size, 20);
+        assert_eq!(refreshed["src/a.ts"].stat, touched);
+        assert_eq!(refreshed["src/a.ts"].hash, cached.hash);
+    }
+
+    #[test]
+    fn test_update_refreshes_targets_when_only_makefile_changed() {
+        let dir = std::env::temp_dir().join(format!("codegraph_update_mk_{}", std::process::id()));
+        let _ = std::fs::remove_dir_all(&dir);
+        std::fs::create_dir_all(&dir).unwrap();
+        std::fs::write(dir.join("Makefile"), "build:\n\techo build\n").unwrap();
+
+        let opts = ScanOptions::new().write_slices(false);
+        let graph = opts.scan_and_save(&dir).unwrap();
+        assert_eq!(graph.targets[0].name, "build");
+
+        // 没有源文件变化，只有 Makefile 改了：update 仍需刷新并写回 targets
+        std::fs::write(dir.join("Makefile"), "deploy:\n\techo deploy\n").unwrap();
+        let outcome = opts.update(&dir).unwrap();
+        let saved = load_graph(&dir.join(".codemap")).unwrap();
+        let _ = std::fs::remove_dir_all(&dir);
+
+        assert!(outcome.changes.is_empty());
+        let names =
+            |g: &CodeGraph| -> Vec<String> { g.targets.iter().map(|t| t.name.clone()).collect() };
+        assert_eq!(names(&outcome.graph), vec!["deploy"]);
+        assert_eq!(names(&saved), vec!["deploy"]);
+    }
 }
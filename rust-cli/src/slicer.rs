@@ -1,5 +1,6 @@
 use crate::graph::{CodeGraph, ModuleEntry};
 use serde::{Deserialize, Serialize};
+use std::collections::BTreeMap;
 use std::path::Path;
 
 // ── 输出数据结构 ──────────────────────────────────────────────────────────────
@@ -30,6 +31,12 @@ pub struct OverviewModule {
     #[serde(rename = "dependedBy")]
     pub depended_by: Vec<String>,
     pub stats: ModuleStats,
+    /// 各标签的 TODO / FIXME / HACK / XXX 注释数（见 todos.rs）
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub todos: BTreeMap<String, u32>,
+    /// 模块及其符号的持久注释（`codegraph note add`）
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub notes: Vec<crate::notes::AttachedNote>,
 }
 
 #[derive(Debug, Clone, Serialize, Deserialize)]
@@ -73,6 +80,16 @@ pub struct ModuleSlice {
     #[serde(rename = "dependedBy")]
     pub depended_by: Vec<String>,
     pub stats: ModuleStats,
+    /// 用到的第三方 API（仅在启用 external 层时填充）
+    #[serde(
+        rename = "externalApis",
+        default,
+        skip_serializing_if = "Vec::is_empty"
+    )]
+    pub external_apis: Vec<crate::external::ExternalApi>,
+    /// 模块及其符号的持久注释（`codegraph note add`）
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub notes: Vec<crate::notes::AttachedNote>,
 }
 
 #[derive(Debug, Clone, Serialize, Deserialize)]
@@ -109,6 +126,8 @@ pub fn generate_overview(graph: &CodeGraph) -> Overview {
                 depends_on: mod_data.depends_on.clone(),
                 depended_by: mod_data.depended_by.clone(),
                 stats,
+                todos: crate::todos::module_todo_counts(graph, &mod_data.files),
+                notes: vec![],
             }
         })
         .collect();
@@ -125,7 +144,7 @@ pub fn generate_overview(graph: &CodeGraph) -> Overview {
 
 /// 生成所有模块的切片
 #[allow(dead_code)]
-pub fn generate_slices(graph: &CodeGraph) -> std::collections::HashMap<String, ModuleSlice> {
+pub fn generate_slices(graph: &CodeGraph) -> std::collections::BTreeMap<String, ModuleSlice> {
     graph
         .modules
         .iter()
@@ -187,6 +206,8 @@ pub fn build_module_slice(
             total_variables,
             total_lines,
         },
+        external_apis: vec![],
+        notes: vec![],
     }
 }
 
@@ -243,15 +264,29 @@ pub fn save_slices(output_dir: &Path, graph: &CodeGraph) -> anyhow::Result<()> {
     let slices_dir = output_dir.join("slices");
     std::fs::create_dir_all(&slices_dir)?;
 
+    // 持久注释（.codemap/notes/）附加到概览与切片中
+    let notes = crate::notes::load_notes(output_dir)
+        .map(|entries| crate::notes::attached_notes(graph, &entries))
+        .unwrap_or_default();
+
     // 保存 _overview.json
-    let overview = generate_overview(graph);
-    let overview_json = serde_json::to_string_pretty(&overview)?;
+    let mut overview = generate_overview(graph);
+    crate::notes::attach_to_overview(&mut overview, graph, &notes);
+    let overview_json = crate::graph::to_record_lines(&overview)?;
     std::fs::write(slices_dir.join("_overview.json"), overview_json)?;
 
-    // 保存各模块切片
-    let slices = generate_slices(graph);
+    // 保存各模块切片（启用 external 层时附带用到的第三方 API）
+    let mut slices = generate_slices(graph);
+    if let Ok(Some(layer)) = crate::external::load(output_dir) {
+        for slice in slices.values_mut() {
+            crate::external::attach_apis(slice, graph, &layer);
+        }
+    }
+    for slice in slices.values_mut() {
+        crate::notes::attach_to_slice(slice, graph, &notes);
+    }
     for (mod_name, slice) in &slices {
-        let slice_json = serde_json::to_string_pretty(slice)?;
+        let slice_json = crate::graph::to_record_lines(slice)?;
         // 净化模块名，防止路径穿越
         let safe_name = mod_name.replace(['/', '\\', '.'], "_");
         std::fs::write(slices_dir.join(format!("{}.json", safe_name)), slice_json)?;
@@ -326,14 +361,9 @@ mod tests {
                 module: "_root".to_string(),
                 hash: "sha256:abcdef123456".to_string(),
                 lines: 10,
-                functions: vec![],
-                classes: vec![],
-                types: vec![],
-                variables: vec![],
-                imports: vec![],
                 exports: vec!["main".to_string()],
                 is_entry_point: true,
-                symbol_refs: std::collections::BTreeMap::new(),
+                ..Default::default()
             },
         );
 
@@ -0,0 +1,465 @@
+/// 源码文本视图（各分析器共用）
+///
+/// config_keys、audit_sites、bindings、deprecations、todos、doc_coverage、concurrency、errors
+/// 都按行匹配源码。本模块借助 tree-sitter 语法树把每个字节标注为代码、字符串内容或注释，
+/// 并生成与原文逐字节对齐的代码视图（注释与字符串内容替换为空格，保留引号与换行），
+/// 使分析器只在代码中匹配关键字，需要时再从同一位置的原文读取键名、说明等文本。
+/// 内容不是合法 UTF-8（或没有语法树）时按各语言的词法规则近似标注。
+use std::ops::Range;
+
+use tree_sitter::Tree;
+
+use crate::graph::{ClassInfo, FunctionInfo};
+use crate::languages::walk_nodes;
+use crate::traverser::Language;
+
+/// 注释节点
+const COMMENT_KINDS: &[&str] = &["comment", "line_comment", "block_comment"];
+
+/// 字符串与字符字面量节点
+const STRING_KINDS: &[&str] = &[
+    "string",
+    "template_string",
+    "string_literal",
+    "raw_string_literal",
+    "interpreted_string_literal",
+    "char_literal",
+    "character_literal",
+    "rune_literal",
+    "text_block",
+];
+
+/// 字符串中的插值（`${expr}`、f-string 的 `{expr}`），其中仍是代码
+const INTERPOLATION_KINDS: &[&str] = &["template_substitution", "interpolation"];
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 字节所属的区域
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Region {
+    Code,
+    /// 字符串内容（不含前缀与引号）
+    String,
+    Comment,
+}
+
+/// 带区域标注的源码
+#[derive(Debug, Clone)]
+pub struct SourceText {
+    text: String,
+    /// 注释与字符串内容替换为空格后的代码视图，与 text 逐字节对齐
+    code: String,
+    regions: Vec<Region>,
+    /// 各行的字节范围（与 `str::lines` 一致，不含换行符）
+    lines: Vec<Range<usize>>,
+}
+
+/// 一行源码的原文与代码视图，列号在两者间通用
+#[derive(Debug, Clone, Copy)]
+pub struct SourceLine<'a> {
+    pub raw: &'a str,
+    pub code: &'a str,
+    regions: &'a [Region],
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+impl SourceText {
+    /// 按语法树中的注释、字符串与插值节点标注区域
+    pub fn from_tree(lang: Language, content: &[u8], tree: &Tree) -> Self {
+        let Ok(text) = std::str::from_utf8(content) else {
+            return Self::scan(lang, content);
+        };
+        let mut regions = vec![Region::Code; text.len()];
+        // 先序遍历：插值节点在其所在字符串之后访问，重新标回代码
+        walk_nodes(tree.root_node(), &mut |node| {
+            let end = node.end_byte().min(text.len());
+            let start = node.start_byte().min(end);
+            let kind = node.kind();
+            if COMMENT_KINDS.contains(&kind) {
+                regions[start..end].fill(Region::Comment);
+            } else if STRING_KINDS.contains(&kind) {
+                let body = string_body(&text.as_bytes()[start..end]);
+                regions[start + body.start..start + body.end].fill(Region::String);
+            } else if INTERPOLATION_KINDS.contains(&kind) {
+                regions[start..end].fill(Region::Code);
+            }
+        });
+        Self::build(text.to_string(), regions)
+    }
+
+    /// 按词法规则近似标注：行注释、块注释、字符串与字符字面量
+    /// （含 Python / Java 三引号、Rust 原始字符串、JS 模板插值）
+    pub fn scan(lang: Language, content: &[u8]) -> Self {
+        let text = String::from_utf8_lossy(content).into_owned();
+        let regions = lex(lang, text.as_bytes());
+        Self::build(text, regions)
+    }
+
+    /// 逐行的原文与代码视图
+    pub fn lines(&self) -> Vec<SourceLine<'_>> {
+        self.lines
+            .iter()
+            .map(|r| SourceLine {
+                raw: &self.text[r.clone()],
+                code: &self.code[r.clone()],
+                regions: &self.regions[r.clone()],
+            })
+            .collect()
+    }
+
+    /// 逐行的代码视图
+    pub fn code_lines(&self) -> Vec<&str> {
+        self.lines.iter().map(|r| &self.code[r.clone()]).collect()
+    }
+
+    fn build(text: String, regions: Vec<Region>) -> Self {
+        let masked: Vec<u8> = text
+            .bytes()
+            .zip(&regions)
+            .map(|(b, r)| {
+                if *r == Region::Code || b == b'\n' || b == b'\r' {
+                    b
+                } else {
+                    b' '
+                }
+            })
+            .collect();
+        // 区域边界总在字符边界上，整字符替换为空格后仍是合法 UTF-8
+        let code = String::from_utf8(masked).unwrap_or_else(|_| text.clone());
+        let base = text.as_ptr() as usize;
+        let lines = text
+            .lines()
+            .map(|l| {
+                let start = l.as_ptr() as usize - base;
+                start..start + l.len()
+            })
+            .collect();
+        SourceText {
+            text,
+            code,
+            regions,
+            lines,
+        }
+    }
+}
+
+impl<'a> SourceLine<'a> {
+    /// 行内是否有代码（不只是空白、注释或字符串内容）
+    pub fn has_code(&self) -> bool {
+        !self.code.trim().is_empty()
+    }
+
+    /// 指定列所属的区域；超出行尾视为代码
+    pub fn region_at(&self, col: usize) -> Region {
+        self.regions.get(col).copied().unwrap_or(Region::Code)
+    }
+
+    /// 行内各段注释的原文（含 `//`、`#`、`/*` 等标记）
+    pub fn comments(&self) -> impl Iterator<Item = &'a str> {
+        self.runs(Region::Comment)
+    }
+
+    /// 行内各段字符串内容的原文
+    pub fn strings(&self) -> impl Iterator<Item = &'a str> {
+        self.runs(Region::String)
+    }
+
+    /// needle 在原文中出现、且起始于代码区域的位置
+    pub fn code_matches<'n>(&self, needle: &'n str) -> impl Iterator<Item = usize> + 'n
+    where
+        'a: 'n,
+    {
+        let regions = self.regions;
+        self.raw
+            .match_indices(needle)
+            .map(|(pos, _)| pos)
+            .filter(move |&pos| regions[pos] == Region::Code)
+    }
+
+    fn runs(&self, region: Region) -> impl Iterator<Item = &'a str> {
+        let (raw, regions) = (self.raw, self.regions);
+        let mut pos = 0;
+        std::iter::from_fn(move || {
+            let start = pos + regions[pos..].iter().position(|r| *r == region)?;
+            pos = start
+                + regions[start..]
+                    .iter()
+                    .take_while(|r| **r == region)
+                    .count();
+            Some(raw.get(start..pos).unwrap_or_default())
+        })
+    }
+}
+
+/// 包含该行的最内层函数；不在函数内时取最内层类
+pub fn enclosing_symbol(
+    functions: &[FunctionInfo],
+    classes: &[ClassInfo],
+    line: u32,
+) -> Option<String> {
+    let innermost_fn = functions
+        .iter()
+        .filter(|f| f.start_line <= line && line <= f.end_line)
+        .min_by_key(|f| f.end_line - f.start_line);
+    if let Some(f) = innermost_fn {
+        return Some(f.name.clone());
+    }
+    classes
+        .iter()
+        .filter(|c| c.start_line <= line && line <= c.end_line)
+        .min_by_key(|c| c.end_line - c.start_line)
+        .map(|c| c.name.clone())
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+/// 字面量中去掉前缀与引号后的内容范围：`"x"`、`r#"x"#`、`f'x'`、`"""x"""`、`` `x` ``
+fn string_body(literal: &[u8]) -> Range<usize> {
+    let Some(open) = literal
+        .iter()
+        .position(|b| matches!(b, b'"' | b'\'' | b'`'))
+    else {
+        return 0..0;
+    };
+    let quote = literal[open];
+    let quotes = literal[open..].iter().take_while(|&&b| b == quote).count();
+    // `""` 这样的空字符串整体都是引号
+    let quotes = if quotes == 2 { 1 } else { quotes };
+    let start = open + quotes;
+    let hashes = if literal[..open].contains(&b'#') {
+        literal[start..]
+            .iter()
+            .rev()
+            .take_while(|&&b| b == b'#')
+            .count()
+    } else {
+        0
+    };
+    let closing = literal[start..literal.len() - hashes]
+        .iter()
+        .rev()
+        .take(quotes)
+        .take_while(|&&b| b == quote)
+        .count();
+    start..(literal.len() - hashes - closing).max(start)
+}
+
+/// 近似词法扫描，返回每个字节的区域
+fn lex(lang: Language, src: &[u8]) -> Vec<Region> {
+    let mut regions = vec![Region::Code; src.len()];
+    let python = lang == Language::Python;
+    let line_comment: &[u8] = if python { b"#" } else { b"//" };
+    let mut i = 0;
+    while i < src.len() {
+        let rest = &src[i..];
+        let end = if rest.starts_with(line_comment) {
+            let end = find(src, i, b"\n").unwrap_or(src.len());
+            regions[i..end].fill(Region::Comment);
+            end
+        } else if !python && rest.starts_with(b"/*") {
+            let end = find(src, i + 2, b"*/").map_or(src.len(), |p| p + 2);
+            regions[i..end].fill(Region::Comment);
+            end
+        } else if matches!(src[i], b'"' | b'\'') || (src[i] == b'`' && has_backtick_strings(lang)) {
+            lex_string(lang, src, i, &mut regions)
+        } else {
+            i + 1
+        };
+        i = end;
+    }
+    regions
+}
+
+/// 从引号处开始标注一个字面量，返回其后的位置
+fn lex_string(lang: Language, src: &[u8], open: usize, regions: &mut [Region]) -> usize {
+    let quote = src[open];
+    let paint = |range: Range<usize>, regions: &mut [Region]| regions[range].fill(Region::String);
+
+    // Python / Java 的三引号字符串
+    let triple = [quote; 3];
+    if matches!(lang, Language::Python | Language::Java) && src[open..].starts_with(&triple) {
+        let body = open + 3;
+        let end = find(src, body, &triple).unwrap_or(src.len());
+        paint(body..end, regions);
+        return (end + 3).min(src.len());
+    }
+    // Rust 原始字符串 `r"…"`、`r#"…"#`
+    if lang == Language::Rust && quote == b'"' {
+        let hashes = src[..open].iter().rev().take_while(|&&b| b == b'#').count();
+        if src[..open - hashes].ends_with(b"r") {
+            let mut closing = vec![b'"'];
+            closing.resize(hashes + 1, b'#');
+            let end = find(src, open + 1, &closing).unwrap_or(src.len());
+            paint(open + 1..end, regions);
+            return (end + closing.len()).min(src.len());
+        }
+    }
+    // Rust 的 `'` 也可能是生命周期
+    if lang == Language::Rust && quote == b'\'' && !is_rust_char(&src[open..]) {
+        return open + 1;
+    }
+
+    // 模板字符串、Go 原始字符串与 Rust 字符串可以跨行；Go 原始字符串没有转义
+    let multiline = quote == b'`' || (lang == Language::Rust && quote == b'"');
+    let escapes = !(lang == Language::Go && quote == b'`');
+    let mut j = open + 1;
+    while j < src.len() {
+        match src[j] {
+            b if b == quote => return j + 1,
+            b'\n' if !multiline => return j,
+            b'\\' if escapes => {
+                let end = (j + 2).min(src.len());
+                paint(j..end, regions);
+                j = end;
+            }
+            b'$' if quote == b'`' && src[j..].starts_with(b"${") => {
+                // 插值中的表达式仍按代码处理
+                let end = matching_brace(src, j + 1);
+                let inner = lex(lang, &src[j..end]);
+                regions[j..end].copy_from_slice(&inner);
+                j = end;
+            }
+            _ => {
+                paint(j..j + 1, regions);
+                j += 1;
+            }
+        }
+    }
+    j
+}
+
+fn has_backtick_strings(lang: Language) -> bool {
+    matches!(
+        lang,
+        Language::TypeScript | Language::JavaScript | Language::Go
+    )
+}
+
+/// `'a'`、`'\n'`、`'中'` 是字符字面量；`'a` 是生命周期
+fn is_rust_char(rest: &[u8]) -> bool {
+    match rest.get(1) {
+        Some(b'\\') => true,
+        Some(&b) => {
+            let len = match b {
+                0x00..=0x7f => 1,
+                0xc0..=0xdf => 2,
+                0xe0..=0xef => 3,
+                _ => 4,
+            };
+            rest.get(1 + len) == Some(&b'\'')
+        }
+        None => false,
+    }
+}
+
+/// 与 `open` 处的 `{` 匹配的 `}` 之后的位置；未闭合时到结尾
+fn matching_brace(src: &[u8], open: usize) -> usize {
+    let mut depth = 0usize;
+    for (i, &b) in src.iter().enumerate().skip(open) {
+        match b {
+            b'{' => depth += 1,
+            b'}' => {
+                depth -= 1;
+                if depth == 0 {
+                    return i + 1;
+                }
+            }
+            _ => {}
+        }
+    }
+    src.len()
+}
+
+fn find(src: &[u8], from: usize, needle: &[u8]) -> Option<usize> {
+    src.get(from..)?
+        .windows(needle.len())
+        .position(|w| w == needle)
+        .map(|p| from + p)
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_scan_masks_comments_and_string_contents() {
+        let rs = "let a = \"unwrap() // x\"; // a.unwrap()\n/* panic!(\n   \"y\") */ let c = 'x';\nfn f<'a>(s: &'a str) { r#\"q\"#; }\n";
+        let source = SourceText::scan(Language::Rust, rs.as_bytes());
+        assert_eq!(
+            source.code_lines(),
+            vec![
+                "let a = \"             \";              ",
+                "          ",
+                "           let c = ' ';",
+                "fn f<'a>(s: &'a str) { r#\" \"#; }",
+            ]
+        );
+        let lines = source.lines();
+        assert_eq!(
+            lines[0].comments().collect::<Vec<_>>(),
+            vec!["// a.unwrap()"]
+        );
+        assert_eq!(
+            lines[0].strings().collect::<Vec<_>>(),
+            vec!["unwrap() // x"]
+        );
+        assert!(lines[1].comments().next().is_some());
+        assert_eq!(lines[0].code_matches("unwrap").count(), 0);
+
+        let ts = "const q = `SELECT ${cols} FROM t`; // eval(x)\n";
+        let source = SourceText::scan(Language::TypeScript, ts.as_bytes());
+        assert_eq!(
+            source.code_lines(),
+            vec!["const q = `       ${cols}       `;           "]
+        );
+
+        let py = "s = \"\"\"a\n# not a comment\n\"\"\"  # raise X\nt = '#'\n";
+        let source = SourceText::scan(Language::Python, py.as_bytes());
+        let lines = source.lines();
+        assert_eq!(
+            source.code_lines(),
+            vec![
+                "s = \"\"\" ",
+                "               ",
+                "\"\"\"           ",
+                "t = ' '"
+            ]
+        );
+        assert!(!lines[1].has_code());
+        assert_eq!(lines[2].comments().collect::<Vec<_>>(), vec!["# raise X"]);
+    }
+
+    #[test]
+    fn test_string_body_keeps_prefix_and_quotes() {
+        let body = |s: &str| {
+            let r = string_body(s.as_bytes());
+            s[r].to_string()
+        };
+        assert_eq!(body("\"abc\""), "abc");
+        assert_eq!(body("\"\""), "");
+        assert_eq!(body("r#\"a\"b\"#"), "a\"b");
+        assert_eq!(body("f'{x}'"), "{x}");
+        assert_eq!(body("\"\"\"doc\"\"\""), "doc");
+        assert_eq!(body("'\\''"), "\\'");
+        assert_eq!(body("`a ${b}`"), "a ${b}");
+    }
+
+    #[test]
+    fn test_from_tree_marks_comment_and_string_nodes() {
+        let ts = "const a = `x ${eval(y)}`; // eval(z)\nconst b = \"eval(w)\";\n";
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&crate::languages::get_adapter(Language::TypeScript).language())
+            .unwrap();
+        let tree = parser.parse(ts, None).unwrap();
+        let source = SourceText::from_tree(Language::TypeScript, ts.as_bytes(), &tree);
+        let lines = source.lines();
+        assert_eq!(lines[0].code_matches("eval(").count(), 1);
+        assert_eq!(lines[0].comments().collect::<Vec<_>>(), vec!["// eval(z)"]);
+        assert_eq!(lines[1].code_matches("eval(").count(), 0);
+        assert_eq!(lines[1].strings().collect::<Vec<_>>(), vec!["eval(w)"]);
+    }
+}
@@ -0,0 +1,608 @@
+/// 构建目标图（targets）
+///
+/// 解析 Makefile（含 `*.mk`）、justfile 与 package.json 的 scripts，记录每个目标的依赖目标、
+/// 执行的命令，以及命令中运行的源文件：
+/// - 命令中出现的源文件路径（相对构建文件所在目录）
+/// - `python -m pkg.mod` → `pkg/mod.py` 或 `pkg/mod/__main__.py`
+/// - `go run ./cmd/x` → 该目录下的入口文件
+/// - `cargo run [--bin x] [-p pkg]` → `src/main.rs` 或 `src/bin/x.rs`
+///
+/// 依赖目标包括 Makefile 先决条件、just 配方依赖、`$(MAKE) x` / `just x` / `npm run x` 调用，
+/// 以及 npm 的 `pre<name>` / `post<name>` 脚本。`impact` 通过运行的文件找到受影响的目标，
+/// 再沿依赖目标向上传递。
+use serde::Serialize;
+use std::collections::{BTreeSet, HashSet, VecDeque};
+use std::path::Path;
+
+use crate::graph::{BuildTarget, CodeGraph};
+use crate::path_utils::{posix_dirname, posix_normalize};
+use crate::traverser::traverse_target_files;
+
+/// 命令中的 shell 分隔符
+const SHELL_OPERATORS: &[&str] = &["&&", "||", ";", "|", ">", ">>", "<", "2>&1"];
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 受改动影响的构建目标
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct AffectedTarget {
+    /// 可直接执行的形式，如 `make deploy`、`npm run build`
+    pub label: String,
+    pub runner: String,
+    pub name: String,
+    pub file: String,
+    /// 经由哪个依赖目标受到影响（直接运行受影响文件时为 None）
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub via: Option<String>,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 解析项目中的全部构建目标，并把命令关联到图谱中的源文件（按文件、行号排序）
+///
+/// 遍历沿用图谱 config 中记录的符号链接与子模块策略。
+pub fn detect_targets(root_dir: &Path, exclude: &[String], graph: &CodeGraph) -> Vec<BuildTarget> {
+    let mut targets = Vec::new();
+    let opts = graph.config.traverse_options();
+    for path in traverse_target_files(root_dir, exclude, &opts) {
+        let Ok(text) = std::fs::read_to_string(&path) else {
+            continue;
+        };
+        let rel = path
+            .strip_prefix(root_dir)
+            .unwrap_or(&path)
+            .to_string_lossy()
+            .replace('\\', "/");
+        let name = path.file_name().and_then(|n| n.to_str()).unwrap_or("");
+        let mut parsed = match name {
+            "package.json" => parse_package_scripts(&text),
+            "justfile" | "Justfile" | ".justfile" => parse_justfile(&text),
+            _ => parse_makefile(&text),
+        };
+        let build_dir = posix_dirname(&rel).to_string();
+        for t in &mut parsed {
+            t.file = rel.clone();
+            t.runs = linked_files(graph, &build_dir, &t.commands);
+        }
+        targets.extend(parsed);
+    }
+    targets
+}
+
+/// 运行了 files 中任一文件的目标，以及（传递）依赖这些目标的目标
+pub fn targets_affected_by(graph: &CodeGraph, files: &HashSet<&str>) -> Vec<AffectedTarget> {
+    let mut affected: Vec<AffectedTarget> = Vec::new();
+    let mut seen: HashSet<(&str, &str)> = HashSet::new();
+    let mut queue: VecDeque<&BuildTarget> = VecDeque::new();
+    for t in &graph.targets {
+        if t.runs.iter().any(|f| files.contains(f.as_str())) && seen.insert((&t.file, &t.name)) {
+            affected.push(affected_target(t, None));
+            queue.push_back(t);
+        }
+    }
+    while let Some(dep) = queue.pop_front() {
+        for t in &graph.targets {
+            if t.file == dep.file
+                && t.depends_on.contains(&dep.name)
+                && seen.insert((&t.file, &t.name))
+            {
+                affected.push(affected_target(t, Some(dep.name.clone())));
+                queue.push_back(t);
+            }
+        }
+    }
+    affected
+}
+
+/// 目标的执行形式：`make deploy`、`just test`、`npm run build`；不在项目根目录时附带构建文件
+pub fn target_label(t: &BuildTarget) -> String {
+    let command = match t.runner.as_str() {
+        "npm" => format!("npm run {}", t.name),
+        runner => format!("{} {}", runner, t.name),
+    };
+    if t.file.contains('/') {
+        format!("{} ({})", command, t.file)
+    } else {
+        command
+    }
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+fn affected_target(t: &BuildTarget, via: Option<String>) -> AffectedTarget {
+    AffectedTarget {
+        label: target_label(t),
+        runner: t.runner.clone(),
+        name: t.name.clone(),
+        file: t.file.clone(),
+        via,
+    }
+}
+
+fn new_target(runner: &str, name: &str, line: u32) -> BuildTarget {
+    BuildTarget {
+        runner: runner.to_string(),
+        name: name.to_string(),
+        file: String::new(),
+        line,
+        depends_on: vec![],
+        commands: vec![],
+        runs: vec![],
+    }
+}
+
+/// 合并以 `\` 结尾的续行，返回 (起始行号, 文本)
+fn logical_lines(text: &str) -> Vec<(u32, String)> {
+    let mut result: Vec<(u32, String)> = Vec::new();
+    let mut pending: Option<(u32, String)> = None;
+    for (idx, line) in text.lines().enumerate() {
+        let (line_no, mut buf) = match pending.take() {
+            // 续行去掉缩进，与上一行以单个空格相连
+            Some((line_no, buf)) => (line_no, buf + line.trim_start()),
+            None => (idx as u32 + 1, line.to_string()),
+        };
+        if buf.ends_with('\\') {
+            buf.pop();
+            let head = buf.trim_end().to_string();
+            pending = Some((line_no, head + " "));
+        } else {
+            result.push((line_no, buf));
+        }
+    }
+    result.extend(pending);
+    result
+}
+
+/// Makefile：`target ...: prereq ...` 规则及其 tab 缩进的配方（忽略 `.PHONY` 等特殊目标与模式规则）
+fn parse_makefile(text: &str) -> Vec<BuildTarget> {
+    let mut targets: Vec<BuildTarget> = Vec::new();
+    let mut current: Vec<usize> = Vec::new();
+    for (line_no, line) in logical_lines(text) {
+        if line.starts_with('\t') {
+            let cmd = line.trim().trim_start_matches(['@', '-', '+']).trim();
+            if !cmd.is_empty() && !cmd.starts_with('#') {
+                for &i in &current {
+                    targets[i].commands.push(cmd.to_string());
+                }
+            }
+            continue;
+        }
+        let trimmed = line.trim();
+        if trimmed.is_empty() || trimmed.starts_with('#') {
+            continue;
+        }
+        current.clear();
+        let Some(colon) = rule_colon(trimmed) else {
+            continue;
+        };
+        let rest = trimmed[colon + 1..].trim_start_matches(':');
+        let (prereqs, inline) = match rest.split_once(';') {
+            Some((p, cmd)) => (p, Some(cmd.trim())),
+            None => (rest, None),
+        };
+        let prereqs: Vec<String> = prereqs
+            .split_whitespace()
+            .filter(|p| *p != "|" && !p.contains('$') && !p.contains('%'))
+            .map(String::from)
+            .collect();
+        for name in trimmed[..colon].split_whitespace() {
+            if name.starts_with('.') || name.contains('%') || name.contains('$') {
+                continue;
+            }
+            let idx = match targets.iter().position(|t| t.name == name) {
+                Some(i) => i,
+                None => {
+                    targets.push(new_target("make", name, line_no));
+                    targets.len() - 1
+                }
+            };
+            for p in &prereqs {
+                if !targets[idx].depends_on.contains(p) {
+                    targets[idx].depends_on.push(p.clone());
+                }
+            }
+            if let Some(cmd) = inline.filter(|c| !c.is_empty()) {
+                targets[idx].commands.push(cmd.to_string());
+            }
+            current.push(idx);
+        }
+    }
+    add_invoked_dependencies(&mut targets, |cmd| {
+        if cmd.contains(" -C ") {
+            // 切换到其他目录的子 make 不是同文件目标
+            return vec![];
+        }
+        invoked_after(cmd, &["$(MAKE)", "${MAKE}", "make"], usize::MAX)
+    });
+    targets
+}
+
+/// 规则行中分隔目标与先决条件的冒号；变量赋值（`=`、`:=`、`::=`、`?=`、`+=`）返回 None
+fn rule_colon(line: &str) -> Option<usize> {
+    let colon = line.find(':')?;
+    let head = &line[..colon];
+    let after = &line[colon + 1..];
+    if head.trim().is_empty()
+        || head.contains('=')
+        || after.starts_with('=')
+        || after.starts_with(":=")
+        || head.starts_with("export ")
+        || head.starts_with("define ")
+    {
+        return None;
+    }
+    Some(colon)
+}
+
+/// justfile：`name param...: dep...` 配方及其缩进的配方体
+fn parse_justfile(text: &str) -> Vec<BuildTarget> {
+    const DIRECTIVES: &[&str] = &["set ", "alias ", "export ", "import ", "mod ", "!include "];
+    let mut targets: Vec<BuildTarget> = Vec::new();
+    let mut in_recipe = false;
+    for (line_no, line) in logical_lines(text) {
+        if line.starts_with([' ', '\t']) {
+            let cmd = line.trim().trim_start_matches(['@', '-']).trim();
+            if in_recipe && !cmd.is_empty() && !cmd.starts_with('#') {
+                targets.last_mut().unwrap().commands.push(cmd.to_string());
+            }
+            continue;
+        }
+        let trimmed = line.trim();
+        if trimmed.is_empty() || trimmed.starts_with('#') || trimmed.starts_with('[') {
+            // 注释与属性行不结束当前配方的识别
+            continue;
+        }
+        in_recipe = false;
+        if DIRECTIVES.iter().any(|d| trimmed.starts_with(d)) {
+            continue;
+        }
+        let header = trimmed.trim_start_matches('@');
+        let name_len = header
+            .find(|c: char| !(c.is_alphanumeric() || c == '_' || c == '-'))
+            .unwrap_or(header.len());
+        let name = &header[..name_len];
+        if name.is_empty() || !name.starts_with(|c: char| c.is_alphabetic() || c == '_') {
+            continue;
+        }
+        let rest = &header[name_len..];
+        if rest.trim_start().starts_with(":=") {
+            continue;
+        }
+        let Some(colon) = rest
+            .match_indices(':')
+            .map(|(i, _)| i)
+            .find(|&i| !rest[i + 1..].starts_with('='))
+        else {
+            continue;
+        };
+        let mut target = new_target("just", name, line_no);
+        for dep in rest[colon + 1..].split_whitespace() {
+            let dep = dep.trim_start_matches('(').trim_end_matches(')');
+            let is_name = dep.starts_with(|c: char| c.is_alphabetic() || c == '_')
+                && dep
+                    .chars()
+                    .all(|c| c.is_alphanumeric() || c == '_' || c == '-');
+            if is_name && !target.depends_on.iter().any(|d| d == dep) {
+                target.depends_on.push(dep.to_string());
+            }
+        }
+        targets.push(target);
+        in_recipe = true;
+    }
+    // `(dep arg)` 中的参数会被当作依赖名，只保留确实存在的配方
+    let names: HashSet<String> = targets.iter().map(|t| t.name.clone()).collect();
+    for t in &mut targets {
+        t.depends_on.retain(|d| names.contains(d));
+    }
+    add_invoked_dependencies(&mut targets, |cmd| invoked_after(cmd, &["just"], 1));
+    targets
+}
+
+/// package.json 的 scripts；`pre<name>` / `post<name>` 视为 name 的依赖
+fn parse_package_scripts(text: &str) -> Vec<BuildTarget> {
+    let Ok(json) = serde_json::from_str::<serde_json::Value>(text) else {
+        return vec![];
+    };
+    let Some(scripts) = json.get("scripts").and_then(|s| s.as_object()) else {
+        return vec![];
+    };
+    let scripts_offset = text.find("\"scripts\"").unwrap_or(0);
+    let mut targets: Vec<BuildTarget> = Vec::new();
+    for (name, value) in scripts {
+        let Some(cmd) = value.as_str() else {
+            continue;
+        };
+        let key = format!("\"{}\"", name);
+        let line = text[scripts_offset..]
+            .find(&key)
+            .map(|i| text[..scripts_offset + i].matches('\n').count() as u32 + 1)
+            .unwrap_or(0);
+        let mut target = new_target("npm", name, line);
+        target.commands.push(cmd.to_string());
+        targets.push(target);
+    }
+    targets.sort_by_key(|t| t.line);
+    let names: HashSet<String> = targets.iter().map(|t| t.name.clone()).collect();
+    for t in &mut targets {
+        for hook in [format!("pre{}", t.name), format!("post{}", t.name)] {
+            if names.contains(&hook) {
+                t.depends_on.push(hook);
+            }
+        }
+    }
+    add_invoked_dependencies(&mut targets, |cmd| {
+        let mut found = Vec::new();
+        for runner in [
+            "npm run",
+            "npm run-script",
+            "yarn run",
+            "pnpm run",
+            "bun run",
+            "yarn",
+            "pnpm",
+        ] {
+            found.extend(invoked_after(cmd, &[runner], 1));
+        }
+        found
+    });
+    targets
+}
+
+/// 把命令中调用的同文件目标加入依赖
+fn add_invoked_dependencies(targets: &mut [BuildTarget], invoked: impl Fn(&str) -> Vec<String>) {
+    let names: HashSet<String> = targets.iter().map(|t| t.name.clone()).collect();
+    for t in targets.iter_mut() {
+        let calls: Vec<String> = t.commands.iter().flat_map(|c| invoked(c)).collect();
+        for call in calls {
+            if call != t.name && names.contains(&call) && !t.depends_on.contains(&call) {
+                t.depends_on.push(call);
+            }
+        }
+    }
+}
+
+/// 命令中紧跟 runner（可为多词，如 `npm run`）之后的非选项参数，最多 limit 个，遇到 shell 分隔符停止
+fn invoked_after(cmd: &str, runners: &[&str], limit: usize) -> Vec<String> {
+    let tokens = shell_tokens(cmd);
+    let mut found = Vec::new();
+    for runner in runners {
+        let words: Vec<&str> = runner.split(' ').collect();
+        for start in 0..tokens.len() {
+            if !tokens[start..].starts_with(&words) {
+                continue;
+            }
+            let args = tokens[start + words.len()..]
+                .iter()
+                .take_while(|t| !SHELL_OPERATORS.contains(t))
+                .filter(|t| !t.starts_with('-') && !t.contains('='))
+                .take(limit);
+            found.extend(args.map(|t| t.to_string()));
+        }
+    }
+    found
+}
+
+/// 粗略的 shell 分词：按空白切分，去掉引号，并把紧贴的 `;` / `&&` 拆成独立记号
+fn shell_tokens(cmd: &str) -> Vec<&str> {
+    let mut tokens = Vec::new();
+    for raw in cmd.split_whitespace() {
+        let t = raw.trim_matches(['"', '\'']);
+        match t.strip_suffix(';') {
+            Some(head) if !head.is_empty() => {
+                tokens.push(head);
+                tokens.push(";");
+            }
+            _ => tokens.push(t),
+        }
+    }
+    tokens
+}
+
+/// 命令中运行的图谱文件（去重排序）
+fn linked_files(graph: &CodeGraph, build_dir: &str, commands: &[String]) -> Vec<String> {
+    let join = |rel: &str| {
+        if build_dir == "." {
+            posix_normalize(rel)
+        } else {
+            posix_normalize(&format!("{}/{}", build_dir, rel))
+        }
+    };
+    let mut found: BTreeSet<String> = BTreeSet::new();
+    for cmd in commands {
+        let tokens = shell_tokens(cmd);
+        for (i, token) in tokens.iter().enumerate() {
+            let prev = |n: usize| i.checked_sub(n).map(|j| tokens[j]).unwrap_or("");
+            if token.starts_with('-') || token.contains('$') || token.contains('=') {
+                // `python -m pkg.mod`
+                if *token == "-m" {
+                    if let Some(module) = tokens.get(i + 1) {
+                        let base = module.replace('.', "/");
+                        for candidate in [
+                            format!("{}.py", base),
+                            format!("{}/__main__.py", base),
+                            format!("src/{}.py", base),
+                            format!("src/{}/__main__.py", base),
+                        ] {
+                            let path = join(&candidate);
+                            if graph.files.contains_key(&path) {
+                                found.insert(path);
+                            }
+                        }
+                    }
+                }
+                continue;
+            }
+            // `cargo run [--bin x] [-p pkg]`
+            if *token == "run" && prev(1) == "cargo" {
+                let args: Vec<&str> = tokens[i + 1..]
+                    .iter()
+                    .copied()
+                    .take_while(|t| !SHELL_OPERATORS.contains(t) && *t != "--")
+                    .collect();
+                let flag = |name: &str| {
+                    args.iter()
+                        .position(|a| *a == name)
+                        .and_then(|p| args.get(p + 1).copied())
+                };
+                let crate_dir = match flag("-p").or_else(|| flag("--package")) {
+                    Some(pkg) => graph
+                        .packages
+                        .get(pkg)
+                        .map(|p| p.path.clone())
+                        .unwrap_or_else(|| build_dir.to_string()),
+                    None => build_dir.to_string(),
+                };
+                let candidates = match flag("--bin") {
+                    Some(bin) => vec![
+                        format!("{}/src/bin/{}.rs", crate_dir, bin),
+                        format!("{}/src/bin/{}/main.rs", crate_dir, bin),
+                    ],
+                    None => vec![format!("{}/src/main.rs", crate_dir)],
+                };
+                for candidate in candidates {
+                    let path = posix_normalize(&candidate);
+                    if graph.files.contains_key(&path) {
+                        found.insert(path);
+                    }
+                }
+                continue;
+            }
+            let path = join(token);
+            if graph.files.contains_key(&path) {
+                found.insert(path);
+                continue;
+            }
+            // `go run ./cmd/x`、`go build .`：目录中的入口文件
+            if matches!(prev(1), "run" | "build") && prev(2) == "go" {
+                let dir = if path.is_empty() { "." } else { path.as_str() };
+                found.extend(
+                    graph
+                        .files
+                        .iter()
+                        .filter(|(f, e)| e.is_entry_point && posix_dirname(f) == dir)
+                        .map(|(f, _)| f.clone()),
+                );
+            }
+        }
+    }
+    found.into_iter().collect()
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::{create_empty_graph, FileEntry};
+
+    fn file(entry_point: bool) -> FileEntry {
+        FileEntry {
+            language: "python".into(),
+            module: "app".into(),
+            lines: 1,
+            is_entry_point: entry_point,
+            ..Default::default()
+        }
+    }
+
+    fn summary(targets: &[BuildTarget]) -> Vec<(String, u32, Vec<String>)> {
+        targets
+            .iter()
+            .map(|t| (t.name.clone(), t.line, t.depends_on.clone()))
+            .collect()
+    }
+
+    fn s(v: &[&str]) -> Vec<String> {
+        v.iter().map(|x| x.to_string()).collect()
+    }
+
+    #[test]
+    fn test_parse_makefile() {
+        let text = ".PHONY: build deploy\nPY := python3\nOUT = dist\n\nbuild: gen\n\t@$(PY) -m app.build \\\n\t  --out $(OUT)\n\ngen:\n\tpython scripts/gen.py\n\ndeploy: build ; ./deploy.sh\n\t$(MAKE) notify\n\nnotify:\n\tcurl -X POST $$HOOK\n%.o: %.c\n\tcc -c $<\n";
+        let targets = parse_makefile(text);
+        assert_eq!(
+            summary(&targets),
+            vec![
+                ("build".to_string(), 5, s(&["gen"])),
+                ("gen".to_string(), 9, vec![]),
+                ("deploy".to_string(), 12, s(&["build", "notify"])),
+                ("notify".to_string(), 15, vec![]),
+            ]
+        );
+        assert_eq!(targets[0].commands, vec!["$(PY) -m app.build --out $(OUT)"]);
+        assert_eq!(targets[2].commands, vec!["./deploy.sh", "$(MAKE) notify"]);
+    }
+
+    #[test]
+    fn test_parse_justfile_and_scripts() {
+        let just = "set shell := [\"bash\", \"-c\"]\nversion := \"1.0\"\n\n# Run tests\ntest *args: build\n    cargo test {{args}}\n\n[private]\n@build target='debug': (fmt \"all\")\n    cargo build\n    just lint\n\nfmt scope:\n    cargo fmt\n\nlint:\n    cargo clippy\n";
+        let targets = parse_justfile(just);
+        assert_eq!(
+            summary(&targets),
+            vec![
+                ("test".to_string(), 5, s(&["build"])),
+                ("build".to_string(), 9, s(&["fmt", "lint"])),
+                ("fmt".to_string(), 13, vec![]),
+                ("lint".to_string(), 16, vec![]),
+            ]
+        );
+
+        let pkg = "{\n  \"name\": \"web\",\n  \"scripts\": {\n    \"prebuild\": \"npm run clean\",\n    \"build\": \"tsc && node scripts/bundle.js\",\n    \"clean\": \"rm -rf dist\",\n    \"deploy\": \"yarn build && ./deploy.sh\"\n  }\n}\n";
+        let targets = parse_package_scripts(pkg);
+        let by_name = |n: &str| targets.iter().find(|t| t.name == n).unwrap();
+        assert_eq!(by_name("build").line, 5);
+        assert_eq!(by_name("build").depends_on, s(&["prebuild"]));
+        assert_eq!(by_name("prebuild").depends_on, s(&["clean"]));
+        assert_eq!(by_name("deploy").depends_on, s(&["build"]));
+    }
+
+    #[test]
+    fn test_link_and_affected() {
+        let mut graph = create_empty_graph("p", "/tmp/p");
+        for (path, entry_point) in [
+            ("app/build.py", false),
+            ("scripts/gen.py", true),
+            ("cmd/api/main.go", true),
+            ("cmd/api/util.go", false),
+            ("web/scripts/bundle.js", false),
+        ] {
+            graph.files.insert(path.into(), file(entry_point));
+        }
+        assert_eq!(
+            linked_files(
+                &graph,
+                ".",
+                &s(&["python3 -m app.build", "go run ./cmd/api"])
+            ),
+            s(&["app/build.py", "cmd/api/main.go"])
+        );
+        assert_eq!(
+            linked_files(&graph, "web", &s(&["tsc && node scripts/bundle.js"])),
+            s(&["web/scripts/bundle.js"])
+        );
+
+        let mut targets = parse_makefile(
+            "build: gen\n\tpython -m app.build\ngen:\n\tpython scripts/gen.py\ndeploy: build\n\t./deploy.sh\n",
+        );
+        for t in &mut targets {
+            t.file = "Makefile".into();
+            t.runs = linked_files(&graph, ".", &t.commands);
+        }
+        graph.targets = targets;
+
+        let files: HashSet<&str> = HashSet::from(["scripts/gen.py"]);
+        let affected = targets_affected_by(&graph, &files);
+        let labels: Vec<(&str, Option<&str>)> = affected
+            .iter()
+            .map(|a| (a.label.as_str(), a.via.as_deref()))
+            .collect();
+        assert_eq!(
+            labels,
+            vec![
+                ("make gen", None),
+                ("make build", Some("gen")),
+                ("make deploy", Some("build")),
+            ]
+        );
+    }
+}
@@ -0,0 +1,316 @@
+/// TODO / FIXME / HACK / XXX 注释索引（todos）
+///
+/// 扫描时逐行查找以标签开头的注释（`// TODO: ...`、`# FIXME(alice): ...`、`/* HACK */`、
+/// 块注释续行 `* XXX ...`），解析可选的负责人 `TODO(owner)` 与工单号（`ABC-123` / `#123`），
+/// 并归属到所在的函数（或类）与模块。标签需全大写且位于注释开头，句中提到的 todo
+/// 与字符串中的同名文本不计入。
+use serde::Serialize;
+use std::collections::BTreeMap;
+
+use crate::graph::{ClassInfo, CodeGraph, FunctionInfo, Todo};
+use crate::source_text::{enclosing_symbol, SourceText};
+
+/// 支持的标签
+pub const TODO_TAGS: &[&str] = &["TODO", "FIXME", "HACK", "XXX"];
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 带文件与模块的注释
+#[derive(Debug, Clone, Serialize)]
+pub struct TodoItem {
+    pub file: String,
+    pub module: String,
+    pub line: u32,
+    pub tag: String,
+    pub text: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub owner: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub ticket: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub symbol: Option<String>,
+}
+
+/// 过滤条件，None 表示不限
+#[derive(Debug, Default)]
+pub struct TodoFilter<'a> {
+    pub tag: Option<&'a str>,
+    pub owner: Option<&'a str>,
+    pub module: Option<&'a str>,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+/// 从源码中提取标签注释，按行号排序；每行只记第一个标签
+pub fn extract_todos(
+    source: &SourceText,
+    functions: &[FunctionInfo],
+    classes: &[ClassInfo],
+) -> Vec<Todo> {
+    let mut todos = Vec::new();
+    for (idx, line) in source.lines().iter().enumerate() {
+        let Some(body) = line.comments().find_map(parse_tagged) else {
+            continue;
+        };
+        let line_no = idx as u32 + 1;
+        let (tag, owner, ticket, text) = body;
+        todos.push(Todo {
+            tag: tag.to_string(),
+            text,
+            line: line_no,
+            owner,
+            ticket,
+            symbol: enclosing_symbol(functions, classes, line_no),
+        });
+    }
+    todos
+}
+
+/// 按条件汇总图谱中的注释，按文件、行号排序
+pub fn collect_todos(graph: &CodeGraph, filter: &TodoFilter) -> Vec<TodoItem> {
+    let owner = filter.owner.map(normalize_owner);
+    let mut items = Vec::new();
+    for (path, entry) in &graph.files {
+        if filter.module.is_some_and(|m| m != entry.module) {
+            continue;
+        }
+        for todo in &entry.todos {
+            if filter
+                .tag
+                .is_some_and(|t| !t.eq_ignore_ascii_case(&todo.tag))
+            {
+                continue;
+            }
+            if let Some(owner) = &owner {
+                if todo.owner.as_deref().map(normalize_owner).as_ref() != Some(owner) {
+                    continue;
+                }
+            }
+            items.push(TodoItem {
+                file: path.clone(),
+                module: entry.module.clone(),
+                line: todo.line,
+                tag: todo.tag.clone(),
+                text: todo.text.clone(),
+                owner: todo.owner.clone(),
+                ticket: todo.ticket.clone(),
+                symbol: todo.symbol.clone(),
+            });
+        }
+    }
+    items.sort_by(|a, b| a.file.cmp(&b.file).then(a.line.cmp(&b.line)));
+    items
+}
+
+/// 模块内各标签的注释数（overview 使用）
+pub fn module_todo_counts(graph: &CodeGraph, files: &[String]) -> BTreeMap<String, u32> {
+    let mut counts = BTreeMap::new();
+    for todo in files
+        .iter()
+        .filter_map(|f| graph.files.get(f))
+        .flat_map(|entry| &entry.todos)
+    {
+        *counts.entry(todo.tag.clone()).or_default() += 1;
+    }
+    counts
+}
+
+/// 单行文本：`file:line [TAG] text (owner, ticket) in symbol`
+pub fn format_todo(t: &TodoItem) -> String {
+    let mut out = format!("{}:{} [{}]", t.file, t.line, t.tag);
+    if !t.text.is_empty() {
+        out.push_str(&format!(" {}", t.text));
+    }
+    let meta: Vec<&str> = [t.owner.as_deref(), t.ticket.as_deref()]
+        .into_iter()
+        .flatten()
+        .collect();
+    if !meta.is_empty() {
+        out.push_str(&format!(" ({})", meta.join(", ")));
+    }
+    if let Some(symbol) = &t.symbol {
+        out.push_str(&format!(" in {}", symbol));
+    }
+    out
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+/// 解析一段注释 `// TAG(owner): text`，返回 (标签, 负责人, 工单号, 说明)
+fn parse_tagged(comment: &str) -> Option<(&'static str, Option<String>, Option<String>, String)> {
+    let body = comment
+        .trim_start()
+        .trim_start_matches(['/', '*', '!', '#'])
+        .trim_start();
+    let tag = TODO_TAGS.iter().find(|t| {
+        body.strip_prefix(**t)
+            .is_some_and(|rest| !rest.starts_with(|c: char| c.is_alphanumeric() || c == '_'))
+    })?;
+    let mut rest = &body[tag.len()..];
+
+    let mut owner = None;
+    let mut ticket = None;
+    if let Some(inner) = rest.strip_prefix('(') {
+        if let Some(close) = inner.find(')') {
+            let value = inner[..close].trim();
+            if is_ticket(value) {
+                ticket = Some(value.to_string());
+            } else if !value.is_empty() {
+                owner = Some(value.to_string());
+            }
+            rest = &inner[close + 1..];
+        }
+    }
+
+    let text = rest
+        .trim_start_matches([':', '-', ' ', '\t'])
+        .trim_end()
+        .trim_end_matches("*/")
+        .trim_end()
+        .to_string();
+    if ticket.is_none() {
+        ticket = text
+            .split(|c: char| c.is_whitespace() || matches!(c, ',' | '(' | ')' | '[' | ']' | ':'))
+            .find(|w| is_ticket(w))
+            .map(String::from);
+    }
+    Some((tag, owner, ticket, text))
+}
+
+/// `ABC-123` 或 `#123`
+fn is_ticket(s: &str) -> bool {
+    if let Some(num) = s.strip_prefix('#') {
+        return !num.is_empty() && num.chars().all(|c| c.is_ascii_digit());
+    }
+    let Some((project, num)) = s.split_once('-') else {
+        return false;
+    };
+    project.len() >= 2
+        && project.starts_with(|c: char| c.is_ascii_uppercase())
+        && project
+            .chars()
+            .all(|c| c.is_ascii_uppercase() || c.is_ascii_digit())
+        && !num.is_empty()
+        && num.chars().all(|c| c.is_ascii_digit())
+}
+
+/// 负责人比对时忽略 `@` 前缀与大小写
+fn normalize_owner(owner: &str) -> String {
+    owner.trim_start_matches('@').to_lowercase()
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::traverser::Language;
+
+    fn extract(lang: Language, src: &str) -> Vec<Todo> {
+        let functions = vec![FunctionInfo {
+            name: "handler".into(),
+            signature: String::new(),
+            start_line: 2,
+            end_line: 5,
+        }];
+        extract_todos(&SourceText::scan(lang, src.as_bytes()), &functions, &[])
+    }
+
+    #[test]
+    fn test_extract_tags_owner_and_ticket() {
+        let rs = "// TODO(alice): split this module\n\
+                  fn handler() {\n\
+                  let x = 1; // FIXME: overflow, see PROJ-42\n\
+                  let url = \"http://example.com\"; // a todo in prose\n\
+                  /* HACK(#17) */\n\
+                  }\n\
+                  // TODOS are not tags\n";
+        let todos = extract(Language::Rust, rs);
+        let summary: Vec<_> = todos
+            .iter()
+            .map(|t| {
+                (
+                    t.line,
+                    t.tag.as_str(),
+                    t.owner.as_deref(),
+                    t.ticket.as_deref(),
+                    t.symbol.as_deref(),
+                )
+            })
+            .collect();
+        assert_eq!(
+            summary,
+            vec![
+                (1, "TODO", Some("alice"), None, None),
+                (3, "FIXME", None, Some("PROJ-42"), Some("handler")),
+                (5, "HACK", None, Some("#17"), Some("handler")),
+            ]
+        );
+        assert_eq!(todos[0].text, "split this module");
+        assert_eq!(todos[2].text, "");
+
+        let py = "x = 1\ndef handler():\n    # XXX: remove after migration\n    s = '#TODO'\n";
+        let todos = extract(Language::Python, py);
+        assert_eq!(todos.len(), 1); // 字符串中的 `#TODO` 不是注释
+        assert_eq!(todos[0].tag, "XXX");
+        assert_eq!(todos[0].text, "remove after migration");
+
+        let ts = "/**\n * Parses input.\n * TODO(bob): handle BOM\n */\nconst s = \"// FIXME: not a comment\";\n";
+        let todos = extract(Language::TypeScript, ts);
+        assert_eq!(todos.len(), 1);
+        assert_eq!((todos[0].line, todos[0].tag.as_str()), (3, "TODO"));
+        assert_eq!(todos[0].owner.as_deref(), Some("bob"));
+    }
+
+    #[test]
+    fn test_collect_with_filters() {
+        let mut graph = crate::graph::create_empty_graph("p", "/tmp/p");
+        let mut entry = crate::graph::FileEntry {
+            language: "rust".into(),
+            module: "core".into(),
+            lines: 10,
+            ..Default::default()
+        };
+        entry.todos = extract_todos(
+            &SourceText::scan(
+                Language::Rust,
+                b"// TODO(@Alice): a\n// FIXME: b\n// TODO: c\n",
+            ),
+            &[],
+            &[],
+        );
+        graph.files.insert("src/lib.rs".into(), entry);
+
+        let all = collect_todos(&graph, &TodoFilter::default());
+        assert_eq!(all.len(), 3);
+        let todo_only = collect_todos(
+            &graph,
+            &TodoFilter {
+                tag: Some("todo"),
+                ..Default::default()
+            },
+        );
+        assert_eq!(todo_only.len(), 2);
+        let alice = collect_todos(
+            &graph,
+            &TodoFilter {
+                owner: Some("alice"),
+                ..Default::default()
+            },
+        );
+        assert_eq!(alice.len(), 1);
+        assert_eq!(format_todo(&alice[0]), "src/lib.rs:1 [TODO] a (@Alice)");
+        let other = collect_todos(
+            &graph,
+            &TodoFilter {
+                module: Some("other"),
+                ..Default::default()
+            },
+        );
+        assert!(other.is_empty());
+
+        let counts = module_todo_counts(&graph, &["src/lib.rs".to_string()]);
+        assert_eq!(counts.get("TODO"), Some(&2));
+        assert_eq!(counts.get("FIXME"), Some(&1));
+    }
+}
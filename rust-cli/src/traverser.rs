@@ -1,4 +1,6 @@
 use ignore::WalkBuilder;
+use serde::{Deserialize, Serialize};
+use std::collections::HashMap;
 use std::path::{Path, PathBuf};
 
 /// 默认排除目录
@@ -83,35 +85,236 @@ pub fn effective_language(path: &Path, base: Language, project_has_cpp: bool) ->
     }
 }
 
-/// 遍历目录，返回所有支持语言的源文件路径
+/// git 子模块的处理方式
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum SubmodulePolicy {
+    /// 不扫描子模块
+    Skip,
+    /// 子模块写入只读层 external.json（只保留导出 API），不进入项目图谱
+    External,
+    /// 子模块与项目文件一样作为普通模块扫描
+    #[default]
+    Scan,
+}
+
+impl SubmodulePolicy {
+    pub const ALL: &'static [&'static str] = &["skip", "external", "scan"];
+
+    pub fn as_str(self) -> &'static str {
+        match self {
+            SubmodulePolicy::Skip => "skip",
+            SubmodulePolicy::External => "external",
+            SubmodulePolicy::Scan => "scan",
+        }
+    }
+
+    pub fn parse(s: &str) -> Option<Self> {
+        match s {
+            "skip" => Some(SubmodulePolicy::Skip),
+            "external" => Some(SubmodulePolicy::External),
+            "scan" => Some(SubmodulePolicy::Scan),
+            _ => None,
+        }
+    }
+
+    pub fn is_default(&self) -> bool {
+        *self == SubmodulePolicy::default()
+    }
+}
+
+/// 源文件遍历选项（扫描时记录在 graph.json 的 config 中，update 沿用）
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub struct TraverseOptions {
+    /// 进入指向目录的符号链接；循环链接被跳过。
+    /// 指向文件的链接无论是否开启都会保留（包括指向项目外的，悬空链接除外），同一真实文件只保留一条路径。
+    pub follow_symlinks: bool,
+    pub submodules: SubmodulePolicy,
+}
+
+/// 遍历目录，返回所有支持语言的源文件路径（不进入目录链接，子模块按普通目录扫描）
 pub fn traverse_files(root_dir: &Path, extra_exclude: &[String]) -> Vec<PathBuf> {
+    traverse_files_with(root_dir, extra_exclude, &TraverseOptions::default())
+}
+
+/// 按指定的符号链接与子模块策略遍历源文件
+pub fn traverse_files_with(
+    root_dir: &Path,
+    extra_exclude: &[String],
+    opts: &TraverseOptions,
+) -> Vec<PathBuf> {
+    // 只保留支持语言的文件
+    walk_files(root_dir, extra_exclude, opts, |path| {
+        detect_language(path).is_some()
+    })
+}
+
+/// 读取根目录 `.gitmodules` 中声明的子模块路径（相对项目根，posix 形式，排序去重）
+pub fn detect_submodules(root_dir: &Path) -> Vec<String> {
+    let Ok(text) = std::fs::read_to_string(root_dir.join(".gitmodules")) else {
+        return vec![];
+    };
+    let mut paths: Vec<String> = text
+        .lines()
+        .filter_map(|line| {
+            let (key, value) = line.trim().split_once('=')?;
+            if key.trim() != "path" {
+                return None;
+            }
+            let path = value.trim().trim_matches('"').trim_end_matches('/');
+            Some(path.replace('\\', "/"))
+        })
+        .filter(|p| !p.is_empty())
+        .collect();
+    paths.sort();
+    paths.dedup();
+    paths
+}
+
+/// 构建清单文件名（另有任意 `*.csproj`）
+pub const MANIFEST_FILES: &[&str] = &[
+    "Cargo.toml",
+    "package.json",
+    "go.mod",
+    "pyproject.toml",
+    "pom.xml",
+    "build.gradle",
+    "build.gradle.kts",
+];
+
+/// 构建脚本文件名（另有任意 `*.mk`）；package.json 中的 scripts 也是构建目标
+pub const TARGET_FILES: &[&str] = &[
+    "Makefile",
+    "makefile",
+    "GNUmakefile",
+    "justfile",
+    "Justfile",
+    ".justfile",
+    "package.json",
+];
+
+/// 遍历目录，返回所有构建清单文件路径（排除规则、符号链接与子模块策略与 [`traverse_files_with`] 一致）
+pub fn traverse_manifests(
+    root_dir: &Path,
+    extra_exclude: &[String],
+    opts: &TraverseOptions,
+) -> Vec<PathBuf> {
+    traverse_named(root_dir, extra_exclude, opts, |name| {
+        MANIFEST_FILES.contains(&name) || name.ends_with(".csproj")
+    })
+}
+
+/// 遍历目录，返回所有 Makefile / justfile / package.json 路径（规则同 [`traverse_manifests`]）
+pub fn traverse_target_files(
+    root_dir: &Path,
+    extra_exclude: &[String],
+    opts: &TraverseOptions,
+) -> Vec<PathBuf> {
+    traverse_named(root_dir, extra_exclude, opts, |name| {
+        TARGET_FILES.contains(&name) || name.ends_with(".mk")
+    })
+}
+
+/// 按文件名筛选的遍历
+fn traverse_named(
+    root_dir: &Path,
+    extra_exclude: &[String],
+    opts: &TraverseOptions,
+    matches: impl Fn(&str) -> bool,
+) -> Vec<PathBuf> {
+    walk_files(root_dir, extra_exclude, opts, |path| {
+        matches(path.file_name().and_then(|n| n.to_str()).unwrap_or(""))
+    })
+}
+
+/// 遍历项目文件（隐藏文件参与遍历，遵守 .gitignore 与排除规则），返回满足 keep 的文件
+///
+/// 符号链接与子模块按 opts 处理：指向文件的链接保留（悬空链接除外），
+/// 只有进入目录链接受 `follow_symlinks` 控制；skip / external 时跳过子模块目录。
+fn walk_files(
+    root_dir: &Path,
+    extra_exclude: &[String],
+    opts: &TraverseOptions,
+    keep: impl Fn(&Path) -> bool,
+) -> Vec<PathBuf> {
     let mut files = Vec::new();
 
+    // skip / external 时子模块不进入项目图谱
+    let submodule_dirs: Vec<PathBuf> = match opts.submodules {
+        SubmodulePolicy::Scan => vec![],
+        _ => detect_submodules(root_dir)
+            .iter()
+            .map(|p| root_dir.join(p))
+            .collect(),
+    };
+    let mut saw_link = opts.follow_symlinks;
+
+    // 跟随链接时 ignore 会检测循环，循环处以 Err 返回并被 flatten 跳过
     let walker = WalkBuilder::new(root_dir)
         .hidden(false)
         .git_ignore(true)
         .git_global(true)
         .git_exclude(true)
+        .follow_links(opts.follow_symlinks)
         .build();
 
     for entry in walker.flatten() {
         let path = entry.path().to_path_buf();
 
+        // is_file 跟随链接：悬空链接与目录链接在此被跳过，指向文件的链接（含项目外）保留
         if !path.is_file() {
             continue;
         }
+        if entry.path_is_symlink() {
+            saw_link = true;
+        }
 
         // 检查是否在默认排除目录中
         if is_excluded(&path, root_dir, extra_exclude) {
             continue;
         }
+        if submodule_dirs.iter().any(|d| path.starts_with(d)) {
+            continue;
+        }
 
-        // 只保留支持语言的文件
-        if detect_language(&path).is_some() {
+        if keep(&path) {
             files.push(path);
         }
     }
 
+    files.sort();
+    if saw_link {
+        files = dedup_real_paths(root_dir, files);
+    }
+    files
+}
+
+/// 按真实路径去重：同一文件经多条路径可达时，优先保留不经过符号链接的路径，否则保留字典序最小的路径
+fn dedup_real_paths(root_dir: &Path, files: Vec<PathBuf>) -> Vec<PathBuf> {
+    let real_root = root_dir
+        .canonicalize()
+        .unwrap_or_else(|_| root_dir.to_path_buf());
+    let is_direct = |path: &Path, real: &Path| {
+        path.strip_prefix(root_dir)
+            .map(|rel| real_root.join(rel) == real)
+            .unwrap_or(false)
+    };
+
+    let mut chosen: HashMap<PathBuf, PathBuf> = HashMap::new();
+    for path in files {
+        let Ok(real) = path.canonicalize() else {
+            continue;
+        };
+        let replace = match chosen.get(&real) {
+            None => true,
+            Some(existing) => !is_direct(existing, &real) && is_direct(&path, &real),
+        };
+        if replace {
+            chosen.insert(real, path);
+        }
+    }
+
+    let mut files: Vec<PathBuf> = chosen.into_values().collect();
     files.sort();
     files
 }
@@ -203,4 +406,95 @@ mod tests {
         let no_cpp: Vec<PathBuf> = vec![PathBuf::from("a.c"), PathBuf::from("b.h")];
         assert!(!has_cpp_source_files(&no_cpp));
     }
+
+    #[cfg(unix)]
+    #[test]
+    fn test_traverse_symlinks_and_submodules() {
+        let base = std::env::temp_dir().join(format!("codegraph_traverse_{}", std::process::id()));
+        let _ = std::fs::remove_dir_all(&base);
+        let root = base.join("proj");
+        for dir in ["src/lib", "vendored/dep", "build", "../outside"] {
+            std::fs::create_dir_all(root.join(dir)).unwrap();
+        }
+        std::fs::write(root.join("src/lib/a.ts"), "export const a = 1;\n").unwrap();
+        std::fs::write(root.join("build/gen.ts"), "export const g = 1;\n").unwrap();
+        std::fs::write(base.join("outside/x.ts"), "export const x = 1;\n").unwrap();
+        std::fs::write(root.join("vendored/dep/b.ts"), "export const b = 2;\n").unwrap();
+        // 子模块内的清单与构建脚本与源码一样遵循子模块策略
+        for manifest in [
+            "Makefile",
+            "vendored/dep/Makefile",
+            "vendored/dep/package.json",
+        ] {
+            std::fs::write(root.join(manifest), "{}\n").unwrap();
+        }
+        std::fs::write(
+            root.join(".gitmodules"),
+            "[submodule \"dep\"]\n\tpath = vendored/dep\n\turl = https://example.com/dep.git\n",
+        )
+        .unwrap();
+        // 指向同一目录的别名，以及指回根目录的循环链接
+        std::os::unix::fs::symlink(root.join("src/lib"), root.join("src/alias")).unwrap();
+        std::os::unix::fs::symlink(&root, root.join("src/loop")).unwrap();
+        // 文件链接：指向排除目录中的文件（保留）、重复指向已扫描文件（去重）、
+        // 指向项目外（保留，如 monorepo 中链接进来的共享源码）与悬空链接（跳过）
+        let file_links = [
+            ("build/gen.ts", "src/gen.ts"),
+            ("src/lib/a.ts", "src/dup.ts"),
+            ("../outside/x.ts", "src/x.ts"),
+            ("missing.ts", "src/broken.ts"),
+        ];
+        for (target, link) in file_links {
+            std::os::unix::fs::symlink(root.join(target), root.join(link)).unwrap();
+        }
+
+        let rel = |files: Vec<PathBuf>| -> Vec<String> {
+            files
+                .iter()
+                .map(|f| {
+                    f.strip_prefix(&root)
+                        .unwrap()
+                        .to_string_lossy()
+                        .into_owned()
+                })
+                .collect()
+        };
+        let submodules = detect_submodules(&root);
+        let plain = rel(traverse_files(&root, &[]));
+        let skip = TraverseOptions {
+            follow_symlinks: true,
+            submodules: SubmodulePolicy::Skip,
+        };
+        let followed = rel(traverse_files_with(&root, &[], &skip));
+        let targets_scanned = rel(traverse_target_files(
+            &root,
+            &[],
+            &TraverseOptions::default(),
+        ));
+        let targets_skipped = rel(traverse_target_files(&root, &[], &skip));
+        let manifests_skipped = rel(traverse_manifests(&root, &[], &skip));
+        let _ = std::fs::remove_dir_all(&base);
+
+        assert_eq!(submodules, vec!["vendored/dep"]);
+        assert_eq!(
+            plain,
+            vec![
+                "src/gen.ts",
+                "src/lib/a.ts",
+                "src/x.ts",
+                "vendored/dep/b.ts"
+            ]
+        );
+        assert_eq!(followed, vec!["src/gen.ts", "src/lib/a.ts", "src/x.ts"]);
+        assert_eq!(
+            targets_scanned,
+            vec![
+                "Makefile",
+                "vendored/dep/Makefile",
+                "vendored/dep/package.json"
+            ]
+        );
+        assert_eq!(targets_skipped, vec!["Makefile"]);
+        assert!(manifests_skipped.is_empty());
+    }
 }
@@ -0,0 +1,1161 @@
+/// 终端浏览界面（tui）的状态与绘制
+///
+/// 本文件不依赖终端：命令层把按键转换成 [`Key`]，[`App::handle_key`] 更新状态并返回需要命令层执行的
+/// [`Action`]（打开编辑器、退出）；[`App::render`] 把当前状态画到字符网格 [`Canvas`] 上，再由命令层用
+/// crossterm 输出。这样界面逻辑可以脱离终端测试，SSH 下也只依赖 ANSI 转义序列。
+///
+/// 布局：
+/// - 左列：模块列表（搜索时为搜索结果），下方为所选模块的依赖面板（dependsOn / dependedBy）
+/// - 中列：模块内文件及其大纲（函数、类、类型）
+/// - 右列：所选条目的详情——符号的签名与引用、文件的语言与导入、模块的文件；按 `i` 时显示影响分析
+use std::path::Path;
+
+use crate::graph::CodeGraph;
+use crate::impact::analyze_impact;
+use crate::query::find_callers;
+
+/// 搜索结果最多保留的条数
+const MAX_SEARCH_RESULTS: usize = 200;
+
+/// 影响分析的 BFS 深度（与 `impact` 命令默认值一致）
+const IMPACT_DEPTH: u32 = 3;
+
+/// 底部按键提示
+const HELP: &str =
+    "↑↓/jk move  ←→/Tab pane  / search  Enter open  e edit  i impact  Esc back  q quit";
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// 终端无关的按键
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Key {
+    Up,
+    Down,
+    PageUp,
+    PageDown,
+    Home,
+    End,
+    Left,
+    Right,
+    Tab,
+    BackTab,
+    Enter,
+    Esc,
+    Backspace,
+    Char(char),
+}
+
+/// 需要命令层执行的动作
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum Action {
+    None,
+    Quit,
+    /// 在编辑器中打开（file 为相对项目根的路径）
+    Edit {
+        file: String,
+        line: u32,
+    },
+}
+
+/// 当前焦点所在的面板
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Pane {
+    Modules,
+    Outline,
+    References,
+}
+
+/// 大纲中的一行：文件或文件内的符号
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct OutlineItem {
+    pub file: String,
+    /// file / function / class / type
+    pub kind: &'static str,
+    pub name: String,
+    pub line: u32,
+    pub end_line: u32,
+}
+
+/// 搜索命中的条目
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct SearchHit {
+    /// module / file / function / class / type
+    pub kind: &'static str,
+    pub name: String,
+    pub module: String,
+    pub file: Option<String>,
+    pub line: u32,
+    pub score: i32,
+}
+
+/// 符号的一处引用
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Reference {
+    pub file: String,
+    pub line: u32,
+    /// 该行是 import 语句而不是使用处
+    pub import: bool,
+}
+
+/// 单元格样式
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Style {
+    Normal,
+    Bold,
+    Dim,
+    /// 反色（选中行、标题栏）
+    Selected,
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+struct Cell {
+    /// 宽字符占两格，第二格为 '\0'
+    ch: char,
+    style: Style,
+}
+
+/// 字符网格，一帧的绘制结果
+pub struct Canvas {
+    pub width: u16,
+    pub height: u16,
+    cells: Vec<Cell>,
+}
+
+/// 浏览器状态
+pub struct App {
+    graph: CodeGraph,
+    modules: Vec<String>,
+    pub focus: Pane,
+    module_idx: usize,
+    outline: Vec<OutlineItem>,
+    outline_idx: usize,
+    references: Vec<Reference>,
+    reference_idx: usize,
+    /// Some 时处于搜索模式
+    search: Option<String>,
+    hits: Vec<SearchHit>,
+    hit_idx: usize,
+    /// 影响分析结果（覆盖右列），Esc 关闭
+    impact: Option<Vec<String>>,
+    impact_scroll: usize,
+    /// 状态栏消息（下一次按键后清除）
+    pub message: String,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+impl Canvas {
+    pub fn new(width: u16, height: u16) -> Self {
+        let blank = Cell {
+            ch: ' ',
+            style: Style::Normal,
+        };
+        Canvas {
+            width,
+            height,
+            cells: vec![blank; width as usize * height as usize],
+        }
+    }
+
+    /// 从 (x, y) 起写入文本，最多占 max_width 列，返回实际占用的列数
+    pub fn put(&mut self, x: u16, y: u16, text: &str, max_width: u16, style: Style) -> u16 {
+        if y >= self.height {
+            return 0;
+        }
+        let limit = max_width.min(self.width.saturating_sub(x));
+        let mut used = 0u16;
+        for ch in text.chars() {
+            let ch = if ch.is_control() { ' ' } else { ch };
+            let w = char_width(ch);
+            if used + w > limit {
+                break;
+            }
+            let idx = self.index(x + used, y);
+            self.cells[idx] = Cell { ch, style };
+            if w == 2 {
+                self.cells[idx + 1] = Cell { ch: '\0', style };
+            }
+            used += w;
+        }
+        used
+    }
+
+    /// 把一段区域的样式设为 style（用于整行高亮），不改变内容
+    pub fn fill(&mut self, x: u16, y: u16, width: u16, style: Style) {
+        if y >= self.height {
+            return;
+        }
+        for col in x..(x + width).min(self.width) {
+            let idx = self.index(col, y);
+            self.cells[idx].style = style;
+        }
+    }
+
+    /// 一行内容按样式切分成若干段，供命令层逐段输出
+    pub fn row_runs(&self, y: u16) -> Vec<(Style, String)> {
+        let mut runs: Vec<(Style, String)> = Vec::new();
+        for x in 0..self.width {
+            let cell = self.cells[self.index(x, y)];
+            if cell.ch == '\0' {
+                continue;
+            }
+            match runs.last_mut() {
+                Some((style, text)) if *style == cell.style => text.push(cell.ch),
+                _ => runs.push((cell.style, cell.ch.to_string())),
+            }
+        }
+        runs
+    }
+
+    /// 一行的纯文本（去掉行尾空白）
+    pub fn row_text(&self, y: u16) -> String {
+        let text: String = self.row_runs(y).into_iter().map(|(_, t)| t).collect();
+        text.trim_end().to_string()
+    }
+
+    fn index(&self, x: u16, y: u16) -> usize {
+        y as usize * self.width as usize + x as usize
+    }
+}
+
+impl App {
+    pub fn new(graph: CodeGraph) -> Self {
+        let modules: Vec<String> = graph.modules.keys().cloned().collect();
+        let mut app = App {
+            graph,
+            modules,
+            focus: Pane::Modules,
+            module_idx: 0,
+            outline: vec![],
+            outline_idx: 0,
+            references: vec![],
+            reference_idx: 0,
+            search: None,
+            hits: vec![],
+            hit_idx: 0,
+            impact: None,
+            impact_scroll: 0,
+            message: String::new(),
+        };
+        app.select_module(0);
+        app
+    }
+
+    /// 处理一次按键
+    pub fn handle_key(&mut self, key: Key) -> Action {
+        self.message.clear();
+        if self.search.is_some() {
+            return self.handle_search_key(key);
+        }
+        if self.impact.is_some() {
+            match key {
+                Key::Esc | Key::Char('i') => self.impact = None,
+                Key::Up | Key::Char('k') => {
+                    self.impact_scroll = self.impact_scroll.saturating_sub(1)
+                }
+                Key::Down | Key::Char('j') => self.impact_scroll += 1,
+                Key::PageUp => self.impact_scroll = self.impact_scroll.saturating_sub(10),
+                Key::PageDown => self.impact_scroll += 10,
+                Key::Char('q') => return Action::Quit,
+                _ => {}
+            }
+            return Action::None;
+        }
+
+        match key {
+            Key::Char('q') => return Action::Quit,
+            Key::Char('/') => {
+                self.search = Some(String::new());
+                self.hits.clear();
+                self.hit_idx = 0;
+            }
+            Key::Up | Key::Char('k') => self.move_selection(-1),
+            Key::Down | Key::Char('j') => self.move_selection(1),
+            Key::PageUp => self.move_selection(-10),
+            Key::PageDown => self.move_selection(10),
+            Key::Home | Key::Char('g') => self.move_selection(i64::MIN / 2),
+            Key::End | Key::Char('G') => self.move_selection(i64::MAX / 2),
+            Key::Right | Key::Tab | Key::Char('l') => self.cycle_focus(true),
+            Key::Left | Key::BackTab | Key::Char('h') => self.cycle_focus(false),
+            Key::Esc => {
+                if self.focus != Pane::Modules {
+                    self.cycle_focus(false);
+                }
+            }
+            Key::Enter => match self.focus {
+                Pane::Modules => self.cycle_focus(true),
+                _ => return self.edit_action(),
+            },
+            Key::Char('e') => return self.edit_action(),
+            Key::Char('i') => self.open_impact(),
+            _ => {}
+        }
+        Action::None
+    }
+
+    /// 把当前状态画成 width × height 的字符网格
+    pub fn render(&self, width: u16, height: u16) -> Canvas {
+        let mut canvas = Canvas::new(width, height);
+        if width < 20 || height < 6 {
+            canvas.put(0, 0, "terminal too small", width, Style::Bold);
+            return canvas;
+        }
+
+        // 标题栏
+        let title = format!(
+            " codegraph · {} · {} modules · {} files",
+            self.graph.project.name,
+            self.modules.len(),
+            self.graph.files.len()
+        );
+        canvas.put(0, 0, &title, width, Style::Selected);
+        canvas.fill(0, 0, width, Style::Selected);
+
+        let body_top = 1u16;
+        let body_height = height - 2;
+        let left_w = (width as u32 * 28 / 100).max(16) as u16;
+        let mid_w = (width as u32 * 36 / 100).max(16) as u16;
+        let mid_x = left_w + 1;
+        let right_x = mid_x + mid_w + 1;
+        let right_w = width.saturating_sub(right_x);
+        for y in body_top..body_top + body_height {
+            canvas.put(left_w, y, "│", 1, Style::Dim);
+            canvas.put(mid_x + mid_w, y, "│", 1, Style::Dim);
+        }
+
+        self.render_left(&mut canvas, 0, body_top, left_w, body_height);
+        self.render_outline(&mut canvas, mid_x, body_top, mid_w, body_height);
+        if right_w > 0 {
+            self.render_detail(&mut canvas, right_x, body_top, right_w, body_height);
+        }
+
+        // 状态栏
+        let status = match &self.search {
+            Some(query) => format!(
+                "/{}▏  {} hit(s)  ↑↓ select  Enter jump  Esc cancel",
+                query,
+                self.hits.len()
+            ),
+            None if !self.message.is_empty() => self.message.clone(),
+            None => HELP.to_string(),
+        };
+        canvas.put(0, height - 1, &status, width, Style::Dim);
+        canvas
+    }
+
+    /// 当前选中条目对应的相对路径与行号（打开编辑器用）
+    pub fn selected_location(&self) -> Option<(String, u32)> {
+        match self.focus {
+            Pane::References => self
+                .references
+                .get(self.reference_idx)
+                .map(|r| (r.file.clone(), r.line)),
+            Pane::Outline => self
+                .outline
+                .get(self.outline_idx)
+                .map(|o| (o.file.clone(), o.line)),
+            Pane::Modules => self.outline.first().map(|o| (o.file.clone(), 1)),
+        }
+    }
+
+    // ── 按键处理 ──
+
+    fn handle_search_key(&mut self, key: Key) -> Action {
+        let Some(query) = self.search.as_mut() else {
+            return Action::None;
+        };
+        match key {
+            Key::Esc => {
+                self.search = None;
+                return Action::None;
+            }
+            Key::Enter => {
+                if let Some(hit) = self.hits.get(self.hit_idx).cloned() {
+                    self.jump_to(&hit);
+                }
+                self.search = None;
+                return Action::None;
+            }
+            Key::Up => self.hit_idx = self.hit_idx.saturating_sub(1),
+            Key::Down => {
+                self.hit_idx = (self.hit_idx + 1).min(self.hits.len().saturating_sub(1));
+            }
+            Key::Backspace => {
+                query.pop();
+                self.refresh_hits();
+            }
+            Key::Char(c) => {
+                query.push(c);
+                self.refresh_hits();
+            }
+            _ => {}
+        }
+        Action::None
+    }
+
+    fn refresh_hits(&mut self) {
+        let query = self.search.clone().unwrap_or_default();
+        self.hits = search_graph(&self.graph, &query);
+        self.hit_idx = 0;
+    }
+
+    /// 跳到搜索结果：选中其模块，再在大纲中选中对应文件或符号
+    fn jump_to(&mut self, hit: &SearchHit) {
+        let Some(idx) = self.modules.iter().position(|m| *m == hit.module) else {
+            return;
+        };
+        self.select_module(idx);
+        self.focus = Pane::Modules;
+        if let Some(file) = &hit.file {
+            let pos = self.outline.iter().position(|o| {
+                o.file == *file
+                    && (hit.kind == "file" || (o.name == hit.name && o.line == hit.line))
+            });
+            if let Some(pos) = pos {
+                self.select_outline(pos);
+                self.focus = Pane::Outline;
+            }
+        }
+    }
+
+    fn move_selection(&mut self, delta: i64) {
+        let step = |idx: usize, len: usize| -> usize {
+            if len == 0 {
+                return 0;
+            }
+            (idx as i64 + delta).clamp(0, len as i64 - 1) as usize
+        };
+        match self.focus {
+            Pane::Modules => {
+                let idx = step(self.module_idx, self.modules.len());
+                if idx != self.module_idx {
+                    self.select_module(idx);
+                }
+            }
+            Pane::Outline => {
+                let idx = step(self.outline_idx, self.outline.len());
+                self.select_outline(idx);
+            }
+            Pane::References => {
+                self.reference_idx = step(self.reference_idx, self.references.len());
+            }
+        }
+    }
+
+    fn cycle_focus(&mut self, forward: bool) {
+        let mut panes = vec![Pane::Modules, Pane::Outline];
+        if !self.references.is_empty() {
+            panes.push(Pane::References);
+        }
+        let pos = panes.iter().position(|p| *p == self.focus).unwrap_or(0);
+        let next = if forward {
+            (pos + 1) % panes.len()
+        } else {
+            (pos + panes.len() - 1) % panes.len()
+        };
+        self.focus = panes[next];
+    }
+
+    fn edit_action(&mut self) -> Action {
+        match self.selected_location() {
+            Some((file, line)) => Action::Edit { file, line },
+            None => {
+                self.message = "Nothing to open.".to_string();
+                Action::None
+            }
+        }
+    }
+
+    fn open_impact(&mut self) {
+        let target = match self.focus {
+            Pane::Modules => self.modules.get(self.module_idx).cloned(),
+            _ => self.selected_location().map(|(file, _)| file),
+        };
+        let Some(target) = target else {
+            self.message = "Nothing selected.".to_string();
+            return;
+        };
+        self.impact = Some(impact_lines(&self.graph, &target));
+        self.impact_scroll = 0;
+    }
+
+    fn select_module(&mut self, idx: usize) {
+        self.module_idx = idx;
+        self.outline = self
+            .modules
+            .get(idx)
+            .map(|m| module_outline(&self.graph, m))
+            .unwrap_or_default();
+        self.select_outline(0);
+    }
+
+    fn select_outline(&mut self, idx: usize) {
+        self.outline_idx = idx;
+        self.references = self
+            .outline
+            .get(idx)
+            .map(|item| symbol_references(&self.graph, item))
+            .unwrap_or_default();
+        self.reference_idx = 0;
+    }
+
+    // ── 绘制 ──
+
+    fn render_left(&self, canvas: &mut Canvas, x: u16, y: u16, w: u16, h: u16) {
+        if self.search.is_some() {
+            canvas.put(x, y, "Search", w, Style::Bold);
+            let rows: Vec<String> = self
+                .hits
+                .iter()
+                .map(|hit| format!("{} {}", kind_marker(hit.kind), hit.name))
+                .collect();
+            draw_list(canvas, x, y + 1, w, h - 1, &rows, self.hit_idx, true);
+            return;
+        }
+
+        // 依赖面板占下方约三分之一
+        let module = self.modules.get(self.module_idx);
+        let entry = module.and_then(|m| self.graph.modules.get(m));
+        let dep_h = (h / 3).max(4).min(h.saturating_sub(3));
+        let list_h = h - dep_h;
+
+        canvas.put(
+            x,
+            y,
+            &format!("Modules ({})", self.modules.len()),
+            w,
+            Style::Bold,
+        );
+        draw_list(
+            canvas,
+            x,
+            y + 1,
+            w,
+            list_h - 1,
+            &self.modules,
+            self.module_idx,
+            self.focus == Pane::Modules,
+        );
+
+        let dep_y = y + list_h;
+        canvas.put(x, dep_y, &"─".repeat(w as usize), w, Style::Dim);
+        let mut lines: Vec<(String, Style)> = Vec::new();
+        if let Some(entry) = entry {
+            lines.push((
+                format!("Depends on ({})", entry.depends_on.len()),
+                Style::Bold,
+            ));
+            lines.extend(
+                entry
+                    .depends_on
+                    .iter()
+                    .map(|d| (format!("  → {}", d), Style::Normal)),
+            );
+            lines.push((
+                format!("Used by ({})", entry.depended_by.len()),
+                Style::Bold,
+            ));
+            lines.extend(
+                entry
+                    .depended_by
+                    .iter()
+                    .map(|d| (format!("  ← {}", d), Style::Normal)),
+            );
+        }
+        for (i, (text, style)) in lines.iter().take(dep_h as usize - 1).enumerate() {
+            canvas.put(x, dep_y + 1 + i as u16, text, w, *style);
+        }
+    }
+
+    fn render_outline(&self, canvas: &mut Canvas, x: u16, y: u16, w: u16, h: u16) {
+        let title = match self.modules.get(self.module_idx) {
+            Some(m) => format!("Outline · {}", m),
+            None => "Outline".to_string(),
+        };
+        canvas.put(x + 1, y, &title, w - 1, Style::Bold);
+        let rows: Vec<String> = self
+            .outline
+            .iter()
+            .map(|item| match item.kind {
+                "file" => format!("{} ({} lines)", item.name, item.end_line),
+                kind => format!("  {} {}  :{}", kind_marker(kind), item.name, item.line),
+            })
+            .collect();
+        draw_list(
+            canvas,
+            x + 1,
+            y + 1,
+            w - 1,
+            h - 1,
+            &rows,
+            self.outline_idx,
+            self.focus == Pane::Outline,
+        );
+    }
+
+    fn render_detail(&self, canvas: &mut Canvas, x: u16, y: u16, w: u16, h: u16) {
+        let x = x + 1;
+        let w = w.saturating_sub(1);
+        if let Some(lines) = &self.impact {
+            canvas.put(x, y, "Impact  (Esc to close)", w, Style::Bold);
+            for (i, line) in lines
+                .iter()
+                .skip(self.impact_scroll)
+                .take(h as usize - 1)
+                .enumerate()
+            {
+                canvas.put(x, y + 1 + i as u16, line, w, Style::Normal);
+            }
+            return;
+        }
+
+        let mut lines = self.detail_lines();
+        let header_len = lines.len();
+        for (i, r) in self.references.iter().enumerate() {
+            let text = format!(
+                "  {}:{}{}",
+                r.file,
+                r.line,
+                if r.import { "  (import)" } else { "" }
+            );
+            let style = if self.focus == Pane::References && i == self.reference_idx {
+                Style::Selected
+            } else {
+                Style::Normal
+            };
+            lines.push((text, style));
+        }
+
+        // 选中的引用保持在可见范围内
+        let visible = h as usize;
+        let target = header_len + self.reference_idx;
+        let offset = if self.focus == Pane::References && target >= visible {
+            target + 1 - visible
+        } else {
+            0
+        };
+        for (i, (text, style)) in lines.iter().skip(offset).take(visible).enumerate() {
+            let row = y + i as u16;
+            canvas.put(x, row, text, w, *style);
+            if *style == Style::Selected {
+                canvas.fill(x, row, w, Style::Selected);
+            }
+        }
+    }
+
+    /// 右列引用列表之前的内容
+    fn detail_lines(&self) -> Vec<(String, Style)> {
+        let mut lines: Vec<(String, Style)> = Vec::new();
+        let item = match self.focus {
+            Pane::Modules => None,
+            _ => self.outline.get(self.outline_idx),
+        };
+        match item {
+            None => {
+                let Some(module) = self.modules.get(self.module_idx) else {
+                    lines.push(("No modules in the graph.".to_string(), Style::Dim));
+                    return lines;
+                };
+                let entry = &self.graph.modules[module];
+                lines.push((format!("module {}", module), Style::Bold));
+                lines.push((format!("{} file(s)", entry.files.len()), Style::Dim));
+                lines.push((String::new(), Style::Normal));
+                lines.extend(entry.files.iter().map(|f| (f.clone(), Style::Normal)));
+            }
+            Some(item) if item.kind == "file" => {
+                let Some(file) = self.graph.files.get(&item.file) else {
+                    return lines;
+                };
+                lines.push((item.file.clone(), Style::Bold));
+                lines.push((
+                    format!(
+                        "{} · {} lines · {} fn · {} class{}",
+                        file.language,
+                        file.lines,
+                        file.functions.len(),
+                        file.classes.len(),
+                        if file.is_entry_point {
+                            " · entry point"
+                        } else {
+                            ""
+                        }
+                    ),
+                    Style::Dim,
+                ));
+                if !file.imports.is_empty() {
+                    lines.push((String::new(), Style::Normal));
+                    lines.push((format!("Imports ({})", file.imports.len()), Style::Bold));
+                    for imp in &file.imports {
+                        lines.push((
+                            format!("  :{} {}", imp.import_line, imp.source),
+                            Style::Normal,
+                        ));
+                    }
+                }
+            }
+            Some(item) => {
+                lines.push((format!("{} {}", item.kind, item.name), Style::Bold));
+                lines.push((
+                    format!("{}:{}-{}", item.file, item.line, item.end_line),
+                    Style::Dim,
+                ));
+                if let Some(sig) = symbol_signature(&self.graph, item) {
+                    lines.push((sig, Style::Normal));
+                }
+                lines.push((String::new(), Style::Normal));
+                lines.push((
+                    format!("References ({})", self.references.len()),
+                    Style::Bold,
+                ));
+            }
+        }
+        lines
+    }
+}
+
+/// 模糊匹配：query 的字符按顺序出现在 candidate 中（不区分大小写）时返回分数，越高越相关
+///
+/// 连续命中、在词首（开头、分隔符之后、驼峰边界）命中加分；完全相同额外加分；候选越长分数越低。
+pub fn fuzzy_score(query: &str, candidate: &str) -> Option<i32> {
+    if query.is_empty() {
+        return None;
+    }
+    let chars: Vec<char> = candidate.chars().collect();
+    let mut score = 0i32;
+    let mut pos = 0usize;
+    let mut prev: Option<usize> = None;
+    for q in query.chars().flat_map(char::to_lowercase) {
+        let found = (pos..chars.len()).find(|&i| chars[i].to_lowercase().eq(std::iter::once(q)))?;
+        score += 1;
+        if found > 0 && prev == Some(found - 1) {
+            score += 5;
+        }
+        let boundary = found == 0
+            || !chars[found - 1].is_alphanumeric()
+            || (chars[found].is_uppercase() && chars[found - 1].is_lowercase());
+        if boundary {
+            score += 3;
+        }
+        prev = Some(found);
+        pos = found + 1;
+    }
+    if candidate.to_lowercase() == query.to_lowercase() {
+        score += 20;
+    }
+    Some(score - chars.len() as i32 / 8)
+}
+
+/// 在模块名、文件路径与符号名中模糊搜索（按分数排序，最多 MAX_SEARCH_RESULTS 条）
+pub fn search_graph(graph: &CodeGraph, query: &str) -> Vec<SearchHit> {
+    let mut hits = Vec::new();
+    let mut push = |kind: &'static str, name: &str, module: &str, file: Option<&str>, line: u32| {
+        if let Some(score) = fuzzy_score(query, name) {
+            hits.push(SearchHit {
+                kind,
+                name: name.to_string(),
+                module: module.to_string(),
+                file: file.map(String::from),
+                line,
+                score,
+            });
+        }
+    };
+    for module in graph.modules.keys() {
+        push("module", module, module, None, 0);
+    }
+    for (path, file) in &graph.files {
+        push("file", path, &file.module, Some(path), 1);
+        for f in &file.functions {
+            push("function", &f.name, &file.module, Some(path), f.start_line);
+        }
+        for c in &file.classes {
+            push("class", &c.name, &file.module, Some(path), c.start_line);
+        }
+        for t in &file.types {
+            push("type", &t.name, &file.module, Some(path), t.start_line);
+        }
+    }
+    hits.sort_by(|a, b| b.score.cmp(&a.score).then_with(|| a.name.cmp(&b.name)));
+    hits.truncate(MAX_SEARCH_RESULTS);
+    hits
+}
+
+/// 模块大纲：每个文件一行，其后按行号列出文件内的函数、类与类型
+pub fn module_outline(graph: &CodeGraph, module: &str) -> Vec<OutlineItem> {
+    let Some(entry) = graph.modules.get(module) else {
+        return vec![];
+    };
+    let mut files = entry.files.clone();
+    files.sort();
+    let mut items = Vec::new();
+    for path in files {
+        let Some(file) = graph.files.get(&path) else {
+            continue;
+        };
+        items.push(OutlineItem {
+            file: path.clone(),
+            kind: "file",
+            name: path.clone(),
+            line: 1,
+            end_line: file.lines,
+        });
+        let mut symbols: Vec<OutlineItem> = Vec::new();
+        let mut add = |kind: &'static str, name: &str, line: u32, end_line: u32| {
+            symbols.push(OutlineItem {
+                file: path.clone(),
+                kind,
+                name: name.to_string(),
+                line,
+                end_line,
+            });
+        };
+        for f in &file.functions {
+            add("function", &f.name, f.start_line, f.end_line);
+        }
+        for c in &file.classes {
+            add("class", &c.name, c.start_line, c.end_line);
+        }
+        for t in &file.types {
+            add("type", &t.name, t.start_line, t.end_line);
+        }
+        symbols.sort_by_key(|s| (s.line, s.name.clone()));
+        items.extend(symbols);
+    }
+    items
+}
+
+/// 打开 file:line 的编辑器命令行（`$VISUAL` / `$EDITOR` 的值，可带参数）
+///
+/// VS Code 系用 `-g file:line`，Sublime / Zed / Helix 用 `file:line`，其余（vi、nano、emacs 等）用 `+line file`。
+pub fn editor_command(editor: &str, path: &str, line: u32) -> Vec<String> {
+    let mut parts: Vec<String> = editor.split_whitespace().map(String::from).collect();
+    if parts.is_empty() {
+        parts.push("vi".to_string());
+    }
+    let program = Path::new(&parts[0])
+        .file_name()
+        .and_then(|n| n.to_str())
+        .unwrap_or("")
+        .to_string();
+    match program.as_str() {
+        "code" | "code-insiders" | "codium" | "cursor" => {
+            parts.push("-g".to_string());
+            parts.push(format!("{}:{}", path, line));
+        }
+        "subl" | "zed" | "hx" | "helix" => parts.push(format!("{}:{}", path, line)),
+        _ => {
+            parts.push(format!("+{}", line));
+            parts.push(path.to_string());
+        }
+    }
+    parts
+}
+
+// ── 内部工具函数 ──────────────────────────────────────────────────────────────
+
+/// 绘制可滚动列表，选中行保持可见
+#[allow(clippy::too_many_arguments)]
+fn draw_list(
+    canvas: &mut Canvas,
+    x: u16,
+    y: u16,
+    w: u16,
+    h: u16,
+    rows: &[String],
+    selected: usize,
+    focused: bool,
+) {
+    let h = h as usize;
+    if h == 0 {
+        return;
+    }
+    let offset = if selected >= h { selected + 1 - h } else { 0 };
+    for (i, row) in rows.iter().enumerate().skip(offset).take(h) {
+        let line = y + (i - offset) as u16;
+        let style = match (i == selected, focused) {
+            (true, true) => Style::Selected,
+            (true, false) => Style::Bold,
+            _ => Style::Normal,
+        };
+        canvas.put(x, line, row, w, style);
+        if style == Style::Selected {
+            canvas.fill(x, line, w, Style::Selected);
+        }
+    }
+}
+
+fn kind_marker(kind: &str) -> &'static str {
+    match kind {
+        "module" => "[mod]",
+        "file" => "[file]",
+        "function" => "ƒ",
+        "class" => "◆",
+        "type" => "τ",
+        _ => "·",
+    }
+}
+
+fn symbol_signature(graph: &CodeGraph, item: &OutlineItem) -> Option<String> {
+    let file = graph.files.get(&item.file)?;
+    file.functions
+        .iter()
+        .find(|f| f.name == item.name && f.start_line == item.line)
+        .map(|f| f.signature.clone())
+        .filter(|s| !s.is_empty() && *s != item.name)
+}
+
+/// 符号的引用：同文件使用处与其他文件的 import / 使用处，按文件、行号排序
+fn symbol_references(graph: &CodeGraph, item: &OutlineItem) -> Vec<Reference> {
+    if item.kind == "file" {
+        return vec![];
+    }
+    let (_, refs) = find_callers(graph, &item.file, &item.name);
+    let mut result = Vec::new();
+    for r in refs {
+        if r.use_lines.is_empty() && r.import_line > 0 {
+            result.push(Reference {
+                file: r.file.clone(),
+                line: r.import_line,
+                import: true,
+            });
+        }
+        for &line in &r.use_lines {
+            result.push(Reference {
+                file: r.file.clone(),
+                line,
+                import: false,
+            });
+        }
+    }
+    result.sort_by(|a, b| a.file.cmp(&b.file).then(a.line.cmp(&b.line)));
+    result
+}
+
+/// 影响分析结果的文本行
+fn impact_lines(graph: &CodeGraph, target: &str) -> Vec<String> {
+    let result = analyze_impact(graph, target, IMPACT_DEPTH);
+    let list = |items: &[String]| {
+        if items.is_empty() {
+            "(none)".to_string()
+        } else {
+            items.join(", ")
+        }
+    };
+    let mut lines = vec![
+        format!("{} ({})", target, result.target_type.as_str()),
+        format!("Module: {}", result.target_module),
+        format!("Direct dependants: {}", list(&result.direct_dependants)),
+        format!(
+            "Transitive dependants: {}",
+            list(&result.transitive_dependants)
+        ),
+        String::new(),
+        format!("Impacted files ({}):", result.impacted_files.len()),
+    ];
+    lines.extend(result.impacted_files.iter().map(|f| format!("  {}", f)));
+    if !result.targets.is_empty() {
+        lines.push(String::new());
+        lines.push(format!("Build targets ({}):", result.targets.len()));
+        for t in &result.targets {
+            match &t.via {
+                Some(via) => lines.push(format!("  {} (via {})", t.label, via)),
+                None => lines.push(format!("  {}", t.label)),
+            }
+        }
+    }
+    if !result.audit_sites.is_empty() {
+        lines.push(String::new());
+        lines.push(format!("Audit sites ({}):", result.audit_sites.len()));
+        for site in &result.audit_sites {
+            lines.push(format!("  {}", crate::audit_sites::format_finding(site)));
+        }
+    }
+    lines
+}
+
+/// 终端显示宽度：CJK、全角与常见表情符号占两列
+fn char_width(ch: char) -> u16 {
+    let c = ch as u32;
+    let wide = matches!(c,
+        0x1100..=0x115F
+        | 0x2E80..=0x303E
+        | 0x3041..=0x33FF
+        | 0x3400..=0x4DBF
+        | 0x4E00..=0x9FFF
+        | 0xA000..=0xA4CF
+        | 0xAC00..=0xD7A3
+        | 0xF900..=0xFAFF
+        | 0xFE30..=0xFE4F
+        | 0xFF00..=0xFF60
+        | 0xFFE0..=0xFFE6
+        | 0x1F300..=0x1F64F
+        | 0x1F900..=0x1F9FF
+        | 0x20000..=0x3FFFD);
+    if wide {
+        2
+    } else {
+        1
+    }
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::graph::{
+        create_empty_graph, FileEntry, FunctionInfo, ImportInfo, ModuleEntry, SymbolRef,
+    };
+
+    fn file(module: &str, functions: &[(&str, u32)]) -> FileEntry {
+        FileEntry {
+            language: "typescript".into(),
+            module: module.into(),
+            lines: 40,
+            functions: functions
+                .iter()
+                .map(|(name, line)| FunctionInfo {
+                    name: name.to_string(),
+                    signature: format!("function {}()", name),
+                    start_line: *line,
+                    end_line: line + 5,
+                })
+                .collect(),
+            exports: functions.iter().map(|(n, _)| n.to_string()).collect(),
+            ..Default::default()
+        }
+    }
+
+    fn sample_graph() -> CodeGraph {
+        let mut graph = create_empty_graph("demo", "/tmp/demo");
+        graph.files.insert(
+            "src/auth/login.ts".into(),
+            file("auth", &[("validateToken", 20), ("login", 3)]),
+        );
+        let mut api = file("api", &[("handleRequest", 1)]);
+        api.imports.push(ImportInfo {
+            source: "../auth/login".into(),
+            symbols: vec!["login".into()],
+            is_external: false,
+            import_line: 1,
+        });
+        api.symbol_refs.insert(
+            "login".into(),
+            SymbolRef {
+                symbol: "login".into(),
+                import_line: 1,
+                use_lines: vec![7, 9],
+            },
+        );
+        graph.files.insert("src/api/routes.ts".into(), api);
+        graph.modules.insert(
+            "api".into(),
+            ModuleEntry {
+                files: vec!["src/api/routes.ts".into()],
+                depends_on: vec!["auth".into()],
+                depended_by: vec![],
+            },
+        );
+        graph.modules.insert(
+            "auth".into(),
+            ModuleEntry {
+                files: vec!["src/auth/login.ts".into()],
+                depends_on: vec![],
+                depended_by: vec!["api".into()],
+            },
+        );
+        graph
+    }
+
+    #[test]
+    fn test_fuzzy_score_and_search() {
+        assert!(fuzzy_score("vt", "validateToken").is_some());
+        assert!(fuzzy_score("tv", "validateToken").is_none());
+        assert!(fuzzy_score("login", "login") > fuzzy_score("login", "loginHandler"));
+        assert!(fuzzy_score("vt", "validateToken") > fuzzy_score("vt", "divotate"));
+
+        let graph = sample_graph();
+        let hits = search_graph(&graph, "login");
+        assert_eq!(hits[0].kind, "function");
+        assert_eq!(hits[0].name, "login");
+        assert!(hits
+            .iter()
+            .any(|h| h.kind == "file" && h.name == "src/auth/login.ts"));
+    }
+
+    #[test]
+    fn test_navigation_search_and_edit() {
+        let mut app = App::new(sample_graph());
+        // 模块按名称排序：api, auth；大纲中符号按行号排列
+        app.handle_key(Key::Down);
+        let names: Vec<&str> = app.outline.iter().map(|o| o.name.as_str()).collect();
+        assert_eq!(names, vec!["src/auth/login.ts", "login", "validateToken"]);
+
+        // 搜索跳转到 login，引用列出其他文件中的使用处
+        app.handle_key(Key::Char('/'));
+        for c in "login".chars() {
+            app.handle_key(Key::Char(c));
+        }
+        app.handle_key(Key::Enter);
+        assert_eq!(app.focus, Pane::Outline);
+        assert_eq!(app.outline[app.outline_idx].name, "login");
+        let refs: Vec<(String, u32)> = app
+            .references
+            .iter()
+            .map(|r| (r.file.clone(), r.line))
+            .collect();
+        assert_eq!(
+            refs,
+            vec![
+                ("src/api/routes.ts".to_string(), 7),
+                ("src/api/routes.ts".to_string(), 9)
+            ]
+        );
+
+        app.handle_key(Key::Tab);
+        app.handle_key(Key::Down);
+        assert_eq!(
+            app.handle_key(Key::Enter),
+            Action::Edit {
+                file: "src/api/routes.ts".into(),
+                line: 9
+            }
+        );
+
+        app.handle_key(Key::Char('i'));
+        assert!(app.impact.as_ref().unwrap()[0].starts_with("src/api/routes.ts"));
+        app.handle_key(Key::Esc);
+        assert!(app.impact.is_none());
+        assert_eq!(app.handle_key(Key::Char('q')), Action::Quit);
+    }
+
+    #[test]
+    fn test_render_and_editor_command() {
+        let app = App::new(sample_graph());
+        let canvas = app.render(100, 20);
+        assert!(canvas.row_text(0).contains("demo · 2 modules · 2 files"));
+        assert!(canvas.row_text(2).starts_with("api"));
+        assert_eq!(canvas.row_runs(2)[0].0, Style::Selected);
+        let body: Vec<String> = (0..20).map(|y| canvas.row_text(y)).collect();
+        assert!(body.iter().any(|l| l.contains("Depends on (1)")));
+        assert!(body.iter().any(|l| l.contains("ƒ handleRequest  :1")));
+
+        // 宽字符占两列
+        let mut c = Canvas::new(6, 1);
+        assert_eq!(c.put(0, 0, "模块abc", 6, Style::Normal), 6);
+        assert_eq!(c.row_text(0), "模块ab");
+
+        assert_eq!(
+            editor_command("vim", "/p/a.rs", 12),
+            vec!["vim", "+12", "/p/a.rs"]
+        );
+        assert_eq!(
+            editor_command("code --wait", "/p/a.rs", 12),
+            vec!["code", "--wait", "-g", "/p/a.rs:12"]
+        );
+        assert_eq!(
+            editor_command("", "/p/a.rs", 3),
+            vec!["vi", "+3", "/p/a.rs"]
+        );
+    }
+}
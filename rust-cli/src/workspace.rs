@@ -0,0 +1,463 @@
+/// 多仓库联邦（workspace）
+///
+/// workspace 文件列出多个已扫描（含 .codemap/）的仓库。
+/// 加载后将各仓库的外部 import 按包名（Go module 路径、npm 包名、Maven 坐标、
+/// crate 名、Python 包名）解析到其他仓库的文件，并合成一张联邦图谱：
+/// 模块名与文件路径统一加上 `repo:` 前缀，跨仓库 import 作为模块依赖边注入，
+/// 因此 query / impact / path 可直接复用单仓库的实现。
+use serde::{Deserialize, Serialize};
+use std::path::{Path, PathBuf};
+
+use crate::graph::{create_empty_graph, CodeGraph, FileEntry, ModuleEntry};
+use crate::manifest::detect_package_aliases;
+use crate::path_utils::{posix_dirname, strip_extension};
+
+/// 默认 workspace 文件名
+pub const WORKSPACE_FILE: &str = "codemap.workspace.json";
+
+// ── 数据结构 ──────────────────────────────────────────────────────────────────
+
+/// workspace 文件格式
+///
+/// ```json
+/// { "repos": [ { "name": "api", "path": "../api", "packages": ["github.com/acme/api"] } ] }
+/// ```
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct WorkspaceFile {
+    pub repos: Vec<RepoSpec>,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct RepoSpec {
+    /// 结果前缀使用的仓库名
+    pub name: String,
+    /// 仓库根目录（相对 workspace 文件所在目录）
+    pub path: String,
+    /// 其他仓库 import 本仓库时使用的包名；为空时从清单文件自动检测
+    #[serde(default)]
+    pub packages: Vec<String>,
+}
+
+/// 已加载的仓库
+#[derive(Debug, Clone)]
+pub struct Repo {
+    pub name: String,
+    pub root: PathBuf,
+    pub packages: Vec<String>,
+    pub graph: CodeGraph,
+}
+
+/// 一条跨仓库 import
+#[derive(Debug, Clone, Serialize)]
+pub struct CrossRef {
+    #[serde(rename = "fromRepo")]
+    pub from_repo: String,
+    #[serde(rename = "fromFile")]
+    pub from_file: String,
+    #[serde(rename = "fromModule")]
+    pub from_module: String,
+    pub source: String,
+    pub line: u32,
+    #[serde(rename = "toRepo")]
+    pub to_repo: String,
+    #[serde(rename = "toFile")]
+    pub to_file: String,
+    #[serde(rename = "toModule")]
+    pub to_module: String,
+}
+
+/// 多仓库联邦
+#[derive(Debug, Clone)]
+pub struct Federation {
+    pub repos: Vec<Repo>,
+    pub cross_refs: Vec<CrossRef>,
+}
+
+// ── 公共函数 ──────────────────────────────────────────────────────────────────
+
+impl Federation {
+    /// 读取 workspace 文件并加载各仓库的图谱
+    pub fn load(workspace_file: &Path) -> anyhow::Result<Self> {
+        let text = std::fs::read_to_string(workspace_file).map_err(|e| {
+            anyhow::anyhow!(
+                "cannot read workspace file {}: {}",
+                workspace_file.display(),
+                e
+            )
+        })?;
+        let spec: WorkspaceFile = serde_json::from_str(&text)?;
+        let base_dir = workspace_file.parent().unwrap_or(Path::new("."));
+
+        let mut repos = Vec::new();
+        for repo in &spec.repos {
+            if repo.name.contains(':') {
+                anyhow::bail!("repo name '{}' must not contain ':'", repo.name);
+            }
+            let root = base_dir.join(&repo.path);
+            let graph = CodeGraph::load(&root.join(".codemap"))
+                .map_err(|e| anyhow::anyhow!("repo '{}': {}", repo.name, e))?;
+            let packages = if repo.packages.is_empty() {
+                detect_package_aliases(&root)
+            } else {
+                repo.packages.clone()
+            };
+            repos.push(Repo {
+                name: repo.name.clone(),
+                root,
+                packages,
+                graph,
+            });
+        }
+        Ok(Self::from_repos(repos))
+    }
+
+    /// 由已加载的仓库构建联邦（解析跨仓库 import）
+    pub fn from_repos(repos: Vec<Repo>) -> Self {
+        let cross_refs = resolve_cross_refs(&repos);
+        Self { repos, cross_refs }
+    }
+
+    /// 合成联邦图谱：模块名与文件路径加 `repo:` 前缀，并注入跨仓库依赖边
+    pub fn merged_graph(&self) -> CodeGraph {
+        let mut merged = create_empty_graph("workspace", "");
+
+        for repo in &self.repos {
+            let g = &repo.graph;
+            for (path, file) in &g.files {
+                let mut entry = file.clone();
+                entry.module = qualify(&repo.name, &file.module);
+                merged.files.insert(qualify(&repo.name, path), entry);
+            }
+            for (name, module) in &g.modules {
+                merged.modules.insert(
+                    qualify(&repo.name, name),
+                    ModuleEntry {
+                        files: qualify_all(&repo.name, &module.files),
+                        depends_on: qualify_all(&repo.name, &module.depends_on),
+                        depended_by: qualify_all(&repo.name, &module.depended_by),
+                    },
+                );
+            }
+            merged.summary.total_functions += g.summary.total_functions;
+            merged.summary.total_classes += g.summary.total_classes;
+            merged.summary.total_variables += g.summary.total_variables;
+            for (lang, count) in &g.summary.languages {
+                *merged.summary.languages.entry(lang.clone()).or_insert(0) += count;
+            }
+            merged
+                .summary
+                .entry_points
+                .extend(qualify_all(&repo.name, &g.summary.entry_points));
+        }
+
+        // 跨仓库依赖边
+        for cr in &self.cross_refs {
+            let from = qualify(&cr.from_repo, &cr.from_module);
+            let to = qualify(&cr.to_repo, &cr.to_module);
+            if let Some(m) = merged.modules.get_mut(&from) {
+                insert_sorted(&mut m.depends_on, &to);
+            }
+            if let Some(m) = merged.modules.get_mut(&to) {
+                insert_sorted(&mut m.depended_by, &from);
+            }
+        }
+
+        merged.summary.total_files = merged.files.len() as u32;
+        merged.summary.modules = merged.modules.keys().cloned().collect();
+        merged.summary.entry_points.sort();
+        merged.config.languages = merged.summary.languages.keys().cloned().collect();
+        merged
+    }
+}
+
+/// 在联邦图谱中定位模块：支持 `repo:module`，或在仓库间唯一的裸模块名
+pub fn resolve_module(merged: &CodeGraph, name: &str) -> Option<String> {
+    if merged.modules.contains_key(name) {
+        return Some(name.to_string());
+    }
+    let suffix = format!(":{}", name);
+    let matches: Vec<&String> = merged
+        .modules
+        .keys()
+        .filter(|m| m.ends_with(&suffix))
+        .collect();
+    match matches.as_slice() {
+        [only] => Some((*only).clone()),
+        _ => None,
+    }
+}
+
+/// 仓库名前缀
+pub fn qualify(repo: &str, name: &str) -> String {
+    format!("{}:{}", repo, name)
+}
+
+// ── 跨仓库 import 解析 ────────────────────────────────────────────────────────
+
+fn resolve_cross_refs(repos: &[Repo]) -> Vec<CrossRef> {
+    let mut refs = Vec::new();
+    for (ai, from) in repos.iter().enumerate() {
+        for (file_path, file) in &from.graph.files {
+            for imp in file.imports.iter().filter(|i| i.is_external) {
+                for (bi, to) in repos.iter().enumerate() {
+                    if ai == bi {
+                        continue;
+                    }
+                    let Some(rest) = to
+                        .packages
+                        .iter()
+                        .find_map(|p| strip_package(&imp.source, p))
+                    else {
+                        continue;
+                    };
+                    for (to_file, to_module) in resolve_in_repo(&to.graph, &rest, &imp.symbols) {
+                        refs.push(CrossRef {
+                            from_repo: from.name.clone(),
+                            from_file: file_path.clone(),
+                            from_module: file.module.clone(),
+                            source: imp.source.clone(),
+                            line: imp.import_line,
+                            to_repo: to.name.clone(),
+                            to_file,
+                            to_module,
+                        });
+                    }
+                }
+            }
+        }
+    }
+    refs
+}
+
+/// 若 import 源以包名开头，返回剩余部分（统一为 `/` 分隔）
+///
+/// Maven 坐标 `groupId:artifactId` 按 groupId 匹配 Java 包路径。
+fn strip_package(source: &str, package: &str) -> Option<String> {
+    let package = package.split(':').next().unwrap_or(package);
+    if package.is_empty() {
+        return None;
+    }
+    if source == package {
+        return Some(String::new());
+    }
+    for sep in ["/", "::", "."] {
+        if let Some(rest) = source
+            .strip_prefix(package)
+            .and_then(|r| r.strip_prefix(sep))
+        {
+            return Some(match sep {
+                "/" => rest.to_string(),
+                _ => rest.replace(sep, "/"),
+            });
+        }
+    }
+    None
+}
+
+/// 将包内路径与导入符号解析为目标仓库中的 (文件, 模块)
+///
+/// 先按路径匹配文件或目录（Go 包即目录），再用导入符号收窄到定义该符号的文件；
+/// 导入包根且没有符号时无法定位到文件，返回空。
+fn resolve_in_repo(graph: &CodeGraph, rest: &str, symbols: &[String]) -> Vec<(String, String)> {
+    let mut candidates: Vec<(&String, &FileEntry)> = if rest.is_empty() {
+        graph.files.iter().collect()
+    } else {
+        graph
+            .files
+            .iter()
+            .filter(|(path, _)| path_matches(path, rest))
+            .collect()
+    };
+
+    if !symbols.is_empty() {
+        let defining: Vec<(&String, &FileEntry)> = candidates
+            .iter()
+            .copied()
+            .filter(|(_, f)| symbols.iter().any(|s| defines(f, s)))
+            .collect();
+        if !defining.is_empty() {
+            candidates = defining;
+        } else if rest.is_empty() {
+            candidates.clear();
+        }
+    } else if rest.is_empty() {
+        candidates.clear();
+    }
+
+    candidates
+        .into_iter()
+        .map(|(p, f)| (p.clone(), f.module.clone()))
+        .collect()
+}
+
+fn path_matches(path: &str, rest: &str) -> bool {
+    let stem = strip_extension(path);
+    let dir = posix_dirname(path);
+    let tail = format!("/{}", rest);
+    stem == rest || stem.ends_with(&tail) || dir == rest || dir.ends_with(&tail)
+}
+
+fn defines(file: &FileEntry, symbol: &str) -> bool {
+    file.exports.iter().any(|e| e == symbol)
+        || file.functions.iter().any(|f| f.name == symbol)
+        || file.classes.iter().any(|c| c.name == symbol)
+        || file.types.iter().any(|t| t.name == symbol)
+        || file.variables.iter().any(|v| v.name == symbol)
+}
+
+fn qualify_all(repo: &str, names: &[String]) -> Vec<String> {
+    names.iter().map(|n| qualify(repo, n)).collect()
+}
+
+fn insert_sorted(list: &mut Vec<String>, value: &str) {
+    if let Err(pos) = list.binary_search_by(|v| v.as_str().cmp(value)) {
+        list.insert(pos, value.to_string());
+    }
+}
+
+// ── 测试 ──────────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::differ::merge_graph_update;
+    use crate::graph::{FunctionInfo, ImportInfo};
+    use std::collections::HashMap;
+
+    fn file(module: &str, func: &str, imports: Vec<ImportInfo>) -> FileEntry {
+        FileEntry {
+            language: "go".to_string(),
+            module: module.to_string(),
+            hash: "sha256:0000000000000000".to_string(),
+            lines: 10,
+            functions: vec![FunctionInfo {
+                name: func.to_string(),
+                signature: format!("{}()", func),
+                start_line: 1,
+                end_line: 3,
+            }],
+            imports,
+            exports: vec![func.to_string()],
+            ..Default::default()
+        }
+    }
+
+    fn repo(name: &str, packages: &[&str], files: Vec<(&str, FileEntry)>) -> Repo {
+        let mut graph = create_empty_graph(name, "");
+        let updated: HashMap<String, FileEntry> =
+            files.into_iter().map(|(p, f)| (p.to_string(), f)).collect();
+        merge_graph_update(&mut graph, updated, &[]);
+        Repo {
+            name: name.to_string(),
+            root: PathBuf::from(name),
+            packages: packages.iter().map(|p| p.to_string()).collect(),
+            graph,
+        }
+    }
+
+    fn ext_import(source: &str, symbols: &[&str]) -> ImportInfo {
+        ImportInfo {
+            source: source.to_string(),
+            symbols: symbols.iter().map(|s| s.to_string()).collect(),
+            is_external: true,
+            import_line: 2,
+        }
+    }
+
+    fn federation() -> Federation {
+        let api = repo(
+            "api",
+            &["github.com/acme/api"],
+            vec![
+                ("auth/login.go", file("auth", "Login", vec![])),
+                ("billing/pay.go", file("billing", "Pay", vec![])),
+            ],
+        );
+        let web = repo(
+            "web",
+            &["@acme/web"],
+            vec![(
+                "src/pages/home.ts",
+                file(
+                    "pages",
+                    "Home",
+                    vec![ext_import("github.com/acme/api/auth", &["auth"])],
+                ),
+            )],
+        );
+        Federation::from_repos(vec![api, web])
+    }
+
+    #[test]
+    fn test_strip_package() {
+        assert_eq!(
+            strip_package("github.com/acme/api/auth", "github.com/acme/api").as_deref(),
+            Some("auth")
+        );
+        assert_eq!(strip_package("@acme/ui", "@acme/ui").as_deref(), Some(""));
+        assert_eq!(
+            strip_package("com.acme.lib", "com.acme:lib").as_deref(),
+            Some("lib")
+        );
+        assert_eq!(
+            strip_package("acme_core::auth", "acme_core").as_deref(),
+            Some("auth")
+        );
+        assert_eq!(
+            strip_package("github.com/acme/apix", "github.com/acme/api"),
+            None
+        );
+    }
+
+    #[test]
+    fn test_resolve_cross_refs_go_package_dir() {
+        let fed = federation();
+        assert_eq!(fed.cross_refs.len(), 1);
+        let cr = &fed.cross_refs[0];
+        assert_eq!(cr.from_repo, "web");
+        assert_eq!(cr.to_repo, "api");
+        assert_eq!(cr.to_file, "auth/login.go");
+        assert_eq!(cr.to_module, "auth");
+    }
+
+    #[test]
+    fn test_resolve_in_repo_narrows_by_symbol() {
+        let api = federation().repos.remove(0);
+        let hits = resolve_in_repo(&api.graph, "", &["Pay".to_string()]);
+        assert_eq!(
+            hits,
+            vec![("billing/pay.go".to_string(), "billing".to_string())]
+        );
+        assert!(resolve_in_repo(&api.graph, "", &[]).is_empty());
+    }
+
+    #[test]
+    fn test_merged_graph_prefixes_and_links_repos() {
+        let merged = federation().merged_graph();
+        assert!(merged.files.contains_key("api:auth/login.go"));
+        assert_eq!(merged.files["web:src/pages/home.ts"].module, "web:pages");
+        assert_eq!(merged.modules["web:pages"].depends_on, vec!["api:auth"]);
+        assert_eq!(merged.modules["api:auth"].depended_by, vec!["web:pages"]);
+        assert_eq!(merged.summary.total_files, 3);
+
+        let impact = crate::impact::analyze_impact(&merged, "api:auth", 3);
+        assert_eq!(impact.direct_dependants, vec!["web:pages"]);
+        assert!(impact
+            .impacted_files
+            .contains(&"web:src/pages/home.ts".to_string()));
+    }
+
+    #[test]
+    fn test_resolve_module() {
+        let merged = federation().merged_graph();
+        assert_eq!(
+            resolve_module(&merged, "billing").as_deref(),
+            Some("api:billing")
+        );
+        assert_eq!(
+            resolve_module(&merged, "web:pages").as_deref(),
+            Some("web:pages")
+        );
+        assert_eq!(resolve_module(&merged, "missing"), None);
+    }
+}
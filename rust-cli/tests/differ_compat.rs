@@ -3,7 +3,7 @@
 /// 移植自 ccplugin/cli/test/differ.test.js
 use codegraph::differ::{detect_changed_files, merge_graph_update};
 use codegraph::graph::{CodeGraph, FileEntry, GraphConfig, GraphSummary, ModuleEntry, ProjectInfo};
-use std::collections::HashMap;
+use std::collections::{BTreeMap, HashMap};
 
 fn make_file_entry(
     module: &str,
@@ -31,17 +31,12 @@ fn make_file_entry(
                 end_line: 5,
             })
             .collect(),
-        types: vec![],
-        variables: vec![],
-        imports: vec![],
-        exports: vec![],
-        is_entry_point: false,
-        symbol_refs: std::collections::BTreeMap::new(),
+        ..Default::default()
     }
 }
 
 fn make_graph() -> CodeGraph {
-    let mut files = HashMap::new();
+    let mut files = BTreeMap::new();
     files.insert(
         "src/auth/login.ts".to_string(),
         make_file_entry("auth", "sha256:aaa", 1, 0),
@@ -55,7 +50,7 @@ fn make_graph() -> CodeGraph {
         make_file_entry("old", "sha256:ccc", 0, 0),
     );
 
-    let mut modules = HashMap::new();
+    let mut modules = BTreeMap::new();
     modules.insert(
         "auth".to_string(),
         ModuleEntry {
@@ -92,6 +87,9 @@ fn make_graph() -> CodeGraph {
         config: GraphConfig {
             languages: vec![],
             exclude_patterns: vec![],
+            follow_symlinks: false,
+            submodules: Default::default(),
+            submodule_paths: vec![],
         },
         summary: GraphSummary {
             total_files: 3,
@@ -104,6 +102,10 @@ fn make_graph() -> CodeGraph {
         },
         modules,
         files,
+        packages: BTreeMap::new(),
+        broken_imports: vec![],
+        binding_edges: vec![],
+        targets: vec![],
     }
 }
 
@@ -7,7 +7,7 @@ use codegraph::languages::{self, get_adapter};
 use codegraph::query::{query_symbol, QueryOptions};
 use codegraph::scanner::{convert_functions, convert_imports, convert_variables};
 use codegraph::traverser::Language;
-use std::collections::HashMap;
+use std::collections::BTreeMap;
 
 // ── 辅助函数 ──────────────────────────────────────────────────────────────────
 
@@ -383,7 +383,7 @@ fn make_variable_graph() -> CodeGraph {
     let functions = convert_functions(&lang_fns);
     let imports = convert_imports(&lang_imports);
 
-    let mut files = HashMap::new();
+    let mut files = BTreeMap::new();
     files.insert(
         "src/app.ts".to_string(),
         FileEntry {
@@ -392,17 +392,14 @@ fn make_variable_graph() -> CodeGraph {
             hash: "sha256:test".into(),
             lines: 12,
             functions,
-            classes: vec![],
-            types: vec![],
             variables,
             imports,
             exports: vec!["MAX_RETRIES".into(), "handler".into(), "login".into()],
-            is_entry_point: false,
-            symbol_refs: std::collections::BTreeMap::new(),
+            ..Default::default()
         },
     );
 
-    let mut modules = HashMap::new();
+    let mut modules = BTreeMap::new();
     modules.insert(
         "app".into(),
         ModuleEntry {
@@ -423,18 +420,25 @@ fn make_variable_graph() -> CodeGraph {
         config: GraphConfig {
             languages: vec![],
             exclude_patterns: vec![],
+            follow_symlinks: false,
+            submodules: Default::default(),
+            submodule_paths: vec![],
         },
         summary: GraphSummary {
             total_files: 1,
             total_functions: 2,
             total_classes: 0,
             total_variables: 3,
-            languages: HashMap::new(),
+            languages: BTreeMap::new(),
             modules: vec!["app".into()],
             entry_points: vec![],
         },
         modules,
         files,
+        packages: BTreeMap::new(),
+        broken_imports: vec![],
+        binding_edges: vec![],
+        targets: vec![],
     }
 }
 